@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"instant-messaging-app/api/handlers"
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/utils"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// AdminListUsers lists users with optional query, role and status filters
+func AdminListUsers(userRepo repositories.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, pageSize := utils.NormalizePagination(c.QueryInt("page", 1), c.QueryInt("page_size", utils.DefaultPageSize))
+
+		users, total, err := services.ListUsersForAdmin(c.UserContext(), userRepo, c.Query("query"), c.Query("role"), c.Query("status"), page, pageSize)
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve users")
+		}
+
+		return c.JSON(fiber.Map{
+			"users":     dtos.ToAdminUserDTOs(users),
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		})
+	}
+}
+
+// AdminDeactivateUser deactivates an account and revokes its sessions
+func AdminDeactivateUser(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return adminUpdateUser(c, repos, services.DeactivateUser)
+	}
+}
+
+// AdminReactivateUser reactivates a deactivated account
+func AdminReactivateUser(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return adminUpdateUser(c, repos, services.ReactivateUser)
+	}
+}
+
+// AdminForceLogout revokes every session of a user
+func AdminForceLogout(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return adminUpdateUser(c, repos, services.ForceLogout)
+	}
+}
+
+// AdminSetUserRole changes the role of a user
+func AdminSetUserRole(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Request struct {
+			Role string `json:"role"`
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return responses.Error(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		if !models.IsValidRole(req.Role) {
+			return responses.Error(c, fiber.StatusBadRequest, "Role must be one of admin, moderator or user")
+		}
+
+		return adminUpdateUser(c, repos, func(ctx context.Context, repos repositories.Repositories, audit services.AuditContext, userID uint) (models.User, error) {
+			return services.SetUserRole(ctx, repos, audit, userID, req.Role)
+		})
+	}
+}
+
+// AdminGetStats reports user, message and connection counters
+func AdminGetStats(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		stats, err := services.GetSystemStats(c.UserContext(), repos)
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to compute stats")
+		}
+
+		return c.JSON(fiber.Map{
+			"users":              stats.Users,
+			"active_users":       stats.ActiveUsers,
+			"deactivated_users":  stats.DeactivatedUsers,
+			"admins":             stats.Admins,
+			"moderators":         stats.Moderators,
+			"messages":           stats.Messages,
+			"messages_last_24h":  stats.MessagesLast24h,
+			"active_connections": handlers.ActiveConnections(),
+		})
+	}
+}
+
+// AdminListAuditLogs lists audit log entries, newest first
+func AdminListAuditLogs(audits repositories.AuditRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, pageSize := utils.NormalizePagination(c.QueryInt("page", 1), c.QueryInt("page_size", utils.DefaultPageSize))
+
+		logs, total, err := services.ListAuditLogs(c.UserContext(), audits, uint(c.QueryInt("actor_id", 0)), c.Query("action"), page, pageSize)
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve audit logs")
+		}
+
+		return c.JSON(fiber.Map{
+			"audit_logs": logs,
+			"page":       page,
+			"page_size":  pageSize,
+			"total":      total,
+		})
+	}
+}
+
+// adminUpdateUser parses the target user ID, runs action on behalf of the
+// current admin and renders the updated user
+func adminUpdateUser(c *fiber.Ctx, repos repositories.Repositories, action func(context.Context, repositories.Repositories, services.AuditContext, uint) (models.User, error)) error {
+	targetUserID, err := strconv.Atoi(c.Params("userId"))
+	if err != nil || targetUserID <= 0 {
+		return responses.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	claims := c.Locals("claims").(utils.Claims)
+	audit := services.AuditContext{
+		ActorID:   claims.UserID,
+		ActorRole: claims.Role,
+		IP:        c.IP(),
+	}
+
+	user, err := action(c.UserContext(), repos, audit, uint(targetUserID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return responses.Error(c, fiber.StatusNotFound, "User not found")
+	}
+	if err != nil {
+		return requestError(c, err, "Failed to update the user")
+	}
+
+	return c.JSON(dtos.ToAdminUserDTO(user))
+}
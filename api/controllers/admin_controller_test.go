@@ -0,0 +1,74 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/models"
+)
+
+func TestAdminUsers(t *testing.T) {
+	ctx := context.Background()
+	admin, api := newUser(t, "admin", models.RoleAdmin)
+	bob, bobAPI := newUser(t, "bob", models.RoleUser)
+	before, err := api.AdminGetStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each action is applied and counted by the stats
+	if user, err := api.AdminSetUserRole(ctx, uint64(bob.ID), client.RoleChange{Role: models.RoleModerator}); err != nil || user.Role != models.RoleModerator {
+		t.Fatalf("unexpected role change %+v (%v)", user, err)
+	}
+	if user, err := api.AdminDeactivateUser(ctx, uint64(bob.ID)); err != nil || user.DeactivatedAt == nil {
+		t.Fatalf("unexpected deactivation %+v (%v)", user, err)
+	}
+	_, err = api.AdminDeactivateUser(ctx, uint64(bob.ID))
+	expectStatus(t, err, http.StatusBadRequest, "a second deactivation")
+	if err.(*client.Error).Message != "user is already deactivated" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	after, err := api.AdminGetStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Moderators != before.Moderators+1 || after.DeactivatedUsers != before.DeactivatedUsers+1 || after.Users != before.Users {
+		t.Fatalf("unexpected stats %+v, before %+v", after, before)
+	}
+	if user, err := api.AdminReactivateUser(ctx, uint64(bob.ID)); err != nil || user.DeactivatedAt != nil {
+		t.Fatalf("unexpected reactivation %+v (%v)", user, err)
+	}
+
+	// A forced logout revokes the token of bob
+	if _, err := api.AdminForceLogout(ctx, uint64(bob.ID)); err != nil {
+		t.Fatal(err)
+	}
+	_, err = bobAPI.ListBlockedUsers(ctx)
+	expectStatus(t, err, http.StatusUnauthorized, "a revoked token")
+
+	_, err = api.AdminDeactivateUser(ctx, uint64(admin.ID))
+	expectStatus(t, err, http.StatusBadRequest, "deactivating oneself")
+	_, err = api.AdminForceLogout(ctx, uint64(bob.ID)+1000)
+	expectStatus(t, err, http.StatusNotFound, "an unknown user")
+
+	// Every action was audited, newest first
+	logs, err := api.AdminListAuditLogs(ctx, client.AdminListAuditLogsParams{ActorID: uint64(admin.ID)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"user.force_logout", "user.reactivate", "user.deactivate", "user.set_role"}
+	if logs.Total != int64(len(expected)) || len(logs.AuditLogs) != len(expected) {
+		t.Fatalf("expected %d audit log entries, got %+v", len(expected), logs)
+	}
+	for i, action := range expected {
+		if entry := logs.AuditLogs[i]; entry.Action != action || entry.TargetID != uint64(bob.ID) {
+			t.Fatalf("unexpected entry %d %+v, expected %s", i, entry, action)
+		}
+	}
+	filtered, err := api.AdminListAuditLogs(ctx, client.AdminListAuditLogsParams{ActorID: uint64(admin.ID), Action: "user.set_role"})
+	if err != nil || filtered.Total != 1 || filtered.AuditLogs[0].Details != `{"previous_role":"user","role":"moderator"}` {
+		t.Fatalf("unexpected filtered audit log %+v (%v)", filtered, err)
+	}
+}
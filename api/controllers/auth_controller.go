@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"instant-messaging-app/api/responses"
 	"instant-messaging-app/api/services"
 	"instant-messaging-app/utils"
 
@@ -11,39 +12,32 @@ import (
 func Register(c *fiber.Ctx) error {
 	// Define the request body structure
 	type Request struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Username    string `json:"username"`
+		Password    string `json:"password"`
+		DisplayName string `json:"display_name"`
 	}
 
 	// Parse the request body
 	var req Request
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return responses.Error(c, fiber.StatusBadRequest, "Invalid request body")
 	}
 
 	// Basic validation
 	if req.Username == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Username is required",
-		})
+		return responses.Error(c, fiber.StatusBadRequest, "Username is required")
 	}
 	if len(req.Password) < 6 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Password must be at least 6 characters long",
-		})
+		return responses.Error(c, fiber.StatusBadRequest, "Password must be at least 6 characters long")
 	}
 
 	// Generate a UUID for WebSocket tracking
 	uuid := utils.GenerateUUID()
 
 	// Publish the registration request to RabbitMQ
-	err := services.PublishRegistrationRequest(uuid, req.Username, req.Password)
+	err := services.PublishRegistrationRequest(c.UserContext(), uuid, req.Username, req.Password, req.DisplayName)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process registration",
-		})
+		return responses.Error(c, fiber.StatusInternalServerError, "Failed to process registration")
 	}
 
 	// Respond with the UUID for WebSocket tracking
@@ -64,32 +58,24 @@ func Login(c *fiber.Ctx) error {
 	// Parse the request body
 	var req Request
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return responses.Error(c, fiber.StatusBadRequest, "Invalid request body")
 	}
 
 	// Basic validation
 	if req.Username == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Username is required",
-		})
+		return responses.Error(c, fiber.StatusBadRequest, "Username is required")
 	}
 	if len(req.Password) < 6 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Password must be at least 6 characters long",
-		})
+		return responses.Error(c, fiber.StatusBadRequest, "Password must be at least 6 characters long")
 	}
 
 	// Generate a UUID for WebSocket tracking
 	uuid := utils.GenerateUUID()
 
 	// Publish the registration request to RabbitMQ
-	err := services.PublishLoginRequest(uuid, req.Username, req.Password)
+	err := services.PublishLoginRequest(c.UserContext(), uuid, req.Username, req.Password)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process login",
-		})
+		return responses.Error(c, fiber.StatusInternalServerError, "Failed to process login")
 	}
 
 	// Respond with the UUID for WebSocket tracking
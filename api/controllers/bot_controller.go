@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"errors"
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/utils"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ListBots lists the bots of the authenticated user
+func ListBots(users repositories.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := c.Locals("claims").(utils.Claims)
+
+		bots, err := services.ListBots(c.UserContext(), users, claims.UserID)
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve bots")
+		}
+		return c.JSON(fiber.Map{"bots": dtos.ToBotDTOs(bots)})
+	}
+}
+
+// CreateBot creates a bot owned by the authenticated user
+func CreateBot(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Request struct {
+			Username    string `json:"username"`
+			DisplayName string `json:"display_name"`
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return responses.Error(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+
+		bot, err := services.CreateBot(c.UserContext(), repos, auditContext(c), req.Username, req.DisplayName)
+		if err != nil {
+			return requestError(c, err, "Failed to create the bot")
+		}
+		return c.Status(fiber.StatusCreated).JSON(dtos.ToBotDTO(bot))
+	}
+}
+
+// DeactivateBot deactivates a bot, revokes its keys and disconnects it
+func DeactivateBot(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		botID, err := pathID(c, "botId", "bot ID")
+		if err != nil {
+			return err
+		}
+
+		bot, err := services.DeactivateBot(c.UserContext(), repos, auditContext(c), botID)
+		if err != nil {
+			return ownedError(c, err, "Bot not found", "Failed to deactivate the bot")
+		}
+		return c.JSON(dtos.ToBotDTO(bot))
+	}
+}
+
+// ListAPIKeys lists the keys of a bot, without their secrets
+func ListAPIKeys(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		botID, err := pathID(c, "botId", "bot ID")
+		if err != nil {
+			return err
+		}
+		claims := c.Locals("claims").(utils.Claims)
+
+		keys, err := services.ListAPIKeys(c.UserContext(), repos, claims.UserID, botID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.Error(c, fiber.StatusNotFound, "Bot not found")
+		}
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve API keys")
+		}
+		return c.JSON(fiber.Map{"api_keys": dtos.ToAPIKeyDTOs(keys)})
+	}
+}
+
+// CreateAPIKey issues a key to a bot. The response is the only one that
+// carries the key itself.
+func CreateAPIKey(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Request struct {
+			Name      string   `json:"name"`
+			Scopes    []string `json:"scopes"`
+			RateLimit int      `json:"rate_limit"`
+			// ExpiresIn is the lifetime of the key in seconds, 0 for no expiry
+			ExpiresIn int64 `json:"expires_in"`
+		}
+
+		botID, err := pathID(c, "botId", "bot ID")
+		if err != nil {
+			return err
+		}
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return responses.Error(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+
+		created, err := services.CreateAPIKey(c.UserContext(), repos, auditContext(c), botID, req.Name, req.Scopes, req.RateLimit, time.Duration(req.ExpiresIn)*time.Second)
+		if err != nil {
+			return ownedError(c, err, "Bot not found", "Failed to create the API key")
+		}
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"api_key": dtos.ToAPIKeyDTO(created.Key),
+			"key":     created.Secret,
+		})
+	}
+}
+
+// RevokeAPIKey revokes a key of a bot and disconnects its sessions
+func RevokeAPIKey(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		botID, err := pathID(c, "botId", "bot ID")
+		if err != nil {
+			return err
+		}
+		keyID, err := pathID(c, "keyId", "API key ID")
+		if err != nil {
+			return err
+		}
+
+		key, err := services.RevokeAPIKey(c.UserContext(), repos, auditContext(c), botID, keyID)
+		if err != nil {
+			return ownedError(c, err, "API key not found", "Failed to revoke the API key")
+		}
+		return c.JSON(dtos.ToAPIKeyDTO(key))
+	}
+}
+
+// auditContext identifies the authenticated user as the actor of a change
+func auditContext(c *fiber.Ctx) services.AuditContext {
+	claims := c.Locals("claims").(utils.Claims)
+	return services.AuditContext{
+		ActorID:   claims.UserID,
+		ActorRole: claims.Role,
+		IP:        c.IP(),
+	}
+}
+
+// pathID parses a positive ID path parameter, described by label in errors
+func pathID(c *fiber.Ctx, name, label string) (uint, error) {
+	id, err := strconv.Atoi(c.Params(name))
+	if err != nil || id <= 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "Invalid "+label)
+	}
+	return uint(id), nil
+}
+
+// ownedError answers 404 for the resources the user does not own, and
+// handles the other failures like requestError
+func ownedError(c *fiber.Ctx, err error, notFound, message string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return responses.Error(c, fiber.StatusNotFound, notFound)
+	}
+	return requestError(c, err, message)
+}
+
+// requestError answers 400 with the message of a validation error of the
+// services. The other failures are logged and answer 500 with message, so
+// that the errors of the database are not shown to the client.
+func requestError(c *fiber.Ctx, err error, message string) error {
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		return responses.Error(c, fiber.StatusBadRequest, validationErr.Message)
+	}
+	slog.ErrorContext(c.UserContext(), message, "error", err)
+	return responses.Error(c, fiber.StatusInternalServerError, message)
+}
@@ -0,0 +1,141 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/models"
+)
+
+func TestBotAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	alice, owner := newUser(t, "alice", models.RoleUser)
+	_, bobAPI := newUser(t, "bob", models.RoleUser)
+
+	bot, err := owner.CreateBot(ctx, client.CreateBotRequest{Username: alice.Username + "-bot", DisplayName: "Alice's bot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := repos.Users.FindBot(ctx, alice.ID, uint(bot.ID)); err != nil || !stored.IsBot() {
+		t.Fatalf("unexpected bot %+v (%v)", stored, err)
+	}
+	newKey := func(name string, rateLimit int, scopes ...string) (*client.Client, client.APIKey) {
+		t.Helper()
+		created, err := owner.CreateAPIKey(ctx, bot.ID, client.CreateAPIKeyRequest{Name: name, Scopes: scopes, RateLimit: rateLimit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return newClient(created.Key), created.APIKey
+	}
+
+	t.Run("scopes", func(t *testing.T) {
+		// The scheduled messages need messages:send
+		sender, _ := newKey("sender", 0, "messages:send")
+		if _, err := sender.ListScheduledMessages(ctx, client.ListScheduledMessagesParams{}); err != nil {
+			t.Fatal(err)
+		}
+		reader, _ := newKey("reader", 0, "users:read")
+		_, err := reader.ListScheduledMessages(ctx, client.ListScheduledMessagesParams{})
+		expectStatus(t, err, http.StatusForbidden, "scheduling without messages:send")
+		// The commands are listed and answered with messages:send
+		if _, err := sender.ListCommands(ctx, client.ListCommandsParams{}); err != nil {
+			t.Fatal(err)
+		}
+		_, err = reader.ListCommands(ctx, client.ListCommandsParams{})
+		expectStatus(t, err, http.StatusForbidden, "listing commands without messages:send")
+		_, err = reader.RespondToCommand(ctx, 1, client.CommandAnswer{Text: "hi"})
+		expectStatus(t, err, http.StatusForbidden, "answering without messages:send")
+		// Keys do not manage bots nor their commands, nor reach the admin API
+		_, err = sender.ListBots(ctx)
+		expectStatus(t, err, http.StatusForbidden, "listing bots with a key")
+		_, err = sender.RegisterBotCommand(ctx, bot.ID, client.RegisterBotCommandRequest{Name: "deploy"})
+		expectStatus(t, err, http.StatusForbidden, "registering a command with a key")
+		_, err = sender.AdminGetStats(ctx)
+		expectStatus(t, err, http.StatusForbidden, "the admin API with a key")
+		_, err = newClient("imk_not-a-key").ListScheduledMessages(ctx, client.ListScheduledMessagesParams{})
+		expectStatus(t, err, http.StatusUnauthorized, "an unknown key")
+	})
+
+	t.Run("revocation", func(t *testing.T) {
+		sender, senderKey := newKey("revoked", 0, "messages:send")
+		if _, err := owner.RevokeAPIKey(ctx, bot.ID, senderKey.ID); err != nil {
+			t.Fatal(err)
+		}
+		_, err := sender.ListScheduledMessages(ctx, client.ListScheduledMessagesParams{})
+		expectStatus(t, err, http.StatusUnauthorized, "a revoked key")
+		_, err = bobAPI.RevokeAPIKey(ctx, bot.ID, senderKey.ID)
+		expectStatus(t, err, http.StatusNotFound, "the key of the bot of another user")
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		limited, _ := newKey("limited", 2, "messages:send")
+		for i := 0; i < 2; i++ {
+			if _, err := limited.ListScheduledMessages(ctx, client.ListScheduledMessagesParams{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, err := limited.ListScheduledMessages(ctx, client.ListScheduledMessagesParams{})
+		expectStatus(t, err, http.StatusTooManyRequests, "a request over the rate limit")
+	})
+
+	t.Run("deactivation", func(t *testing.T) {
+		// Usernames are unique and the bots of other users are not found
+		_, err := owner.CreateBot(ctx, client.CreateBotRequest{Username: bot.Username})
+		expectStatus(t, err, http.StatusBadRequest, "a taken username")
+		_, err = bobAPI.DeactivateBot(ctx, bot.ID)
+		expectStatus(t, err, http.StatusNotFound, "the bot of another user")
+
+		// Deactivating the bot revokes every key
+		sender, _ := newKey("last sender", 0, "messages:send")
+		deactivated, err := owner.DeactivateBot(ctx, bot.ID)
+		if err != nil || deactivated.DeactivatedAt == nil {
+			t.Fatalf("unexpected deactivation %+v (%v)", deactivated, err)
+		}
+		_, err = sender.ListScheduledMessages(ctx, client.ListScheduledMessagesParams{})
+		expectStatus(t, err, http.StatusUnauthorized, "a key of a deactivated bot")
+		keys, err := owner.ListAPIKeys(ctx, bot.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range keys.APIKeys {
+			if key.RevokedAt == nil {
+				t.Fatalf("the key %+v was not revoked", key)
+			}
+		}
+		_, err = owner.CreateAPIKey(ctx, bot.ID, client.CreateAPIKeyRequest{Name: "late", Scopes: []string{"users:read"}})
+		expectStatus(t, err, http.StatusBadRequest, "a key for a deactivated bot")
+	})
+}
+
+func TestBotAPIKeysOfDeactivatedOwners(t *testing.T) {
+	ctx := context.Background()
+	carol, owner := newUser(t, "carol", models.RoleUser)
+	bot, err := owner.CreateBot(ctx, client.CreateBotRequest{Username: carol.Username + "-bot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := owner.CreateAPIKey(ctx, bot.ID, client.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"messages:send"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := newClient(created.Key)
+
+	// The keys stop working while the owner is deactivated
+	setDeactivated := func(at *time.Time) {
+		t.Helper()
+		if err := repos.Users.Update(ctx, &carol, map[string]interface{}{"deactivated_at": at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	setDeactivated(&now)
+	_, err = sender.ListScheduledMessages(ctx, client.ListScheduledMessagesParams{})
+	expectStatus(t, err, http.StatusUnauthorized, "a key of the bot of a deactivated owner")
+	setDeactivated(nil)
+	if _, err := sender.ListScheduledMessages(ctx, client.ListScheduledMessagesParams{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ListCommands lists the slash commands whose name starts with the prefix
+// query parameter, for the autocomplete of the clients
+func ListCommands(commands repositories.CommandRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		listed, err := services.ListCommands(c.UserContext(), commands, c.Query("prefix"))
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve commands")
+		}
+		return c.JSON(fiber.Map{"commands": dtos.ToCommandDTOs(listed)})
+	}
+}
+
+// ListBotCommands lists the commands registered by a bot of the authenticated user
+func ListBotCommands(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		botID, err := pathID(c, "botId", "bot ID")
+		if err != nil {
+			return err
+		}
+		claims := c.Locals("claims").(utils.Claims)
+
+		commands, err := services.ListBotCommands(c.UserContext(), repos, claims.UserID, botID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.Error(c, fiber.StatusNotFound, "Bot not found")
+		}
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve commands")
+		}
+		return c.JSON(fiber.Map{"commands": dtos.ToBotCommandDTOs(commands)})
+	}
+}
+
+// RegisterBotCommand registers a slash command run by a bot
+func RegisterBotCommand(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Request struct {
+			Name        string `json:"name"`
+			Usage       string `json:"usage"`
+			Description string `json:"description"`
+		}
+
+		botID, err := pathID(c, "botId", "bot ID")
+		if err != nil {
+			return err
+		}
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return responses.Error(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+
+		command, err := services.RegisterBotCommand(c.UserContext(), repos, auditContext(c), botID, req.Name, req.Usage, req.Description)
+		if err != nil {
+			return ownedError(c, err, "Bot not found", "Failed to register the command")
+		}
+		return c.Status(fiber.StatusCreated).JSON(dtos.ToBotCommandDTO(command))
+	}
+}
+
+// DeleteBotCommand deletes a command of a bot
+func DeleteBotCommand(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		botID, err := pathID(c, "botId", "bot ID")
+		if err != nil {
+			return err
+		}
+		commandID, err := pathID(c, "commandId", "command ID")
+		if err != nil {
+			return err
+		}
+
+		command, err := services.DeleteBotCommand(c.UserContext(), repos, auditContext(c), botID, commandID)
+		if err != nil {
+			return ownedError(c, err, "Command not found", "Failed to delete the command")
+		}
+		return c.JSON(dtos.ToBotCommandDTO(command))
+	}
+}
+
+// RespondToCommand answers an invocation of a command of the authenticated
+// bot. The answer is shown to the invoker only, under the name of the bot,
+// and never stored: a message in the conversation would be attributed to
+// the invoker. An invocation is answered once.
+func RespondToCommand(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		invocationID, err := pathID(c, "invocationId", "invocation ID")
+		if err != nil {
+			return err
+		}
+		claims := c.Locals("claims").(utils.Claims)
+
+		var request struct {
+			Text string `json:"text"`
+		}
+		if err := c.BodyParser(&request); err != nil {
+			return responses.Error(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+
+		invocation, err := services.FindCommandInvocation(c.UserContext(), repos.Commands, claims.UserID, invocationID)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return responses.Error(c, fiber.StatusNotFound, "Invocation not found")
+		case errors.Is(err, services.ErrInvocationExpired):
+			return responses.Error(c, fiber.StatusGone, "The invocation expired")
+		case errors.Is(err, repositories.ErrInvocationAnswered):
+			return responses.Error(c, fiber.StatusConflict, "The invocation was already answered")
+		case err != nil:
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve the invocation")
+		}
+		bot, err := repos.Users.FindByID(c.UserContext(), claims.UserID)
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve the bot")
+		}
+		senderName := bot.DisplayName
+		if senderName == "" {
+			senderName = bot.Username
+		}
+
+		var response types.SendMessageResponse
+		err = services.Request(c.UserContext(), "send_message_response", &response, func(ctx context.Context, uuid string) error {
+			return services.PublishSendMessage(ctx, uuid, invocation.UserID, invocation.ReceiverID, request.Text, services.SendOptions{
+				SenderName:   senderName,
+				Reply:        true,
+				Verbatim:     true,
+				Ephemeral:    true,
+				InvocationID: invocation.ID,
+			})
+		})
+		if err != nil {
+			return serviceError(c, err, "Failed to send message")
+		}
+		return c.Status(fiber.StatusCreated).JSON(sentMessage{
+			Message:   response.Message,
+			Seq:       response.SenderSeq,
+			Ephemeral: response.Ephemeral,
+		})
+	}
+}
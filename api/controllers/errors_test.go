@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"instant-messaging-app/api/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func TestOwnedError(t *testing.T) {
+	app := fiber.New()
+	app.Get("/:case", func(c *fiber.Ctx) error {
+		switch c.Params("case") {
+		case "invalid":
+			return ownedError(c, fmt.Errorf("creating: %w", &services.ValidationError{Message: "name is required"}), "Bot not found", "Failed to create")
+		case "missing":
+			return ownedError(c, gorm.ErrRecordNotFound, "Bot not found", "Failed to create")
+		default:
+			return ownedError(c, errors.New("database is locked"), "Bot not found", "Failed to create")
+		}
+	})
+
+	// Only the messages of validation errors reach the client
+	for _, test := range []struct {
+		path    string
+		status  int
+		message string
+	}{
+		{"/invalid", fiber.StatusBadRequest, "name is required"},
+		{"/missing", fiber.StatusNotFound, "Bot not found"},
+		{"/failed", fiber.StatusInternalServerError, "Failed to create"},
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", test.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status || body.Error != test.message {
+			t.Errorf("%s: expected %d %q, got %d %q", test.path, test.status, test.message, resp.StatusCode, body.Error)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListIncomingWebhooks lists the incoming webhooks of the authenticated user
+func ListIncomingWebhooks(webhooks repositories.IncomingWebhookRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := c.Locals("claims").(utils.Claims)
+
+		created, err := services.ListIncomingWebhooks(c.UserContext(), webhooks, claims.UserID)
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve incoming webhooks")
+		}
+		return c.JSON(fiber.Map{"incoming_webhooks": dtos.ToIncomingWebhookDTOs(created)})
+	}
+}
+
+// CreateIncomingWebhook creates an incoming webhook posting into the
+// conversation of the authenticated user with a receiver. The response is the
+// only one that carries its token.
+func CreateIncomingWebhook(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Request struct {
+			ReceiverID uint   `json:"receiver_id"`
+			Name       string `json:"name"`
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return responses.Error(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+
+		created, err := services.CreateIncomingWebhook(c.UserContext(), repos, auditContext(c), req.ReceiverID, req.Name)
+		if err != nil {
+			return requestError(c, err, "Failed to create the incoming webhook")
+		}
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"incoming_webhook": dtos.ToIncomingWebhookDTO(created.Webhook),
+			"token":            created.Token,
+			"url":              fmt.Sprintf("%s/api/hooks/%d?token=%s", c.BaseURL(), created.Webhook.ID, created.Token),
+		})
+	}
+}
+
+// DeleteIncomingWebhook deletes an incoming webhook
+func DeleteIncomingWebhook(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		webhookID, err := pathID(c, "webhookId", "incoming webhook ID")
+		if err != nil {
+			return err
+		}
+
+		webhook, err := services.DeleteIncomingWebhook(c.UserContext(), repos, auditContext(c), webhookID)
+		if err != nil {
+			return ownedError(c, err, "Incoming webhook not found", "Failed to delete the incoming webhook")
+		}
+		return c.JSON(dtos.ToIncomingWebhookDTO(webhook))
+	}
+}
+
+// PostIncomingWebhook sends the text of the request body from the user of an
+// incoming webhook to its receiver, through the message service like
+// PostMessage. The token is passed in the token query parameter, which is not
+// logged.
+func PostIncomingWebhook(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		webhookID, err := pathID(c, "webhookId", "incoming webhook ID")
+		if err != nil {
+			return err
+		}
+		webhook, err := services.AuthenticateIncomingWebhook(c.UserContext(), repos, webhookID, c.Query("token"))
+		if err != nil {
+			return responses.Error(c, fiber.StatusUnauthorized, "Unauthorized: "+err.Error())
+		}
+		if ok, wait := services.AllowIncomingMessage(webhook.ID); !ok {
+			c.Set(fiber.HeaderRetryAfter, fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+			return responses.Error(c, fiber.StatusTooManyRequests, "Rate limit of the incoming webhook exceeded")
+		}
+
+		var request struct {
+			Text     string `json:"text"`
+			Username string `json:"username"`
+		}
+		if err := c.BodyParser(&request); err != nil {
+			return responses.Error(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		senderName, err := services.NormalizeSenderName(request.Username)
+		if err != nil {
+			return requestError(c, err, "Failed to send the message")
+		}
+
+		var response types.SendMessageResponse
+		err = services.Request(c.UserContext(), "send_message_response", &response, func(ctx context.Context, uuid string) error {
+			return services.PublishSendMessage(ctx, uuid, webhook.UserID, webhook.ReceiverID, request.Text, services.SendOptions{SenderName: senderName, Reply: true, Verbatim: true})
+		})
+		if err != nil {
+			return serviceError(c, err, "Failed to send message")
+		}
+		return c.Status(fiber.StatusCreated).JSON(sentMessage{
+			Message: response.Message,
+			Seq:     response.SenderSeq,
+		})
+	}
+}
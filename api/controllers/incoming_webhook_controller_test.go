@@ -0,0 +1,70 @@
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/models"
+)
+
+// postHook posts text to the incoming webhook at url and returns the status
+func postHook(t *testing.T, url, text string) int {
+	t.Helper()
+	hooks := &http.Client{Transport: appTransport{}}
+	resp, err := hooks.Post(url, "application/json", strings.NewReader(fmt.Sprintf(`{"text":%q}`, text)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestIncomingWebhooks(t *testing.T) {
+	ctx := context.Background()
+	_, owner := newUser(t, "alice", models.RoleUser)
+	bob, bobAPI := newUser(t, "bob", models.RoleUser)
+
+	created, err := owner.CreateIncomingWebhook(ctx, client.CreateIncomingWebhookRequest{ReceiverID: uint64(bob.ID), Name: " ci "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.IncomingWebhook.Name != "ci" || !strings.HasPrefix(created.Token, "imh_") || !strings.HasSuffix(created.URL, "?token="+created.Token) {
+		t.Fatalf("unexpected incoming webhook %+v", created)
+	}
+	listed, err := owner.ListIncomingWebhooks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.IncomingWebhooks) != 1 || listed.IncomingWebhooks[0].ID != created.IncomingWebhook.ID {
+		t.Fatalf("unexpected incoming webhooks %+v", listed.IncomingWebhooks)
+	}
+
+	// The hook is authenticated by its token
+	wrongToken := fmt.Sprintf("http://gateway.test/api/hooks/%d?token=imh_wrong", created.IncomingWebhook.ID)
+	if status := postHook(t, wrongToken, "nope"); status != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong token to be refused, got %d", status)
+	}
+
+	// The receiver must exist and only the owner may delete the webhook
+	_, err = owner.CreateIncomingWebhook(ctx, client.CreateIncomingWebhookRequest{ReceiverID: uint64(bob.ID) + 1000, Name: "nobody"})
+	expectStatus(t, err, http.StatusBadRequest, "an unknown receiver")
+	if err.(*client.Error).Message != "receiver not found" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, err = owner.CreateIncomingWebhook(ctx, client.CreateIncomingWebhookRequest{ReceiverID: uint64(bob.ID)})
+	expectStatus(t, err, http.StatusBadRequest, "a missing name")
+	_, err = bobAPI.DeleteIncomingWebhook(ctx, created.IncomingWebhook.ID)
+	expectStatus(t, err, http.StatusNotFound, "the incoming webhook of another user")
+
+	// A deleted webhook refuses its token
+	if _, err := owner.DeleteIncomingWebhook(ctx, created.IncomingWebhook.ID); err != nil {
+		t.Fatal(err)
+	}
+	if status := postHook(t, created.URL, "gone"); status != http.StatusUnauthorized {
+		t.Fatalf("expected a deleted webhook to be refused, got %d", status)
+	}
+}
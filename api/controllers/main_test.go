@@ -0,0 +1,117 @@
+package controllers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/api/routes"
+	"instant-messaging-app/broker"
+	"instant-messaging-app/client"
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	// repos are the repositories the routes run on
+	repos repositories.Repositories
+	// app serves the routes of the gateway, without the daemons behind it
+	app *fiber.App
+)
+
+// TestMain serves the routes on a SQLite database and an in-process broker.
+// The routes that only relay a request to the user or message service are
+// left to the e2e scenarios.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "api-controllers-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cfg := config.Default()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = filepath.Join(dir, "api.db")
+	cfg.JWT.Secret = utils.GenerateUUID()
+	config.Cfg = cfg
+	config.InitDatabase()
+	config.Broker = broker.NewMemoryBroker()
+	if err := declare(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repos = repositories.NewGormRepositories(config.DB)
+	app = fiber.New(fiber.Config{ErrorHandler: responses.ErrorHandler})
+	routes.SetupRoutes(app, ctx, repos)
+
+	code := m.Run()
+	cancel()
+	config.Broker.Close()
+	if sqlDB, err := config.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// declare declares the notification exchanges the services publish to
+func declare() error {
+	if err := config.Broker.DeclareExchange(config.Cfg.Exchanges.Notification, broker.ExchangeDirect); err != nil {
+		return err
+	}
+	return config.Broker.DeclareExchange(config.Cfg.Exchanges.NotificationBroadcast, broker.ExchangeFanout)
+}
+
+// appTransport hands the requests of the clients to app, in-process
+type appTransport struct{}
+
+func (appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return app.Test(req, -1)
+}
+
+// newClient returns a client of the routes authenticating with token
+func newClient(token string) *client.Client {
+	c := client.New("http://gateway.test", token)
+	c.HTTPClient = &http.Client{Transport: appTransport{}}
+	return c
+}
+
+// names numbers the users of the tests, so that every test gets its own
+var names atomic.Int64
+
+// newUser stores a user whose username starts with name and role, and
+// returns it with a client of a session of theirs
+func newUser(t *testing.T, name, role string) (models.User, *client.Client) {
+	t.Helper()
+	user := models.User{Username: fmt.Sprintf("%s%d", name, names.Add(1)), Password: "-", Role: role}
+	if err := repos.Users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	token, err := utils.GenerateJWT(user.ID, user.Username, user.Role, user.TokenVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, newClient(token)
+}
+
+// expectStatus fails unless err is an error of the client with the given
+// HTTP status
+func expectStatus(t *testing.T, err error, status int, what string) {
+	t.Helper()
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != status {
+		t.Fatalf("%s: expected status %d, got %v", what, status, err)
+	}
+}
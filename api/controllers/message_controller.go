@@ -1,79 +1,108 @@
 package controllers
 
 import (
+	"context"
+
 	"instant-messaging-app/api/services"
 	"instant-messaging-app/dtos"
-	"strconv"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-// GetMessages retrieves messages between the authenticated user and another user
-func GetMessages(c *fiber.Ctx) error {
-	// Extract the JWT token from the context
-	userToken := c.Locals("user").(*jwt.Token)
-
-	// Extract claims from the token
-	claims := userToken.Claims.(jwt.MapClaims)
-
-	// Parse user ID from claims (assuming "user_id" is stored in the JWT)
-	userID := uint(claims["user_id"].(float64))
+// sentMessage is the answer to a sent message; Seq numbers it in the event
+// stream of the sender. Ephemeral answers of commands are not stored, so they
+// have neither an ID nor a Seq.
+type sentMessage struct {
+	Message   dtos.MessageDTO `json:"message"`
+	Seq       uint64          `json:"seq"`
+	Ephemeral bool            `json:"ephemeral,omitempty"`
+}
 
-	// Get the ID of the user to fetch messages with
-	targetUserID, err := strconv.Atoi(c.Params("userId"))
+// GetConversation returns the messages exchanged with another user, oldest
+// first, like the getMessages WebSocket message
+func GetConversation(c *fiber.Ctx) error {
+	response, err := getConversation(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return err
 	}
+	return c.JSON(response)
+}
 
-	// Fetch messages using the service
-	messages, err := services.GetMessagesBetweenUsers(userID, uint(targetUserID))
+// PostMessage sends a message to another user, like the sendMessage WebSocket
+// message: both peers get it on their realtime connections
+func PostMessage(c *fiber.Ctx) error {
+	response, err := sendMessage(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve messages",
-		})
+		return err
 	}
+	return c.Status(fiber.StatusCreated).JSON(sentMessage{
+		Message:   response.Message,
+		Seq:       response.SenderSeq,
+		Ephemeral: response.Ephemeral,
+	})
+}
 
-	messagesDTOs := dtos.ToMessageDTOs(messages)
-
-	// Return the messages
-	return c.JSON(messagesDTOs)
+// GetMessages is GetConversation answering with the bare list of messages
+func GetMessages(c *fiber.Ctx) error {
+	response, err := getConversation(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(response.Messages)
 }
 
-// SendMessage envoie un message à un utilisateur
+// SendMessage is PostMessage answering with the bare message
 func SendMessage(c *fiber.Ctx) error {
-	userId, err := strconv.Atoi(c.Params("userId"))
+	response, err := sendMessage(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "ID de l'utilisateur invalide",
-		})
+		return err
 	}
+	return c.JSON(response.Message)
+}
 
-	userToken := c.Locals("user").(*jwt.Token)
-	claims := userToken.Claims.(jwt.MapClaims)
-	currentUserId := uint(claims["user_id"].(float64))
+// getConversation requests the conversation with the userId user
+func getConversation(c *fiber.Ctx) (types.GetMessagesResponse, error) {
+	var response types.GetMessagesResponse
+	claims := c.Locals("claims").(utils.Claims)
 
-	type Request struct {
-		Content string `json:"content"`
+	otherUserID, err := c.ParamsInt("userId")
+	if err != nil || otherUserID <= 0 {
+		return response, fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
 	}
 
-	var req Request
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Bad request",
-		})
+	err = services.Request(c.UserContext(), "get_messages_response", &response, func(ctx context.Context, uuid string) error {
+		return services.PublishGetMessages(ctx, uuid, claims.UserID, uint(otherUserID))
+	})
+	if err != nil {
+		return response, serviceError(c, err, "Failed to retrieve messages")
 	}
+	return response, nil
+}
 
-	message, err := services.CreateMessage(currentUserId, uint(userId), req.Content)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to send message",
-		})
+// sendMessage sends the message of the request body to the userId user
+func sendMessage(c *fiber.Ctx) (types.SendMessageResponse, error) {
+	var response types.SendMessageResponse
+	claims := c.Locals("claims").(utils.Claims)
+
+	receiverID, err := c.ParamsInt("userId")
+	if err != nil || receiverID <= 0 {
+		return response, fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
 	}
 
-	messageDTO := dtos.ToMessageDTO(message)
+	var request struct {
+		Content string `json:"content"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return response, fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
 
-	return c.JSON(messageDTO)
-}
\ No newline at end of file
+	err = services.Request(c.UserContext(), "send_message_response", &response, func(ctx context.Context, uuid string) error {
+		return services.PublishSendMessage(ctx, uuid, claims.UserID, uint(receiverID), request.Content, services.SendOptions{Reply: true})
+	})
+	if err != nil {
+		return response, serviceError(c, err, "Failed to send message")
+	}
+	return response, nil
+}
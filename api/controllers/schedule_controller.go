@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"errors"
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ListScheduledMessages lists the scheduled messages and reminders of the
+// authenticated user with the status query parameter, pending by default
+func ListScheduledMessages(scheduled repositories.ScheduledMessageRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := c.Locals("claims").(utils.Claims)
+		status := c.Query("status")
+		if status != "" && !models.IsScheduleStatus(status) {
+			return responses.Error(c, fiber.StatusBadRequest, "Invalid status")
+		}
+
+		messages, err := services.ListScheduledMessages(c.UserContext(), scheduled, claims.UserID, status)
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve scheduled messages")
+		}
+		return c.JSON(fiber.Map{"scheduled_messages": dtos.ToScheduledMessageDTOs(messages)})
+	}
+}
+
+// GetScheduledMessage returns a scheduled message of the authenticated user
+func GetScheduledMessage(scheduled repositories.ScheduledMessageRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := pathID(c, "scheduleId", "scheduled message ID")
+		if err != nil {
+			return err
+		}
+		claims := c.Locals("claims").(utils.Claims)
+
+		message, err := services.FindScheduledMessage(c.UserContext(), scheduled, claims.UserID, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.Error(c, fiber.StatusNotFound, "Scheduled message not found")
+		}
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve the scheduled message")
+		}
+		return c.JSON(dtos.ToScheduledMessageDTO(message))
+	}
+}
+
+// ScheduleMessage schedules a message to another user, sent by the scheduler
+// daemon at send_at
+func ScheduleMessage(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Request struct {
+			ReceiverID uint   `json:"receiver_id"`
+			Content    string `json:"content"`
+			SendAt     string `json:"send_at"`
+			TimeZone   string `json:"time_zone"`
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return responses.Error(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+
+		message, err := services.ScheduleMessage(c.UserContext(), repos, auditContext(c), req.ReceiverID, req.Content, req.SendAt, req.TimeZone)
+		if err != nil {
+			return requestError(c, err, "Failed to schedule the message")
+		}
+		return c.Status(fiber.StatusCreated).JSON(dtos.ToScheduledMessageDTO(message))
+	}
+}
+
+// UpdateScheduledMessage edits the content, time or time zone of a pending
+// scheduled message
+func UpdateScheduledMessage(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Request struct {
+			Content  string `json:"content"`
+			SendAt   string `json:"send_at"`
+			TimeZone string `json:"time_zone"`
+		}
+
+		id, err := pathID(c, "scheduleId", "scheduled message ID")
+		if err != nil {
+			return err
+		}
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return responses.Error(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+
+		message, err := services.UpdateScheduledMessage(c.UserContext(), repos, auditContext(c), id, req.Content, req.SendAt, req.TimeZone)
+		if err != nil {
+			return scheduleError(c, err, "Failed to update the scheduled message")
+		}
+		return c.JSON(dtos.ToScheduledMessageDTO(message))
+	}
+}
+
+// CancelScheduledMessage cancels a pending scheduled message
+func CancelScheduledMessage(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := pathID(c, "scheduleId", "scheduled message ID")
+		if err != nil {
+			return err
+		}
+
+		message, err := services.CancelScheduledMessage(c.UserContext(), repos, auditContext(c), id)
+		if err != nil {
+			return scheduleError(c, err, "Failed to cancel the scheduled message")
+		}
+		return c.JSON(dtos.ToScheduledMessageDTO(message))
+	}
+}
+
+// scheduleError is ownedError answering 409 for the messages that were
+// already sent or canceled
+func scheduleError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, services.ErrScheduleNotPending) {
+		return responses.Error(c, fiber.StatusConflict, "The message was already sent or canceled")
+	}
+	return ownedError(c, err, "Scheduled message not found", message)
+}
@@ -0,0 +1,41 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/models"
+)
+
+func TestScheduledMessages(t *testing.T) {
+	ctx := context.Background()
+	_, alice := newUser(t, "alice", models.RoleUser)
+	bob, bobAPI := newUser(t, "bob", models.RoleUser)
+
+	sendAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	scheduled, err := alice.ScheduleMessage(ctx, client.ScheduleMessageRequest{ReceiverID: uint64(bob.ID), Content: "later", SendAt: sendAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The validation errors are shown to the client
+	_, err = alice.ScheduleMessage(ctx, client.ScheduleMessageRequest{ReceiverID: uint64(bob.ID), Content: "past", SendAt: "2000-01-01T00:00:00Z"})
+	expectStatus(t, err, http.StatusBadRequest, "a time in the past")
+	if err.(*client.Error).Message != "send_at must be in the future" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, err = alice.UpdateScheduledMessage(ctx, scheduled.ID, client.UpdateScheduledMessageRequest{})
+	expectStatus(t, err, http.StatusBadRequest, "an empty update")
+
+	// Only the sender may change the message, and only while it is pending
+	_, err = bobAPI.CancelScheduledMessage(ctx, scheduled.ID)
+	expectStatus(t, err, http.StatusNotFound, "the message of another user")
+	if _, err := alice.CancelScheduledMessage(ctx, scheduled.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = alice.UpdateScheduledMessage(ctx, scheduled.ID, client.UpdateScheduledMessageRequest{Content: "again"})
+	expectStatus(t, err, http.StatusConflict, "a canceled message")
+}
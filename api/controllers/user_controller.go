@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetUsers lists the active users visible to the authenticated user, like the
+// getUsers WebSocket message
+func GetUsers(c *fiber.Ctx) error {
+	claims := c.Locals("claims").(utils.Claims)
+	var response types.GetUsersResponse
+	err := services.Request(c.UserContext(), "get_users_response", &response, func(ctx context.Context, uuid string) error {
+		return services.PublishGetUsers(ctx, uuid, claims.UserID)
+	})
+	if err != nil {
+		return serviceError(c, err, "Failed to retrieve users")
+	}
+	return c.JSON(response)
+}
+
+// SearchUsers returns one page of the user directory, like the searchUsers
+// WebSocket message
+func SearchUsers(c *fiber.Ctx) error {
+	claims := c.Locals("claims").(utils.Claims)
+	query := c.Query("query")
+	page := c.QueryInt("page")
+	pageSize := c.QueryInt("page_size")
+
+	var response types.SearchUsersResponse
+	err := services.Request(c.UserContext(), "search_users_response", &response, func(ctx context.Context, uuid string) error {
+		return services.PublishSearchUsers(ctx, uuid, claims.UserID, query, page, pageSize)
+	})
+	if err != nil {
+		return serviceError(c, err, "Failed to search users")
+	}
+	return c.JSON(response)
+}
+
+// GetSelf returns the authenticated user, like the getSelf WebSocket message
+func GetSelf(c *fiber.Ctx) error {
+	claims := c.Locals("claims").(utils.Claims)
+
+	var response types.GetSelfResponse
+	err := services.Request(c.UserContext(), "get_self_response", &response, func(ctx context.Context, uuid string) error {
+		return services.PublishGetSelf(ctx, uuid, claims.UserID)
+	})
+	if err != nil {
+		return serviceError(c, err, "Failed to retrieve user")
+	}
+	return c.JSON(response)
+}
+
+// ListBlockedUsers lists the users blocked by the authenticated user
+func ListBlockedUsers(blocks repositories.BlockRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := c.Locals("claims").(utils.Claims)
+
+		users, err := services.ListBlockedUsers(c.UserContext(), blocks, claims.UserID)
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve blocked users")
+		}
+		return c.JSON(types.GetUsersResponse{Users: dtos.ToUserDTOs(users)})
+	}
+}
+
+// BlockUser blocks a user for the authenticated user; neither finds the
+// other in the user directory anymore
+func BlockUser(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := pathID(c, "userId", "user ID")
+		if err != nil {
+			return err
+		}
+		claims := c.Locals("claims").(utils.Claims)
+
+		blocked, err := services.BlockUser(c.UserContext(), repos, claims.UserID, userID)
+		switch {
+		case errors.Is(err, services.ErrBlockSelf):
+			return responses.Error(c, fiber.StatusBadRequest, "Users cannot block themselves")
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return responses.Error(c, fiber.StatusNotFound, "User not found")
+		case err != nil:
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to block the user")
+		}
+		return c.JSON(dtos.ToUserDTO(blocked))
+	}
+}
+
+// UnblockUser lifts a block of the authenticated user
+func UnblockUser(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := pathID(c, "userId", "user ID")
+		if err != nil {
+			return err
+		}
+		claims := c.Locals("claims").(utils.Claims)
+
+		unblocked, err := services.UnblockUser(c.UserContext(), repos, claims.UserID, userID)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return responses.Error(c, fiber.StatusNotFound, "Block not found")
+		case err != nil:
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to unblock the user")
+		}
+		return c.JSON(dtos.ToUserDTO(unblocked))
+	}
+}
+
+// serviceError turns the failure of a request to a service into the error
+// answered by the gateway, which the error handler writes in the envelope
+func serviceError(c *fiber.Ctx, err error, message string) error {
+	var serviceErr *services.ServiceError
+	switch {
+	case errors.As(err, &serviceErr):
+		return fiber.NewError(responses.Status(serviceErr.Code), serviceErr.Message)
+	case errors.Is(err, services.ErrNoReply):
+		slog.WarnContext(c.UserContext(), message, "error", err)
+		return fiber.NewError(fiber.StatusGatewayTimeout, message+": the service did not reply")
+	default:
+		slog.ErrorContext(c.UserContext(), message, "error", err)
+		return fiber.NewError(fiber.StatusInternalServerError, message)
+	}
+}
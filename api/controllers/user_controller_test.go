@@ -0,0 +1,65 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/models"
+)
+
+func TestBlockUsers(t *testing.T) {
+	ctx := context.Background()
+	alice, aliceAPI := newUser(t, "alice", models.RoleUser)
+	bob, bobAPI := newUser(t, "bob", models.RoleUser)
+
+	// A block is stored once, however many times it is asked for
+	blocked, err := aliceAPI.BlockUser(ctx, uint64(bob.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blocked.ID != uint64(bob.ID) || blocked.Username != bob.Username {
+		t.Fatalf("unexpected blocked user %+v", blocked)
+	}
+	if _, err := aliceAPI.BlockUser(ctx, uint64(bob.ID)); err != nil {
+		t.Fatalf("blocking twice: %v", err)
+	}
+	list, err := aliceAPI.ListBlockedUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Users) != 1 || list.Users[0].ID != uint64(bob.ID) {
+		t.Fatalf("unexpected blocked users %+v", list.Users)
+	}
+	if list, err := bobAPI.ListBlockedUsers(ctx); err != nil || len(list.Users) != 0 {
+		t.Fatalf("expected bob to block nobody, got %+v (%v)", list, err)
+	}
+
+	_, err = aliceAPI.BlockUser(ctx, uint64(alice.ID))
+	expectStatus(t, err, http.StatusBadRequest, "blocking oneself")
+	_, err = aliceAPI.BlockUser(ctx, uint64(bob.ID)+1000)
+	expectStatus(t, err, http.StatusNotFound, "blocking an unknown user")
+
+	if _, err := aliceAPI.UnblockUser(ctx, uint64(bob.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := aliceAPI.ListBlockedUsers(ctx); err != nil || len(list.Users) != 0 {
+		t.Fatalf("expected alice to block nobody, got %+v (%v)", list, err)
+	}
+	_, err = aliceAPI.UnblockUser(ctx, uint64(bob.ID))
+	expectStatus(t, err, http.StatusNotFound, "a missing block")
+}
+
+func TestRESTErrors(t *testing.T) {
+	ctx := context.Background()
+	_, api := newUser(t, "alice", models.RoleUser)
+
+	// The requests are refused before reaching the services
+	_, err := newClient("invalid").GetSelf(ctx)
+	expectStatus(t, err, http.StatusUnauthorized, "an invalid token")
+	_, err = newClient("").ListBlockedUsers(ctx)
+	expectStatus(t, err, http.StatusUnauthorized, "a missing token")
+	_, err = api.PostMessage(ctx, 0, client.MessageContent{Content: "nobody"})
+	expectStatus(t, err, http.StatusBadRequest, "an invalid receiver")
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"errors"
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ListWebhooks lists the webhooks of the authenticated user
+func ListWebhooks(webhooks repositories.WebhookRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := c.Locals("claims").(utils.Claims)
+
+		registered, err := services.ListWebhooks(c.UserContext(), webhooks, claims.UserID)
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve webhooks")
+		}
+		return c.JSON(fiber.Map{"webhooks": dtos.ToWebhookDTOs(registered)})
+	}
+}
+
+// CreateWebhook registers a webhook of the authenticated user. The response
+// is the only one that carries its signing secret.
+func CreateWebhook(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		type Request struct {
+			URL    string   `json:"url"`
+			Events []string `json:"events"`
+			// AllUsers receives the events of every user, not only those of the
+			// owner; it needs the webhooks:manage permission
+			AllUsers bool `json:"all_users"`
+		}
+
+		var req Request
+		if err := c.BodyParser(&req); err != nil {
+			return responses.Error(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		audit := auditContext(c)
+		if req.AllUsers && !models.HasPermission(audit.ActorRole, models.PermissionManageWebhooks) {
+			return responses.Error(c, fiber.StatusForbidden, "Insufficient permissions")
+		}
+
+		webhook, err := services.CreateWebhook(c.UserContext(), repos, audit, req.URL, req.Events, req.AllUsers)
+		if err != nil {
+			return requestError(c, err, "Failed to create the webhook")
+		}
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"webhook": dtos.ToWebhookDTO(webhook),
+			"secret":  webhook.Secret,
+		})
+	}
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func DeleteWebhook(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		webhookID, err := pathID(c, "webhookId", "webhook ID")
+		if err != nil {
+			return err
+		}
+
+		webhook, err := services.DeleteWebhook(c.UserContext(), repos, auditContext(c), webhookID)
+		if err != nil {
+			return ownedError(c, err, "Webhook not found", "Failed to delete the webhook")
+		}
+		return c.JSON(dtos.ToWebhookDTO(webhook))
+	}
+}
+
+// EnableWebhook enables a webhook disabled after repeated failures
+func EnableWebhook(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		webhookID, err := pathID(c, "webhookId", "webhook ID")
+		if err != nil {
+			return err
+		}
+
+		webhook, err := services.EnableWebhook(c.UserContext(), repos, auditContext(c), webhookID)
+		if err != nil {
+			return ownedError(c, err, "Webhook not found", "Failed to enable the webhook")
+		}
+		return c.JSON(dtos.ToWebhookDTO(webhook))
+	}
+}
+
+// ListWebhookDeliveries lists the delivery log of a webhook, newest first
+func ListWebhookDeliveries(webhooks repositories.WebhookRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		webhookID, err := pathID(c, "webhookId", "webhook ID")
+		if err != nil {
+			return err
+		}
+		claims := c.Locals("claims").(utils.Claims)
+		page, pageSize := utils.NormalizePagination(c.QueryInt("page", 1), c.QueryInt("page_size", utils.DefaultPageSize))
+
+		deliveries, total, err := services.ListWebhookDeliveries(c.UserContext(), webhooks, claims.UserID, webhookID, page, pageSize)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return responses.Error(c, fiber.StatusNotFound, "Webhook not found")
+		}
+		if err != nil {
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to retrieve deliveries")
+		}
+		return c.JSON(fiber.Map{
+			"deliveries": dtos.ToWebhookDeliveryDTOs(deliveries),
+			"page":       page,
+			"page_size":  pageSize,
+			"total":      total,
+		})
+	}
+}
+
+// ReplayWebhookDelivery sends the event of a delivery to its webhook again
+func ReplayWebhookDelivery(repos repositories.Repositories) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		webhookID, err := pathID(c, "webhookId", "webhook ID")
+		if err != nil {
+			return err
+		}
+		deliveryID, err := pathID(c, "deliveryId", "delivery ID")
+		if err != nil {
+			return err
+		}
+
+		delivery, err := services.ReplayWebhookDelivery(c.UserContext(), repos, auditContext(c), webhookID, deliveryID)
+		if err != nil {
+			return ownedError(c, err, "Delivery not found", "Failed to replay the delivery")
+		}
+		return c.Status(fiber.StatusAccepted).JSON(dtos.ToWebhookDeliveryDTO(delivery))
+	}
+}
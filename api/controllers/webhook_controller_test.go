@@ -0,0 +1,38 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/models"
+)
+
+func TestWebhooks(t *testing.T) {
+	ctx := context.Background()
+	_, alice := newUser(t, "alice", models.RoleUser)
+	_, bob := newUser(t, "bob", models.RoleUser)
+
+	created, err := alice.CreateWebhook(ctx, client.CreateWebhookRequest{URL: "https://hooks.example.com/in", Events: []string{"message_created"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The validation errors are shown to the client
+	_, err = alice.CreateWebhook(ctx, client.CreateWebhookRequest{URL: "http://100.64.0.1/in"})
+	expectStatus(t, err, http.StatusBadRequest, "a shared address")
+	if err.(*client.Error).Message != "url must not point to a private address" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, err = alice.CreateWebhook(ctx, client.CreateWebhookRequest{URL: "https://hooks.example.com/in", Events: []string{"message_unknown"}})
+	expectStatus(t, err, http.StatusBadRequest, "an unknown event")
+	_, err = alice.EnableWebhook(ctx, created.Webhook.ID)
+	expectStatus(t, err, http.StatusBadRequest, "an enabled webhook")
+
+	// Only the owner may delete the webhook, and all_users needs webhooks:manage
+	_, err = bob.DeleteWebhook(ctx, created.Webhook.ID)
+	expectStatus(t, err, http.StatusNotFound, "the webhook of another user")
+	_, err = bob.CreateWebhook(ctx, client.CreateWebhookRequest{URL: "https://hooks.example.com/all", AllUsers: true})
+	expectStatus(t, err, http.StatusForbidden, "an all_users webhook of a user")
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/metrics"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/tracing"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+
+	"github.com/gofiber/contrib/websocket"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// activeConnections counts the realtime connections currently served by this
+// gateway, over every transport
+var activeConnections int64
+
+// ActiveConnections returns the number of open WebSockets, event streams and
+// long-poll sessions on this gateway
+func ActiveConnections() int64 {
+	return atomic.LoadInt64(&activeConnections)
+}
+
+// Resume asks for the events a reconnecting client missed to be replayed
+type Resume struct {
+	// Messages stores the event streams of the users
+	Messages repositories.MessageRepository
+	// From is the sequence number of the last event the client received
+	From uint64
+}
+
+// sendErrorResponse sends an error response to the client
+func sendErrorResponse(ctx context.Context, out *outbox, errorMessage string) error {
+	return sendCodedErrorResponse(ctx, out, "", errorMessage)
+}
+
+// sendCodedErrorResponse sends an error response carrying the error code of a
+// service to the client
+func sendCodedErrorResponse(ctx context.Context, out *outbox, code, errorMessage string) error {
+	response := struct {
+		Type  string `json:"type"`
+		Error string `json:"error"`
+		Code  string `json:"code,omitempty"`
+	}{
+		Type:  "error",
+		Error: errorMessage,
+		Code:  code,
+	}
+
+	return sendOutgoingMessage(ctx, out, "error", response)
+}
+
+// consumeNotifications queues the notifications of the connection queue uuid
+// in out until ctx is canceled, after replaying the missed events when
+// resume is set. The queue is deleted on return.
+func consumeNotifications(ctx context.Context, uuid string, userID uint, out *outbox, resume *Resume) {
+	// Create a new context specifically for this consumer
+	consumerCtx, cancel := context.WithCancel(ctx)
+
+	defer func() {
+		// Cleanup when the consumer exits
+		cancel()
+		slog.DebugContext(ctx, "Consumer cleanup completed")
+
+		// Delete the queue after the connection is closed
+		if err := config.CleanupQueue(uuid); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete the connection queue", "error", err)
+		} else {
+			slog.DebugContext(ctx, "Connection queue deleted")
+		}
+	}()
+
+	msgs, err := config.Broker.Consume(consumerCtx, uuid)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to start the connection consumer", "error", err)
+		return
+	}
+
+	// The queue was bound before the replay, so the live events published in
+	// the meantime wait in it and only those already replayed are skipped
+	var replayedUpTo uint64
+	if resume != nil {
+		replayedUpTo = replayEvents(consumerCtx, userID, out, resume)
+	}
+
+	// Listen for messages or context cancellation
+	for {
+		select {
+		case <-consumerCtx.Done():
+			slog.DebugContext(ctx, "Consumer context canceled")
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				slog.DebugContext(ctx, "Consumer closed")
+				return
+			}
+			// Process the message under the trace of the service that published it
+			// and log it under its correlation ID but as this connection
+			msgCtx, span := tracing.StartConsume(consumerCtx, "websocket", msg)
+			msgCtx = logging.WithConnection(msgCtx, uuid, userID)
+			if err := processMessage(msgCtx, userID, replayedUpTo, msg.Body, out); err != nil && len(msg.Body) > 0 {
+				slog.ErrorContext(msgCtx, "Failed to process notification", "error", err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}
+	}
+}
+
+// replayEvents sends the events of the user after resume.From, then a resumed
+// notification, and returns the sequence number of the last event covered.
+// When more events were missed than the replay limit, none is sent and the
+// client is told to reload its conversations instead.
+func replayEvents(ctx context.Context, userID uint, out *outbox, resume *Resume) uint64 {
+	limit := config.Cfg.WebSocket.ReplayLimit
+	events, err := resume.Messages.ListEventsAfter(ctx, userID, resume.From, limit+1)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load the missed events", "resume_from", resume.From, "error", err)
+		sendErrorResponse(ctx, out, "Failed to replay the missed events")
+		return 0
+	}
+
+	resumed := types.ResumedNotification{
+		ResumeFrom: resume.From,
+		LastSeq:    resume.From,
+		Complete:   len(events) <= limit,
+	}
+	var replayedUpTo uint64
+	if resumed.Complete {
+		for _, event := range events {
+			// The events of deleted messages are skipped, but still covered
+			if event.Message.ID == 0 {
+				replayedUpTo = event.Seq
+				resumed.LastSeq = event.Seq
+				continue
+			}
+			err := sendReplayedEvent(ctx, out, types.Event{
+				Type: "send_message_response",
+				Seq:  event.Seq,
+				Data: types.SendMessageResponse{Message: dtos.ToMessageDTO(event.Message), Muted: event.Muted},
+			})
+			if err != nil {
+				return event.Seq
+			}
+			replayedUpTo = event.Seq
+			resumed.LastSeq = event.Seq
+			resumed.Replayed++
+		}
+	} else {
+		// The reload covers everything up to now
+		replayedUpTo, err = resume.Messages.LastEventSeq(ctx, userID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to load the last event sequence number", "error", err)
+		}
+		resumed.LastSeq = replayedUpTo
+	}
+
+	slog.InfoContext(ctx, "Realtime session resumed", "resume_from", resume.From, "replayed", resumed.Replayed, "complete", resumed.Complete)
+	sendOutgoingMessage(ctx, out, "resumed", types.Notification{Type: "resumed", Data: resumed})
+	return replayedUpTo
+}
+
+// processMessage routes and handles different types of messages. Messages
+// numbered up to replayedUpTo were already replayed to the client.
+func processMessage(ctx context.Context, userID uint, replayedUpTo uint64, message []byte, out *outbox) error {
+	// Generic message format with a type field and a data field
+	var baseMessage struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+
+	// Parse the base structure of the message
+	if err := json.Unmarshal(message, &baseMessage); err != nil {
+		return err
+	}
+
+	// Route the message based on its type
+	switch baseMessage.Type {
+	case "registration_response":
+		var registrationResponse types.RegistrationResponse
+		if err := json.Unmarshal(baseMessage.Data, &registrationResponse); err != nil {
+			return err
+		}
+		return sendOutgoingMessage(ctx, out, baseMessage.Type, registrationResponse)
+	case "login_response":
+		var loginResponse types.LoginResponse
+		if err := json.Unmarshal(baseMessage.Data, &loginResponse); err != nil {
+			return err
+		}
+		return sendOutgoingMessage(ctx, out, baseMessage.Type, loginResponse)
+	case "get_users_response":
+		var usersResponse types.GetUsersResponse
+		if err := json.Unmarshal(baseMessage.Data, &usersResponse); err != nil {
+			return err
+		}
+		return sendOutgoingMessage(ctx, out, baseMessage.Type, baseMessage)
+	case "search_users_response":
+		var searchResponse types.SearchUsersResponse
+		if err := json.Unmarshal(baseMessage.Data, &searchResponse); err != nil {
+			return err
+		}
+		return sendOutgoingMessage(ctx, out, baseMessage.Type, baseMessage)
+	case "get_self_response":
+		var selfResponse types.GetSelfResponse
+		if err := json.Unmarshal(baseMessage.Data, &selfResponse); err != nil {
+			return err
+		}
+		return sendOutgoingMessage(ctx, out, baseMessage.Type, baseMessage)
+	case "get_messages_response":
+		var selfResponse types.GetMessagesResponse
+		if err := json.Unmarshal(baseMessage.Data, &selfResponse); err != nil {
+			return err
+		}
+		return sendOutgoingMessage(ctx, out, baseMessage.Type, baseMessage)
+	case "send_message_response":
+		var selfResponse types.SendMessageResponse
+		if err := json.Unmarshal(baseMessage.Data, &selfResponse); err != nil {
+			return err
+		}
+		if selfResponse.Message.ReceiverID != userID && selfResponse.Message.SenderID != userID {
+			return nil
+		}
+		// Ephemeral messages answer a command of their sender only
+		if selfResponse.Ephemeral && selfResponse.Message.SenderID != userID {
+			return nil
+		}
+
+		// Each peer gets the sequence number of its own event stream
+		seq := selfResponse.SenderSeq
+		if selfResponse.Message.ReceiverID == userID {
+			seq = selfResponse.ReceiverSeq
+		}
+		if seq != 0 && seq <= replayedUpTo {
+			return nil
+		}
+		slog.DebugContext(ctx, "Delivering message", "message_id", selfResponse.Message.ID, "seq", seq, "content", selfResponse.Message.Content)
+		data := types.SendMessageResponse{Message: selfResponse.Message, Ephemeral: selfResponse.Ephemeral}
+		if selfResponse.Message.ReceiverID == userID {
+			data.Muted = selfResponse.Muted
+		}
+		return sendOutgoingMessage(ctx, out, baseMessage.Type, types.Event{
+			Type: baseMessage.Type,
+			Seq:  seq,
+			Data: data,
+		})
+	case "command_invocation":
+		var invocation types.CommandInvocation
+		if err := json.Unmarshal(baseMessage.Data, &invocation); err != nil {
+			return err
+		}
+		if userID == 0 || invocation.BotID != userID {
+			return nil
+		}
+		slog.DebugContext(ctx, "Delivering command invocation", "invocation_id", invocation.ID, "command", invocation.Command)
+		return sendOutgoingMessage(ctx, out, baseMessage.Type, types.Notification{Type: baseMessage.Type, Data: invocation})
+	case "error":
+		var errorResponse types.ErrorResponse
+		if err := json.Unmarshal(baseMessage.Data, &errorResponse); err != nil {
+			return err
+		}
+		return sendCodedErrorResponse(ctx, out, errorResponse.Code, errorResponse.Error)
+	case "force_logout":
+		var forceLogout types.ForceLogoutNotification
+		if err := json.Unmarshal(baseMessage.Data, &forceLogout); err != nil {
+			return err
+		}
+		if userID == 0 || forceLogout.UserID != userID {
+			return nil
+		}
+		// The revocation of an API key only ends the sessions of that key
+		if forceLogout.APIKeyID != 0 {
+			if claims, _ := utils.ClaimsFromContext(ctx); claims.APIKeyID != forceLogout.APIKeyID {
+				return nil
+			}
+		}
+		slog.InfoContext(ctx, "Force logout received, closing the connection")
+		sendOutgoingMessage(ctx, out, baseMessage.Type, baseMessage)
+		out.closeAfterPending(websocket.CloseNormalClosure, "logged out")
+		return nil
+	default:
+		slog.WarnContext(ctx, "Unknown notification type", "type", baseMessage.Type)
+		return nil
+	}
+}
+
+// sendOutgoingMessage serializes a message of the given type and queues it
+// for the transport of the client, counting the messages actually queued
+func sendOutgoingMessage(ctx context.Context, out *outbox, messageType string, message interface{}) error {
+	rawMessage, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to serialize message: %w", err)
+	}
+
+	frame := outboundFrame{data: rawMessage}
+	if event, ok := message.(types.Event); ok {
+		frame.seq = event.Seq
+	}
+	if err := out.write(ctx, frame); err != nil {
+		return err
+	}
+	metrics.WebSocketMessages.WithLabelValues("out", messageType).Inc()
+	return nil
+}
+
+// sendReplayedEvent queues a replayed event, waiting for room in the buffer
+// of the client rather than applying the slow consumer policy
+func sendReplayedEvent(ctx context.Context, out *outbox, event types.Event) error {
+	rawMessage, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to serialize message: %w", err)
+	}
+
+	if err := out.writeWait(ctx, outboundFrame{data: rawMessage, seq: event.Seq}); err != nil {
+		return err
+	}
+	metrics.WebSocketMessages.WithLabelValues("out", event.Type).Inc()
+	return nil
+}
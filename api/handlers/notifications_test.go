@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+
+	"gorm.io/gorm"
+)
+
+// storedEvents is a MessageRepository holding the events of one user
+type storedEvents struct {
+	repositories.MessageRepository
+	events []models.UserEvent
+}
+
+func (s storedEvents) ListEventsAfter(ctx context.Context, userID uint, afterSeq uint64, limit int) ([]models.UserEvent, error) {
+	return s.events, nil
+}
+
+func TestReplayEvents(t *testing.T) {
+	saved := config.Cfg.WebSocket
+	defer func() { config.Cfg.WebSocket = saved }()
+	config.Cfg.WebSocket.SendBuffer = 10
+	config.Cfg.WebSocket.ReplayLimit = 10
+
+	message := func(id uint, content string) models.Message {
+		return models.Message{Model: gorm.Model{ID: id}, SenderID: 2, ReceiverID: 1, Content: content}
+	}
+	resume := &Resume{From: 4, Messages: storedEvents{events: []models.UserEvent{
+		{UserID: 1, Seq: 5, MessageID: 10, Message: message(10, "muted"), Muted: true},
+		// The message of this event was deleted
+		{UserID: 1, Seq: 6, MessageID: 11},
+		{UserID: 1, Seq: 7, MessageID: 12, Message: message(12, "alerted")},
+	}}}
+	out := newOutbox()
+	if replayedUpTo := replayEvents(context.Background(), 1, out, resume); replayedUpTo != 7 {
+		t.Fatalf("expected the events up to 7 to be covered, got %d", replayedUpTo)
+	}
+
+	// The events keep their muted flag and the deleted message is skipped
+	for _, expected := range []struct {
+		seq     uint64
+		content string
+		muted   bool
+	}{{5, "muted", true}, {7, "alerted", false}} {
+		var event struct {
+			Seq  uint64                    `json:"seq"`
+			Data types.SendMessageResponse `json:"data"`
+		}
+		if err := json.Unmarshal((<-out.send).data, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Seq != expected.seq || event.Data.Message.Content != expected.content || event.Data.Muted != expected.muted {
+			t.Fatalf("expected event %d %q muted %v, got %+v", expected.seq, expected.content, expected.muted, event)
+		}
+	}
+	var resumed struct {
+		Data types.ResumedNotification `json:"data"`
+	}
+	if err := json.Unmarshal((<-out.send).data, &resumed); err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Data.LastSeq != 7 || resumed.Data.Replayed != 2 || !resumed.Data.Complete {
+		t.Fatalf("unexpected resumed notification %+v", resumed.Data)
+	}
+}
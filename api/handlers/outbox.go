@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/metrics"
+
+	"github.com/gofiber/contrib/websocket"
+)
+
+var (
+	// errClientClosed is returned when writing to a closed connection
+	errClientClosed = errors.New("connection closed")
+	// errFrameDropped is returned when a frame was dropped for a slow client
+	errFrameDropped = errors.New("outbound buffer full, frame dropped")
+	// errSlowConsumer is returned when a slow client was disconnected
+	errSlowConsumer = errors.New("outbound buffer full, client disconnected")
+	// errEventDropped is returned when a slow client was disconnected
+	// rather than dropping an event
+	errEventDropped = errors.New("outbound buffer full, event dropped, client disconnected")
+)
+
+// outboundFrame is a text frame, or a close frame when closeCode is set. seq
+// is the sequence number of the event the frame carries, if any.
+type outboundFrame struct {
+	data        []byte
+	seq         uint64
+	closeCode   int
+	closeReason string
+}
+
+// outbox buffers the frames of one realtime connection until its transport
+// writes them. The WebSocket, SSE and long-poll transports each drain it from
+// a single goroutine, so the producers never write to a connection
+// concurrently.
+type outbox struct {
+	send chan outboundFrame
+	done chan struct{}
+
+	closeOnce   sync.Once
+	closeCode   int
+	closeReason string
+}
+
+// newOutbox returns an outbox holding up to websocket.send_buffer frames
+func newOutbox() *outbox {
+	return &outbox{
+		send: make(chan outboundFrame, config.Cfg.WebSocket.SendBuffer),
+		done: make(chan struct{}),
+	}
+}
+
+// write queues a frame. When the buffer is full the websocket.slow_consumer
+// policy applies: the frame is dropped, or the client is disconnected with a
+// policy violation close code. An event is never dropped silently: the client
+// would miss its seq, so it is disconnected with a try again later close code
+// and resumes from the last seq it received.
+func (o *outbox) write(ctx context.Context, frame outboundFrame) error {
+	select {
+	case <-o.done:
+		return errClientClosed
+	default:
+	}
+
+	select {
+	case o.send <- frame:
+		return nil
+	default:
+	}
+
+	if config.Cfg.WebSocket.SlowConsumer == "drop" && frame.seq != 0 {
+		metrics.WebSocketSlowConsumers.WithLabelValues("resume").Inc()
+		slog.WarnContext(ctx, "Disconnecting a slow client instead of dropping an event", "seq", frame.seq)
+		o.stop(websocket.CloseTryAgainLater, fmt.Sprintf("event %d dropped, resume from the last seq received", frame.seq))
+		return errEventDropped
+	}
+	if config.Cfg.WebSocket.SlowConsumer == "drop" {
+		metrics.WebSocketSlowConsumers.WithLabelValues("drop").Inc()
+		slog.WarnContext(ctx, "Dropping a frame for a slow client")
+		return errFrameDropped
+	}
+	metrics.WebSocketSlowConsumers.WithLabelValues("disconnect").Inc()
+	slog.WarnContext(ctx, "Disconnecting a slow client")
+	o.stop(websocket.ClosePolicyViolation, "slow consumer")
+	return errSlowConsumer
+}
+
+// writeWait queues a frame, waiting for room in the buffer. It returns once
+// the frame is queued or the client stopped.
+func (o *outbox) writeWait(ctx context.Context, frame outboundFrame) error {
+	select {
+	case o.send <- frame:
+		return nil
+	case <-o.done:
+		return errClientClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeAfterPending closes the connection once the frames already queued are
+// written, or right away when the buffer is full
+func (o *outbox) closeAfterPending(code int, reason string) {
+	select {
+	case o.send <- outboundFrame{closeCode: code, closeReason: reason}:
+	default:
+		o.stop(code, reason)
+	}
+}
+
+// stop ends the connection without flushing the queued frames. A non-zero
+// code sends a close frame first on transports that have one.
+func (o *outbox) stop(code int, reason string) {
+	o.closeOnce.Do(func() {
+		o.closeCode = code
+		o.closeReason = reason
+		close(o.done)
+	})
+}
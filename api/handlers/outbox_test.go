@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"instant-messaging-app/config"
+
+	"github.com/gofiber/contrib/websocket"
+)
+
+func TestOutboxSlowConsumer(t *testing.T) {
+	saved := config.Cfg.WebSocket
+	defer func() { config.Cfg.WebSocket = saved }()
+	config.Cfg.WebSocket.SendBuffer = 1
+
+	t.Run("drop", func(t *testing.T) {
+		config.Cfg.WebSocket.SlowConsumer = "drop"
+		out := newOutbox()
+		if err := out.write(context.Background(), outboundFrame{data: []byte("first")}); err != nil {
+			t.Fatal(err)
+		}
+
+		// A frame without seq is dropped, the connection stays open
+		if err := out.write(context.Background(), outboundFrame{data: []byte("dropped")}); !errors.Is(err, errFrameDropped) {
+			t.Fatalf("expected the frame to be dropped, got %v", err)
+		}
+		select {
+		case <-out.done:
+			t.Fatal("dropping a frame closed the connection")
+		default:
+		}
+
+		// An event is not: the client is told to resume instead
+		if err := out.write(context.Background(), outboundFrame{data: []byte("event"), seq: 42}); !errors.Is(err, errEventDropped) {
+			t.Fatalf("expected the client to be disconnected, got %v", err)
+		}
+		select {
+		case <-out.done:
+		default:
+			t.Fatal("the connection is still open")
+		}
+		if out.closeCode != websocket.CloseTryAgainLater || out.closeReason != "event 42 dropped, resume from the last seq received" {
+			t.Fatalf("unexpected close %d %q", out.closeCode, out.closeReason)
+		}
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		config.Cfg.WebSocket.SlowConsumer = "disconnect"
+		out := newOutbox()
+		if err := out.write(context.Background(), outboundFrame{data: []byte("first")}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := out.write(context.Background(), outboundFrame{data: []byte("second")}); !errors.Is(err, errSlowConsumer) {
+			t.Fatalf("expected the client to be disconnected, got %v", err)
+		}
+		if out.closeCode != websocket.ClosePolicyViolation {
+			t.Fatalf("unexpected close code %d", out.closeCode)
+		}
+		if err := out.write(context.Background(), outboundFrame{data: []byte("third")}); !errors.Is(err, errClientClosed) {
+			t.Fatalf("expected the outbox to be closed, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/config"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// pollSessions holds the open long-poll sessions by ID
+var pollSessions sync.Map
+
+// pollSession is a long-poll client. Its queue is consumed into the outbox
+// between polls, and the session is dropped once it goes unpolled for
+// poll.session_ttl.
+type pollSession struct {
+	// id also names the connection queue
+	id     string
+	userID uint
+	// apiKeyID is the key that opened the session of a bot, if any
+	apiKeyID uint
+	out      *outbox
+	cancel   context.CancelFunc
+	expiry   *time.Timer
+
+	// busy allows a single poll at a time
+	busy sync.Mutex
+	// pending is the last batch returned, until a poll acknowledges cursor
+	pending []json.RawMessage
+	cursor  uint64
+
+	closeOnce sync.Once
+}
+
+// HandlePoll serves long-poll requests. A request without a session opens
+// one and returns its ID right away; the events missed since resume_from,
+// when given, come with the next poll. A request for a session waits up to
+// poll.timeout for notifications and returns them with the cursor to pass to
+// the next poll.
+func HandlePoll(ctx context.Context, messages repositories.MessageRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := c.Locals("claims").(utils.Claims)
+
+		var session *pollSession
+		if id := c.Query("session"); id != "" {
+			value, ok := pollSessions.Load(id)
+			if ok {
+				session = value.(*pollSession)
+			}
+			if session == nil || session.userID != claims.UserID || session.apiKeyID != claims.APIKeyID {
+				return responses.Error(c, fiber.StatusNotFound, "Unknown or expired poll session")
+			}
+		} else {
+			resume, err := resumeFrom(c, messages)
+			if err != nil {
+				return responses.Error(c, fiber.StatusBadRequest, err.Error())
+			}
+			session, err = openPollSession(utils.WithClaims(ctx, claims), claims.UserID, resume)
+			if err != nil {
+				slog.ErrorContext(c.UserContext(), "Failed to initialize the connection queue", "error", err)
+				return responses.Error(c, fiber.StatusInternalServerError, "Failed to initialize user queue")
+			}
+			return c.JSON(types.PollResponse{Session: session.id, Events: []json.RawMessage{}})
+		}
+
+		if !session.busy.TryLock() {
+			return responses.Error(c, fiber.StatusConflict, "Another poll of this session is in progress")
+		}
+		defer session.busy.Unlock()
+
+		cursor, _ := strconv.ParseUint(c.Query("cursor"), 10, 64)
+		response, open := session.poll(ctx, cursor)
+		if !open {
+			session.close()
+		}
+		return c.JSON(response)
+	}
+}
+
+// openPollSession declares the queue of a new session and starts consuming it
+func openPollSession(ctx context.Context, userID uint, resume *Resume) (*pollSession, error) {
+	id := utils.GenerateUUID()
+	if err := config.DeclareConnectionQueue(id, true); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(logging.WithConnection(ctx, id, userID))
+	claims, _ := utils.ClaimsFromContext(ctx)
+	session := &pollSession{
+		id:       id,
+		userID:   userID,
+		apiKeyID: claims.APIKeyID,
+		out:      newOutbox(),
+		cancel:   cancel,
+	}
+	session.expiry = time.AfterFunc(config.Cfg.Poll.SessionTTL, session.close)
+	pollSessions.Store(id, session)
+	atomic.AddInt64(&activeConnections, 1)
+
+	slog.InfoContext(ctx, "Poll session opened")
+	go consumeNotifications(ctx, id, userID, session.out, resume)
+	return session, nil
+}
+
+// poll returns the batch that cursor did not acknowledge, or else waits up to
+// poll.timeout for new notifications. open is false once the session ended.
+func (s *pollSession) poll(ctx context.Context, cursor uint64) (types.PollResponse, bool) {
+	s.expiry.Stop()
+	defer s.expiry.Reset(config.Cfg.Poll.SessionTTL)
+
+	open := true
+	if cursor == s.cursor || len(s.pending) == 0 {
+		s.pending, open = s.next(ctx, config.Cfg.Poll.Timeout)
+		if len(s.pending) > 0 {
+			s.cursor++
+		}
+	}
+
+	events := s.pending
+	if events == nil {
+		events = []json.RawMessage{}
+	}
+	return types.PollResponse{Session: s.id, Cursor: s.cursor, Events: events, Closed: !open}, open
+}
+
+// next waits up to timeout for a first frame, then takes the frames already
+// queued behind it
+func (s *pollSession) next(ctx context.Context, timeout time.Duration) ([]json.RawMessage, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var frames []json.RawMessage
+	select {
+	case frame := <-s.out.send:
+		if frame.closeCode != 0 {
+			return frames, false
+		}
+		frames = append(frames, frame.data)
+	case <-s.out.done:
+		return frames, false
+	case <-ctx.Done():
+		return frames, false
+	case <-timer.C:
+		return frames, true
+	}
+
+	for {
+		select {
+		case frame := <-s.out.send:
+			if frame.closeCode != 0 {
+				return frames, false
+			}
+			frames = append(frames, frame.data)
+		default:
+			return frames, true
+		}
+	}
+}
+
+// close drops the session and its queue
+func (s *pollSession) close() {
+	s.closeOnce.Do(func() {
+		pollSessions.Delete(s.id)
+		s.expiry.Stop()
+		s.out.stop(0, "")
+		// Ends the consumer, which deletes the queue
+		s.cancel()
+		atomic.AddInt64(&activeConnections, -1)
+	})
+}
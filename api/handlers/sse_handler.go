@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/config"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HandleEventStream streams the notifications of the authenticated user as
+// Server-Sent Events until the client leaves or ctx, the server context, ends.
+// The data of each event is a frame of the WebSocket protocol, and message
+// events carry their sequence number as the event ID so that browsers resume
+// through Last-Event-ID.
+func HandleEventStream(ctx context.Context, messages repositories.MessageRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := c.Locals("claims").(utils.Claims)
+
+		resume, err := resumeFrom(c, messages)
+		if err != nil {
+			return responses.Error(c, fiber.StatusBadRequest, err.Error())
+		}
+
+		// The stream gets its own queue, bound like the one of /ws/auth
+		queueName := utils.GenerateUUID()
+		if err := config.DeclareConnectionQueue(queueName, true); err != nil {
+			slog.ErrorContext(c.UserContext(), "Failed to initialize the connection queue", "error", err)
+			return responses.Error(c, fiber.StatusInternalServerError, "Failed to initialize user queue")
+		}
+
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Set(fiber.HeaderConnection, "keep-alive")
+		c.Set("X-Accel-Buffering", "no") // Keeps nginx from buffering the stream
+
+		streamCtx := logging.WithConnection(utils.WithClaims(ctx, claims), queueName, claims.UserID)
+		conn := c.Context().Conn()
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			streamEvents(streamCtx, w, conn, queueName, claims.UserID, resume)
+		})
+		return nil
+	}
+}
+
+// streamEvents writes the notifications of the queue as events, with a
+// comment every websocket.ping_interval so that idle proxies keep the stream
+// open and a client that left is noticed
+func streamEvents(ctx context.Context, w *bufio.Writer, conn net.Conn, uuid string, userID uint, resume *Resume) {
+	ctx, cancel := context.WithCancel(ctx)
+	out := newOutbox()
+
+	atomic.AddInt64(&activeConnections, 1)
+	defer func() {
+		atomic.AddInt64(&activeConnections, -1)
+		out.stop(0, "")
+		// Ends the consumer, which deletes the queue
+		cancel()
+	}()
+
+	slog.InfoContext(ctx, "Event stream opened")
+	go consumeNotifications(ctx, uuid, userID, out, resume)
+
+	ticker := time.NewTicker(config.Cfg.WebSocket.PingInterval)
+	defer ticker.Stop()
+
+	// Send the headers right away rather than with the first event
+	w.WriteString(": connected\n\n")
+	for {
+		conn.SetWriteDeadline(time.Now().Add(config.Cfg.WebSocket.WriteTimeout))
+		if err := w.Flush(); err != nil {
+			slog.InfoContext(ctx, "Event stream closed", "reason", err.Error())
+			return
+		}
+
+		select {
+		case frame := <-out.send:
+			if frame.closeCode != 0 {
+				slog.InfoContext(ctx, "Event stream closed", "reason", frame.closeReason)
+				return
+			}
+			if frame.seq != 0 {
+				fmt.Fprintf(w, "id: %d\n", frame.seq)
+			}
+			fmt.Fprintf(w, "data: %s\n\n", frame.data)
+		case <-ticker.C:
+			w.WriteString(": ping\n\n")
+		case <-out.done:
+			slog.InfoContext(ctx, "Event stream closed", "reason", out.closeReason)
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// resumeFrom returns the resume request of a realtime fallback: the
+// Last-Event-ID header sent by a reconnecting EventSource, or the
+// resume_from query parameter. It is nil when neither is set.
+func resumeFrom(c *fiber.Ctx, messages repositories.MessageRepository) (*Resume, error) {
+	value := c.Get("Last-Event-ID")
+	if value == "" {
+		value = c.Query("resume_from")
+	}
+	if value == "" {
+		return nil, nil
+	}
+
+	from, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sequence number %q", value)
+	}
+	return &Resume{Messages: messages, From: from}, nil
+}
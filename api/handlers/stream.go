@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/utils"
+)
+
+// Frame is a frame of the /ws/auth protocol; Seq is the sequence number of
+// the message it carries, if any
+type Frame struct {
+	Data []byte
+	Seq  uint64
+}
+
+// StreamClosedError is returned by StreamNotifications when the gateway ended
+// the stream, after a forced logout or for a slow consumer
+type StreamClosedError struct {
+	Reason string
+}
+
+func (e *StreamClosedError) Error() string {
+	return "stream closed: " + e.Reason
+}
+
+// StreamNotifications delivers the notifications of userID to send, as
+// /ws/auth does, until ctx ends or send fails. It is the realtime transport of
+// the APIs that are not served by Fiber.
+func StreamNotifications(ctx context.Context, userID uint, resume *Resume, send func(Frame) error) error {
+	// The stream gets its own queue, bound like the one of /ws/auth
+	queueName := utils.GenerateUUID()
+	if err := config.DeclareConnectionQueue(queueName, true); err != nil {
+		return fmt.Errorf("failed to initialize the connection queue: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(logging.WithConnection(ctx, queueName, userID))
+	out := newOutbox()
+
+	atomic.AddInt64(&activeConnections, 1)
+	defer func() {
+		atomic.AddInt64(&activeConnections, -1)
+		out.stop(0, "")
+		// Ends the consumer, which deletes the queue
+		cancel()
+	}()
+
+	slog.InfoContext(ctx, "Notification stream opened")
+	go consumeNotifications(ctx, queueName, userID, out, resume)
+
+	for {
+		select {
+		case frame := <-out.send:
+			if frame.closeCode != 0 {
+				slog.InfoContext(ctx, "Notification stream closed", "reason", frame.closeReason)
+				return &StreamClosedError{Reason: frame.closeReason}
+			}
+			if err := send(Frame{Data: frame.data, Seq: frame.seq}); err != nil {
+				slog.InfoContext(ctx, "Notification stream closed", "reason", err.Error())
+				return err
+			}
+		case <-out.done:
+			slog.InfoContext(ctx, "Notification stream closed", "reason", out.closeReason)
+			return &StreamClosedError{Reason: out.closeReason}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
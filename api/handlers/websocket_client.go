@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"instant-messaging-app/config"
+
+	"github.com/gofiber/contrib/websocket"
+)
+
+// wsClient writes the outbox of one WebSocket from its writer goroutine,
+// which also sends the pings
+type wsClient struct {
+	*outbox
+	conn *websocket.Conn
+	// closed is closed once the writer stopped using conn
+	closed chan struct{}
+}
+
+// newWSClient sets up the heartbeat of conn and starts its writer, which
+// runs until stop is called or a write fails. The handler must call wait
+// before returning, since conn is released to a pool afterwards.
+func newWSClient(ctx context.Context, conn *websocket.Conn) *wsClient {
+	client := &wsClient{
+		outbox: newOutbox(),
+		conn:   conn,
+		closed: make(chan struct{}),
+	}
+
+	// A client that neither answers the pings nor sends anything is gone
+	pongTimeout := config.Cfg.WebSocket.PongTimeout
+	conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	go client.writePump(ctx)
+	return client
+}
+
+// wait blocks until the writer stopped using the connection
+func (c *wsClient) wait() {
+	<-c.closed
+}
+
+// extendReadDeadline keeps the connection open after a message was received
+func (c *wsClient) extendReadDeadline() {
+	c.conn.SetReadDeadline(time.Now().Add(config.Cfg.WebSocket.PongTimeout))
+}
+
+// writePump writes the queued frames and the pings until the client stops.
+// Closing the socket on exit also ends the read loop.
+func (c *wsClient) writePump(ctx context.Context) {
+	ticker := time.NewTicker(config.Cfg.WebSocket.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.stop(0, "")
+		c.conn.Close()
+		close(c.closed)
+	}()
+
+	writeTimeout := config.Cfg.WebSocket.WriteTimeout
+	for {
+		select {
+		case frame := <-c.send:
+			if frame.closeCode != 0 {
+				c.writeClose(frame.closeCode, frame.closeReason)
+				return
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, frame.data); err != nil {
+				slog.DebugContext(ctx, "WebSocket write failed", "error", err)
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				slog.DebugContext(ctx, "WebSocket ping failed", "error", err)
+				return
+			}
+		case <-c.done:
+			if c.closeCode != 0 {
+				c.writeClose(c.closeCode, c.closeReason)
+			}
+			return
+		}
+	}
+}
+
+// writeClose sends a close frame, ignoring errors since the socket is closed next
+func (c *wsClient) writeClose(code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(config.Cfg.WebSocket.WriteTimeout))
+}
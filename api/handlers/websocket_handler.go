@@ -4,50 +4,87 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync/atomic"
 
+	"instant-messaging-app/api/responses"
 	"instant-messaging-app/api/services"
 	"instant-messaging-app/config"
-	"instant-messaging-app/types"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/metrics"
+	"instant-messaging-app/models"
+	"instant-messaging-app/tracing"
+	"instant-messaging-app/utils"
 
 	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// HandleWebSocketConnection manages the WebSocket connection and integrates it with RabbitMQ
-func HandleWebSocketConnection(conn *websocket.Conn, uuid string, userID uint, ctx context.Context) {
+// incomingTypes lists the message types accepted from clients, with the
+// scope an API key needs to send them
+var incomingTypes = map[string]string{
+	"getUsers":    models.ScopeUsersRead,
+	"searchUsers": models.ScopeUsersRead,
+	"getSelf":     models.ScopeUsersRead,
+	"getMessages": models.ScopeMessagesRead,
+	"sendMessage": models.ScopeMessagesSend,
+}
+
+// HandleWebSocketConnection manages the WebSocket connection and integrates it
+// with RabbitMQ. When resume is set, the missed events are sent before the
+// live ones.
+func HandleWebSocketConnection(conn *websocket.Conn, uuid string, userID uint, resume *Resume, ctx context.Context) {
+	// Every log line of the connection carries its UUID and user
+	ctx = logging.WithConnection(ctx, uuid, userID)
+
+	// Every write goes through the client, which also keeps the heartbeat
+	client := newWSClient(ctx, conn)
+
+	atomic.AddInt64(&activeConnections, 1)
+	metrics.WebSocketConnections.Inc()
 	defer func() {
+		atomic.AddInt64(&activeConnections, -1)
+		metrics.WebSocketConnections.Dec()
+		// The writer must be done with the connection before it is released
+		client.stop(0, "")
+		client.wait()
 		conn.Close()
 
 		// Delete the queue when the WebSocket is closed
 		if err := config.CleanupQueue(uuid); err != nil {
-			log.Printf("Failed to delete queue %s: %v", uuid, err)
+			slog.ErrorContext(ctx, "Failed to delete the connection queue", "error", err)
 		} else {
-			log.Printf("Queue %s deleted successfully", uuid)
+			slog.DebugContext(ctx, "Connection queue deleted")
 		}
 	}()
 
-	log.Printf("WebSocket connection established for identifier: %s", uuid)
+	slog.InfoContext(ctx, "WebSocket connection established")
 
 	// Start consuming messages for this WebSocket connection
-	go consumeNotifications(ctx, uuid, userID, conn)
+	go consumeNotifications(ctx, uuid, userID, client.outbox, resume)
 
-	// Keep the WebSocket connection alive
+	// Read until the client leaves, stops answering the pings or the writer
+	// closes the socket
 	for {
 		_, rawMessage, err := conn.ReadMessage()
 		if err != nil {
-			log.Printf("WebSocket connection closed for identifier: %s", uuid)
+			slog.InfoContext(ctx, "WebSocket connection closed", "reason", err.Error())
 			break
 		}
+		client.extendReadDeadline()
 
 		// Handle the incoming message
-		if err := handleIncomingWebSocketMessage(conn, rawMessage, uuid, userID); err != nil {
-			log.Printf("Failed to handle message for identifier %s: %v", uuid, err)
+		if err := handleIncomingWebSocketMessage(ctx, client, rawMessage, uuid, userID); err != nil {
+			slog.ErrorContext(ctx, "Failed to handle WebSocket message", "error", err)
 		}
 	}
 }
 
-// handleIncomingWebSocketMessage parses and routes the incoming WebSocket message
-func handleIncomingWebSocketMessage(conn *websocket.Conn, rawMessage []byte, uuid string, userID uint) error {
+// handleIncomingWebSocketMessage parses and routes the incoming WebSocket
+// message; each message starts the trace of the requests it publishes
+func handleIncomingWebSocketMessage(ctx context.Context, client *wsClient, rawMessage []byte, uuid string, userID uint) error {
 	// Generic message format with a type field
 	var baseMessage struct {
 		Type string `json:"type"`
@@ -58,53 +95,108 @@ func handleIncomingWebSocketMessage(conn *websocket.Conn, rawMessage []byte, uui
 		return fmt.Errorf("failed to parse message type: %w", err)
 	}
 
+	// Unknown types are counted together so clients cannot create new series
+	typeLabel := baseMessage.Type
+	scope, known := incomingTypes[typeLabel]
+	if !known {
+		typeLabel = "unknown"
+	}
+	metrics.WebSocketMessages.WithLabelValues("in", typeLabel).Inc()
+
+	// Each message is a new request for the services it reaches
+	ctx = logging.WithCorrelationID(ctx, logging.NewCorrelationID())
+	ctx, span := tracing.Tracer().Start(ctx, "websocket "+typeLabel,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("websocket.connection", uuid),
+			attribute.Int64("enduser.id", int64(userID)),
+		),
+	)
+	defer span.End()
+
+	// The sessions of API keys are limited to their scopes and rate
+	if claims, ok := utils.ClaimsFromContext(ctx); ok && known {
+		if !claims.HasScope(scope) {
+			return sendCodedErrorResponse(ctx, client.outbox, responses.Code(fiber.StatusForbidden), "The API key lacks the scope "+scope)
+		}
+		if ok, _ := services.AllowRequest(claims); !ok {
+			return sendCodedErrorResponse(ctx, client.outbox, responses.Code(fiber.StatusTooManyRequests), "Rate limit of the API key exceeded")
+		}
+	}
+
 	// Route the message based on its type
 	switch baseMessage.Type {
 	case "getUsers":
 		if userID == 0 {
-			return sendErrorResponse(conn, "Unauthorized request: getUsers requires authentication")
+			return sendErrorResponse(ctx, client.outbox, "Unauthorized request: getUsers requires authentication")
+		}
+		return handleGetUsers(ctx, client, uuid, userID)
+	case "searchUsers":
+		if userID == 0 {
+			return sendErrorResponse(ctx, client.outbox, "Unauthorized request: searchUsers requires authentication")
 		}
-		return handleGetUsers(conn, uuid)
+		return handleSearchUsers(ctx, client, uuid, userID, rawMessage)
 	case "getSelf":
 		if userID == 0 {
-			return sendErrorResponse(conn, "Unauthorized request: getSelf requires authentication")
+			return sendErrorResponse(ctx, client.outbox, "Unauthorized request: getSelf requires authentication")
 		}
-		return handleGetSelf(conn, uuid, userID)
+		return handleGetSelf(ctx, client, uuid, userID)
 	case "getMessages":
 		if userID == 0 {
-			return sendErrorResponse(conn, "Unauthorized request: getMessages requires authentication")
+			return sendErrorResponse(ctx, client.outbox, "Unauthorized request: getMessages requires authentication")
 		}
-		return handleGetMessages(conn, uuid, userID, rawMessage)
+		return handleGetMessages(ctx, client, uuid, userID, rawMessage)
 	case "sendMessage":
 		if userID == 0 {
-			return sendErrorResponse(conn, "Unauthorized request: sendMessage requires authentication")
+			return sendErrorResponse(ctx, client.outbox, "Unauthorized request: sendMessage requires authentication")
 		}
-		return handleSendMessage(uuid, userID, rawMessage)
+		return handleSendMessage(ctx, uuid, userID, rawMessage)
 	default:
-		return sendErrorResponse(conn, fmt.Sprintf("Unknown message type: %s", baseMessage.Type))
+		return sendErrorResponse(ctx, client.outbox, fmt.Sprintf("Unknown message type: %s", baseMessage.Type))
 	}
 }
 
-// handleGetUsers retrieves the list of users and sends them to the WebSocket client
-func handleGetUsers(conn *websocket.Conn, uuid string) error {
-	err := services.PublishGetUsers(uuid)
+// handleGetUsers retrieves the list of users visible to userID and sends them
+// to the WebSocket client
+func handleGetUsers(ctx context.Context, client *wsClient, uuid string, userID uint) error {
+	err := services.PublishGetUsers(ctx, uuid, userID)
 	if err != nil {
-		return sendErrorResponse(conn, fmt.Sprintf("Failed to retrieve users: %v", err))
+		return sendErrorResponse(ctx, client.outbox, fmt.Sprintf("Failed to retrieve users: %v", err))
 	}
 
 	return nil
 }
 
-func handleGetSelf(conn *websocket.Conn, uuid string, userID uint) error {
-	err := services.PublishGetSelf(uuid, userID)
+func handleSearchUsers(ctx context.Context, client *wsClient, uuid string, userID uint, message []byte) error {
+	// Parse the message to extract the query and pagination
+	var searchUsersRequest struct {
+		Type     string `json:"type"`
+		Query    string `json:"query"`
+		Page     int    `json:"page"`
+		PageSize int    `json:"page_size"`
+	}
+	if err := json.Unmarshal(message, &searchUsersRequest); err != nil {
+		return sendErrorResponse(ctx, client.outbox, "Invalid searchUsers request")
+	}
+
+	err := services.PublishSearchUsers(ctx, uuid, userID, searchUsersRequest.Query, searchUsersRequest.Page, searchUsersRequest.PageSize)
 	if err != nil {
-		return sendErrorResponse(conn, fmt.Sprintf("Failed to retrieve users: %v", err))
+		return sendErrorResponse(ctx, client.outbox, fmt.Sprintf("Failed to search users: %v", err))
 	}
 
 	return nil
 }
 
-func handleGetMessages(conn *websocket.Conn, uuid string, userID uint, message []byte) error {
+func handleGetSelf(ctx context.Context, client *wsClient, uuid string, userID uint) error {
+	err := services.PublishGetSelf(ctx, uuid, userID)
+	if err != nil {
+		return sendErrorResponse(ctx, client.outbox, fmt.Sprintf("Failed to retrieve users: %v", err))
+	}
+
+	return nil
+}
+
+func handleGetMessages(ctx context.Context, client *wsClient, uuid string, userID uint, message []byte) error {
 	// Parse the message to extract the recipient ID
 	var getMessagesRequest struct {
 		Type		string `json:"type"`
@@ -113,15 +205,15 @@ func handleGetMessages(conn *websocket.Conn, uuid string, userID uint, message [
 	json.Unmarshal(message, &getMessagesRequest)
 
 	// Fetch users from the database
-	err := services.PublishGetMessages(uuid, userID, getMessagesRequest.ReceiverID)
+	err := services.PublishGetMessages(ctx, uuid, userID, getMessagesRequest.ReceiverID)
 	if err != nil {
-		return sendErrorResponse(conn, fmt.Sprintf("Failed to retrieve users: %v", err))
+		return sendErrorResponse(ctx, client.outbox, fmt.Sprintf("Failed to retrieve users: %v", err))
 	}
 
 	return nil
 }
 
-func handleSendMessage(uuid string, userID uint, message []byte) error {
+func handleSendMessage(ctx context.Context, uuid string, userID uint, message []byte) error {
 	// Parse the message to extract the recipient ID
 	var sendMessageRequest struct {
 		Type		string `json:"type"`
@@ -131,140 +223,10 @@ func handleSendMessage(uuid string, userID uint, message []byte) error {
 	json.Unmarshal(message, &sendMessageRequest)
 
 	// Fetch users from the database
-	err := services.PublishSendMessage(uuid, userID, sendMessageRequest.ReceiverID, sendMessageRequest.Content)
+	err := services.PublishSendMessage(ctx, uuid, userID, sendMessageRequest.ReceiverID, sendMessageRequest.Content, services.SendOptions{})
 	if err != nil {
 		return fmt.Errorf("Failed to send message: %v", err)
 	}
 
 	return nil
 }
-
-// sendErrorResponse sends an error response to the WebSocket client
-func sendErrorResponse(conn *websocket.Conn, errorMessage string) error {
-	response := struct {
-		Type  string `json:"type"`
-		Error string `json:"error"`
-	}{
-		Type:  "error",
-		Error: errorMessage,
-	}
-
-	return sendMessageToWebSocket(conn, response)
-}
-
-func consumeNotifications(ctx context.Context, uuid string, userID uint, conn *websocket.Conn) {
-	// Create a new context specifically for this consumer
-	consumerCtx, cancel := context.WithCancel(ctx)
-
-	defer func() {
-		// Cleanup when the consumer exits
-		cancel()
-		log.Printf("Consumer cleanup completed for identifier: %s", uuid)
-
-		// Delete the queue after the WebSocket is closed
-		if err := config.CleanupQueue(uuid); err != nil {
-			log.Printf("Failed to delete queue %s: %v", uuid, err)
-		} else {
-			log.Printf("Queue %s deleted successfully", uuid)
-		}
-	}()
-
-	msgs, err := config.RabbitMQCh.Consume(
-		uuid, 		// Queue name
-		"",         // Consumer tag
-		true,       // Auto-acknowledge
-		false,      // Exclusive
-		false,      // No-local
-		false,      // No-wait
-		nil,
-	)
-	if err != nil {
-		log.Printf("Failed to start consumer for queue %s: %v", uuid, err)
-		return
-	}
-
-	// Listen for messages or context cancellation
-	for {
-		select {
-		case <-consumerCtx.Done():
-			log.Printf("Consumer context canceled for identifier: %s", uuid)
-			return
-		case msg := <-msgs:
-			// Process the message
-			if err := processMessage(userID, msg.Body, conn); err != nil && len(msg.Body) > 0 {
-				log.Printf("Failed to process message for queue %s: %v", uuid, err)
-			}
-		}
-	}
-}
-
-// processMessage routes and handles different types of messages
-func processMessage(userID uint, message []byte, conn *websocket.Conn) error {
-	// Generic message format with a type field and a data field
-	var baseMessage struct {
-		Type string          `json:"type"`
-		Data json.RawMessage `json:"data"`
-	}
-
-	// Parse the base structure of the message
-	if err := json.Unmarshal(message, &baseMessage); err != nil {
-		return err
-	}
-
-	// Route the message based on its type
-	switch baseMessage.Type {
-	case "registration_response":
-		var registrationResponse types.RegistrationResponse
-		if err := json.Unmarshal(baseMessage.Data, &registrationResponse); err != nil {
-			return err
-		}
-		return sendMessageToWebSocket(conn, registrationResponse)
-	case "login_response":
-		var loginResponse types.LoginResponse
-		if err := json.Unmarshal(baseMessage.Data, &loginResponse); err != nil {
-			return err
-		}
-		return sendMessageToWebSocket(conn, loginResponse)
-	case "get_users_response":
-		var usersResponse types.GetUsersResponse
-		if err := json.Unmarshal(baseMessage.Data, &usersResponse); err != nil {
-			return err
-		}
-		return sendMessageToWebSocket(conn, baseMessage)
-	case "get_self_response":
-		var selfResponse types.GetSelfResponse
-		if err := json.Unmarshal(baseMessage.Data, &selfResponse); err != nil {
-			return err
-		}
-		return sendMessageToWebSocket(conn, baseMessage)
-	case "get_messages_response":
-		var selfResponse types.GetMessagesResponse
-		if err := json.Unmarshal(baseMessage.Data, &selfResponse); err != nil {
-			return err
-		}
-		return sendMessageToWebSocket(conn, baseMessage)
-	case "send_message_response":
-		var selfResponse types.SendMessageResponse
-		if err := json.Unmarshal(baseMessage.Data, &selfResponse); err != nil {
-			return err
-		}
-		if selfResponse.Message.ReceiverID != userID && selfResponse.Message.SenderID != userID {
-			return nil
-		}
-		log.Printf("Recevied message: %s", selfResponse.Message.Content)
-		return sendMessageToWebSocket(conn, baseMessage)
-	default:
-		log.Printf("Unknown message type: %s", baseMessage.Type)
-		return nil
-	}
-}
-
-// sendMessageToWebSocket sends a structured message to the WebSocket client
-func sendMessageToWebSocket(conn *websocket.Conn, message interface{}) error {
-	rawMessage, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("failed to serialize message: %w", err)
-	}
-
-	return conn.WriteMessage(websocket.TextMessage, rawMessage)
-}
\ No newline at end of file
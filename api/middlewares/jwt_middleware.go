@@ -1,26 +1,99 @@
 package middlewares
 
 import (
+	"fmt"
+	"math"
+	"strings"
+
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/repositories"
 	"instant-messaging-app/utils"
 
 	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 )
 
-// Protected is a middleware function that validates the JWT token
-func Protected() fiber.Handler {
-	return jwtware.New(jwtware.Config{ // Use jwtware here
-		SigningKey:   jwtware.SigningKey{Key: []byte(utils.GetJWTSecret())}, // Fetch the secret key
-		ErrorHandler: jwtErrorHandler,        // Handle errors for invalid tokens
-	})
+// Protected is a middleware function that validates the JWT token, or the
+// API key of a bot, and checks the session against the current state of the
+// account
+func Protected(repos repositories.Repositories) fiber.Handler {
+	return apiKeyOrJWT(repos, false, jwtware.New(jwtware.Config{ // Use jwtware here
+		SigningKey:     jwtware.SigningKey{Key: []byte(utils.GetJWTSecret())}, // Fetch the secret key
+		ErrorHandler:   jwtErrorHandler,                                       // Handle errors for invalid tokens
+		SuccessHandler: sessionHandler(repos.Users),                           // Reject revoked or deactivated sessions
+	}))
+}
+
+// ProtectedStream is Protected for the realtime fallbacks. Since browsers
+// cannot set headers on an EventSource, the token may also be passed in the
+// access_token query parameter.
+func ProtectedStream(repos repositories.Repositories) fiber.Handler {
+	return apiKeyOrJWT(repos, true, jwtware.New(jwtware.Config{
+		SigningKey:     jwtware.SigningKey{Key: []byte(utils.GetJWTSecret())},
+		TokenLookup:    "header:Authorization,query:access_token",
+		AuthScheme:     "Bearer",
+		ErrorHandler:   jwtErrorHandler,
+		SuccessHandler: sessionHandler(repos.Users),
+	}))
+}
+
+// apiKeyOrJWT authenticates the requests carrying an API key as their bearer
+// token, or in the access_token query parameter when query is set, and hands
+// the other ones to jwt. Each request of a key counts against its rate limit.
+func apiKeyOrJWT(repos repositories.Repositories, query bool, jwt fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, _ := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if token == "" && query {
+			token = c.Query("access_token")
+		}
+		if !strings.HasPrefix(token, services.APIKeyPrefix) {
+			return jwt(c)
+		}
+
+		claims, err := services.AuthenticateAPIKey(c.UserContext(), repos, token)
+		if err != nil {
+			return jwtErrorHandler(c, err)
+		}
+		if ok, wait := services.AllowRequest(claims); !ok {
+			c.Set(fiber.HeaderRetryAfter, fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+			return responses.Error(c, fiber.StatusTooManyRequests, "Rate limit of the API key exceeded")
+		}
+
+		c.Locals("claims", claims)
+		return c.Next()
+	}
 }
 
 // jwtErrorHandler handles JWT validation errors
 func jwtErrorHandler(c *fiber.Ctx, err error) error {
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized: " + err.Error(),
-		})
+		return responses.Error(c, fiber.StatusUnauthorized, "Unauthorized: "+err.Error())
 	}
 	return nil
 }
+
+// sessionHandler checks the token against the current account state and
+// stores the resulting claims in the "claims" local
+func sessionHandler(users repositories.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := c.Locals("user").(*jwt.Token)
+
+		claims, err := utils.ClaimsFromMap(token.Claims.(jwt.MapClaims))
+		if err != nil {
+			return jwtErrorHandler(c, err)
+		}
+
+		user, err := services.ValidateSession(c.UserContext(), users, claims)
+		if err != nil {
+			return jwtErrorHandler(c, err)
+		}
+
+		// Always enforce the current role rather than the one baked into the token
+		claims.Role = user.Role
+		c.Locals("claims", claims)
+
+		return c.Next()
+	}
+}
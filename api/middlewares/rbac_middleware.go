@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/models"
+	"instant-messaging-app/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequirePermission rejects requests whose role does not grant permission.
+// It must be mounted after Protected, which stores the session claims.
+func RequirePermission(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("claims").(utils.Claims)
+		if !ok {
+			return responses.Error(c, fiber.StatusUnauthorized, "Unauthorized: missing session")
+		}
+
+		if !models.HasPermission(claims.Role, permission) {
+			return responses.Error(c, fiber.StatusForbidden, "Forbidden: missing permission "+permission)
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireRole rejects requests whose role is not one of roles
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("claims").(utils.Claims)
+		if !ok {
+			return responses.Error(c, fiber.StatusUnauthorized, "Unauthorized: missing session")
+		}
+
+		for _, role := range roles {
+			if claims.Role == role {
+				return c.Next()
+			}
+		}
+
+		return responses.Error(c, fiber.StatusForbidden, "Forbidden: insufficient role")
+	}
+}
+
+// RequireScope rejects the sessions of API keys that were not granted scope.
+// Sessions opened with a password may perform every operation of their user.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("claims").(utils.Claims)
+		if !ok {
+			return responses.Error(c, fiber.StatusUnauthorized, "Unauthorized: missing session")
+		}
+
+		if !claims.HasScope(scope) {
+			return responses.Error(c, fiber.StatusForbidden, "Forbidden: the API key lacks the scope "+scope)
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireUserSession rejects the sessions of API keys, for the routes that
+// only the person behind an account may use
+func RequireUserSession() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("claims").(utils.Claims)
+		if !ok {
+			return responses.Error(c, fiber.StatusUnauthorized, "Unauthorized: missing session")
+		}
+
+		if claims.APIKeyID != 0 {
+			return responses.Error(c, fiber.StatusForbidden, "Forbidden: API keys cannot use this route")
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,52 @@
+package responses
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"instant-messaging-app/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Error answers with the error envelope of the API: a message for humans and
+// a code for programs, derived from the status
+func Error(c *fiber.Ctx, status int, message string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"error": message,
+		"code":  Code(status),
+	})
+}
+
+// Code returns the error code of an HTTP status, such as not_found for 404
+func Code(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// Status returns the HTTP status matching the error code sent by a service
+func Status(code string) int {
+	switch code {
+	case types.ErrorCodeBadRequest:
+		return fiber.StatusBadRequest
+	case types.ErrorCodeNotFound:
+		return fiber.StatusNotFound
+	case types.ErrorCodeConflict:
+		return fiber.StatusConflict
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
+
+// ErrorHandler is the error handler of the gateway. Errors returned by the
+// handlers, such as the 404 of unknown routes, get the envelope too.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return Error(c, fiberErr.Code, fiberErr.Message)
+	}
+
+	slog.ErrorContext(c.UserContext(), "Request failed", "error", err)
+	return Error(c, fiber.StatusInternalServerError, "Internal server error")
+}
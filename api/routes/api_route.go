@@ -5,10 +5,16 @@ import (
 	"instant-messaging-app/api/controllers"
 	"instant-messaging-app/api/handlers"
 	"instant-messaging-app/api/middlewares"
+	"instant-messaging-app/api/responses"
+	"instant-messaging-app/api/services"
 	"instant-messaging-app/config"
+	"instant-messaging-app/docs"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
 	"instant-messaging-app/types"
 	"instant-messaging-app/utils"
-	"log"
+	"log/slog"
+	"time"
 
 	"context"
 
@@ -16,14 +22,18 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// SetupRoutes configures all application routes
-func SetupRoutes(app *fiber.App, ctx context.Context) {
+// SetupRoutes configures all application routes; repos backs the routes
+// that read the database directly
+func SetupRoutes(app *fiber.App, ctx context.Context, repos repositories.Repositories) {
 	api := app.Group("/api")
 
 	// Public routes
 	api.Post("/register", controllers.Register)
 	api.Post("/login", controllers.Login)
 
+	// OpenAPI and AsyncAPI documents; docs check compares them with the routes
+	docs.Register(api)
+
 	// Authenticated WebSocket route (for chat and other interactions)
 	app.Get("/ws/auth", func(c *fiber.Ctx) error {
 		return websocket.New(func(conn *websocket.Conn) {
@@ -34,75 +44,72 @@ func SetupRoutes(app *fiber.App, ctx context.Context) {
 				conn.Close()
 			}()
 
-			// Read the initial message containing the JWT token
+			// Read the initial message containing the JWT token, giving up on
+			// clients that never send it
+			conn.SetReadDeadline(time.Now().Add(config.Cfg.WebSocket.PongTimeout))
 			_, message, err := conn.ReadMessage()
 			if err != nil {
-				log.Println("Failed to read WebSocket message:", err)
+				slog.Warn("Failed to read the WebSocket authentication message", "error", err)
 				conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "error", "message": "Failed to read authentication token"}`))
 				return
 			}
 
 			var request types.TokenRequest
 			if err := json.Unmarshal(message, &request); err != nil {
-				log.Println("Failed to unmarshal request")
+				slog.Warn("Invalid WebSocket authentication message", "error", err)
 				conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "error", "message": "Invalid request format"}`))
 				return
 			}
 
-			userID, err := utils.ValidateJWT(request.Token)
+			// The token is a JWT or the API key of a bot; revoked sessions
+			// and deactivated accounts are rejected
+			claims, err := services.Authenticate(ctx, repos, request.Token)
 			if err != nil {
-				log.Println("Invalid token:", err)
+				slog.Warn("Rejected WebSocket session", "user_id", claims.UserID, "error", err)
 				conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "error", "message": "Invalid authentication token"}`))
 				return
 			}
 
+			// Receiving the messages of the user is reading them
+			if !claims.HasScope(models.ScopeMessagesRead) {
+				slog.Warn("Rejected WebSocket session", "user_id", claims.UserID, "api_key_id", claims.APIKeyID, "error", "missing scope")
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "error", "message": "The API key lacks the scope messages:read"}`))
+				return
+			}
+			userID := claims.UserID
+			ctx = utils.WithClaims(ctx, claims)
+
 			// Acknowledge successful authentication
 			conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "auth", "success": true, "message": "Authenticated successfully"}`))
 
 			// Use the user_id as the identifier for the WebSocket
 			queueName := utils.GenerateUUID()
 
-			// Declare a queue for the authenticated user
-			if _, err := config.RabbitMQCh.QueueDeclare(
-				queueName,
-				true,
-				true,
-				false,
-				false,
-				nil,
-			); err != nil {
-				log.Printf("Failed to declare queue for user_id %d: %v", userID, err)
+			// Declare a queue for the authenticated user and bind it to the exchanges
+			if err := config.DeclareConnectionQueue(queueName, true); err != nil {
+				slog.Error("Failed to initialize the connection queue", "user_id", userID, "error", err)
 				conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "error", "message": "Failed to initialize user queue"}`))
 				return
 			}
 
-			// Bind the queue to the exchanges
-			if err := config.RabbitMQCh.QueueBind(queueName, queueName, "notification_exchange", false, nil); err != nil {
-				log.Printf("Failed to bind queue %s to exchange: %v", queueName, err)
-				return
-			}
-			if err := config.RabbitMQCh.QueueBind(queueName, "", "notification_broadcast_exchange", false, nil); err != nil {
-				log.Printf("Failed to bind queue %s to exchange: %v", queueName, err)
-				return
+			// Handle the WebSocket connection, replaying the events missed
+			// since resume_from first when the client asked for it
+			var resume *handlers.Resume
+			if request.ResumeFrom != nil {
+				resume = &handlers.Resume{Messages: repos.Messages, From: *request.ResumeFrom}
 			}
-
-			// Handle the WebSocket connection
-			handlers.HandleWebSocketConnection(conn, queueName, userID, ctx)
+			handlers.HandleWebSocketConnection(conn, queueName, userID, resume, ctx)
 		})(c)
 	})
 	// Non-authenticated WebSocket route (for registration and login)
 	app.Get("/ws/:uuid", func(c *fiber.Ctx) error {
 		uuid := c.Params("uuid")
 		if uuid == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "UUID is required",
-			})
+			return responses.Error(c, fiber.StatusBadRequest, "UUID is required")
 		}
 
 		if !config.QueueExists(uuid) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Queue not found for UUID",
-			})
+			return responses.Error(c, fiber.StatusNotFound, "Queue not found for UUID")
 		}
 
 		return websocket.New(func(conn *websocket.Conn) {
@@ -113,11 +120,84 @@ func SetupRoutes(app *fiber.App, ctx context.Context) {
 				conn.Close()
 			}()
 
-			handlers.HandleWebSocketConnection(conn, uuid, 0, ctx)
+			handlers.HandleWebSocketConnection(conn, uuid, 0, nil, ctx)
 		})(c)
 	})
 
-	// Protected routes
-	api.Get("/messages/:userId", middlewares.Protected(), controllers.GetMessages) // Retrieve messages
-	api.Post("/messages/:userId", middlewares.Protected(), controllers.SendMessage) // Send a message
+	// Realtime fallbacks for clients that cannot keep a WebSocket open; they
+	// bind the same queues as /ws/auth and send the same frames
+	readMessages := middlewares.RequireScope(models.ScopeMessagesRead)
+	sendMessages := middlewares.RequireScope(models.ScopeMessagesSend)
+	readUsers := middlewares.RequireScope(models.ScopeUsersRead)
+	api.Get("/events", middlewares.ProtectedStream(repos), readMessages, handlers.HandleEventStream(ctx, repos.Messages))
+	api.Get("/poll", middlewares.ProtectedStream(repos), readMessages, handlers.HandlePoll(ctx, repos.Messages))
+
+	// Protected routes; they go through the same services as the WebSocket
+	// messages, so sent messages reach the realtime connections too. API keys
+	// only reach the routes of their scopes.
+	protected := middlewares.Protected(repos)
+	api.Get("/users", protected, readUsers, controllers.GetUsers)
+	api.Get("/users/search", protected, readUsers, controllers.SearchUsers)
+	api.Get("/users/me", protected, readUsers, controllers.GetSelf)
+	api.Get("/users/blocked", protected, middlewares.RequireUserSession(), controllers.ListBlockedUsers(repos.Blocks))
+	api.Put("/users/:userId/block", protected, middlewares.RequireUserSession(), controllers.BlockUser(repos))
+	api.Delete("/users/:userId/block", protected, middlewares.RequireUserSession(), controllers.UnblockUser(repos))
+	api.Get("/conversations/:userId", protected, readMessages, controllers.GetConversation)
+	api.Post("/conversations/:userId/messages", protected, sendMessages, controllers.PostMessage)
+	api.Get("/messages/:userId", protected, readMessages, controllers.GetMessages) // Retrieve messages
+	api.Post("/messages/:userId", protected, sendMessages, controllers.SendMessage) // Send a message
+
+	// Bots and their API keys, managed by their owner
+	bots := api.Group("/bots", protected, middlewares.RequireUserSession())
+	bots.Get("", controllers.ListBots(repos.Users))
+	bots.Post("", controllers.CreateBot(repos))
+	bots.Post("/:botId/deactivate", controllers.DeactivateBot(repos))
+	bots.Get("/:botId/keys", controllers.ListAPIKeys(repos))
+	bots.Post("/:botId/keys", controllers.CreateAPIKey(repos))
+	bots.Post("/:botId/keys/:keyId/revoke", controllers.RevokeAPIKey(repos))
+	bots.Get("/:botId/commands", controllers.ListBotCommands(repos))
+	bots.Post("/:botId/commands", controllers.RegisterBotCommand(repos))
+	bots.Delete("/:botId/commands/:commandId", controllers.DeleteBotCommand(repos))
+
+	// Slash commands: the autocomplete listing, and the answers of the bots
+	// to the invocations of their commands
+	api.Get("/commands", protected, sendMessages, controllers.ListCommands(repos.Commands))
+	api.Post("/commands/invocations/:invocationId/respond", protected, sendMessages, controllers.RespondToCommand(repos))
+
+	// Messages and reminders sent later by the scheduler daemon, managed by
+	// their sender
+	scheduled := api.Group("/scheduled-messages", protected, sendMessages)
+	scheduled.Get("", controllers.ListScheduledMessages(repos.Scheduled))
+	scheduled.Post("", controllers.ScheduleMessage(repos))
+	scheduled.Get("/:scheduleId", controllers.GetScheduledMessage(repos.Scheduled))
+	scheduled.Patch("/:scheduleId", controllers.UpdateScheduledMessage(repos))
+	scheduled.Delete("/:scheduleId", controllers.CancelScheduledMessage(repos))
+
+	// Outgoing webhooks and their delivery log, managed by their owner; the
+	// webhook daemon sends the deliveries
+	webhooks := api.Group("/webhooks", protected, middlewares.RequireUserSession())
+	webhooks.Get("", controllers.ListWebhooks(repos.Webhooks))
+	webhooks.Post("", controllers.CreateWebhook(repos))
+	webhooks.Delete("/:webhookId", controllers.DeleteWebhook(repos))
+	webhooks.Post("/:webhookId/enable", controllers.EnableWebhook(repos))
+	webhooks.Get("/:webhookId/deliveries", controllers.ListWebhookDeliveries(repos.Webhooks))
+	webhooks.Post("/:webhookId/deliveries/:deliveryId/replay", controllers.ReplayWebhookDelivery(repos))
+
+	// Incoming webhooks post into a conversation of their owner; the hook
+	// itself is authenticated by its token rather than a session
+	incoming := api.Group("/incoming-webhooks", protected, middlewares.RequireUserSession())
+	incoming.Get("", controllers.ListIncomingWebhooks(repos.IncomingWebhooks))
+	incoming.Post("", controllers.CreateIncomingWebhook(repos))
+	incoming.Delete("/:webhookId", controllers.DeleteIncomingWebhook(repos))
+	api.Post("/hooks/:webhookId", controllers.PostIncomingWebhook(repos))
+
+	// Admin routes
+	admin := api.Group("/admin", protected, middlewares.RequireUserSession())
+	admin.Get("/users", middlewares.RequirePermission(models.PermissionListUsers), controllers.AdminListUsers(repos.Users))
+	admin.Post("/users/:userId/deactivate", middlewares.RequirePermission(models.PermissionManageUsers), controllers.AdminDeactivateUser(repos))
+	admin.Post("/users/:userId/reactivate", middlewares.RequirePermission(models.PermissionManageUsers), controllers.AdminReactivateUser(repos))
+	admin.Post("/users/:userId/logout", middlewares.RequirePermission(models.PermissionRevokeSession), controllers.AdminForceLogout(repos))
+	admin.Put("/users/:userId/role", middlewares.RequirePermission(models.PermissionManageRoles), controllers.AdminSetUserRole(repos))
+	admin.Get("/stats", middlewares.RequirePermission(models.PermissionViewStats), controllers.AdminGetStats(repos))
+	admin.Get("/audit-logs", middlewares.RequirePermission(models.PermissionViewAuditLog), controllers.AdminListAuditLogs(repos.Audit))
 }
\ No newline at end of file
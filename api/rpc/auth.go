@@ -0,0 +1,125 @@
+package rpc
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/utils"
+
+	messagingv1 "instant-messaging-app/proto/messaging/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods can be called without a token
+var publicMethods = map[string]bool{
+	messagingv1.Auth_Register_FullMethodName: true,
+	messagingv1.Auth_Login_FullMethodName:    true,
+}
+
+// methodScopes are the API key scopes required by the other methods. The
+// three Subscribe calls stream the same notifications, messages included.
+var methodScopes = map[string]string{
+	messagingv1.Auth_Subscribe_FullMethodName:           models.ScopeMessagesRead,
+	messagingv1.Users_GetUsers_FullMethodName:           models.ScopeUsersRead,
+	messagingv1.Users_SearchUsers_FullMethodName:        models.ScopeUsersRead,
+	messagingv1.Users_GetSelf_FullMethodName:            models.ScopeUsersRead,
+	messagingv1.Users_Subscribe_FullMethodName:          models.ScopeMessagesRead,
+	messagingv1.Messages_GetConversation_FullMethodName: models.ScopeMessagesRead,
+	messagingv1.Messages_SendMessage_FullMethodName:     models.ScopeMessagesSend,
+	messagingv1.Messages_Subscribe_FullMethodName:       models.ScopeMessagesRead,
+}
+
+// claimsFromContext returns the claims stored by the authenticator
+func claimsFromContext(ctx context.Context) utils.Claims {
+	claims, _ := utils.ClaimsFromContext(ctx)
+	return claims
+}
+
+// authenticator checks the JWT or the API key of the authorization metadata
+// like the Protected middleware checks the Authorization header
+type authenticator struct {
+	repos repositories.Repositories
+}
+
+func (a *authenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if publicMethods[info.FullMethod] {
+		return handler(srv, stream)
+	}
+	ctx, err := a.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticate validates the bearer token and the session it belongs to, and
+// stores the claims, with the current role, in the context. Calls with an API
+// key must also be allowed by its scopes and its rate limit.
+func (a *authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata must be a bearer token")
+	}
+
+	claims, err := services.Authenticate(ctx, a.repos, token)
+	if err != nil {
+		slog.WarnContext(ctx, "Rejected gRPC session", "user_id", claims.UserID, "error", err)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !claims.HasScope(methodScopes[method]) {
+		return nil, status.Errorf(codes.PermissionDenied, "API key lacks the %s scope", methodScopes[method])
+	}
+	if ok, retryAfter := services.AllowRequest(claims); !ok {
+		return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %s", retryAfter.Round(time.Second))
+	}
+
+	ctx = utils.WithClaims(ctx, claims)
+	return logging.WithConnection(ctx, "", claims.UserID), nil
+}
+
+// correlationUnaryInterceptor gives each call a correlation ID, like each
+// HTTP request and WebSocket message
+func correlationUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(logging.WithCorrelationID(ctx, logging.NewCorrelationID()), req)
+}
+
+func correlationStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := logging.WithCorrelationID(stream.Context(), logging.NewCorrelationID())
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
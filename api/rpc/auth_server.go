@@ -0,0 +1,67 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/types"
+
+	messagingv1 "instant-messaging-app/proto/messaging/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// authServer registers and logs in users through the user service
+type authServer struct {
+	messagingv1.UnimplementedAuthServer
+	subscriber subscriber
+}
+
+func (s *authServer) Subscribe(req *messagingv1.SubscribeRequest, stream messagingv1.Auth_SubscribeServer) error {
+	return s.subscriber.subscribe(req, stream)
+}
+
+func (s *authServer) Register(ctx context.Context, req *messagingv1.RegisterRequest) (*messagingv1.RegisterResponse, error) {
+	// The same validation as the REST controller
+	if req.Username == "" {
+		return nil, status.Error(codes.InvalidArgument, "Username is required")
+	}
+	if len(req.Password) < 6 {
+		return nil, status.Error(codes.InvalidArgument, "Password must be at least 6 characters long")
+	}
+
+	var response types.RegistrationResponse
+	err := services.Request(ctx, "registration_response", &response, func(ctx context.Context, uuid string) error {
+		return services.PublishRegistrationRequest(ctx, uuid, req.Username, req.Password, req.DisplayName)
+	})
+	if err != nil {
+		return nil, serviceStatus(ctx, err, "Failed to process registration")
+	}
+	if !response.Success {
+		if strings.Contains(response.Message, "already taken") {
+			return nil, status.Error(codes.AlreadyExists, response.Message)
+		}
+		return nil, status.Error(codes.Internal, response.Message)
+	}
+	return &messagingv1.RegisterResponse{Message: response.Message}, nil
+}
+
+func (s *authServer) Login(ctx context.Context, req *messagingv1.LoginRequest) (*messagingv1.LoginResponse, error) {
+	if req.Username == "" {
+		return nil, status.Error(codes.InvalidArgument, "Username is required")
+	}
+
+	var response types.LoginResponse
+	err := services.Request(ctx, "login_response", &response, func(ctx context.Context, uuid string) error {
+		return services.PublishLoginRequest(ctx, uuid, req.Username, req.Password)
+	})
+	if err != nil {
+		return nil, serviceStatus(ctx, err, "Failed to process login")
+	}
+	if !response.Success {
+		return nil, status.Error(codes.Unauthenticated, response.Message)
+	}
+	return &messagingv1.LoginResponse{Token: response.Token}, nil
+}
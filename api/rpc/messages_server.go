@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"context"
+
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/types"
+
+	messagingv1 "instant-messaging-app/proto/messaging/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// messagesServer reads and sends messages through the message service
+type messagesServer struct {
+	messagingv1.UnimplementedMessagesServer
+	subscriber subscriber
+}
+
+func (s *messagesServer) Subscribe(req *messagingv1.SubscribeRequest, stream messagingv1.Messages_SubscribeServer) error {
+	return s.subscriber.subscribe(req, stream)
+}
+
+func (s *messagesServer) GetConversation(ctx context.Context, req *messagingv1.GetConversationRequest) (*messagingv1.GetConversationResponse, error) {
+	claims := claimsFromContext(ctx)
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid user ID")
+	}
+
+	var response types.GetMessagesResponse
+	err := services.Request(ctx, "get_messages_response", &response, func(ctx context.Context, uuid string) error {
+		return services.PublishGetMessages(ctx, uuid, claims.UserID, uint(req.UserId))
+	})
+	if err != nil {
+		return nil, serviceStatus(ctx, err, "Failed to retrieve messages")
+	}
+	return &messagingv1.GetConversationResponse{Messages: toMessages(response.Messages)}, nil
+}
+
+func (s *messagesServer) SendMessage(ctx context.Context, req *messagingv1.SendMessageRequest) (*messagingv1.SendMessageResponse, error) {
+	claims := claimsFromContext(ctx)
+	if req.ReceiverId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid user ID")
+	}
+
+	// Both users are notified by the broadcast, as for the sendMessage frame
+	var response types.SendMessageResponse
+	err := services.Request(ctx, "send_message_response", &response, func(ctx context.Context, uuid string) error {
+		return services.PublishSendMessage(ctx, uuid, claims.UserID, uint(req.ReceiverId), req.Content, services.SendOptions{Reply: true})
+	})
+	if err != nil {
+		return nil, serviceStatus(ctx, err, "Failed to send message")
+	}
+	return &messagingv1.SendMessageResponse{
+		Message: toMessage(response.Message),
+		Seq:     response.SenderSeq,
+	}, nil
+}
+
+func toMessage(message dtos.MessageDTO) *messagingv1.Message {
+	return &messagingv1.Message{
+		Id:         uint64(message.ID),
+		SenderId:   uint64(message.SenderID),
+		ReceiverId: uint64(message.ReceiverID),
+		Content:    message.Content,
+	}
+}
+
+func toMessages(messages []dtos.MessageDTO) []*messagingv1.Message {
+	result := make([]*messagingv1.Message, len(messages))
+	for i, message := range messages {
+		result[i] = toMessage(message)
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+// Package rpc serves the gRPC API of the gateway. Its services publish the
+// same requests as the REST controllers and stream the notifications of
+// /ws/auth.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+
+	messagingv1 "instant-messaging-app/proto/messaging/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NewServer returns a gRPC server exposing the Auth, Users and Messages
+// services; repos backs the session checks and the subscription replays
+func NewServer(repos repositories.Repositories) *grpc.Server {
+	authenticator := &authenticator{repos: repos}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(correlationUnaryInterceptor, authenticator.unary),
+		grpc.ChainStreamInterceptor(correlationStreamInterceptor, authenticator.stream),
+	)
+
+	subscriber := subscriber{messages: repos.Messages}
+	messagingv1.RegisterAuthServer(server, &authServer{subscriber: subscriber})
+	messagingv1.RegisterUsersServer(server, &usersServer{subscriber: subscriber})
+	messagingv1.RegisterMessagesServer(server, &messagesServer{subscriber: subscriber})
+	return server
+}
+
+// Serve serves the gRPC API on port until ctx is canceled, then stops the
+// server gracefully
+func Serve(ctx context.Context, repos repositories.Repositories, port string) error {
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+
+	server := NewServer(repos)
+	go func() {
+		<-ctx.Done()
+		// Subscriptions never end on their own, so they are cut after a grace
+		// period
+		timer := time.AfterFunc(5*time.Second, server.Stop)
+		server.GracefulStop()
+		timer.Stop()
+	}()
+
+	slog.Info("gRPC API running", "port", port)
+	return server.Serve(listener)
+}
+
+// serviceStatus turns the failure of a request to a service into a gRPC
+// status, like the REST controllers turn it into an HTTP status
+func serviceStatus(ctx context.Context, err error, message string) error {
+	var serviceErr *services.ServiceError
+	switch {
+	case errors.As(err, &serviceErr):
+		switch serviceErr.Code {
+		case types.ErrorCodeBadRequest:
+			return status.Error(codes.InvalidArgument, serviceErr.Message)
+		case types.ErrorCodeNotFound:
+			return status.Error(codes.NotFound, serviceErr.Message)
+		default:
+			return status.Error(codes.Internal, serviceErr.Message)
+		}
+	case errors.Is(err, services.ErrNoReply):
+		slog.WarnContext(ctx, message, "error", err)
+		return status.Error(codes.DeadlineExceeded, message+": the service did not reply")
+	default:
+		slog.ErrorContext(ctx, message, "error", err)
+		return status.Error(codes.Internal, message)
+	}
+}
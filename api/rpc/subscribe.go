@@ -0,0 +1,103 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"instant-messaging-app/api/handlers"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+
+	messagingv1 "instant-messaging-app/proto/messaging/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// subscriber implements the Subscribe call of the three services
+type subscriber struct {
+	messages repositories.MessageRepository
+}
+
+// subscribe streams the notifications of /ws/auth, converted to Notification
+// messages, until the client leaves or the session is revoked
+func (s subscriber) subscribe(req *messagingv1.SubscribeRequest, stream grpc.ServerStreamingServer[messagingv1.Notification]) error {
+	ctx := stream.Context()
+	claims := claimsFromContext(ctx)
+
+	var resume *handlers.Resume
+	if req.ResumeFrom != nil {
+		resume = &handlers.Resume{Messages: s.messages, From: *req.ResumeFrom}
+	}
+
+	err := handlers.StreamNotifications(ctx, claims.UserID, resume, func(frame handlers.Frame) error {
+		notification, err := toNotification(frame)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to convert a notification", "error", err)
+			return nil
+		}
+		if notification == nil {
+			return nil
+		}
+		return stream.Send(notification)
+	})
+
+	var closed *handlers.StreamClosedError
+	switch {
+	case errors.As(err, &closed) && closed.Reason == "slow consumer":
+		return status.Error(codes.ResourceExhausted, closed.Reason)
+	case errors.As(err, &closed):
+		return status.Error(codes.Unauthenticated, closed.Reason)
+	case errors.Is(err, context.Canceled):
+		return nil
+	default:
+		return err
+	}
+}
+
+// toNotification converts a frame of /ws/auth. Frames that only answer the
+// requests of a WebSocket are skipped, and nil is returned for them.
+func toNotification(frame handlers.Frame) (*messagingv1.Notification, error) {
+	var base struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(frame.Data, &base); err != nil {
+		return nil, err
+	}
+
+	notification := &messagingv1.Notification{Seq: frame.Seq}
+	switch base.Type {
+	case "send_message_response":
+		var response types.SendMessageResponse
+		if err := json.Unmarshal(base.Data, &response); err != nil {
+			return nil, err
+		}
+		notification.Event = &messagingv1.Notification_Message{Message: toMessage(response.Message)}
+	case "resumed":
+		var resumed types.ResumedNotification
+		if err := json.Unmarshal(base.Data, &resumed); err != nil {
+			return nil, err
+		}
+		notification.Event = &messagingv1.Notification_Resumed{Resumed: &messagingv1.Resumed{
+			ResumeFrom: resumed.ResumeFrom,
+			LastSeq:    resumed.LastSeq,
+			Replayed:   int32(resumed.Replayed),
+			Complete:   resumed.Complete,
+		}}
+	case "force_logout":
+		var forceLogout types.ForceLogoutNotification
+		if err := json.Unmarshal(base.Data, &forceLogout); err != nil {
+			return nil, err
+		}
+		notification.Event = &messagingv1.Notification_ForceLogout{ForceLogout: &messagingv1.ForceLogout{
+			UserId: uint64(forceLogout.UserID),
+		}}
+	default:
+		return nil, nil
+	}
+	return notification, nil
+}
@@ -0,0 +1,82 @@
+package rpc
+
+import (
+	"context"
+
+	"instant-messaging-app/api/services"
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/types"
+
+	messagingv1 "instant-messaging-app/proto/messaging/v1"
+)
+
+// usersServer reads the user directory through the user service
+type usersServer struct {
+	messagingv1.UnimplementedUsersServer
+	subscriber subscriber
+}
+
+func (s *usersServer) Subscribe(req *messagingv1.SubscribeRequest, stream messagingv1.Users_SubscribeServer) error {
+	return s.subscriber.subscribe(req, stream)
+}
+
+func (s *usersServer) GetUsers(ctx context.Context, req *messagingv1.GetUsersRequest) (*messagingv1.GetUsersResponse, error) {
+	claims := claimsFromContext(ctx)
+	var response types.GetUsersResponse
+	err := services.Request(ctx, "get_users_response", &response, func(ctx context.Context, uuid string) error {
+		return services.PublishGetUsers(ctx, uuid, claims.UserID)
+	})
+	if err != nil {
+		return nil, serviceStatus(ctx, err, "Failed to retrieve users")
+	}
+	return &messagingv1.GetUsersResponse{Users: toUsers(response.Users)}, nil
+}
+
+func (s *usersServer) SearchUsers(ctx context.Context, req *messagingv1.SearchUsersRequest) (*messagingv1.SearchUsersResponse, error) {
+	claims := claimsFromContext(ctx)
+
+	var response types.SearchUsersResponse
+	err := services.Request(ctx, "search_users_response", &response, func(ctx context.Context, uuid string) error {
+		return services.PublishSearchUsers(ctx, uuid, claims.UserID, req.Query, int(req.Page), int(req.PageSize))
+	})
+	if err != nil {
+		return nil, serviceStatus(ctx, err, "Failed to search users")
+	}
+	return &messagingv1.SearchUsersResponse{
+		Users:    toUsers(response.Users),
+		Query:    response.Query,
+		Page:     int32(response.Page),
+		PageSize: int32(response.PageSize),
+		Total:    response.Total,
+		HasMore:  response.HasMore,
+	}, nil
+}
+
+func (s *usersServer) GetSelf(ctx context.Context, req *messagingv1.GetSelfRequest) (*messagingv1.GetSelfResponse, error) {
+	claims := claimsFromContext(ctx)
+
+	var response types.GetSelfResponse
+	err := services.Request(ctx, "get_self_response", &response, func(ctx context.Context, uuid string) error {
+		return services.PublishGetSelf(ctx, uuid, claims.UserID)
+	})
+	if err != nil {
+		return nil, serviceStatus(ctx, err, "Failed to retrieve user")
+	}
+	return &messagingv1.GetSelfResponse{User: toUser(response.User)}, nil
+}
+
+func toUser(user dtos.UserDTO) *messagingv1.User {
+	return &messagingv1.User{
+		Id:          uint64(user.ID),
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+	}
+}
+
+func toUsers(users []dtos.UserDTO) []*messagingv1.User {
+	result := make([]*messagingv1.User, len(users))
+	for i, user := range users {
+		result[i] = toUser(user)
+	}
+	return result
+}
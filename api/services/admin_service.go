@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+	"log/slog"
+	"time"
+)
+
+// AuditContext identifies who performed an administrative action
+type AuditContext struct {
+	ActorID   uint
+	ActorRole string
+	IP        string
+}
+
+// SystemStats aggregates the counters reported by the admin stats endpoint
+type SystemStats struct {
+	Users            int64 `json:"users"`
+	ActiveUsers      int64 `json:"active_users"`
+	DeactivatedUsers int64 `json:"deactivated_users"`
+	Admins           int64 `json:"admins"`
+	Moderators       int64 `json:"moderators"`
+	Messages         int64 `json:"messages"`
+	MessagesLast24h  int64 `json:"messages_last_24h"`
+}
+
+// ListUsersForAdmin returns one page of users, including deactivated ones
+func ListUsersForAdmin(ctx context.Context, users repositories.UserRepository, query, role, status string, page, pageSize int) ([]models.User, int64, error) {
+	return users.ListForAdmin(ctx, repositories.AdminUserFilter{
+		Query:  query,
+		Role:   role,
+		Status: status,
+	}, page, pageSize)
+}
+
+// DeactivateUser disables an account and revokes all of its sessions, and
+// those of its bots
+func DeactivateUser(ctx context.Context, repos repositories.Repositories, audit AuditContext, userID uint) (models.User, error) {
+	if audit.ActorID == userID {
+		return models.User{}, invalid("cannot deactivate your own account")
+	}
+
+	now := time.Now()
+	user, err := updateUserWithAudit(ctx, repos, audit, userID, "user.deactivate", nil, func(user *models.User) (map[string]interface{}, error) {
+		if !user.IsActive() {
+			return nil, invalid("user is already deactivated")
+		}
+		user.DeactivatedAt = &now
+		user.TokenVersion++
+		return map[string]interface{}{
+			"deactivated_at": now,
+			"token_version":  user.TokenVersion,
+		}, nil
+	})
+	if err == nil {
+		publishForceLogout(user.ID)
+		// The API keys of the bots stop working with their owner, so that
+		// their realtime sessions are closed as well
+		bots, err := repos.Users.ListByOwner(ctx, user.ID)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to list the bots of a deactivated user", "user_id", user.ID, "error", err)
+		}
+		for _, bot := range bots {
+			publishForceLogout(bot.ID)
+		}
+	}
+	return user, err
+}
+
+// ReactivateUser re-enables a previously deactivated account
+func ReactivateUser(ctx context.Context, repos repositories.Repositories, audit AuditContext, userID uint) (models.User, error) {
+	return updateUserWithAudit(ctx, repos, audit, userID, "user.reactivate", nil, func(user *models.User) (map[string]interface{}, error) {
+		if user.IsActive() {
+			return nil, invalid("user is not deactivated")
+		}
+		user.DeactivatedAt = nil
+		return map[string]interface{}{"deactivated_at": nil}, nil
+	})
+}
+
+// ForceLogout revokes every token issued to a user and disconnects their WebSockets
+func ForceLogout(ctx context.Context, repos repositories.Repositories, audit AuditContext, userID uint) (models.User, error) {
+	user, err := updateUserWithAudit(ctx, repos, audit, userID, "user.force_logout", nil, func(user *models.User) (map[string]interface{}, error) {
+		user.TokenVersion++
+		return map[string]interface{}{"token_version": user.TokenVersion}, nil
+	})
+	if err == nil {
+		publishForceLogout(user.ID)
+	}
+	return user, err
+}
+
+// SetUserRole changes the role of a user
+func SetUserRole(ctx context.Context, repos repositories.Repositories, audit AuditContext, userID uint, role string) (models.User, error) {
+	if !models.IsValidRole(role) {
+		return models.User{}, invalid("unknown role")
+	}
+	if audit.ActorID == userID {
+		return models.User{}, invalid("cannot change your own role")
+	}
+
+	details := map[string]interface{}{"role": role}
+	return updateUserWithAudit(ctx, repos, audit, userID, "user.set_role", details, func(user *models.User) (map[string]interface{}, error) {
+		details["previous_role"] = user.Role
+		user.Role = role
+		return map[string]interface{}{"role": role}, nil
+	})
+}
+
+// GetSystemStats computes the database backed counters for the stats endpoint
+func GetSystemStats(ctx context.Context, repos repositories.Repositories) (SystemStats, error) {
+	var stats SystemStats
+	users := []struct {
+		target *int64
+		filter repositories.AdminUserFilter
+	}{
+		{&stats.Users, repositories.AdminUserFilter{}},
+		{&stats.ActiveUsers, repositories.AdminUserFilter{Status: "active"}},
+		{&stats.DeactivatedUsers, repositories.AdminUserFilter{Status: "deactivated"}},
+		{&stats.Admins, repositories.AdminUserFilter{Role: models.RoleAdmin}},
+		{&stats.Moderators, repositories.AdminUserFilter{Role: models.RoleModerator}},
+	}
+	for _, count := range users {
+		var err error
+		if *count.target, err = repos.Users.Count(ctx, count.filter); err != nil {
+			return stats, err
+		}
+	}
+
+	var err error
+	if stats.Messages, err = repos.Messages.Count(ctx, time.Time{}); err != nil {
+		return stats, err
+	}
+	stats.MessagesLast24h, err = repos.Messages.Count(ctx, time.Now().Add(-24*time.Hour))
+	return stats, err
+}
+
+// ListAuditLogs returns one page of the audit log, newest first
+func ListAuditLogs(ctx context.Context, audits repositories.AuditRepository, actorID uint, action string, page, pageSize int) ([]models.AuditLog, int64, error) {
+	return audits.List(ctx, repositories.AuditLogFilter{ActorID: actorID, Action: action}, page, pageSize)
+}
+
+// updateUserWithAudit loads the target user, writes the columns returned by
+// update and records the audit entry in the same transaction so that no
+// action goes unlogged
+func updateUserWithAudit(ctx context.Context, repos repositories.Repositories, audit AuditContext, userID uint, action string, details map[string]interface{}, update func(user *models.User) (map[string]interface{}, error)) (models.User, error) {
+	var user models.User
+	err := repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		var err error
+		if user, err = tx.Users.FindByID(ctx, userID); err != nil {
+			return err
+		}
+		if user.Role == models.RoleAdmin && audit.ActorRole != models.RoleAdmin {
+			return invalid("only admins can manage admin accounts")
+		}
+		fields, err := update(&user)
+		if err != nil {
+			return err
+		}
+		if err := tx.Users.Update(ctx, &user, fields); err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, action, "user", user.ID, details)
+	})
+	return user, err
+}
+
+// RecordAudit writes an audit log entry through audits, which belongs to the
+// transaction of the action when there is one
+func RecordAudit(ctx context.Context, audits repositories.AuditRepository, audit AuditContext, action, targetType string, targetID uint, details map[string]interface{}) error {
+	encoded := ""
+	if details != nil {
+		raw, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		encoded = string(raw)
+	}
+
+	return audits.Create(ctx, &models.AuditLog{
+		ActorID:    audit.ActorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    encoded,
+		IP:         audit.IP,
+	})
+}
+
+// publishForceLogout tells every gateway to drop the WebSockets of a user
+func publishForceLogout(userID uint) {
+	slog.Info("Publishing force logout", "target_user_id", userID)
+	utils.PublishNotification(context.Background(), config.Cfg.Exchanges.NotificationBroadcast, "", "force_logout", types.ForceLogoutNotification{
+		UserID: userID,
+	})
+}
@@ -1,81 +1,70 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"instant-messaging-app/broker"
 	"instant-messaging-app/config"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
 	"instant-messaging-app/types"
-	"log"
-
-	amqp "github.com/rabbitmq/amqp091-go"
+	"instant-messaging-app/utils"
+	"log/slog"
 )
 
 // PublishRegistrationRequest publishes a registration request to RabbitMQ
-func PublishRegistrationRequest(uuid, username, password string) error {
+func PublishRegistrationRequest(ctx context.Context, uuid, username, password, displayName string) error {
+	ctx = logging.WithConnection(ctx, uuid, 0)
+
 	// Define the registration request payload
 	request := types.AuthenicationRequest{
-		UUID:     uuid,
-		Username: username,
-		Password: password,
+		UUID:        uuid,
+		Username:    username,
+		Password:    password,
+		DisplayName: displayName,
 	}
 
 	// Marshal the request to JSON
 	body, err := json.Marshal(request)
 	if err != nil {
-		log.Printf("Failed to marshal registration request: %v", err)
+		slog.ErrorContext(ctx, "Failed to marshal registration request", "error", err)
 		return fmt.Errorf("failed to marshal registration request")
 	}
 	// Create and bind a queue for the UUID
-	_, err = config.RabbitMQCh.QueueDeclare(
-		uuid, // Queue name
-		true,      // Durable
-		true,      // Auto-delete (deleted when last consumer disconnects)
-		false,     // Exclusive
-		false,     // No-wait
-		nil,       // Arguments
-	)
+	err = config.DeclareConnectionQueue(uuid, false)
 	if err != nil {
-		log.Printf("Failed to declare queue for UUID %s: %v", uuid, err)
-		return fmt.Errorf("failed to declare queue for UUID %s: %w", uuid, err)
+		slog.ErrorContext(ctx, "Failed to declare the connection queue", "error", err)
+		return err
 	}
 
-	// Bind the queue to the notification exchange with the UUID as the routing key
-	err = config.RabbitMQCh.QueueBind(
-		uuid,             // Queue name
-		uuid,             // Routing key
-		"notification_exchange", // Exchange name
-		false,
-		nil,
-	)
-	if err != nil {
-		log.Printf("Failed to bind queue %s to exchange: %v", uuid, err)
-		return fmt.Errorf("failed to bind queue %s: %w", uuid, err)
-	}
-
-	log.Printf("Queue created and bound for UUID: %s", uuid)
+	slog.DebugContext(ctx, "Connection queue declared")
 
 	// Publish the message to the "user_direct_exchange" with the routing key "registration"
-	err = config.RabbitMQCh.Publish(
-		"user_direct_exchange", // Exchange name
+	err = config.Broker.Publish(
+		ctx,
+		config.Cfg.Exchanges.UserDirect, // Exchange name
 		"registration",         // Routing key
-		false,                  // Mandatory
-		false,                  // Immediate
-		amqp.Publishing{
+		broker.Message{
 			ContentType: "application/json",
 			Body:        body,
 		},
 	)
 	if err != nil {
-		log.Printf("Failed to publish registration request: %v", err)
+		slog.ErrorContext(ctx, "Failed to publish registration request", "error", err)
 		return fmt.Errorf("failed to publish registration request")
 	}
 
-	log.Printf("Published registration request for UUID %s", uuid)
+	slog.DebugContext(ctx, "Published registration request")
 	return nil
 }
 
 // PublishRegistrationRequest publishes a registration request to RabbitMQ
-func PublishLoginRequest(uuid, username, password string) error {
+func PublishLoginRequest(ctx context.Context, uuid, username, password string) error {
+	ctx = logging.WithConnection(ctx, uuid, 0)
+
 	// Define the registration request payload
 	request := types.AuthenicationRequest{
 		UUID:     uuid,
@@ -86,54 +75,50 @@ func PublishLoginRequest(uuid, username, password string) error {
 	// Marshal the request to JSON
 	body, err := json.Marshal(request)
 	if err != nil {
-		log.Printf("Failed to marshal registration request: %v", err)
+		slog.ErrorContext(ctx, "Failed to marshal login request", "error", err)
 		return fmt.Errorf("failed to marshal registration request")
 	}
 	// Create and bind a queue for the UUID
-	_, err = config.RabbitMQCh.QueueDeclare(
-		uuid, // Queue name
-		true,      // Durable
-		true,      // Auto-delete (deleted when last consumer disconnects)
-		false,     // Exclusive
-		false,     // No-wait
-		nil,       // Arguments
-	)
+	err = config.DeclareConnectionQueue(uuid, false)
 	if err != nil {
-		log.Printf("Failed to declare queue for UUID %s: %v", uuid, err)
-		return fmt.Errorf("failed to declare queue for UUID %s: %w", uuid, err)
+		slog.ErrorContext(ctx, "Failed to declare the connection queue", "error", err)
+		return err
 	}
 
-	// Bind the queue to the notification exchange with the UUID as the routing key
-	err = config.RabbitMQCh.QueueBind(
-		uuid,             // Queue name
-		uuid,             // Routing key
-		"notification_exchange", // Exchange name
-		false,
-		nil,
-	)
-	if err != nil {
-		log.Printf("Failed to bind queue %s to exchange: %v", uuid, err)
-		return fmt.Errorf("failed to bind queue %s: %w", uuid, err)
-	}
-
-	log.Printf("Queue created and bound for UUID: %s", uuid)
+	slog.DebugContext(ctx, "Connection queue declared")
 
 	// Publish the message to the "user_direct_exchange" with the routing key "registration"
-	err = config.RabbitMQCh.Publish(
-		"user_direct_exchange", // Exchange name
+	err = config.Broker.Publish(
+		ctx,
+		config.Cfg.Exchanges.UserDirect, // Exchange name
 		"login",         // Routing key
-		false,                  // Mandatory
-		false,                  // Immediate
-		amqp.Publishing{
+		broker.Message{
 			ContentType: "application/json",
 			Body:        body,
 		},
 	)
 	if err != nil {
-		log.Printf("Failed to publish login request: %v", err)
+		slog.ErrorContext(ctx, "Failed to publish login request", "error", err)
 		return fmt.Errorf("failed to login registration request")
 	}
 
-	log.Printf("Published login request for UUID %s", uuid)
+	slog.DebugContext(ctx, "Published login request")
 	return nil
-}
\ No newline at end of file
+}
+
+// ValidateSession checks that the token still belongs to an active account and
+// has not been revoked by a force logout. It returns the current user so that
+// callers see role changes without waiting for a new token.
+func ValidateSession(ctx context.Context, users repositories.UserRepository, claims utils.Claims) (models.User, error) {
+	user, err := users.FindByID(ctx, claims.UserID)
+	if err != nil {
+		return user, errors.New("user not found")
+	}
+	if !user.IsActive() {
+		return user, errors.New("account deactivated")
+	}
+	if user.TokenVersion != claims.TokenVersion {
+		return user, errors.New("session revoked")
+	}
+	return user, nil
+}
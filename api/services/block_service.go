@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+
+	"gorm.io/gorm"
+)
+
+// ErrBlockSelf is returned when users try to block themselves
+var ErrBlockSelf = errors.New("users cannot block themselves")
+
+// ListBlockedUsers returns the users blocked by userID
+func ListBlockedUsers(ctx context.Context, blocks repositories.BlockRepository, userID uint) ([]models.User, error) {
+	return blocks.ListBlocked(ctx, userID)
+}
+
+// BlockUser blocks blockedID for userID and returns the blocked user. The two
+// no longer find each other in the user directory.
+func BlockUser(ctx context.Context, repos repositories.Repositories, userID, blockedID uint) (models.User, error) {
+	if userID == blockedID {
+		return models.User{}, ErrBlockSelf
+	}
+	blocked, err := repos.Users.FindByID(ctx, blockedID)
+	if err != nil {
+		return blocked, err
+	}
+	return blocked, repos.Blocks.Block(ctx, userID, blockedID)
+}
+
+// UnblockUser lifts the block of blockedID by userID and returns the
+// unblocked user, or gorm.ErrRecordNotFound when there was no block
+func UnblockUser(ctx context.Context, repos repositories.Repositories, userID, blockedID uint) (models.User, error) {
+	unblocked, err := repos.Blocks.Unblock(ctx, userID, blockedID)
+	if err != nil {
+		return models.User{}, err
+	}
+	if !unblocked {
+		return models.User{}, gorm.ErrRecordNotFound
+	}
+	return repos.Users.FindByID(ctx, blockedID)
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix starts every API key, which tells them apart from JWTs
+const APIKeyPrefix = "imk_"
+
+// botPassword is stored as the password of bots. It is not a bcrypt hash, so
+// no password ever matches it.
+const botPassword = "!"
+
+// CreatedAPIKey is a new API key with its secret, which is only known at creation
+type CreatedAPIKey struct {
+	Key    models.APIKey
+	Secret string
+}
+
+// ListBots returns the bots created by ownerID
+func ListBots(ctx context.Context, users repositories.UserRepository, ownerID uint) ([]models.User, error) {
+	return users.ListByOwner(ctx, ownerID)
+}
+
+// CreateBot creates a bot account owned by the actor
+func CreateBot(ctx context.Context, repos repositories.Repositories, audit AuditContext, username, displayName string) (models.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return models.User{}, invalid("username is required")
+	}
+
+	ownerID := audit.ActorID
+	bot := models.User{
+		Username:    username,
+		DisplayName: displayName,
+		Password:    botPassword,
+		Role:        models.RoleUser,
+		OwnerID:     &ownerID,
+	}
+	err := repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		owned, err := tx.Users.CountByOwner(ctx, ownerID)
+		if err != nil {
+			return err
+		}
+		if owned >= int64(config.Cfg.Bots.MaxPerOwner) {
+			return invalid("a user may own at most %d bots", config.Cfg.Bots.MaxPerOwner)
+		}
+
+		_, err = tx.Users.FindByUsername(ctx, username)
+		if err == nil {
+			return invalid("username already taken")
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := tx.Users.Create(ctx, &bot); err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, "bot.create", "user", bot.ID, map[string]interface{}{"username": username})
+	})
+	return bot, err
+}
+
+// DeactivateBot deactivates a bot of the actor, revokes its API keys and
+// disconnects its sessions
+func DeactivateBot(ctx context.Context, repos repositories.Repositories, audit AuditContext, botID uint) (models.User, error) {
+	var bot models.User
+	err := repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		var err error
+		if bot, err = tx.Users.FindBot(ctx, audit.ActorID, botID); err != nil {
+			return err
+		}
+		if !bot.IsActive() {
+			return invalid("bot is already deactivated")
+		}
+
+		now := time.Now()
+		bot.DeactivatedAt = &now
+		bot.TokenVersion++
+		err = tx.Users.Update(ctx, &bot, map[string]interface{}{
+			"deactivated_at": now,
+			"token_version":  bot.TokenVersion,
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.APIKeys.RevokeByUser(ctx, bot.ID, now); err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, "bot.deactivate", "user", bot.ID, nil)
+	})
+	if err == nil {
+		publishForceLogout(bot.ID)
+	}
+	return bot, err
+}
+
+// ListAPIKeys returns the keys of a bot of ownerID
+func ListAPIKeys(ctx context.Context, repos repositories.Repositories, ownerID, botID uint) ([]models.APIKey, error) {
+	if _, err := repos.Users.FindBot(ctx, ownerID, botID); err != nil {
+		return nil, err
+	}
+	return repos.APIKeys.ListByUser(ctx, botID)
+}
+
+// CreateAPIKey issues a key to a bot of the actor. A zero rateLimit selects
+// bots.default_rate_limit, and a zero ttl a key that does not expire.
+func CreateAPIKey(ctx context.Context, repos repositories.Repositories, audit AuditContext, botID uint, name string, scopes []string, rateLimit int, ttl time.Duration) (CreatedAPIKey, error) {
+	var created CreatedAPIKey
+	if strings.TrimSpace(name) == "" {
+		return created, invalid("name is required")
+	}
+	if len(scopes) == 0 {
+		return created, invalid("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !models.IsValidScope(scope) {
+			return created, invalid("unknown scope %q, expected one of %s", scope, strings.Join(models.Scopes, ", "))
+		}
+	}
+	if rateLimit < 0 || rateLimit > config.Cfg.Bots.MaxRateLimit {
+		return created, invalid("rate_limit must be between 0 and %d requests per minute", config.Cfg.Bots.MaxRateLimit)
+	}
+	if ttl < 0 {
+		return created, invalid("expires_in must not be negative")
+	}
+
+	secret, err := generateToken(APIKeyPrefix)
+	if err != nil {
+		return created, err
+	}
+	key := models.APIKey{
+		UserID:    botID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    secret[:len(APIKeyPrefix)+8],
+		Hash:      hashAPIKey(secret),
+		Scopes:    strings.Join(scopes, ","),
+		RateLimit: rateLimit,
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+
+	err = repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		bot, err := tx.Users.FindBot(ctx, audit.ActorID, botID)
+		if err != nil {
+			return err
+		}
+		if !bot.IsActive() {
+			return invalid("bot is deactivated")
+		}
+		if err := tx.APIKeys.Create(ctx, &key); err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, "api_key.create", "api_key", key.ID, map[string]interface{}{
+			"bot_id": botID,
+			"name":   key.Name,
+			"scopes": scopes,
+		})
+	})
+	return CreatedAPIKey{Key: key, Secret: secret}, err
+}
+
+// RevokeAPIKey revokes a key of a bot of the actor and disconnects the
+// sessions opened with it
+func RevokeAPIKey(ctx context.Context, repos repositories.Repositories, audit AuditContext, botID, keyID uint) (models.APIKey, error) {
+	var key models.APIKey
+	err := repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		if _, err := tx.Users.FindBot(ctx, audit.ActorID, botID); err != nil {
+			return err
+		}
+		var err error
+		if key, err = tx.APIKeys.FindByUser(ctx, botID, keyID); err != nil {
+			return err
+		}
+		if key.RevokedAt != nil {
+			return invalid("API key is already revoked")
+		}
+
+		now := time.Now()
+		key.RevokedAt = &now
+		if err := tx.APIKeys.Update(ctx, &key, map[string]interface{}{"revoked_at": now}); err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, "api_key.revoke", "api_key", key.ID, map[string]interface{}{"bot_id": botID})
+	})
+	if err == nil {
+		slog.Info("Publishing force logout", "target_user_id", botID, "api_key_id", key.ID)
+		utils.PublishNotification(context.Background(), config.Cfg.Exchanges.NotificationBroadcast, "", "force_logout", types.ForceLogoutNotification{
+			UserID:   botID,
+			APIKeyID: key.ID,
+		})
+	}
+	return key, err
+}
+
+// Authenticate checks a bearer token, a JWT or an API key, against the
+// current state of the account and returns the claims of its session
+func Authenticate(ctx context.Context, repos repositories.Repositories, token string) (utils.Claims, error) {
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		return AuthenticateAPIKey(ctx, repos, token)
+	}
+
+	claims, err := utils.ParseJWT(token)
+	if err != nil {
+		return claims, err
+	}
+	user, err := ValidateSession(ctx, repos.Users, claims)
+	if err != nil {
+		return claims, err
+	}
+
+	// Always enforce the current role rather than the one baked into the token
+	claims.Role = user.Role
+	return claims, nil
+}
+
+// AuthenticateAPIKey returns the claims of the session of an API key, which
+// must be neither revoked nor expired and belong to an active bot of an
+// active owner
+func AuthenticateAPIKey(ctx context.Context, repos repositories.Repositories, secret string) (utils.Claims, error) {
+	key, err := repos.APIKeys.FindByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		return utils.Claims{}, errors.New("invalid API key")
+	}
+	now := time.Now()
+	if !key.IsUsable(now) {
+		return utils.Claims{}, errors.New("API key revoked or expired")
+	}
+
+	bot, err := repos.Users.FindByID(ctx, key.UserID)
+	if err != nil {
+		return utils.Claims{}, errors.New("user not found")
+	}
+	if !bot.IsActive() {
+		return utils.Claims{}, errors.New("account deactivated")
+	}
+	// The bots of a deactivated owner stop with them
+	if bot.OwnerID != nil {
+		owner, err := repos.Users.FindByID(ctx, *bot.OwnerID)
+		if err != nil || !owner.IsActive() {
+			return utils.Claims{}, errors.New("owner deactivated")
+		}
+	}
+
+	// Recording every use would write on each request
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute {
+		if err := repos.APIKeys.Update(ctx, &key, map[string]interface{}{"last_used_at": now}); err != nil {
+			slog.WarnContext(ctx, "Failed to record the use of an API key", "api_key_id", key.ID, "error", err)
+		}
+	}
+
+	rateLimit := key.RateLimit
+	if rateLimit == 0 {
+		rateLimit = config.Cfg.Bots.DefaultRateLimit
+	}
+	return utils.Claims{
+		UserID:       bot.ID,
+		Username:     bot.Username,
+		Role:         bot.Role,
+		TokenVersion: bot.TokenVersion,
+		APIKeyID:     key.ID,
+		Scopes:       key.ScopeList(),
+		RateLimit:    rateLimit,
+	}, nil
+}
+
+// generateToken returns a new random key or token starting with prefix
+func generateToken(prefix string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashAPIKey returns the stored form of a key or token. They are random, so
+// a fast hash is enough to keep a leaked database from revealing them.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// CommandSearchLimit bounds the bot commands of the autocomplete listing
+	CommandSearchLimit = 50
+	// MaxCommandsPerBot bounds the commands a bot may register
+	MaxCommandsPerBot = 25
+	// MaxCommandUsageLength and MaxCommandDescriptionLength bound the help
+	// shown by the autocomplete listing
+	MaxCommandUsageLength       = 100
+	MaxCommandDescriptionLength = 200
+)
+
+// ErrInvocationExpired is returned when a bot answers an invocation after
+// models.CommandInvocationTTL
+var ErrInvocationExpired = errors.New("the invocation expired")
+
+// ListCommands returns the built-in commands and those of the active bots
+// whose name starts with prefix, sorted by name, for the autocomplete
+func ListCommands(ctx context.Context, commands repositories.CommandRepository, prefix string) ([]models.CommandInfo, error) {
+	prefix = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(prefix), "/"))
+
+	var listed []models.CommandInfo
+	for _, command := range models.BuiltinCommands {
+		if strings.HasPrefix(command.Name, prefix) {
+			listed = append(listed, command)
+		}
+	}
+	registered, err := commands.Search(ctx, prefix, CommandSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+	for _, command := range registered {
+		listed = append(listed, command.Info())
+	}
+	sort.Slice(listed, func(i, j int) bool { return listed[i].Name < listed[j].Name })
+	return listed, nil
+}
+
+// ListBotCommands returns the commands of a bot of ownerID
+func ListBotCommands(ctx context.Context, repos repositories.Repositories, ownerID, botID uint) ([]models.BotCommand, error) {
+	if _, err := repos.Users.FindBot(ctx, ownerID, botID); err != nil {
+		return nil, err
+	}
+	return repos.Commands.ListByBot(ctx, botID)
+}
+
+// RegisterBotCommand registers a slash command run by a bot of the actor.
+// Command names are shared by every user, so a name is taken by the first
+// bot registering it.
+func RegisterBotCommand(ctx context.Context, repos repositories.Repositories, audit AuditContext, botID uint, name, usage, description string) (models.BotCommand, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "/"))
+	usage = strings.TrimSpace(usage)
+	description = strings.TrimSpace(description)
+	switch {
+	case !models.IsValidCommandName(name):
+		return models.BotCommand{}, invalid("name must be 1 to 32 lowercase letters, digits, - or _, starting with a letter or a digit")
+	case models.IsBuiltinCommand(name):
+		return models.BotCommand{}, invalid("/%s is a built-in command", name)
+	case utf8.RuneCountInString(usage) > MaxCommandUsageLength:
+		return models.BotCommand{}, invalid("usage must be at most %d characters", MaxCommandUsageLength)
+	case utf8.RuneCountInString(description) > MaxCommandDescriptionLength:
+		return models.BotCommand{}, invalid("description must be at most %d characters", MaxCommandDescriptionLength)
+	}
+
+	command := models.BotCommand{
+		BotID:       botID,
+		Name:        name,
+		Usage:       usage,
+		Description: description,
+	}
+	err := repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		bot, err := tx.Users.FindBot(ctx, audit.ActorID, botID)
+		if err != nil {
+			return err
+		}
+		if !bot.IsActive() {
+			return invalid("bot is deactivated")
+		}
+
+		registered, err := tx.Commands.CountByBot(ctx, botID)
+		if err != nil {
+			return err
+		}
+		if registered >= MaxCommandsPerBot {
+			return invalid("a bot may register at most %d commands", MaxCommandsPerBot)
+		}
+		taken, err := tx.Commands.IsNameTaken(ctx, name)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return invalid("/%s is already registered", name)
+		}
+
+		if err := tx.Commands.Create(ctx, &command); err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, "bot_command.create", "bot_command", command.ID, map[string]interface{}{
+			"bot_id": botID,
+			"name":   name,
+		})
+	})
+	return command, err
+}
+
+// DeleteBotCommand deletes a command of a bot of the actor, freeing its name
+func DeleteBotCommand(ctx context.Context, repos repositories.Repositories, audit AuditContext, botID, commandID uint) (models.BotCommand, error) {
+	var command models.BotCommand
+	err := repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		if _, err := tx.Users.FindBot(ctx, audit.ActorID, botID); err != nil {
+			return err
+		}
+		var err error
+		if command, err = tx.Commands.FindByBot(ctx, botID, commandID); err != nil {
+			return err
+		}
+		if err := tx.Commands.Delete(ctx, &command); err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, "bot_command.delete", "bot_command", command.ID, map[string]interface{}{
+			"bot_id": botID,
+			"name":   command.Name,
+		})
+	})
+	return command, err
+}
+
+// FindCommandInvocation returns an invocation of a command of botID that the
+// bot may still answer. The message service marks it answered with the
+// answer, so that a race between two answers is settled there.
+func FindCommandInvocation(ctx context.Context, commands repositories.CommandRepository, botID, invocationID uint) (models.CommandInvocation, error) {
+	invocation, err := commands.FindInvocation(ctx, invocationID)
+	if err != nil {
+		return invocation, err
+	}
+	if invocation.BotID != botID {
+		return invocation, gorm.ErrRecordNotFound
+	}
+	if time.Now().After(invocation.ExpiresAt()) {
+		return invocation, ErrInvocationExpired
+	}
+	if invocation.AnsweredAt != nil {
+		return invocation, repositories.ErrInvocationAnswered
+	}
+	return invocation, nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// IncomingWebhookTokenPrefix starts every incoming webhook token
+const IncomingWebhookTokenPrefix = "imh_"
+
+// MaxSenderNameLength bounds the username override of incoming webhook messages
+const MaxSenderNameLength = 64
+
+// ErrInvalidIncomingWebhook is returned for unknown incoming webhooks, wrong
+// tokens and webhooks whose user was deactivated alike
+var ErrInvalidIncomingWebhook = errors.New("invalid incoming webhook or token")
+
+// CreatedIncomingWebhook is a new incoming webhook with its token, which is
+// only known at creation
+type CreatedIncomingWebhook struct {
+	Webhook models.IncomingWebhook
+	Token   string
+}
+
+// ListIncomingWebhooks returns the incoming webhooks of userID
+func ListIncomingWebhooks(ctx context.Context, webhooks repositories.IncomingWebhookRepository, userID uint) ([]models.IncomingWebhook, error) {
+	return webhooks.ListByUser(ctx, userID)
+}
+
+// CreateIncomingWebhook creates an incoming webhook posting messages from the
+// actor to receiverID
+func CreateIncomingWebhook(ctx context.Context, repos repositories.Repositories, audit AuditContext, receiverID uint, name string) (CreatedIncomingWebhook, error) {
+	var created CreatedIncomingWebhook
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return created, invalid("name is required")
+	}
+	if receiverID == 0 {
+		return created, invalid("receiver_id is required")
+	}
+
+	token, err := generateToken(IncomingWebhookTokenPrefix)
+	if err != nil {
+		return created, err
+	}
+	webhook := models.IncomingWebhook{
+		UserID:     audit.ActorID,
+		ReceiverID: receiverID,
+		Name:       name,
+		Prefix:     token[:len(IncomingWebhookTokenPrefix)+8],
+		Hash:       hashAPIKey(token),
+	}
+
+	err = repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		receiver, err := tx.Users.FindByID(ctx, receiverID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return invalid("receiver not found")
+			}
+			return err
+		}
+		if !receiver.IsActive() {
+			return invalid("receiver is deactivated")
+		}
+
+		created, err := tx.IncomingWebhooks.CountByUser(ctx, audit.ActorID)
+		if err != nil {
+			return err
+		}
+		if created >= int64(config.Cfg.Webhooks.MaxIncomingPerUser) {
+			return invalid("a user may create at most %d incoming webhooks", config.Cfg.Webhooks.MaxIncomingPerUser)
+		}
+
+		if err := tx.IncomingWebhooks.Create(ctx, &webhook); err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, "incoming_webhook.create", "incoming_webhook", webhook.ID, map[string]interface{}{
+			"receiver_id": receiverID,
+			"name":        name,
+		})
+	})
+	return CreatedIncomingWebhook{Webhook: webhook, Token: token}, err
+}
+
+// DeleteIncomingWebhook deletes an incoming webhook of the actor; its token
+// is refused from then on
+func DeleteIncomingWebhook(ctx context.Context, repos repositories.Repositories, audit AuditContext, webhookID uint) (models.IncomingWebhook, error) {
+	var webhook models.IncomingWebhook
+	err := repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		var err error
+		if webhook, err = tx.IncomingWebhooks.FindByUser(ctx, audit.ActorID, webhookID); err != nil {
+			return err
+		}
+		if err := tx.IncomingWebhooks.Delete(ctx, &webhook); err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, "incoming_webhook.delete", "incoming_webhook", webhook.ID, map[string]interface{}{
+			"receiver_id": webhook.ReceiverID,
+		})
+	})
+	return webhook, err
+}
+
+// AuthenticateIncomingWebhook returns the incoming webhook webhookID when
+// token is its token and its user is active
+func AuthenticateIncomingWebhook(ctx context.Context, repos repositories.Repositories, webhookID uint, token string) (models.IncomingWebhook, error) {
+	webhook, err := repos.IncomingWebhooks.FindByID(ctx, webhookID)
+	if err != nil {
+		return webhook, ErrInvalidIncomingWebhook
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(token)), []byte(webhook.Hash)) != 1 {
+		return webhook, ErrInvalidIncomingWebhook
+	}
+
+	user, err := repos.Users.FindByID(ctx, webhook.UserID)
+	if err != nil || !user.IsActive() {
+		return webhook, ErrInvalidIncomingWebhook
+	}
+
+	// Recording every use would write on each message
+	now := time.Now()
+	if webhook.LastUsedAt == nil || now.Sub(*webhook.LastUsedAt) > time.Minute {
+		if err := repos.IncomingWebhooks.Update(ctx, &webhook, map[string]interface{}{"last_used_at": now}); err != nil {
+			slog.WarnContext(ctx, "Failed to record the use of an incoming webhook", "incoming_webhook_id", webhook.ID, "error", err)
+		}
+	}
+	return webhook, nil
+}
+
+// NormalizeSenderName trims the username override of an incoming webhook
+// message and checks its length
+func NormalizeSenderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > MaxSenderNameLength {
+		return "", invalid("username must be at most %d characters", MaxSenderNameLength)
+	}
+	return name, nil
+}
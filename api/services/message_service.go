@@ -1,40 +1,16 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"instant-messaging-app/broker"
 	"instant-messaging-app/config"
-	"instant-messaging-app/models"
 	"instant-messaging-app/types"
-	"log"
-
-	amqp "github.com/rabbitmq/amqp091-go"
+	"log/slog"
 )
 
-// GetMessagesBetweenUsers récupère les messages entre deux utilisateurs
-func GetMessagesBetweenUsers(senderID uint, receiverID uint) ([]models.Message, error) {
-	var messages []models.Message
-	err := config.DB.Preload("Sender").Preload("Receiver").
-		Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
-			senderID, receiverID, receiverID, senderID).
-		Order("created_at asc").
-		Find(&messages).Error
-	return messages, err
-}
-
-// CreateMessage crée un nouveau message
-func CreateMessage(senderID uint, receiverID uint, content string) (models.Message, error) {
-	message := models.Message{
-		SenderID:   senderID,
-		ReceiverID: receiverID,
-		Content:    content,
-	}
-
-	err := config.DB.Create(&message).Error
-	return message, err
-}
-
-func PublishGetMessages(uuid string, userID, receiverID uint) error {
+func PublishGetMessages(ctx context.Context, uuid string, userID, receiverID uint) error {
 	// Define the registration request payload
 	request := types.GetMessagesRequest{
 		UUID: uuid,
@@ -45,62 +21,85 @@ func PublishGetMessages(uuid string, userID, receiverID uint) error {
 	// Marshal the request to JSON
 	body, err := json.Marshal(request)
 	if err != nil {
-		log.Printf("Failed to marshal GetMessagesRequest: %v", err)
+		slog.ErrorContext(ctx, "Failed to marshal GetMessagesRequest", "error", err)
 		return fmt.Errorf("failed to marshal GetMessagesRequest")
 	}
 
 	// Publish the message to the "user_direct_exchange" with the routing key "registration"
-	err = config.RabbitMQCh.Publish(
-		"user_direct_exchange", // Exchange name
+	err = config.Broker.Publish(
+		ctx,
+		config.Cfg.Exchanges.UserDirect, // Exchange name
 		"getMessages",         // Routing key
-		false,                  // Mandatory
-		false,                  // Immediate
-		amqp.Publishing{
+		broker.Message{
 			ContentType: "application/json",
 			Body:        body,
 		},
 	)
 	if err != nil {
-		log.Printf("Failed to publish getMessages request: %v", err)
+		slog.ErrorContext(ctx, "Failed to publish getMessages request", "error", err)
 		return fmt.Errorf("failed to publish getMessages request")
 	}
 
-	log.Printf("Published getMessages request for userID %v", userID)
+	slog.DebugContext(ctx, "Published getMessages request")
 	return nil
 }
 
-func PublishSendMessage(uuid string, userID, receiverID uint, content string) error {
+// SendOptions tune a message published with PublishSendMessage
+type SendOptions struct {
+	// SenderName is shown instead of the name of the sender when set
+	SenderName string
+	// Reply also sends the stored message to the uuid queue
+	Reply bool
+	// Verbatim stores the content as is, even when it starts with a command;
+	// the messages of integrations are sent this way
+	Verbatim bool
+	// Ephemeral shows the message to the sessions of the sender only,
+	// without storing it
+	Ephemeral bool
+	// InvocationID is set for the ephemeral answer of a bot to a command
+	// invocation, which is marked answered with the message
+	InvocationID uint
+}
+
+// PublishSendMessage asks the message service to store and broadcast a
+// message. Unless it is verbatim, a message starting with / runs a command
+// instead, whose ephemeral answer only goes to uuid.
+func PublishSendMessage(ctx context.Context, uuid string, userID, receiverID uint, content string, options SendOptions) error {
 	// Define the registration request payload
 	request := types.SendMessageRequest{
 		UUID: uuid,
 		UserID: userID,
 		ReceiverID: receiverID,
 		Content: content,
+		SenderName: options.SenderName,
+		Reply: options.Reply,
+		Verbatim: options.Verbatim,
+		Ephemeral: options.Ephemeral,
+		InvocationID: options.InvocationID,
 	}
 
 	// Marshal the request to JSON
 	body, err := json.Marshal(request)
 	if err != nil {
-		log.Printf("Failed to marshal GetMessagesRequest: %v", err)
+		slog.ErrorContext(ctx, "Failed to marshal SendMessageRequest", "error", err)
 		return fmt.Errorf("failed to marshal GetMessagesRequest")
 	}
 
 	// Publish the message to the "user_direct_exchange" with the routing key "registration"
-	err = config.RabbitMQCh.Publish(
-		"user_direct_exchange", // Exchange name
+	err = config.Broker.Publish(
+		ctx,
+		config.Cfg.Exchanges.UserDirect, // Exchange name
 		"sendMessage",         // Routing key
-		false,                  // Mandatory
-		false,                  // Immediate
-		amqp.Publishing{
+		broker.Message{
 			ContentType: "application/json",
 			Body:        body,
 		},
 	)
 	if err != nil {
-		log.Printf("Failed to publish sendMessage request: %v", err)
+		slog.ErrorContext(ctx, "Failed to publish sendMessage request", "error", err)
 		return fmt.Errorf("failed to publish sendMessage request")
 	}
 
-	log.Printf("Published sendMessage request for userID %v", userID)
+	slog.DebugContext(ctx, "Published sendMessage request")
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/utils"
+)
+
+// apiKeyLimiter holds a token bucket per API key. The buckets live in the
+// memory of each gateway, so the limits apply per instance.
+var apiKeyLimiter = &rateLimiter{buckets: map[uint]*bucket{}}
+
+// AllowRequest counts a request of a session against the rate limit of its
+// API key. When the limit is reached it returns false and how long to wait.
+// Sessions opened with a password are not limited.
+func AllowRequest(claims utils.Claims) (bool, time.Duration) {
+	if claims.APIKeyID == 0 || claims.RateLimit <= 0 {
+		return true, 0
+	}
+	return apiKeyLimiter.allow(claims.APIKeyID, claims.RateLimit, time.Now())
+}
+
+// incomingWebhookLimiter holds a token bucket per incoming webhook
+var incomingWebhookLimiter = &rateLimiter{buckets: map[uint]*bucket{}}
+
+// AllowIncomingMessage counts a message of an incoming webhook against
+// webhooks.incoming_rate_limit, like AllowRequest
+func AllowIncomingMessage(webhookID uint) (bool, time.Duration) {
+	return incomingWebhookLimiter.allow(webhookID, config.Cfg.Webhooks.IncomingRateLimit, time.Now())
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[uint]*bucket
+}
+
+// bucket allows perMinute requests at once, refilled over a minute
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func (l *rateLimiter) allow(key uint, perMinute int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	capacity := float64(perMinute)
+	refill := capacity / time.Minute.Seconds()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		l.prune(now)
+		b = &bucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*refill)
+	b.updated = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / refill * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune drops the buckets unused for a minute, which are full again, once
+// there are many of them
+func (l *rateLimiter) prune(now time.Time) {
+	if len(l.buckets) < 1024 {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) > time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+}
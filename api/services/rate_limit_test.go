@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := &rateLimiter{buckets: map[uint]*bucket{}}
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	// The whole limit is available at once
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.allow(1, 3, now); !ok {
+			t.Fatalf("request %d was refused", i+1)
+		}
+	}
+	ok, wait := limiter.allow(1, 3, now)
+	if ok || wait != 20*time.Second {
+		t.Fatalf("expected to wait 20s, got %v and %s", ok, wait)
+	}
+
+	// Other keys have their own bucket
+	if ok, _ := limiter.allow(2, 3, now); !ok {
+		t.Fatal("the request of another key was refused")
+	}
+
+	// A token is back after a third of a minute
+	if ok, _ := limiter.allow(1, 3, now.Add(20*time.Second)); !ok {
+		t.Fatal("the refilled token was refused")
+	}
+	if ok, _ := limiter.allow(1, 3, now.Add(20*time.Second)); ok {
+		t.Fatal("a single token was refilled, two requests were allowed")
+	}
+}
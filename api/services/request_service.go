@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+)
+
+// ErrNoReply is returned when a service did not answer within http.request_timeout
+var ErrNoReply = errors.New("no reply from the service")
+
+// ServiceError is an error reply of the user or message service
+type ServiceError struct {
+	Code    string
+	Message string
+}
+
+func (e *ServiceError) Error() string {
+	return e.Message
+}
+
+// Request sends a request the way the WebSocket messages are sent and waits
+// for the reply. publish is given the UUID of a reply queue bound like a
+// connection queue, which is deleted once the reply arrived. The data of the
+// reply is decoded into out unless the service answered with an error, which
+// is returned as a *ServiceError.
+func Request(ctx context.Context, replyType string, out interface{}, publish func(ctx context.Context, uuid string) error) error {
+	uuid := utils.GenerateUUID()
+	if err := config.DeclareConnectionQueue(uuid, false); err != nil {
+		return err
+	}
+	defer func() {
+		if err := config.CleanupQueue(uuid); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete the reply queue", "queue", uuid, "error", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, config.Cfg.HTTP.RequestTimeout)
+	defer cancel()
+
+	replies, err := config.Broker.Consume(ctx, uuid)
+	if err != nil {
+		return fmt.Errorf("failed to consume the reply queue: %w", err)
+	}
+	if err := publish(ctx, uuid); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ErrNoReply
+	case msg, ok := <-replies:
+		if !ok {
+			return ErrNoReply
+		}
+
+		var reply struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(msg.Body, &reply); err != nil {
+			return fmt.Errorf("invalid reply: %w", err)
+		}
+		switch reply.Type {
+		case replyType:
+			return json.Unmarshal(reply.Data, out)
+		case "error":
+			var errorResponse types.ErrorResponse
+			if err := json.Unmarshal(reply.Data, &errorResponse); err != nil {
+				return fmt.Errorf("invalid error reply: %w", err)
+			}
+			return &ServiceError{Code: errorResponse.Code, Message: errorResponse.Error}
+		default:
+			return fmt.Errorf("unexpected %s reply", reply.Type)
+		}
+	}
+}
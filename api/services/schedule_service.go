@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+
+	"gorm.io/gorm"
+)
+
+// ScheduledListLimit bounds the scheduled messages of a listing
+const ScheduledListLimit = 100
+
+// ErrScheduleNotPending is returned when a scheduled message that was already
+// sent or canceled is edited or canceled
+var ErrScheduleNotPending = errors.New("the message was already sent or canceled")
+
+// localTimeLayouts are the accepted layouts of a time without an offset, read
+// in the time zone of the request
+var localTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// loadTimeZone returns the IANA time zone called name, UTC when name is empty
+func loadTimeZone(name string) (*time.Location, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "UTC"
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil || strings.EqualFold(name, "Local") {
+		return nil, "", invalid("unknown time zone %q", name)
+	}
+	return location, name, nil
+}
+
+// ParseSendAt parses the time a message is scheduled at: an RFC 3339 time, or
+// a local date and time such as 2026-10-20T09:00 read in location
+func ParseSendAt(value string, location *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if sendAt, err := time.Parse(time.RFC3339, value); err == nil {
+		return sendAt.UTC(), nil
+	}
+	for _, layout := range localTimeLayouts {
+		if sendAt, err := time.ParseInLocation(layout, value, location); err == nil {
+			return sendAt.UTC(), nil
+		}
+	}
+	return time.Time{}, invalid("send_at must be an RFC 3339 time or a local time such as 2026-10-20T09:00")
+}
+
+// checkSendAt validates the time a message is scheduled at
+func checkSendAt(sendAt time.Time) error {
+	now := time.Now()
+	switch {
+	case !sendAt.After(now):
+		return invalid("send_at must be in the future")
+	case sendAt.Sub(now) > config.Cfg.Scheduler.MaxDelay:
+		return invalid("messages may be scheduled at most %s ahead", config.Cfg.Scheduler.MaxDelay)
+	}
+	return nil
+}
+
+// ListScheduledMessages returns the scheduled messages of userID with the
+// given status, pending when empty
+func ListScheduledMessages(ctx context.Context, scheduled repositories.ScheduledMessageRepository, userID uint, status string) ([]models.ScheduledMessage, error) {
+	if status == "" {
+		status = models.SchedulePending
+	}
+	return scheduled.ListByUser(ctx, userID, status, ScheduledListLimit)
+}
+
+// FindScheduledMessage returns a scheduled message of userID
+func FindScheduledMessage(ctx context.Context, scheduled repositories.ScheduledMessageRepository, userID, id uint) (models.ScheduledMessage, error) {
+	message, err := scheduled.FindByID(ctx, id)
+	if err == nil && message.UserID != userID {
+		return models.ScheduledMessage{}, gorm.ErrRecordNotFound
+	}
+	return message, err
+}
+
+// ScheduleMessage schedules a message from the actor to receiverID, sent by
+// the scheduler daemon at sendAt, read in timeZone when it has no offset
+func ScheduleMessage(ctx context.Context, repos repositories.Repositories, audit AuditContext, receiverID uint, content, sendAt, timeZone string) (models.ScheduledMessage, error) {
+	if strings.TrimSpace(content) == "" {
+		return models.ScheduledMessage{}, invalid("content is required")
+	}
+	if receiverID == 0 {
+		return models.ScheduledMessage{}, invalid("receiver_id is required")
+	}
+	location, timeZone, err := loadTimeZone(timeZone)
+	if err != nil {
+		return models.ScheduledMessage{}, err
+	}
+	at, err := ParseSendAt(sendAt, location)
+	if err != nil {
+		return models.ScheduledMessage{}, err
+	}
+	if err := checkSendAt(at); err != nil {
+		return models.ScheduledMessage{}, err
+	}
+
+	message := models.ScheduledMessage{
+		UserID:     audit.ActorID,
+		ReceiverID: receiverID,
+		Kind:       models.ScheduleKindMessage,
+		Content:    content,
+		SendAt:     at,
+		TimeZone:   timeZone,
+		Status:     models.SchedulePending,
+	}
+	err = repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		receiver, err := tx.Users.FindByID(ctx, receiverID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return invalid("receiver not found")
+			}
+			return err
+		}
+		if !receiver.IsActive() {
+			return invalid("receiver is deactivated")
+		}
+
+		pending, err := tx.Scheduled.CountPending(ctx, audit.ActorID)
+		if err != nil {
+			return err
+		}
+		if pending >= int64(config.Cfg.Scheduler.MaxPerUser) {
+			return invalid("a user may have at most %d pending scheduled messages", config.Cfg.Scheduler.MaxPerUser)
+		}
+
+		if err := tx.Scheduled.Create(ctx, &message); err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, "scheduled_message.create", "scheduled_message", message.ID, map[string]interface{}{
+			"receiver_id": receiverID,
+			"send_at":     at,
+			"time_zone":   timeZone,
+		})
+	})
+	return message, err
+}
+
+// UpdateScheduledMessage edits a pending scheduled message of the actor; the
+// empty arguments are left unchanged. A new time zone without a new sendAt
+// keeps the local time of the message, moved to that zone.
+func UpdateScheduledMessage(ctx context.Context, repos repositories.Repositories, audit AuditContext, id uint, content, sendAt, timeZone string) (models.ScheduledMessage, error) {
+	var message models.ScheduledMessage
+	err := repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		var err error
+		if message, err = tx.Scheduled.FindByUser(ctx, audit.ActorID, id); err != nil {
+			return err
+		}
+		if message.Status != models.SchedulePending {
+			return ErrScheduleNotPending
+		}
+
+		fields := map[string]interface{}{}
+		if strings.TrimSpace(content) != "" {
+			fields["content"] = content
+		}
+		location := message.Location()
+		if strings.TrimSpace(timeZone) != "" {
+			if location, timeZone, err = loadTimeZone(timeZone); err != nil {
+				return err
+			}
+			fields["time_zone"] = timeZone
+		}
+		switch {
+		case strings.TrimSpace(sendAt) != "":
+			at, err := ParseSendAt(sendAt, location)
+			if err != nil {
+				return err
+			}
+			fields["send_at"] = at
+		case timeZone != "":
+			local := message.LocalSendAt()
+			fields["send_at"] = time.Date(local.Year(), local.Month(), local.Day(),
+				local.Hour(), local.Minute(), local.Second(), 0, location).UTC()
+		}
+		if len(fields) == 0 {
+			return invalid("nothing to update: set content, send_at or time_zone")
+		}
+		if at, ok := fields["send_at"].(time.Time); ok {
+			if err := checkSendAt(at); err != nil {
+				return err
+			}
+		}
+
+		// The scheduler daemon may have sent the message in the meantime
+		updated, err := tx.Scheduled.UpdatePending(ctx, &message, fields)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return ErrScheduleNotPending
+		}
+		if message, err = tx.Scheduled.FindByID(ctx, message.ID); err != nil {
+			return err
+		}
+		// The content is private, only the schedule is audited
+		delete(fields, "content")
+		return RecordAudit(ctx, tx.Audit, audit, "scheduled_message.update", "scheduled_message", message.ID, fields)
+	})
+	return message, err
+}
+
+// CancelScheduledMessage cancels a pending scheduled message of the actor
+func CancelScheduledMessage(ctx context.Context, repos repositories.Repositories, audit AuditContext, id uint) (models.ScheduledMessage, error) {
+	var message models.ScheduledMessage
+	err := repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		var err error
+		if message, err = tx.Scheduled.FindByUser(ctx, audit.ActorID, id); err != nil {
+			return err
+		}
+		canceled, err := tx.Scheduled.UpdatePending(ctx, &message, map[string]interface{}{"status": models.ScheduleCanceled})
+		if err != nil {
+			return err
+		}
+		if !canceled {
+			return ErrScheduleNotPending
+		}
+		message.Status = models.ScheduleCanceled
+		return RecordAudit(ctx, tx.Audit, audit, "scheduled_message.cancel", "scheduled_message", message.ID, map[string]interface{}{
+			"kind": message.Kind,
+		})
+	})
+	return message, err
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSendAt(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	for _, value := range []string{
+		"2026-10-20T00:00:00Z",
+		"2026-10-20T09:00:00+09:00",
+		"2026-10-20T09:00",
+		"2026-10-20 09:00:00",
+		" 2026-10-20 09:00 ",
+	} {
+		sendAt, err := ParseSendAt(value, tokyo)
+		if err != nil {
+			t.Errorf("ParseSendAt(%q): %v", value, err)
+			continue
+		}
+		if !sendAt.Equal(expected) || sendAt.Location() != time.UTC {
+			t.Errorf("ParseSendAt(%q) = %s, expected %s", value, sendAt, expected)
+		}
+	}
+
+	if _, err := ParseSendAt("tomorrow", tokyo); err == nil {
+		t.Error("expected an invalid time to be refused")
+	}
+}
+
+func TestLoadTimeZone(t *testing.T) {
+	if _, name, err := loadTimeZone(""); err != nil || name != "UTC" {
+		t.Errorf("expected UTC by default, got %q (%v)", name, err)
+	}
+	if location, name, err := loadTimeZone("Europe/Paris"); err != nil || name != "Europe/Paris" || location.String() != "Europe/Paris" {
+		t.Errorf("unexpected zone %v %q (%v)", location, name, err)
+	}
+	for _, name := range []string{"Local", "Mars/Olympus"} {
+		if _, _, err := loadTimeZone(name); err == nil {
+			t.Errorf("expected %q to be refused", name)
+		}
+	}
+}
@@ -1,51 +1,50 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"instant-messaging-app/broker"
 	"instant-messaging-app/config"
-	"instant-messaging-app/models"
 	"instant-messaging-app/types"
-	"log"
-
-	amqp "github.com/rabbitmq/amqp091-go"
+	"log/slog"
 )
 
 
-func PublishGetUsers(uuid string) error {
+func PublishGetUsers(ctx context.Context, uuid string, userID uint) error {
 	// Define the registration request payload
 	request := types.GetUsersRequest{
-		UUID: uuid,
+		UUID:   uuid,
+		UserID: userID,
 	}
 
 	// Marshal the request to JSON
 	body, err := json.Marshal(request)
 	if err != nil {
-		log.Printf("Failed to marshal registration request: %v", err)
+		slog.ErrorContext(ctx, "Failed to marshal getUsers request", "error", err)
 		return fmt.Errorf("failed to marshal registration request")
 	}
 
 	// Publish the message to the "user_direct_exchange" with the routing key "registration"
-	err = config.RabbitMQCh.Publish(
-		"user_direct_exchange", // Exchange name
+	err = config.Broker.Publish(
+		ctx,
+		config.Cfg.Exchanges.UserDirect, // Exchange name
 		"getUsers",         // Routing key
-		false,                  // Mandatory
-		false,                  // Immediate
-		amqp.Publishing{
+		broker.Message{
 			ContentType: "application/json",
 			Body:        body,
 		},
 	)
 	if err != nil {
-		log.Printf("Failed to publish getUsers request: %v", err)
+		slog.ErrorContext(ctx, "Failed to publish getUsers request", "error", err)
 		return fmt.Errorf("failed to publish getUsers request")
 	}
 
-	log.Printf("Published getUsers request for userID %s", uuid)
+	slog.DebugContext(ctx, "Published getUsers request")
 	return nil
 }
 
-func PublishGetSelf(uuid string, userID uint) error {
+func PublishGetSelf(ctx context.Context, uuid string, userID uint) error {
 	// Define the registration request payload
 	request := types.GetSelfRequest{
 		UUID: uuid,
@@ -55,39 +54,61 @@ func PublishGetSelf(uuid string, userID uint) error {
 	// Marshal the request to JSON
 	body, err := json.Marshal(request)
 	if err != nil {
-		log.Printf("Failed to marshal registration request: %v", err)
+		slog.ErrorContext(ctx, "Failed to marshal getSelf request", "error", err)
 		return fmt.Errorf("failed to marshal registration request")
 	}
 
 	// Publish the message to the "user_direct_exchange" with the routing key "registration"
-	err = config.RabbitMQCh.Publish(
-		"user_direct_exchange", // Exchange name
+	err = config.Broker.Publish(
+		ctx,
+		config.Cfg.Exchanges.UserDirect, // Exchange name
 		"getSelf",         // Routing key
-		false,                  // Mandatory
-		false,                  // Immediate
-		amqp.Publishing{
+		broker.Message{
 			ContentType: "application/json",
 			Body:        body,
 		},
 	)
 	if err != nil {
-		log.Printf("Failed to publish getSelf request: %v", err)
+		slog.ErrorContext(ctx, "Failed to publish getSelf request", "error", err)
 		return fmt.Errorf("failed to publish getSelf request")
 	}
 
-	log.Printf("Published getSelf request for userID %s", uuid)
+	slog.DebugContext(ctx, "Published getSelf request")
 	return nil
 }
 
-// GetAllUsers retrieves all users from the database
-func GetAllUsers() ([]models.User, error) {
-	var users []models.User
-	err := config.DB.Select("id, username").Find(&users).Error
-	return users, err
-}
+func PublishSearchUsers(ctx context.Context, uuid string, userID uint, query string, page, pageSize int) error {
+	// Define the search request payload
+	request := types.SearchUsersRequest{
+		UUID:     uuid,
+		UserID:   userID,
+		Query:    query,
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	// Marshal the request to JSON
+	body, err := json.Marshal(request)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal searchUsers request", "error", err)
+		return fmt.Errorf("failed to marshal searchUsers request")
+	}
 
-func GetUserByID(id uint) (models.User, error) {
-	var user models.User
-	err := config.DB.First(&user, id).Error
-	return user, err
-}
\ No newline at end of file
+	// Publish the message to the "user_direct_exchange" with the routing key "searchUsers"
+	err = config.Broker.Publish(
+		ctx,
+		config.Cfg.Exchanges.UserDirect, // Exchange name
+		"searchUsers",          // Routing key
+		broker.Message{
+			ContentType: "application/json",
+			Body:        body,
+		},
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to publish searchUsers request", "error", err)
+		return fmt.Errorf("failed to publish searchUsers request")
+	}
+
+	slog.DebugContext(ctx, "Published searchUsers request")
+	return nil
+}
@@ -0,0 +1,18 @@
+package services
+
+import "fmt"
+
+// ValidationError rejects an invalid request; its message is shown to the
+// client. The other errors of the services are internal failures.
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// invalid returns a ValidationError with a formatted message
+func invalid(format string, args ...interface{}) error {
+	return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
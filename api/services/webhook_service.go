@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/utils"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookSecretPrefix starts every webhook secret
+const WebhookSecretPrefix = "whsec_"
+
+// ListWebhooks returns the webhooks registered by userID
+func ListWebhooks(ctx context.Context, webhooks repositories.WebhookRepository, userID uint) ([]models.Webhook, error) {
+	return webhooks.ListByUser(ctx, userID)
+}
+
+// CreateWebhook registers a webhook of the actor for events, or for every
+// event when empty. With allUsers it receives the events of every user, which
+// the caller must have checked the actor may see.
+func CreateWebhook(ctx context.Context, repos repositories.Repositories, audit AuditContext, rawURL string, events []string, allUsers bool) (models.Webhook, error) {
+	if err := validateWebhookURL(rawURL); err != nil {
+		return models.Webhook{}, err
+	}
+	for _, event := range events {
+		if !models.IsValidWebhookEvent(event) {
+			return models.Webhook{}, invalid("unknown event %q, expected one of %s", event, strings.Join(models.WebhookEvents, ", "))
+		}
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	webhook := models.Webhook{
+		UserID:   audit.ActorID,
+		URL:      rawURL,
+		Secret:   secret,
+		Events:   strings.Join(events, ","),
+		AllUsers: allUsers,
+	}
+	err = repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		registered, err := tx.Webhooks.CountByUser(ctx, audit.ActorID)
+		if err != nil {
+			return err
+		}
+		if registered >= int64(config.Cfg.Webhooks.MaxPerUser) {
+			return invalid("a user may register at most %d webhooks", config.Cfg.Webhooks.MaxPerUser)
+		}
+
+		if err := tx.Webhooks.Create(ctx, &webhook); err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, "webhook.create", "webhook", webhook.ID, map[string]interface{}{
+			"url":       rawURL,
+			"events":    events,
+			"all_users": allUsers,
+		})
+	})
+	return webhook, err
+}
+
+// DeleteWebhook removes a webhook of the actor with its delivery log
+func DeleteWebhook(ctx context.Context, repos repositories.Repositories, audit AuditContext, webhookID uint) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		var err error
+		if webhook, err = tx.Webhooks.FindByUser(ctx, audit.ActorID, webhookID); err != nil {
+			return err
+		}
+		if err := tx.Webhooks.Delete(ctx, &webhook); err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, "webhook.delete", "webhook", webhook.ID, map[string]interface{}{"url": webhook.URL})
+	})
+	return webhook, err
+}
+
+// EnableWebhook enables again a webhook of the actor disabled after repeated
+// failures; its pending deliveries are then sent
+func EnableWebhook(ctx context.Context, repos repositories.Repositories, audit AuditContext, webhookID uint) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		var err error
+		if webhook, err = tx.Webhooks.FindByUser(ctx, audit.ActorID, webhookID); err != nil {
+			return err
+		}
+		if webhook.IsEnabled() {
+			return invalid("webhook is already enabled")
+		}
+
+		webhook.DisabledAt = nil
+		webhook.FailureCount = 0
+		err = tx.Webhooks.Update(ctx, &webhook, map[string]interface{}{
+			"disabled_at":   nil,
+			"failure_count": 0,
+		})
+		if err != nil {
+			return err
+		}
+		return RecordAudit(ctx, tx.Audit, audit, "webhook.enable", "webhook", webhook.ID, nil)
+	})
+	return webhook, err
+}
+
+// ListWebhookDeliveries returns one page of the delivery log of a webhook of
+// userID, newest first
+func ListWebhookDeliveries(ctx context.Context, webhooks repositories.WebhookRepository, userID, webhookID uint, page, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	webhook, err := webhooks.FindByID(ctx, webhookID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if webhook.UserID != userID {
+		return nil, 0, gorm.ErrRecordNotFound
+	}
+	return webhooks.ListDeliveries(ctx, webhookID, page, pageSize)
+}
+
+// ReplayWebhookDelivery sends the event of a delivery to its webhook again,
+// as a new delivery with the same payload and event ID
+func ReplayWebhookDelivery(ctx context.Context, repos repositories.Repositories, audit AuditContext, webhookID, deliveryID uint) (models.WebhookDelivery, error) {
+	var replay models.WebhookDelivery
+	err := repos.Tx.Transaction(ctx, func(tx repositories.Repositories) error {
+		webhook, err := tx.Webhooks.FindByUser(ctx, audit.ActorID, webhookID)
+		if err != nil {
+			return err
+		}
+		if !webhook.IsEnabled() {
+			return invalid("webhook is disabled")
+		}
+		original, err := tx.Webhooks.FindDelivery(ctx, webhook.ID, deliveryID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		deliveries := []models.WebhookDelivery{{
+			WebhookID:     webhook.ID,
+			Event:         original.Event,
+			EventID:       original.EventID,
+			Payload:       original.Payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+			ReplayOf:      &original.ID,
+		}}
+		if err := tx.Webhooks.CreateDeliveries(ctx, deliveries); err != nil {
+			return err
+		}
+		replay = deliveries[0]
+		return RecordAudit(ctx, tx.Audit, audit, "webhook.replay", "webhook", webhook.ID, map[string]interface{}{"delivery_id": original.ID})
+	})
+	return replay, err
+}
+
+// validateWebhookURL accepts absolute http and https URLs. Unless
+// webhooks.allow_private is set, literal private addresses are refused here;
+// the webhook daemon also checks the address a host name resolves to.
+func validateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return invalid("url must be an absolute http or https URL")
+	}
+	if config.Cfg.Webhooks.AllowPrivate {
+		return nil
+	}
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && utils.IsPrivateAddress(ip)) {
+		return invalid("url must not point to a private address")
+	}
+	return nil
+}
+
+// generateWebhookSecret returns a new random signing secret
+func generateWebhookSecret() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return WebhookSecretPrefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,242 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// AMQPBroker implements Broker on top of a RabbitMQ connection
+type AMQPBroker struct {
+	conn     *amqp.Connection
+	prefetch int
+
+	mu sync.Mutex
+	ch *amqp.Channel
+}
+
+// NewAMQPBroker dials RabbitMQ and opens the shared channel. A positive
+// prefetch limits the unacknowledged deliveries per consumer.
+func NewAMQPBroker(url string, prefetch int) (*AMQPBroker, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	b := &AMQPBroker{conn: conn, prefetch: prefetch}
+	if _, err := b.channel(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// channel returns the shared channel, reopening it when a failed operation
+// made the server close it
+func (b *AMQPBroker) channel() (*amqp.Channel, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ch != nil && !b.ch.IsClosed() {
+		return b.ch, nil
+	}
+
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open RabbitMQ channel: %w", err)
+	}
+	if b.prefetch > 0 {
+		if err := ch.Qos(b.prefetch, 0, false); err != nil {
+			ch.Close()
+			return nil, fmt.Errorf("failed to set RabbitMQ prefetch: %w", err)
+		}
+	}
+	b.ch = ch
+	return ch, nil
+}
+
+func (b *AMQPBroker) DeclareExchange(name, kind string) error {
+	ch, err := b.channel()
+	if err != nil {
+		return err
+	}
+	return ch.ExchangeDeclare(
+		name,  // Exchange name
+		kind,  // Type
+		true,  // Durable
+		false, // Auto-deleted
+		false, // Internal
+		false, // No-wait
+		nil,   // Arguments
+	)
+}
+
+func (b *AMQPBroker) DeclareQueue(name string, options QueueOptions) error {
+	ch, err := b.channel()
+	if err != nil {
+		return err
+	}
+	_, err = ch.QueueDeclare(
+		name,                     // Queue name
+		options.Durable,          // Durable
+		options.AutoDelete,       // Auto-delete
+		options.Exclusive,        // Exclusive
+		false,                    // No-wait
+		amqp.Table(options.Args), // Arguments
+	)
+	return err
+}
+
+func (b *AMQPBroker) QueueExists(name string) bool {
+	// A failed passive declare closes the channel, so use a throwaway one
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return false
+	}
+	defer ch.Close()
+
+	_, err = ch.QueueDeclarePassive(name, false, false, false, false, nil)
+	return err == nil
+}
+
+func (b *AMQPBroker) QueueLength(name string) (int, error) {
+	// A failed passive declare closes the channel, so use a throwaway one
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	queue, err := ch.QueueDeclarePassive(name, false, false, false, false, nil)
+	if err != nil {
+		return 0, err
+	}
+	return queue.Messages, nil
+}
+
+func (b *AMQPBroker) BindQueue(queue, exchange, routingKey string) error {
+	ch, err := b.channel()
+	if err != nil {
+		return err
+	}
+	return ch.QueueBind(queue, routingKey, exchange, false, nil)
+}
+
+func (b *AMQPBroker) Publish(ctx context.Context, exchange, routingKey string, message Message) error {
+	ch, err := b.channel()
+	if err != nil {
+		return err
+	}
+	return ch.PublishWithContext(
+		ctx,
+		exchange,   // Exchange name
+		routingKey, // Routing key
+		false,      // Mandatory
+		false,      // Immediate
+		amqp.Publishing{
+			ContentType: message.ContentType,
+			Body:        message.Body,
+			Headers:     amqp.Table(message.Headers),
+		},
+	)
+}
+
+func (b *AMQPBroker) Consume(ctx context.Context, queue string) (<-chan Delivery, error) {
+	ch, err := b.channel()
+	if err != nil {
+		return nil, err
+	}
+
+	tag := "consumer-" + uuid.New().String()
+	msgs, err := ch.Consume(
+		queue, // Queue name
+		tag,   // Consumer tag
+		true,  // Auto-acknowledge
+		false, // Exclusive
+		false, // No-local
+		false, // No-wait
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make(chan Delivery)
+	go func() {
+		defer close(deliveries)
+		defer ch.Cancel(tag, false)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				delivery := Delivery{
+					Exchange:    msg.Exchange,
+					RoutingKey:  msg.RoutingKey,
+					ContentType: msg.ContentType,
+					Body:        msg.Body,
+					Headers:     msg.Headers,
+				}
+				select {
+				case deliveries <- delivery:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+func (b *AMQPBroker) DeleteQueue(name string) error {
+	ch, err := b.channel()
+	if err != nil {
+		return err
+	}
+	_, err = ch.QueueDelete(
+		name,  // Queue name
+		false, // IfUnused
+		false, // IfEmpty
+		false, // NoWait
+	)
+	return err
+}
+
+func (b *AMQPBroker) PurgeQueue(name string) (int, error) {
+	ch, err := b.channel()
+	if err != nil {
+		return 0, err
+	}
+	return ch.QueuePurge(name, false)
+}
+
+func (b *AMQPBroker) Ping() error {
+	if b.conn.IsClosed() {
+		return errors.New("RabbitMQ connection closed")
+	}
+	// Reopens the shared channel if the server closed it
+	_, err := b.channel()
+	return err
+}
+
+func (b *AMQPBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var err error
+	if b.ch != nil && !b.ch.IsClosed() {
+		err = b.ch.Close()
+	}
+	if closeErr := b.conn.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
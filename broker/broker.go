@@ -0,0 +1,69 @@
+package broker
+
+import (
+	"context"
+	"errors"
+)
+
+// Exchange kinds supported by every implementation
+const (
+	ExchangeDirect = "direct"
+	ExchangeFanout = "fanout"
+)
+
+// ErrQueueNotFound is returned when an operation targets an undeclared queue
+var ErrQueueNotFound = errors.New("queue not found")
+
+// ErrExchangeNotFound is returned when an operation targets an undeclared exchange
+var ErrExchangeNotFound = errors.New("exchange not found")
+
+// Message is a message to publish
+type Message struct {
+	ContentType string
+	Body        []byte
+	Headers     map[string]interface{}
+}
+
+// Delivery is a message received from a queue
+type Delivery struct {
+	Exchange    string
+	RoutingKey  string
+	ContentType string
+	Body        []byte
+	Headers     map[string]interface{}
+}
+
+// QueueOptions controls how a queue is declared
+type QueueOptions struct {
+	Durable    bool
+	AutoDelete bool
+	Exclusive  bool
+	Args       map[string]interface{}
+}
+
+// Broker is the message transport used by every service. Consumers are
+// auto-acknowledged and stop when their context is canceled.
+type Broker interface {
+	// DeclareExchange creates the exchange if it does not exist
+	DeclareExchange(name, kind string) error
+	// DeclareQueue creates the queue if it does not exist
+	DeclareQueue(name string, options QueueOptions) error
+	// QueueExists reports whether the queue has been declared
+	QueueExists(name string) bool
+	// BindQueue routes messages published to exchange with routingKey into queue
+	BindQueue(queue, exchange, routingKey string) error
+	// Publish sends a message to an exchange; the empty exchange routes by queue name
+	Publish(ctx context.Context, exchange, routingKey string, message Message) error
+	// Consume delivers the messages of queue until ctx is canceled, then closes the channel
+	Consume(ctx context.Context, queue string) (<-chan Delivery, error)
+	// DeleteQueue removes a queue and its bindings
+	DeleteQueue(name string) error
+	// QueueLength returns the number of messages ready for delivery in a queue
+	QueueLength(name string) (int, error)
+	// PurgeQueue drops every ready message of a queue and returns how many were dropped
+	PurgeQueue(name string) (int, error)
+	// Ping reports an error when the broker can no longer publish or consume
+	Ping() error
+	// Close releases the underlying resources
+	Close() error
+}
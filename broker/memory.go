@@ -0,0 +1,315 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MemoryBroker is an in-process Broker mimicking RabbitMQ direct and fanout
+// exchange semantics. It lets every service run in a single process, for
+// tests and local development, without a RabbitMQ server.
+type MemoryBroker struct {
+	mu        sync.Mutex
+	exchanges map[string]*memoryExchange
+	queues    map[string]*memoryQueue
+}
+
+type memoryExchange struct {
+	kind     string
+	bindings []memoryBinding
+}
+
+type memoryBinding struct {
+	queue      string
+	routingKey string
+}
+
+// memoryQueue is an unbounded FIFO shared by its competing consumers
+type memoryQueue struct {
+	name       string
+	autoDelete bool
+	consumers  int
+	messages   []Delivery
+	// ready is signaled whenever messages are added
+	ready chan struct{}
+	// gone is closed when the queue is deleted
+	gone    chan struct{}
+	deleted bool
+}
+
+// NewMemoryBroker returns an empty in-process broker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		exchanges: map[string]*memoryExchange{},
+		queues:    map[string]*memoryQueue{},
+	}
+}
+
+func (b *MemoryBroker) DeclareExchange(name, kind string) error {
+	if kind != ExchangeDirect && kind != ExchangeFanout {
+		return fmt.Errorf("unsupported exchange kind %s", kind)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if existing, ok := b.exchanges[name]; ok {
+		if existing.kind != kind {
+			return fmt.Errorf("exchange %s already declared as %s", name, existing.kind)
+		}
+		return nil
+	}
+	b.exchanges[name] = &memoryExchange{kind: kind}
+	return nil
+}
+
+func (b *MemoryBroker) DeclareQueue(name string, options QueueOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.queues[name]; ok {
+		return nil
+	}
+	b.queues[name] = &memoryQueue{
+		name:       name,
+		autoDelete: options.AutoDelete,
+		ready:      make(chan struct{}, 1),
+		gone:       make(chan struct{}),
+	}
+	return nil
+}
+
+func (b *MemoryBroker) QueueExists(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, ok := b.queues[name]
+	return ok
+}
+
+func (b *MemoryBroker) QueueLength(name string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	queue, ok := b.queues[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrQueueNotFound, name)
+	}
+	return len(queue.messages), nil
+}
+
+func (b *MemoryBroker) BindQueue(queue, exchange, routingKey string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.queues[queue]; !ok {
+		return fmt.Errorf("%w: %s", ErrQueueNotFound, queue)
+	}
+	ex, ok := b.exchanges[exchange]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrExchangeNotFound, exchange)
+	}
+
+	for _, binding := range ex.bindings {
+		if binding.queue == queue && binding.routingKey == routingKey {
+			return nil
+		}
+	}
+	ex.bindings = append(ex.bindings, memoryBinding{queue: queue, routingKey: routingKey})
+	return nil
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, exchange, routingKey string, message Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delivery := Delivery{
+		Exchange:    exchange,
+		RoutingKey:  routingKey,
+		ContentType: message.ContentType,
+		Body:        message.Body,
+		Headers:     copyHeaders(message.Headers),
+	}
+
+	// The default exchange routes directly to the queue named by the routing key
+	if exchange == "" {
+		if queue, ok := b.queues[routingKey]; ok {
+			queue.push(delivery)
+		}
+		return nil
+	}
+
+	ex, ok := b.exchanges[exchange]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrExchangeNotFound, exchange)
+	}
+
+	// Unroutable messages are dropped, as RabbitMQ does without the mandatory flag
+	for _, binding := range ex.bindings {
+		if ex.kind == ExchangeFanout || binding.routingKey == routingKey {
+			if queue, ok := b.queues[binding.queue]; ok {
+				queue.push(delivery)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Consume(ctx context.Context, queueName string) (<-chan Delivery, error) {
+	b.mu.Lock()
+	queue, ok := b.queues[queueName]
+	if !ok {
+		b.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrQueueNotFound, queueName)
+	}
+	queue.consumers++
+	b.mu.Unlock()
+
+	deliveries := make(chan Delivery)
+	go func() {
+		defer close(deliveries)
+		defer b.releaseConsumer(queue)
+
+		for {
+			delivery, ok := b.next(ctx, queue)
+			if !ok {
+				return
+			}
+			select {
+			case deliveries <- delivery:
+			case <-ctx.Done():
+				// Put the message back for the other consumers
+				b.mu.Lock()
+				queue.messages = append([]Delivery{delivery}, queue.messages...)
+				queue.signal()
+				b.mu.Unlock()
+				return
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+// next blocks until a message is available, the queue is deleted or ctx ends
+func (b *MemoryBroker) next(ctx context.Context, queue *memoryQueue) (Delivery, bool) {
+	for {
+		b.mu.Lock()
+		if queue.deleted {
+			b.mu.Unlock()
+			return Delivery{}, false
+		}
+		if len(queue.messages) > 0 {
+			delivery := queue.messages[0]
+			queue.messages = queue.messages[1:]
+			// Wake up competing consumers if more messages remain
+			if len(queue.messages) > 0 {
+				queue.signal()
+			}
+			b.mu.Unlock()
+			return delivery, true
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-queue.ready:
+		case <-queue.gone:
+			return Delivery{}, false
+		case <-ctx.Done():
+			return Delivery{}, false
+		}
+	}
+}
+
+// releaseConsumer unregisters a consumer and applies auto-delete
+func (b *MemoryBroker) releaseConsumer(queue *memoryQueue) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	queue.consumers--
+	if queue.autoDelete && queue.consumers == 0 && !queue.deleted {
+		b.deleteLocked(queue.name)
+	}
+}
+
+func (b *MemoryBroker) DeleteQueue(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.queues[name]; !ok {
+		return nil
+	}
+	b.deleteLocked(name)
+	return nil
+}
+
+// deleteLocked removes a queue and its bindings; b.mu must be held
+func (b *MemoryBroker) deleteLocked(name string) {
+	queue := b.queues[name]
+	queue.deleted = true
+	queue.messages = nil
+	close(queue.gone)
+	delete(b.queues, name)
+
+	for _, ex := range b.exchanges {
+		kept := ex.bindings[:0]
+		for _, binding := range ex.bindings {
+			if binding.queue != name {
+				kept = append(kept, binding)
+			}
+		}
+		ex.bindings = kept
+	}
+}
+
+func (b *MemoryBroker) PurgeQueue(name string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	queue, ok := b.queues[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrQueueNotFound, name)
+	}
+	purged := len(queue.messages)
+	queue.messages = nil
+	return purged, nil
+}
+
+func (b *MemoryBroker) Ping() error {
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for name := range b.queues {
+		b.deleteLocked(name)
+	}
+	return nil
+}
+
+// push appends a delivery and wakes a consumer; the broker lock must be held
+func (q *memoryQueue) push(delivery Delivery) {
+	q.messages = append(q.messages, delivery)
+	q.signal()
+}
+
+// signal wakes one waiting consumer without blocking
+func (q *memoryQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func copyHeaders(headers map[string]interface{}) map[string]interface{} {
+	if headers == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(headers))
+	for key, value := range headers {
+		copied[key] = value
+	}
+	return copied
+}
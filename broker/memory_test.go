@@ -0,0 +1,219 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestBroker returns a broker with the given exchanges and queues declared
+func newTestBroker(t *testing.T, exchanges map[string]string, queues ...string) *MemoryBroker {
+	t.Helper()
+	b := NewMemoryBroker()
+	t.Cleanup(func() { b.Close() })
+	for name, kind := range exchanges {
+		if err := b.DeclareExchange(name, kind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range queues {
+		if err := b.DeclareQueue(name, QueueOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b
+}
+
+// bind binds queue to exchange with routingKey
+func bind(t *testing.T, b *MemoryBroker, queue, exchange, routingKey string) {
+	t.Helper()
+	if err := b.BindQueue(queue, exchange, routingKey); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// publish publishes body to exchange with routingKey
+func publish(t *testing.T, b *MemoryBroker, exchange, routingKey, body string) {
+	t.Helper()
+	if err := b.Publish(context.Background(), exchange, routingKey, Message{Body: []byte(body)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectLength fails unless queue holds length messages
+func expectLength(t *testing.T, b *MemoryBroker, queue string, length int) {
+	t.Helper()
+	got, err := b.QueueLength(queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != length {
+		t.Fatalf("expected %d messages in %s, got %d", length, queue, got)
+	}
+}
+
+// receive waits for the next delivery of deliveries
+func receive(t *testing.T, deliveries <-chan Delivery) Delivery {
+	t.Helper()
+	select {
+	case delivery, ok := <-deliveries:
+		if !ok {
+			t.Fatal("the deliveries were closed")
+		}
+		return delivery
+	case <-time.After(time.Second):
+		t.Fatal("no delivery")
+	}
+	return Delivery{}
+}
+
+// expectClosed fails unless deliveries gets closed
+func expectClosed(t *testing.T, deliveries <-chan Delivery) {
+	t.Helper()
+	select {
+	case delivery, ok := <-deliveries:
+		if ok {
+			t.Fatalf("unexpected delivery %s", delivery.Body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the deliveries were not closed")
+	}
+}
+
+func TestMemoryDirectRouting(t *testing.T) {
+	b := newTestBroker(t, map[string]string{"direct": ExchangeDirect}, "logins", "registrations")
+	bind(t, b, "logins", "direct", "login")
+	bind(t, b, "registrations", "direct", "registration")
+
+	// Only the queue bound with the routing key gets the message
+	publish(t, b, "direct", "login", "alice")
+	expectLength(t, b, "logins", 1)
+	expectLength(t, b, "registrations", 0)
+
+	deliveries, err := b.Consume(context.Background(), "logins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivery := receive(t, deliveries)
+	if delivery.Exchange != "direct" || delivery.RoutingKey != "login" || string(delivery.Body) != "alice" {
+		t.Fatalf("unexpected delivery %+v", delivery)
+	}
+
+	// The default exchange routes to the queue named by the routing key
+	publish(t, b, "", "registrations", "bob")
+	expectLength(t, b, "registrations", 1)
+}
+
+func TestMemoryFanout(t *testing.T) {
+	b := newTestBroker(t, map[string]string{"broadcast": ExchangeFanout}, "gateway-1", "gateway-2")
+	bind(t, b, "gateway-1", "broadcast", "")
+	bind(t, b, "gateway-2", "broadcast", "ignored")
+
+	// Every bound queue gets the message, whatever the routing key
+	publish(t, b, "broadcast", "anything", "hello")
+	expectLength(t, b, "gateway-1", 1)
+	expectLength(t, b, "gateway-2", 1)
+}
+
+func TestMemoryUnboundPublish(t *testing.T) {
+	b := newTestBroker(t, map[string]string{"direct": ExchangeDirect}, "logins")
+	bind(t, b, "logins", "direct", "login")
+
+	// An unroutable message is dropped without error, like RabbitMQ without
+	// the mandatory flag
+	publish(t, b, "direct", "unknown", "lost")
+	publish(t, b, "", "missing-queue", "lost")
+	expectLength(t, b, "logins", 0)
+
+	err := b.Publish(context.Background(), "missing-exchange", "login", Message{Body: []byte("lost")})
+	if !errors.Is(err, ErrExchangeNotFound) {
+		t.Fatalf("expected ErrExchangeNotFound, got %v", err)
+	}
+	if err := b.BindQueue("missing-queue", "direct", "login"); !errors.Is(err, ErrQueueNotFound) {
+		t.Fatalf("expected ErrQueueNotFound, got %v", err)
+	}
+	if _, err := b.Consume(context.Background(), "missing-queue"); !errors.Is(err, ErrQueueNotFound) {
+		t.Fatalf("expected ErrQueueNotFound, got %v", err)
+	}
+	if err := b.DeclareExchange("direct", ExchangeFanout); err == nil {
+		t.Fatal("an exchange was redeclared with another kind")
+	}
+}
+
+func TestMemoryDeleteQueue(t *testing.T) {
+	b := newTestBroker(t, map[string]string{"direct": ExchangeDirect}, "session")
+	bind(t, b, "session", "direct", "user-1")
+
+	deliveries, err := b.Consume(context.Background(), "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.DeleteQueue("session"); err != nil {
+		t.Fatal(err)
+	}
+	expectClosed(t, deliveries)
+	if b.QueueExists("session") {
+		t.Fatal("the queue still exists")
+	}
+
+	// The bindings went with the queue: a queue declared again with the same
+	// name does not get the messages of the exchange
+	if err := b.DeclareQueue("session", QueueOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	publish(t, b, "direct", "user-1", "lost")
+	expectLength(t, b, "session", 0)
+}
+
+func TestMemoryAutoDelete(t *testing.T) {
+	b := newTestBroker(t, nil)
+	if err := b.DeclareQueue("session", QueueOptions{AutoDelete: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The queue is deleted once its last consumer stops
+	ctx, cancel := context.WithCancel(context.Background())
+	deliveries, err := b.Consume(ctx, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	expectClosed(t, deliveries)
+	if b.QueueExists("session") {
+		t.Fatal("the auto-delete queue outlived its consumer")
+	}
+}
+
+func TestMemoryCompetingConsumers(t *testing.T) {
+	b := newTestBroker(t, nil, "work")
+	first, err := b.Consume(context.Background(), "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := b.Consume(context.Background(), "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each message goes to one consumer only
+	for _, body := range []string{"1", "2", "3", "4"} {
+		publish(t, b, "", "work", body)
+	}
+	received := map[string]int{}
+	for i := 0; i < 4; i++ {
+		select {
+		case delivery := <-first:
+			received[string(delivery.Body)]++
+		case delivery := <-second:
+			received[string(delivery.Body)]++
+		case <-time.After(time.Second):
+			t.Fatalf("got %d of 4 messages", i)
+		}
+	}
+	for _, body := range []string{"1", "2", "3", "4"} {
+		if received[body] != 1 {
+			t.Fatalf("message %s was received %d times", body, received[body])
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+// AuthError reports a registration or login refused by the user service
+type AuthError struct {
+	Message string
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+// authResult is the frame sent on /ws/{uuid} for a registration or a login
+type authResult struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Token   string `json:"token"`
+}
+
+// Register creates an account and waits for the user service to store it
+func (c *Client) Register(ctx context.Context, username, password, displayName string) error {
+	pending, err := c.RequestRegistration(ctx, RegisterRequest{
+		Username:    username,
+		Password:    password,
+		DisplayName: displayName,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.awaitAuthResult(ctx, pending.UUID)
+	return err
+}
+
+// Login authenticates the client: it waits for the token issued by the user
+// service and uses it for the next calls. The token is returned too.
+func (c *Client) Login(ctx context.Context, username, password string) (string, error) {
+	pending, err := c.RequestLogin(ctx, LoginRequest{Username: username, Password: password})
+	if err != nil {
+		return "", err
+	}
+	result, err := c.awaitAuthResult(ctx, pending.UUID)
+	if err != nil {
+		return "", err
+	}
+	c.Token = result.Token
+	return result.Token, nil
+}
+
+// awaitAuthResult reads the outcome of a registration or login from
+// /ws/{uuid}, where the gateway forwards the reply of the user service
+func (c *Client) awaitAuthResult(ctx context.Context, uuid string) (authResult, error) {
+	var result authResult
+
+	conn, err := c.dial(ctx, "/ws/"+uuid)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+
+	// The read is bounded by the context, or by the HTTP client timeout
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout())
+	}
+	conn.SetReadDeadline(deadline)
+
+	_, raw, err := conn.ReadMessage()
+	if err != nil {
+		return result, fmt.Errorf("waiting for the result of %s: %w", uuid, err)
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return result, fmt.Errorf("invalid result frame %q: %w", raw, err)
+	}
+	if !result.Success {
+		return result, &AuthError{Message: result.Message}
+	}
+	return result, nil
+}
+
+// dial opens a WebSocket on path of the gateway
+func (c *Client) dial(ctx context.Context, path string) (*websocket.Conn, error) {
+	url := "ws" + strings.TrimPrefix(c.BaseURL, "http") + path
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		if resp != nil {
+			return nil, &Error{Status: resp.StatusCode, Code: "unexpected_status", Message: "WebSocket upgrade refused on " + path}
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// timeout is the timeout of the HTTP client, or 30 seconds without one
+func (c *Client) timeout() time.Duration {
+	if c.HTTPClient != nil && c.HTTPClient.Timeout > 0 {
+		return c.HTTPClient.Timeout
+	}
+	return 30 * time.Second
+}
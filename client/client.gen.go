@@ -0,0 +1,1070 @@
+// Code generated by clientgen from docs/openapi.yaml. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// APIKey is the APIKey schema of the API
+type APIKey struct {
+	ID    uint64 `json:"id"`
+	BotID uint64 `json:"bot_id"`
+	Name  string `json:"name"`
+	// Start of the key, to recognize it
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	// Requests per minute on each gateway, 0 for API_KEY_RATE_LIMIT
+	RateLimit  int        `json:"rate_limit"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyList is the APIKeyList schema of the API
+type APIKeyList struct {
+	APIKeys []APIKey `json:"api_keys"`
+}
+
+// AdminUser is the AdminUser schema of the API
+type AdminUser struct {
+	ID            uint64     `json:"id"`
+	Username      string     `json:"username"`
+	DisplayName   string     `json:"display_name,omitempty"`
+	Role          string     `json:"role"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	// Owner of the account when it is a bot
+	OwnerID uint64 `json:"owner_id,omitempty"`
+}
+
+// AdminUserList is the AdminUserList schema of the API
+type AdminUserList struct {
+	Users    []AdminUser `json:"users"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+	Total    int64       `json:"total"`
+}
+
+// AuditLog is the AuditLog schema of the API
+type AuditLog struct {
+	ID         uint64    `json:"id"`
+	ActorID    uint64    `json:"actor_id"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"target_type"`
+	TargetID   uint64    `json:"target_id"`
+	Details    string    `json:"details"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AuditLogList is the AuditLogList schema of the API
+type AuditLogList struct {
+	AuditLogs []AuditLog `json:"audit_logs"`
+	Page      int        `json:"page"`
+	PageSize  int        `json:"page_size"`
+	Total     int64      `json:"total"`
+}
+
+// Bot is the Bot schema of the API
+type Bot struct {
+	ID            uint64     `json:"id"`
+	Username      string     `json:"username"`
+	DisplayName   string     `json:"display_name,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+}
+
+// BotCommand is the BotCommand schema of the API
+type BotCommand struct {
+	ID          uint64    `json:"id"`
+	BotID       uint64    `json:"bot_id"`
+	Name        string    `json:"name"`
+	Usage       string    `json:"usage"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BotCommandList is the BotCommandList schema of the API
+type BotCommandList struct {
+	Commands []BotCommand `json:"commands"`
+}
+
+// BotList is the BotList schema of the API
+type BotList struct {
+	Bots []Bot `json:"bots"`
+}
+
+// Command is the Command schema of the API
+type Command struct {
+	// Name of the command, without the /
+	Name        string `json:"name"`
+	Usage       string `json:"usage"`
+	Description string `json:"description"`
+	// Bot running the command, omitted for the built-in commands
+	BotID uint64 `json:"bot_id,omitempty"`
+}
+
+// CommandAnswer is the CommandAnswer schema of the API
+type CommandAnswer struct {
+	Text string `json:"text"`
+}
+
+// CommandList is the CommandList schema of the API
+type CommandList struct {
+	Commands []Command `json:"commands"`
+}
+
+// Conversation is the Conversation schema of the API
+type Conversation struct {
+	Messages []Message `json:"messages"`
+}
+
+// CreateAPIKeyRequest is the CreateAPIKeyRequest schema of the API
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Requests per minute on each gateway, at most API_KEY_MAX_RATE_LIMIT; 0 for API_KEY_RATE_LIMIT
+	RateLimit int `json:"rate_limit,omitempty"`
+	// Lifetime of the key in seconds, 0 for a key that does not expire
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+}
+
+// CreateBotRequest is the CreateBotRequest schema of the API
+type CreateBotRequest struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+// CreateIncomingWebhookRequest is the CreateIncomingWebhookRequest schema of the API
+type CreateIncomingWebhookRequest struct {
+	ReceiverID uint64 `json:"receiver_id"`
+	Name       string `json:"name"`
+}
+
+// CreateWebhookRequest is the CreateWebhookRequest schema of the API
+type CreateWebhookRequest struct {
+	URL string `json:"url"`
+	// Events to receive, every event when empty
+	Events   []string `json:"events,omitempty"`
+	AllUsers bool     `json:"all_users,omitempty"`
+}
+
+// CreatedAPIKey is the CreatedAPIKey schema of the API
+type CreatedAPIKey struct {
+	APIKey APIKey `json:"api_key"`
+	// The secret key, which cannot be retrieved again
+	Key string `json:"key"`
+}
+
+// CreatedIncomingWebhook is the CreatedIncomingWebhook schema of the API
+type CreatedIncomingWebhook struct {
+	IncomingWebhook IncomingWebhook `json:"incoming_webhook"`
+	// The secret token, which cannot be retrieved again
+	Token string `json:"token"`
+	// URL to post messages to, carrying the token
+	URL string `json:"url"`
+}
+
+// CreatedWebhook is the CreatedWebhook schema of the API
+type CreatedWebhook struct {
+	Webhook Webhook `json:"webhook"`
+	// The signing secret, which cannot be retrieved again
+	Secret string `json:"secret"`
+}
+
+// IncomingMessage is the IncomingMessage schema of the API
+type IncomingMessage struct {
+	Text string `json:"text"`
+	// Name shown instead of the owner's, at most 64 characters
+	Username string `json:"username,omitempty"`
+}
+
+// IncomingWebhook is the IncomingWebhook schema of the API
+type IncomingWebhook struct {
+	ID         uint64 `json:"id"`
+	ReceiverID uint64 `json:"receiver_id"`
+	Name       string `json:"name"`
+	// Start of the token, to recognize it
+	Prefix     string     `json:"prefix"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// IncomingWebhookList is the IncomingWebhookList schema of the API
+type IncomingWebhookList struct {
+	IncomingWebhooks []IncomingWebhook `json:"incoming_webhooks"`
+}
+
+// LoginRequest is the LoginRequest schema of the API
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Message is the Message schema of the API
+type Message struct {
+	ID         uint64 `json:"id"`
+	SenderID   uint64 `json:"sender_id"`
+	ReceiverID uint64 `json:"receiver_id"`
+	Content    string `json:"content"`
+	// Name shown instead of the sender's, set by incoming webhooks, reminders and the answers of bots
+	SenderName string `json:"sender_name,omitempty"`
+}
+
+// MessageContent is the MessageContent schema of the API
+type MessageContent struct {
+	Content string `json:"content"`
+}
+
+// PendingRequest is the PendingRequest schema of the API
+type PendingRequest struct {
+	// Connect to /ws/{uuid} to receive the outcome
+	UUID    string `json:"uuid"`
+	Message string `json:"message"`
+}
+
+// PollResponse is the PollResponse schema of the API
+type PollResponse struct {
+	Session string `json:"session"`
+	Cursor  uint64 `json:"cursor"`
+	// Frames of the WebSocket protocol
+	Events []json.RawMessage `json:"events"`
+	Closed bool              `json:"closed"`
+}
+
+// RegisterBotCommandRequest is the RegisterBotCommandRequest schema of the API
+type RegisterBotCommandRequest struct {
+	// 1 to 32 lowercase letters, digits, - or _
+	Name string `json:"name"`
+	// Shown by the autocomplete, at most 100 characters
+	Usage string `json:"usage,omitempty"`
+	// At most 200 characters
+	Description string `json:"description,omitempty"`
+}
+
+// RegisterRequest is the RegisterRequest schema of the API
+type RegisterRequest struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+// RoleChange is the RoleChange schema of the API
+type RoleChange struct {
+	Role string `json:"role"`
+}
+
+// ScheduleMessageRequest is the ScheduleMessageRequest schema of the API
+type ScheduleMessageRequest struct {
+	ReceiverID uint64 `json:"receiver_id"`
+	Content    string `json:"content"`
+	// RFC 3339 time, or local date and time such as 2026-10-20T09:00 read in time_zone
+	SendAt string `json:"send_at"`
+	// IANA time zone such as Europe/Paris, UTC by default
+	TimeZone string `json:"time_zone,omitempty"`
+}
+
+// ScheduledMessage is the ScheduledMessage schema of the API
+type ScheduledMessage struct {
+	ID         uint64 `json:"id"`
+	ReceiverID uint64 `json:"receiver_id"`
+	// Reminders are set with /remind and sent to the user themselves
+	Kind    string `json:"kind"`
+	Content string `json:"content"`
+	// Time the message is sent at, in UTC
+	SendAt time.Time `json:"send_at"`
+	// IANA time zone the time was given in
+	TimeZone string `json:"time_zone"`
+	// send_at in time_zone, in RFC 3339
+	LocalSendAt string `json:"local_send_at"`
+	// sending while the scheduler daemon publishes the message
+	Status    string     `json:"status"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ScheduledMessageList is the ScheduledMessageList schema of the API
+type ScheduledMessageList struct {
+	ScheduledMessages []ScheduledMessage `json:"scheduled_messages"`
+}
+
+// Self is the Self schema of the API
+type Self struct {
+	User User `json:"user"`
+}
+
+// SentMessage is the SentMessage schema of the API
+type SentMessage struct {
+	Message Message `json:"message"`
+	// Sequence number of the message in the sender's event stream
+	Seq uint64 `json:"seq"`
+	// Set for the answers of commands shown to the sender only, which are not stored and have no ID nor seq
+	Ephemeral bool `json:"ephemeral,omitempty"`
+}
+
+// Stats is the Stats schema of the API
+type Stats struct {
+	Users             int64 `json:"users"`
+	ActiveUsers       int64 `json:"active_users"`
+	DeactivatedUsers  int64 `json:"deactivated_users"`
+	Admins            int64 `json:"admins"`
+	Moderators        int64 `json:"moderators"`
+	Messages          int64 `json:"messages"`
+	MessagesLast24h   int64 `json:"messages_last_24h"`
+	ActiveConnections int64 `json:"active_connections"`
+}
+
+// UpdateScheduledMessageRequest is the UpdateScheduledMessageRequest schema of the API
+type UpdateScheduledMessageRequest struct {
+	Content string `json:"content,omitempty"`
+	// RFC 3339 time, or local date and time read in the time zone of the message
+	SendAt string `json:"send_at,omitempty"`
+	// IANA time zone such as Europe/Paris
+	TimeZone string `json:"time_zone,omitempty"`
+}
+
+// User is the User schema of the API
+type User struct {
+	ID          uint64 `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+	// Whether the user is a bot
+	Bot bool `json:"bot,omitempty"`
+}
+
+// UserList is the UserList schema of the API
+type UserList struct {
+	Users []User `json:"users"`
+}
+
+// UserSearchResult is the UserSearchResult schema of the API
+type UserSearchResult struct {
+	Users    []User `json:"users"`
+	Query    string `json:"query"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+	Total    int64  `json:"total"`
+	HasMore  bool   `json:"has_more"`
+}
+
+// Webhook is the Webhook schema of the API
+type Webhook struct {
+	ID     uint64   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Whether the webhook receives the events of every user
+	AllUsers bool `json:"all_users"`
+	// Deliveries failed for good in a row; WEBHOOK_DISABLE_AFTER of them disable the webhook
+	FailureCount int        `json:"failure_count"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+}
+
+// WebhookDelivery is the WebhookDelivery schema of the API
+type WebhookDelivery struct {
+	ID        uint64 `json:"id"`
+	WebhookID uint64 `json:"webhook_id"`
+	Event     string `json:"event"`
+	// ID of the event, shared by the replays of a delivery
+	EventID        string     `json:"event_id"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	ReplayOf       uint64     `json:"replay_of,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// WebhookDeliveryList is the WebhookDeliveryList schema of the API
+type WebhookDeliveryList struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	Total      int64             `json:"total"`
+}
+
+// WebhookList is the WebhookList schema of the API
+type WebhookList struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// AdminListAuditLogsParams holds the query parameters of AdminListAuditLogs
+type AdminListAuditLogsParams struct {
+	ActorID  uint64
+	Action   string
+	Page     int
+	PageSize int
+}
+
+// AdminListAuditLogs calls GET /api/admin/audit-logs: list audit log entries, newest first
+//
+// Requires the audit:view permission.
+func (c *Client) AdminListAuditLogs(ctx context.Context, params AdminListAuditLogsParams) (*AuditLogList, error) {
+	path := "/api/admin/audit-logs"
+	query := url.Values{}
+	if params.ActorID != 0 {
+		query.Set("actor_id", fmt.Sprint(params.ActorID))
+	}
+	if params.Action != "" {
+		query.Set("action", params.Action)
+	}
+	if params.Page != 0 {
+		query.Set("page", fmt.Sprint(params.Page))
+	}
+	if params.PageSize != 0 {
+		query.Set("page_size", fmt.Sprint(params.PageSize))
+	}
+	var result AuditLogList
+	if err := c.do(ctx, http.MethodGet, path, query, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AdminGetStats calls GET /api/admin/stats: report user, message and connection counters
+//
+// Requires the stats:view permission.
+func (c *Client) AdminGetStats(ctx context.Context) (*Stats, error) {
+	path := "/api/admin/stats"
+	var result Stats
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AdminListUsersParams holds the query parameters of AdminListUsers
+type AdminListUsersParams struct {
+	Query    string
+	Role     string
+	Status   string
+	Page     int
+	PageSize int
+}
+
+// AdminListUsers calls GET /api/admin/users: list users with their account details
+//
+// Requires the users:list permission.
+func (c *Client) AdminListUsers(ctx context.Context, params AdminListUsersParams) (*AdminUserList, error) {
+	path := "/api/admin/users"
+	query := url.Values{}
+	if params.Query != "" {
+		query.Set("query", params.Query)
+	}
+	if params.Role != "" {
+		query.Set("role", params.Role)
+	}
+	if params.Status != "" {
+		query.Set("status", params.Status)
+	}
+	if params.Page != 0 {
+		query.Set("page", fmt.Sprint(params.Page))
+	}
+	if params.PageSize != 0 {
+		query.Set("page_size", fmt.Sprint(params.PageSize))
+	}
+	var result AdminUserList
+	if err := c.do(ctx, http.MethodGet, path, query, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AdminDeactivateUser calls POST /api/admin/users/{userId}/deactivate: deactivate an account and revoke its sessions
+//
+// Requires the users:manage permission.
+func (c *Client) AdminDeactivateUser(ctx context.Context, userID uint64) (*AdminUser, error) {
+	path := fmt.Sprintf("/api/admin/users/%s/deactivate", url.PathEscape(fmt.Sprint(userID)))
+	var result AdminUser
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AdminForceLogout calls POST /api/admin/users/{userId}/logout: revoke every session of a user
+//
+// Requires the sessions:revoke permission.
+func (c *Client) AdminForceLogout(ctx context.Context, userID uint64) (*AdminUser, error) {
+	path := fmt.Sprintf("/api/admin/users/%s/logout", url.PathEscape(fmt.Sprint(userID)))
+	var result AdminUser
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AdminReactivateUser calls POST /api/admin/users/{userId}/reactivate: reactivate a deactivated account
+//
+// Requires the users:manage permission.
+func (c *Client) AdminReactivateUser(ctx context.Context, userID uint64) (*AdminUser, error) {
+	path := fmt.Sprintf("/api/admin/users/%s/reactivate", url.PathEscape(fmt.Sprint(userID)))
+	var result AdminUser
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AdminSetUserRole calls PUT /api/admin/users/{userId}/role: change the role of a user
+//
+// Requires the roles:manage permission.
+func (c *Client) AdminSetUserRole(ctx context.Context, userID uint64, body RoleChange) (*AdminUser, error) {
+	path := fmt.Sprintf("/api/admin/users/%s/role", url.PathEscape(fmt.Sprint(userID)))
+	var result AdminUser
+	if err := c.do(ctx, http.MethodPut, path, nil, body, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListBots calls GET /api/bots: list the bots of the authenticated user
+//
+// Bots are managed with a password session, not with an API key.
+func (c *Client) ListBots(ctx context.Context) (*BotList, error) {
+	path := "/api/bots"
+	var result BotList
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateBot calls POST /api/bots: create a bot owned by the authenticated user
+//
+// A user may own at most BOTS_MAX_PER_OWNER bots.
+func (c *Client) CreateBot(ctx context.Context, body CreateBotRequest) (*Bot, error) {
+	path := "/api/bots"
+	var result Bot
+	if err := c.do(ctx, http.MethodPost, path, nil, body, 201, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListBotCommands calls GET /api/bots/{botId}/commands: list the slash commands of a bot
+func (c *Client) ListBotCommands(ctx context.Context, botID uint64) (*BotCommandList, error) {
+	path := fmt.Sprintf("/api/bots/%s/commands", url.PathEscape(fmt.Sprint(botID)))
+	var result BotCommandList
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RegisterBotCommand calls POST /api/bots/{botId}/commands: register a slash command run by a bot
+//
+// Command names are shared by every user and cannot be those of the
+// built-in commands. Running the command sends a command_invocation
+// notification to the realtime connections of the bot, which answers it
+// with respondToCommand.
+func (c *Client) RegisterBotCommand(ctx context.Context, botID uint64, body RegisterBotCommandRequest) (*BotCommand, error) {
+	path := fmt.Sprintf("/api/bots/%s/commands", url.PathEscape(fmt.Sprint(botID)))
+	var result BotCommand
+	if err := c.do(ctx, http.MethodPost, path, nil, body, 201, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteBotCommand calls DELETE /api/bots/{botId}/commands/{commandId}: delete a slash command of a bot, freeing its name
+func (c *Client) DeleteBotCommand(ctx context.Context, botID uint64, commandID uint64) (*BotCommand, error) {
+	path := fmt.Sprintf("/api/bots/%s/commands/%s", url.PathEscape(fmt.Sprint(botID)), url.PathEscape(fmt.Sprint(commandID)))
+	var result BotCommand
+	if err := c.do(ctx, http.MethodDelete, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeactivateBot calls POST /api/bots/{botId}/deactivate: deactivate a bot, revoke its API keys and disconnect it
+func (c *Client) DeactivateBot(ctx context.Context, botID uint64) (*Bot, error) {
+	path := fmt.Sprintf("/api/bots/%s/deactivate", url.PathEscape(fmt.Sprint(botID)))
+	var result Bot
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListAPIKeys calls GET /api/bots/{botId}/keys: list the API keys of a bot, without their secrets
+func (c *Client) ListAPIKeys(ctx context.Context, botID uint64) (*APIKeyList, error) {
+	path := fmt.Sprintf("/api/bots/%s/keys", url.PathEscape(fmt.Sprint(botID)))
+	var result APIKeyList
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateAPIKey calls POST /api/bots/{botId}/keys: issue an API key to a bot
+//
+// The key is only returned by this call. Send it as a bearer token, like a
+// JWT, on every route but /api/bots, /api/webhooks and /api/admin.
+func (c *Client) CreateAPIKey(ctx context.Context, botID uint64, body CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	path := fmt.Sprintf("/api/bots/%s/keys", url.PathEscape(fmt.Sprint(botID)))
+	var result CreatedAPIKey
+	if err := c.do(ctx, http.MethodPost, path, nil, body, 201, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RevokeAPIKey calls POST /api/bots/{botId}/keys/{keyId}/revoke: revoke an API key and disconnect the sessions opened with it
+func (c *Client) RevokeAPIKey(ctx context.Context, botID uint64, keyID uint64) (*APIKey, error) {
+	path := fmt.Sprintf("/api/bots/%s/keys/%s/revoke", url.PathEscape(fmt.Sprint(botID)), url.PathEscape(fmt.Sprint(keyID)))
+	var result APIKey
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListCommandsParams holds the query parameters of ListCommands
+type ListCommandsParams struct {
+	// Keep the commands whose name starts with it, with or without the /
+	Prefix string
+}
+
+// ListCommands calls GET /api/commands: list the slash commands, for autocompletion
+//
+// The built-in commands and those of the active bots.
+func (c *Client) ListCommands(ctx context.Context, params ListCommandsParams) (*CommandList, error) {
+	path := "/api/commands"
+	query := url.Values{}
+	if params.Prefix != "" {
+		query.Set("prefix", params.Prefix)
+	}
+	var result CommandList
+	if err := c.do(ctx, http.MethodGet, path, query, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RespondToCommand calls POST /api/commands/invocations/{invocationId}/respond: answer an invocation of a command of the calling bot
+//
+// The answer is shown under the name of the bot to the realtime
+// connections of the invoker only. It is ephemeral: a stored message
+// would be attributed to the invoker. Each invocation may be answered
+// once, within 30 minutes.
+func (c *Client) RespondToCommand(ctx context.Context, invocationID uint64, body CommandAnswer) (*SentMessage, error) {
+	path := fmt.Sprintf("/api/commands/invocations/%s/respond", url.PathEscape(fmt.Sprint(invocationID)))
+	var result SentMessage
+	if err := c.do(ctx, http.MethodPost, path, nil, body, 201, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetConversation calls GET /api/conversations/{userId}: get the messages exchanged with a user, oldest first
+func (c *Client) GetConversation(ctx context.Context, userID uint64) (*Conversation, error) {
+	path := fmt.Sprintf("/api/conversations/%s", url.PathEscape(fmt.Sprint(userID)))
+	var result Conversation
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PostMessage calls POST /api/conversations/{userId}/messages: send a message to a user
+//
+// Both users also get it as a send_message_response notification. A
+// message starting with / runs a slash command instead; its answer is
+// either stored like a message or ephemeral, only returned to the caller.
+// Start the message with // to send it as is.
+func (c *Client) PostMessage(ctx context.Context, userID uint64, body MessageContent) (*SentMessage, error) {
+	path := fmt.Sprintf("/api/conversations/%s/messages", url.PathEscape(fmt.Sprint(userID)))
+	var result SentMessage
+	if err := c.do(ctx, http.MethodPost, path, nil, body, 201, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PostIncomingWebhookParams holds the query parameters of PostIncomingWebhook
+type PostIncomingWebhookParams struct {
+	Token string
+}
+
+// PostIncomingWebhook calls POST /api/hooks/{webhookId}: post a message through an incoming webhook
+//
+// The message is sent by the owner of the webhook to its receiver, through
+// the message service, so both get it on their realtime connections. Each
+// webhook accepts INCOMING_WEBHOOK_RATE_LIMIT messages per minute.
+func (c *Client) PostIncomingWebhook(ctx context.Context, webhookID uint64, params PostIncomingWebhookParams, body IncomingMessage) (*SentMessage, error) {
+	path := fmt.Sprintf("/api/hooks/%s", url.PathEscape(fmt.Sprint(webhookID)))
+	query := url.Values{}
+	if params.Token != "" {
+		query.Set("token", params.Token)
+	}
+	var result SentMessage
+	if err := c.do(ctx, http.MethodPost, path, query, body, 201, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListIncomingWebhooks calls GET /api/incoming-webhooks: list the incoming webhooks of the authenticated user, without their tokens
+func (c *Client) ListIncomingWebhooks(ctx context.Context) (*IncomingWebhookList, error) {
+	path := "/api/incoming-webhooks"
+	var result IncomingWebhookList
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateIncomingWebhook calls POST /api/incoming-webhooks: create an incoming webhook posting into a conversation of the authenticated user
+//
+// Messages posted to the returned URL are sent by the authenticated user to
+// the receiver. The token is only returned by this call. A user may create
+// at most INCOMING_WEBHOOKS_MAX_PER_USER incoming webhooks.
+func (c *Client) CreateIncomingWebhook(ctx context.Context, body CreateIncomingWebhookRequest) (*CreatedIncomingWebhook, error) {
+	path := "/api/incoming-webhooks"
+	var result CreatedIncomingWebhook
+	if err := c.do(ctx, http.MethodPost, path, nil, body, 201, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteIncomingWebhook calls DELETE /api/incoming-webhooks/{webhookId}: delete an incoming webhook; its token is refused from then on
+func (c *Client) DeleteIncomingWebhook(ctx context.Context, webhookID uint64) (*IncomingWebhook, error) {
+	path := fmt.Sprintf("/api/incoming-webhooks/%s", url.PathEscape(fmt.Sprint(webhookID)))
+	var result IncomingWebhook
+	if err := c.do(ctx, http.MethodDelete, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RequestLogin calls POST /api/login: log in
+//
+// The token is sent in a login_response frame on /ws/{uuid}.
+func (c *Client) RequestLogin(ctx context.Context, body LoginRequest) (*PendingRequest, error) {
+	path := "/api/login"
+	var result PendingRequest
+	if err := c.do(ctx, http.MethodPost, path, nil, body, 202, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetMessages calls GET /api/messages/{userId}: get the messages exchanged with a user as a bare list
+//
+// Deprecated: the API marks this operation as deprecated.
+func (c *Client) GetMessages(ctx context.Context, userID uint64) ([]Message, error) {
+	path := fmt.Sprintf("/api/messages/%s", url.PathEscape(fmt.Sprint(userID)))
+	var result []Message
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SendMessage calls POST /api/messages/{userId}: send a message to a user, answering with the bare message
+//
+// Deprecated: the API marks this operation as deprecated.
+func (c *Client) SendMessage(ctx context.Context, userID uint64, body MessageContent) (*Message, error) {
+	path := fmt.Sprintf("/api/messages/%s", url.PathEscape(fmt.Sprint(userID)))
+	var result Message
+	if err := c.do(ctx, http.MethodPost, path, nil, body, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PollParams holds the query parameters of Poll
+type PollParams struct {
+	Session string
+	Cursor  uint64
+	// Replay the messages after this sequence number first
+	ResumeFrom uint64
+}
+
+// Poll calls GET /api/poll: long-poll the notifications of /ws/auth
+//
+// Without session, opens a session and returns right away. Otherwise
+// waits up to POLL_TIMEOUT for frames; cursor acknowledges the frames of
+// the previous poll.
+func (c *Client) Poll(ctx context.Context, params PollParams) (*PollResponse, error) {
+	path := "/api/poll"
+	query := url.Values{}
+	if params.Session != "" {
+		query.Set("session", params.Session)
+	}
+	if params.Cursor != 0 {
+		query.Set("cursor", fmt.Sprint(params.Cursor))
+	}
+	if params.ResumeFrom != 0 {
+		query.Set("resume_from", fmt.Sprint(params.ResumeFrom))
+	}
+	var result PollResponse
+	if err := c.do(ctx, http.MethodGet, path, query, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RequestRegistration calls POST /api/register: register a user
+//
+// The outcome is sent as a registration_response frame on /ws/{uuid}.
+func (c *Client) RequestRegistration(ctx context.Context, body RegisterRequest) (*PendingRequest, error) {
+	path := "/api/register"
+	var result PendingRequest
+	if err := c.do(ctx, http.MethodPost, path, nil, body, 202, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListScheduledMessagesParams holds the query parameters of ListScheduledMessages
+type ListScheduledMessagesParams struct {
+	Status string
+}
+
+// ListScheduledMessages calls GET /api/scheduled-messages: list the scheduled messages and reminders of the authenticated user
+//
+// Pending messages come next to send first; sent and canceled ones latest
+// first. At most 100 messages are returned.
+func (c *Client) ListScheduledMessages(ctx context.Context, params ListScheduledMessagesParams) (*ScheduledMessageList, error) {
+	path := "/api/scheduled-messages"
+	query := url.Values{}
+	if params.Status != "" {
+		query.Set("status", params.Status)
+	}
+	var result ScheduledMessageList
+	if err := c.do(ctx, http.MethodGet, path, query, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ScheduleMessage calls POST /api/scheduled-messages: schedule a message to another user
+//
+// The scheduler daemon sends the message at send_at through the message
+// service, as if the user sent it then; its content is sent as is, even
+// when it starts with a slash. A user may have at most
+// SCHEDULED_MESSAGES_MAX_PER_USER pending scheduled messages and
+// reminders, at most SCHEDULER_MAX_DELAY ahead.
+func (c *Client) ScheduleMessage(ctx context.Context, body ScheduleMessageRequest) (*ScheduledMessage, error) {
+	path := "/api/scheduled-messages"
+	var result ScheduledMessage
+	if err := c.do(ctx, http.MethodPost, path, nil, body, 201, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetScheduledMessage calls GET /api/scheduled-messages/{scheduleId}: get a scheduled message of the authenticated user
+func (c *Client) GetScheduledMessage(ctx context.Context, scheduleID uint64) (*ScheduledMessage, error) {
+	path := fmt.Sprintf("/api/scheduled-messages/%s", url.PathEscape(fmt.Sprint(scheduleID)))
+	var result ScheduledMessage
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UpdateScheduledMessage calls PATCH /api/scheduled-messages/{scheduleId}: edit a pending scheduled message
+//
+// The omitted fields are left unchanged. A new time_zone without a new
+// send_at keeps the local time of the message, in the new time zone.
+func (c *Client) UpdateScheduledMessage(ctx context.Context, scheduleID uint64, body UpdateScheduledMessageRequest) (*ScheduledMessage, error) {
+	path := fmt.Sprintf("/api/scheduled-messages/%s", url.PathEscape(fmt.Sprint(scheduleID)))
+	var result ScheduledMessage
+	if err := c.do(ctx, http.MethodPatch, path, nil, body, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CancelScheduledMessage calls DELETE /api/scheduled-messages/{scheduleId}: cancel a pending scheduled message
+func (c *Client) CancelScheduledMessage(ctx context.Context, scheduleID uint64) (*ScheduledMessage, error) {
+	path := fmt.Sprintf("/api/scheduled-messages/%s", url.PathEscape(fmt.Sprint(scheduleID)))
+	var result ScheduledMessage
+	if err := c.do(ctx, http.MethodDelete, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetUsers calls GET /api/users: list the active users
+//
+// Deactivated users are left out, as are the users blocking or blocked by the authenticated user.
+func (c *Client) GetUsers(ctx context.Context) (*UserList, error) {
+	path := "/api/users"
+	var result UserList
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListBlockedUsers calls GET /api/users/blocked: list the users blocked by the authenticated user
+//
+// Blocks are managed with a password session, not with an API key.
+func (c *Client) ListBlockedUsers(ctx context.Context) (*UserList, error) {
+	path := "/api/users/blocked"
+	var result UserList
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetSelf calls GET /api/users/me: get the authenticated user
+func (c *Client) GetSelf(ctx context.Context) (*Self, error) {
+	path := "/api/users/me"
+	var result Self
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SearchUsersParams holds the query parameters of SearchUsers
+type SearchUsersParams struct {
+	// Matched against usernames and display names
+	Query    string
+	Page     int
+	PageSize int
+}
+
+// SearchUsers calls GET /api/users/search: search the user directory
+func (c *Client) SearchUsers(ctx context.Context, params SearchUsersParams) (*UserSearchResult, error) {
+	path := "/api/users/search"
+	query := url.Values{}
+	if params.Query != "" {
+		query.Set("query", params.Query)
+	}
+	if params.Page != 0 {
+		query.Set("page", fmt.Sprint(params.Page))
+	}
+	if params.PageSize != 0 {
+		query.Set("page_size", fmt.Sprint(params.PageSize))
+	}
+	var result UserSearchResult
+	if err := c.do(ctx, http.MethodGet, path, query, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// BlockUser calls PUT /api/users/{userId}/block: block a user
+//
+// The user and the authenticated user no longer find each other in the
+// user directory. Blocking a user twice is not an error.
+func (c *Client) BlockUser(ctx context.Context, userID uint64) (*User, error) {
+	path := fmt.Sprintf("/api/users/%s/block", url.PathEscape(fmt.Sprint(userID)))
+	var result User
+	if err := c.do(ctx, http.MethodPut, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UnblockUser calls DELETE /api/users/{userId}/block: unblock a user
+func (c *Client) UnblockUser(ctx context.Context, userID uint64) (*User, error) {
+	path := fmt.Sprintf("/api/users/%s/block", url.PathEscape(fmt.Sprint(userID)))
+	var result User
+	if err := c.do(ctx, http.MethodDelete, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListWebhooks calls GET /api/webhooks: list the webhooks of the authenticated user
+//
+// Webhooks are managed with a password session, not with an API key.
+func (c *Client) ListWebhooks(ctx context.Context) (*WebhookList, error) {
+	path := "/api/webhooks"
+	var result WebhookList
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateWebhook calls POST /api/webhooks: register a webhook of the authenticated user
+//
+// The webhook daemon POSTs the events of the user to the URL, signed with
+// the secret returned by this call only. A user may register at most
+// WEBHOOKS_MAX_PER_USER webhooks; receiving the events of every user
+// requires the webhooks:manage permission.
+func (c *Client) CreateWebhook(ctx context.Context, body CreateWebhookRequest) (*CreatedWebhook, error) {
+	path := "/api/webhooks"
+	var result CreatedWebhook
+	if err := c.do(ctx, http.MethodPost, path, nil, body, 201, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteWebhook calls DELETE /api/webhooks/{webhookId}: delete a webhook and its delivery log
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID uint64) (*Webhook, error) {
+	path := fmt.Sprintf("/api/webhooks/%s", url.PathEscape(fmt.Sprint(webhookID)))
+	var result Webhook
+	if err := c.do(ctx, http.MethodDelete, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListWebhookDeliveriesParams holds the query parameters of ListWebhookDeliveries
+type ListWebhookDeliveriesParams struct {
+	Page     int
+	PageSize int
+}
+
+// ListWebhookDeliveries calls GET /api/webhooks/{webhookId}/deliveries: list the deliveries of a webhook, newest first
+func (c *Client) ListWebhookDeliveries(ctx context.Context, webhookID uint64, params ListWebhookDeliveriesParams) (*WebhookDeliveryList, error) {
+	path := fmt.Sprintf("/api/webhooks/%s/deliveries", url.PathEscape(fmt.Sprint(webhookID)))
+	query := url.Values{}
+	if params.Page != 0 {
+		query.Set("page", fmt.Sprint(params.Page))
+	}
+	if params.PageSize != 0 {
+		query.Set("page_size", fmt.Sprint(params.PageSize))
+	}
+	var result WebhookDeliveryList
+	if err := c.do(ctx, http.MethodGet, path, query, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ReplayWebhookDelivery calls POST /api/webhooks/{webhookId}/deliveries/{deliveryId}/replay: send the event of a delivery again
+//
+// The event is sent as a new delivery, with the same payload and event ID
+// so the receiver can recognize it.
+func (c *Client) ReplayWebhookDelivery(ctx context.Context, webhookID uint64, deliveryID uint64) (*WebhookDelivery, error) {
+	path := fmt.Sprintf("/api/webhooks/%s/deliveries/%s/replay", url.PathEscape(fmt.Sprint(webhookID)), url.PathEscape(fmt.Sprint(deliveryID)))
+	var result WebhookDelivery
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, 202, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// EnableWebhook calls POST /api/webhooks/{webhookId}/enable: enable a webhook disabled after repeated failures
+//
+// Its pending deliveries are sent again.
+func (c *Client) EnableWebhook(ctx context.Context, webhookID uint64) (*Webhook, error) {
+	path := fmt.Sprintf("/api/webhooks/%s/enable", url.PathEscape(fmt.Sprint(webhookID)))
+	var result Webhook
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, 200, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
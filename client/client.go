@@ -0,0 +1,101 @@
+// Package client is the Go SDK of the gateway: a typed client of its REST
+// API, the login handshake and a reconnecting realtime connection. The
+// operations and types in client.gen.go are generated from the OpenAPI
+// document; run go generate after changing docs/openapi.yaml.
+package client
+
+//go:generate go run ../docs/clientgen -o client.gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Client calls the REST API of a gateway
+type Client struct {
+	// BaseURL is the address of the gateway, e.g. http://localhost:8080
+	BaseURL string
+	// Token is the JWT sent as a bearer token; empty for the public routes
+	Token string
+	// HTTPClient sends the requests
+	HTTPClient *http.Client
+}
+
+// New returns a client of the gateway at baseURL authenticating with token
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:    baseURL,
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Error is the error envelope answered by the API, the Error schema of the
+// OpenAPI document
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// do sends a request and decodes the response into out when the status is
+// the expected one; other statuses are returned as an *Error
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}, status int, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != status {
+		apiErr := &Error{Status: resp.StatusCode}
+		if err := json.Unmarshal(content, apiErr); err != nil || apiErr.Code == "" {
+			apiErr.Code = "unexpected_status"
+			apiErr.Message = string(content)
+		}
+		return apiErr
+	}
+	if err := json.Unmarshal(content, out); err != nil {
+		return fmt.Errorf("invalid response from %s %s: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"instant-messaging-app/client"
+)
+
+// gateway answers the requests of the generated client with handler
+func gateway(t *testing.T, handler http.HandlerFunc) *client.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return client.New(server.URL, "secret-token")
+}
+
+func TestGeneratedClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("request", func(t *testing.T) {
+		api := gateway(t, func(w http.ResponseWriter, r *http.Request) {
+			var body client.MessageContent
+			if r.Method != http.MethodPost || r.URL.Path != "/api/conversations/42/messages" ||
+				r.Header.Get("Authorization") != "Bearer secret-token" || r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("unexpected request %s %s %v", r.Method, r.URL, r.Header)
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Content != "hello" {
+				t.Errorf("unexpected body %+v (%v)", body, err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"message":{"id":7,"receiver_id":42,"content":"hello"},"seq":3}`))
+		})
+		sent, err := api.PostMessage(ctx, 42, client.MessageContent{Content: "hello"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sent.Message.ID != 7 || sent.Message.ReceiverID != 42 || sent.Seq != 3 {
+			t.Fatalf("unexpected sent message %+v", sent)
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		api := gateway(t, func(w http.ResponseWriter, r *http.Request) {
+			if query := r.URL.Query(); query.Get("query") != "bob" || query.Get("page") != "2" || query.Has("page_size") {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"users":[{"id":2,"username":"bob"}],"query":"bob"}`))
+		})
+		result, err := api.SearchUsers(ctx, client.SearchUsersParams{Query: "bob", Page: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Users) != 1 || result.Users[0].Username != "bob" {
+			t.Fatalf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		// The error envelope is decoded, anything else is kept as is
+		api := gateway(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/users/me" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"code":"not_found","error":"User not found"}`))
+				return
+			}
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("upstream down"))
+		})
+		var apiErr *client.Error
+		_, err := api.GetSelf(ctx)
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound || apiErr.Code != "not_found" || apiErr.Message != "User not found" {
+			t.Fatalf("expected a not_found error, got %v", err)
+		}
+		_, err = api.ListBots(ctx)
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadGateway || apiErr.Code != "unexpected_status" || apiErr.Message != "upstream down" {
+			t.Fatalf("expected an unexpected_status error, got %v", err)
+		}
+	})
+}
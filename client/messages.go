@@ -0,0 +1,18 @@
+package client
+
+import "context"
+
+// Send sends a message to a user. Both users are also notified on their
+// realtime connections.
+func (c *Client) Send(ctx context.Context, userID uint64, content string) (*SentMessage, error) {
+	return c.PostMessage(ctx, userID, MessageContent{Content: content})
+}
+
+// Conversation returns the messages exchanged with a user, oldest first
+func (c *Client) Conversation(ctx context.Context, userID uint64) ([]Message, error) {
+	conversation, err := c.GetConversation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return conversation.Messages, nil
+}
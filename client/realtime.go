@@ -0,0 +1,470 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+// ErrNotConnected is returned by the requests of a realtime connection
+// while it is reconnecting or once it is closed
+var ErrNotConnected = errors.New("realtime connection is not connected")
+
+// ErrLoggedOut ends a realtime connection whose session was revoked
+var ErrLoggedOut = errors.New("session logged out")
+
+// MessageEvent is a message sent or received by the user. Seq numbers it in
+// the event stream of the user. Ephemeral messages answer a command of the
+// user and are not stored, so they have neither an ID nor a Seq; Muted is set
+// when the user muted the sender.
+type MessageEvent struct {
+	Seq       uint64
+	Message   Message
+	Ephemeral bool
+	Muted     bool
+}
+
+// CommandInvocation is a command of a bot run by a user, which the bot
+// answers with RespondToCommand before ExpiresAt
+type CommandInvocation struct {
+	ID         uint64    `json:"id"`
+	BotID      uint64    `json:"bot_id"`
+	Command    string    `json:"command"`
+	Args       string    `json:"args"`
+	UserID     uint64    `json:"user_id"`
+	ReceiverID uint64    `json:"receiver_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Resumed ends the replay of the events missed before a reconnection. When
+// Complete is false more events were missed than the gateway replays, and the
+// conversations should be reloaded.
+type Resumed struct {
+	ResumeFrom uint64 `json:"resume_from"`
+	LastSeq    uint64 `json:"last_seq"`
+	Replayed   int    `json:"replayed"`
+	Complete   bool   `json:"complete"`
+}
+
+// RealtimeError is an error frame, answering a request the gateway or a
+// service could not handle
+type RealtimeError struct {
+	Code    string `json:"code"`
+	Message string `json:"error"`
+}
+
+func (e *RealtimeError) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Handlers are the callbacks of a realtime connection. They are called one
+// at a time from the goroutine reading the connection; nil ones are skipped.
+type Handlers struct {
+	// OnConnect is called after each successful authentication
+	OnConnect func()
+	// OnDisconnect is called when the connection is lost, before reconnecting
+	OnDisconnect func(err error)
+	OnMessage    func(MessageEvent)
+	OnResumed    func(Resumed)
+	// OnForceLogout is called when the session is revoked; the connection
+	// is closed afterwards and not reestablished
+	OnForceLogout  func()
+	OnError        func(*RealtimeError)
+	OnUsers        func(UserList)
+	OnSearchUsers  func(UserSearchResult)
+	OnSelf         func(Self)
+	OnConversation func(Conversation)
+	// OnCommandInvocation is called on the connections of a bot when one of
+	// its commands is run
+	OnCommandInvocation func(CommandInvocation)
+}
+
+// RealtimeOptions configure a realtime connection
+type RealtimeOptions struct {
+	// ResumeFrom asks for the events after this sequence number to be
+	// replayed on the first connection. Later connections resume from the
+	// last event received; without ResumeFrom, events missed before the
+	// first one is received cannot be replayed.
+	ResumeFrom *uint64
+	// MinBackoff and MaxBackoff bound the delay between reconnection
+	// attempts, doubled after each failure. They default to 500ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// ReadTimeout drops a connection on which nothing, not even a ping, was
+	// received for this long. It defaults to 2 minutes.
+	ReadTimeout time.Duration
+}
+
+// Realtime is an authenticated WebSocket to the gateway that reconnects when
+// it is lost, resuming from the last event received
+type Realtime struct {
+	client   *Client
+	handlers Handlers
+	options  RealtimeOptions
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	closed  bool
+	resume  *uint64
+	lastSeq uint64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Connect opens a realtime connection with the token of the client. The
+// first connection is established before returning; the connection then
+// lives until Close is called, ctx is canceled or the session is revoked.
+func (c *Client) Connect(ctx context.Context, handlers Handlers, options RealtimeOptions) (*Realtime, error) {
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = 500 * time.Millisecond
+	}
+	if options.MaxBackoff < options.MinBackoff {
+		options.MaxBackoff = 30 * time.Second
+	}
+	if options.ReadTimeout <= 0 {
+		options.ReadTimeout = 2 * time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &Realtime{
+		client:   c,
+		handlers: handlers,
+		options:  options,
+		resume:   options.ResumeFrom,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	if r.resume != nil {
+		r.lastSeq = *r.resume
+	}
+
+	conn, err := r.dial(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	go r.run(ctx, conn)
+	return r, nil
+}
+
+// Done is closed once the connection ended for good
+func (r *Realtime) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err returns why the connection ended, once Done is closed: ErrLoggedOut
+// after a forced logout, an *AuthError when the token was rejected on
+// reconnection, or the error of the context. Close leaves it nil.
+func (r *Realtime) Err() error {
+	<-r.done
+	return r.err
+}
+
+// LastSeq returns the sequence number of the last event received
+func (r *Realtime) LastSeq() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastSeq
+}
+
+// Close closes the connection and waits for the reading goroutine to return
+func (r *Realtime) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	if r.conn != nil {
+		r.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second))
+		r.conn.Close()
+	}
+	r.mu.Unlock()
+	r.cancel()
+	<-r.done
+	return nil
+}
+
+// SendMessage sends a message to a user; it is delivered back to OnMessage
+func (r *Realtime) SendMessage(receiverID uint64, content string) error {
+	return r.send(struct {
+		Type       string `json:"type"`
+		ReceiverID uint64 `json:"receiver_id"`
+		Content    string `json:"content"`
+	}{"sendMessage", receiverID, content})
+}
+
+// RequestUsers asks for the list of users, delivered to OnUsers
+func (r *Realtime) RequestUsers() error {
+	return r.send(struct {
+		Type string `json:"type"`
+	}{"getUsers"})
+}
+
+// SearchUsers asks for a page of the users matching query, delivered to
+// OnSearchUsers. Zero page and pageSize select the defaults of the service.
+func (r *Realtime) SearchUsers(query string, page, pageSize int) error {
+	return r.send(struct {
+		Type     string `json:"type"`
+		Query    string `json:"query"`
+		Page     int    `json:"page"`
+		PageSize int    `json:"page_size"`
+	}{"searchUsers", query, page, pageSize})
+}
+
+// RequestSelf asks for the profile of the user, delivered to OnSelf
+func (r *Realtime) RequestSelf() error {
+	return r.send(struct {
+		Type string `json:"type"`
+	}{"getSelf"})
+}
+
+// RequestConversation asks for the messages exchanged with a user,
+// delivered to OnConversation
+func (r *Realtime) RequestConversation(userID uint64) error {
+	return r.send(struct {
+		Type       string `json:"type"`
+		ReceiverID uint64 `json:"receiver_id"`
+	}{"getMessages", userID})
+}
+
+// send writes a request on the current connection
+func (r *Realtime) send(request interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		return ErrNotConnected
+	}
+	r.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return r.conn.WriteJSON(request)
+}
+
+// run reads conn, then reconnects with a growing delay until the
+// connection ends for good
+func (r *Realtime) run(ctx context.Context, conn *websocket.Conn) {
+	defer close(r.done)
+	defer r.cancel()
+
+	for {
+		err := r.read(conn)
+		r.mu.Lock()
+		r.conn = nil
+		closed := r.closed
+		r.mu.Unlock()
+		conn.Close()
+
+		switch {
+		case closed:
+			return
+		case errors.Is(err, ErrLoggedOut):
+			r.err = err
+			return
+		}
+		if r.handlers.OnDisconnect != nil {
+			r.handlers.OnDisconnect(err)
+		}
+
+		conn, err = r.reconnect(ctx)
+		if err != nil {
+			r.mu.Lock()
+			if !r.closed {
+				r.err = err
+			}
+			r.mu.Unlock()
+			return
+		}
+	}
+}
+
+// reconnect dials until a connection is authenticated. Rejected tokens and
+// the end of ctx stop the attempts.
+func (r *Realtime) reconnect(ctx context.Context) (*websocket.Conn, error) {
+	backoff := r.options.MinBackoff
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		conn, err := r.dial(ctx)
+		var authErr *AuthError
+		if err == nil || errors.As(err, &authErr) {
+			return conn, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+		if backoff > r.options.MaxBackoff {
+			backoff = r.options.MaxBackoff
+		}
+	}
+}
+
+// dial opens /ws/auth, authenticates with the token of the client and
+// resumes from the last event received, if any
+func (r *Realtime) dial(ctx context.Context) (*websocket.Conn, error) {
+	conn, err := r.client.dial(ctx, "/ws/auth")
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	request := struct {
+		Type       string  `json:"type"`
+		Token      string  `json:"token"`
+		ResumeFrom *uint64 `json:"resume_from,omitempty"`
+	}{Token: r.client.Token, ResumeFrom: r.resume}
+	r.mu.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if err := conn.WriteJSON(request); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var ack struct {
+		Type    string `json:"type"`
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+	conn.SetReadDeadline(time.Now().Add(r.client.timeout()))
+	if err := conn.ReadJSON(&ack); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("waiting for the authentication: %w", err)
+	}
+	if ack.Type != "auth" || !ack.Success {
+		conn.Close()
+		return nil, &AuthError{Message: ack.Message}
+	}
+
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(r.options.ReadTimeout))
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(10*time.Second))
+	})
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		conn.Close()
+		return nil, ErrNotConnected
+	}
+	r.conn = conn
+	r.mu.Unlock()
+
+	if r.handlers.OnConnect != nil {
+		r.handlers.OnConnect()
+	}
+	return conn, nil
+}
+
+// read dispatches the frames of conn to the handlers until it fails. A
+// forced logout is reported as ErrLoggedOut.
+func (r *Realtime) read(conn *websocket.Conn) error {
+	for {
+		conn.SetReadDeadline(time.Now().Add(r.options.ReadTimeout))
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		var frame struct {
+			Type    string          `json:"type"`
+			Seq     uint64          `json:"seq"`
+			Data    json.RawMessage `json:"data"`
+			Code    string          `json:"code"`
+			Message string          `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &frame); err != nil {
+			return fmt.Errorf("invalid frame %q: %w", raw, err)
+		}
+		if err := r.dispatch(frame.Type, frame.Seq, frame.Data, &RealtimeError{Code: frame.Code, Message: frame.Message}); err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch calls the handler of a frame
+func (r *Realtime) dispatch(frameType string, seq uint64, data json.RawMessage, frameErr *RealtimeError) error {
+	h := r.handlers
+	switch frameType {
+	case "send_message_response":
+		var event struct {
+			Message   Message `json:"message"`
+			Ephemeral bool    `json:"ephemeral"`
+			Muted     bool    `json:"muted"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		if seq != 0 {
+			r.advance(seq)
+		}
+		if h.OnMessage != nil {
+			h.OnMessage(MessageEvent{Seq: seq, Message: event.Message, Ephemeral: event.Ephemeral, Muted: event.Muted})
+		}
+	case "resumed":
+		var resumed Resumed
+		if err := json.Unmarshal(data, &resumed); err != nil {
+			return err
+		}
+		r.advance(resumed.LastSeq)
+		if h.OnResumed != nil {
+			h.OnResumed(resumed)
+		}
+	case "force_logout":
+		if h.OnForceLogout != nil {
+			h.OnForceLogout()
+		}
+		return ErrLoggedOut
+	case "error":
+		if h.OnError != nil {
+			h.OnError(frameErr)
+		}
+	case "get_users_response":
+		return decodeTo(data, h.OnUsers)
+	case "search_users_response":
+		return decodeTo(data, h.OnSearchUsers)
+	case "get_self_response":
+		return decodeTo(data, h.OnSelf)
+	case "get_messages_response":
+		return decodeTo(data, h.OnConversation)
+	case "command_invocation":
+		return decodeTo(data, h.OnCommandInvocation)
+	}
+	return nil
+}
+
+// advance records seq as the last event received and resumes from it on
+// the next connection
+func (r *Realtime) advance(seq uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if seq > r.lastSeq || r.resume == nil {
+		r.lastSeq = seq
+	}
+	last := r.lastSeq
+	r.resume = &last
+}
+
+// decodeTo unmarshals data and passes it to handler, when it is set
+func decodeTo[T any](data json.RawMessage, handler func(T)) error {
+	if handler == nil {
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	handler(v)
+	return nil
+}
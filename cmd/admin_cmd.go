@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	apiservices "instant-messaging-app/api/services"
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	userservices "instant-messaging-app/user/services"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+// jsonFlag switches every admin command to machine readable output
+var jsonFlag = &cli.BoolFlag{
+	Name:  "json",
+	Usage: "Print the result as JSON",
+}
+
+// passwordStdinFlag reads the password from stdin instead of prompting for it
+var passwordStdinFlag = &cli.BoolFlag{
+	Name:  "password-stdin",
+	Usage: "Read the password from the first line of stdin instead of prompting for it",
+}
+
+// AdminCommand builds the one-shot operator commands
+func AdminCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "admin",
+		Usage: "Run one-shot administrative commands",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "create-user",
+				Usage: "Create a user account",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "username", Required: true},
+					passwordStdinFlag,
+					&cli.StringFlag{Name: "display-name"},
+					&cli.StringFlag{Name: "role", Value: models.RoleUser, Usage: "admin, moderator or user"},
+					jsonFlag,
+				},
+				Action: adminCreateUser,
+			},
+			{
+				Name:  "reset-password",
+				Usage: "Reset the password of a user and revoke their sessions",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "username", Required: true},
+					passwordStdinFlag,
+					jsonFlag,
+				},
+				Action: adminResetPassword,
+			},
+			{
+				Name:  "deactivate",
+				Usage: "Deactivate a user and disconnect their WebSockets",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "username", Required: true},
+					jsonFlag,
+				},
+				Action: adminDeactivate,
+			},
+			{
+				Name:  "list-queues",
+				Usage: "List per-connection queues without consumers",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "all", Usage: "Include per-connection queues that still have consumers"},
+					jsonFlag,
+				},
+				Action: adminListQueues,
+			},
+			{
+				Name:      "purge-queue",
+				Usage:     "Purge (or delete) queues",
+				ArgsUsage: "[queue...]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "orphaned", Usage: "Target every orphaned per-connection queue"},
+					&cli.BoolFlag{Name: "delete", Usage: "Delete the queues instead of purging them"},
+					jsonFlag,
+				},
+				Action: adminPurgeQueue,
+			},
+			{
+				Name:   "stats",
+				Usage:  "Report user, message and connection counts",
+				Flags:  []cli.Flag{jsonFlag},
+				Action: adminStats,
+			},
+		},
+	}
+}
+
+// cliAudit identifies actions performed from the command line in the audit log
+var cliAudit = apiservices.AuditContext{
+	ActorID:   0,
+	ActorRole: models.RoleAdmin,
+	IP:        "cli",
+}
+
+func adminCreateUser(c *cli.Context) error {
+	role := c.String("role")
+	if !models.IsValidRole(role) {
+		return fmt.Errorf("unknown role %q: must be admin, moderator or user", role)
+	}
+	password, err := readPassword(c)
+	if err != nil {
+		return err
+	}
+
+	config.InitDatabase()
+
+	user, err := userservices.CreateUser(c.Context, repositories.NewUserRepository(config.DB), c.String("username"), password, c.String("display-name"), role)
+	if err != nil {
+		return err
+	}
+	if err := apiservices.RecordAudit(c.Context, repositories.NewAuditRepository(config.DB), cliAudit, "user.create", "user", user.ID, map[string]interface{}{"role": role}); err != nil {
+		slog.Error("Failed to write audit log", "error", err)
+	}
+
+	return printResult(c, map[string]interface{}{
+		"id":       user.ID,
+		"username": user.Username,
+		"role":     user.Role,
+	}, func() {
+		fmt.Printf("Created user %s (id %d, role %s)\n", user.Username, user.ID, user.Role)
+	})
+}
+
+func adminResetPassword(c *cli.Context) error {
+	password, err := readPassword(c)
+	if err != nil {
+		return err
+	}
+
+	config.InitDatabase()
+
+	user, err := userservices.ResetPassword(c.Context, repositories.NewUserRepository(config.DB), c.String("username"), password)
+	if err != nil {
+		return err
+	}
+	if err := apiservices.RecordAudit(c.Context, repositories.NewAuditRepository(config.DB), cliAudit, "user.reset_password", "user", user.ID, nil); err != nil {
+		slog.Error("Failed to write audit log", "error", err)
+	}
+
+	return printResult(c, map[string]interface{}{
+		"id":       user.ID,
+		"username": user.Username,
+	}, func() {
+		fmt.Printf("Password reset for %s; existing sessions revoked\n", user.Username)
+	})
+}
+
+func adminDeactivate(c *cli.Context) error {
+	config.InitDatabase()
+
+	// RabbitMQ is needed to disconnect the live WebSockets of the user
+	config.SetupRabbitMQ()
+	defer config.CleanupRabbitMQ()
+
+	repos := repositories.NewGormRepositories(config.DB)
+	user, err := userservices.GetUserByUsername(c.Context, repos.Users, c.String("username"))
+	if err != nil {
+		return fmt.Errorf("user %s not found", c.String("username"))
+	}
+
+	user, err = apiservices.DeactivateUser(c.Context, repos, cliAudit, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return printResult(c, map[string]interface{}{
+		"id":             user.ID,
+		"username":       user.Username,
+		"deactivated_at": user.DeactivatedAt,
+	}, func() {
+		fmt.Printf("Deactivated %s (id %d)\n", user.Username, user.ID)
+	})
+}
+
+func adminListQueues(c *cli.Context) error {
+	queues, err := config.ListRabbitMQQueues()
+	if err != nil {
+		return err
+	}
+
+	selected := []config.QueueInfo{}
+	for _, queue := range queues {
+		if queue.IsOrphaned() || (c.Bool("all") && queue.IsConnectionQueue()) {
+			selected = append(selected, queue)
+		}
+	}
+
+	return printResult(c, selected, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "QUEUE\tMESSAGES\tCONSUMERS\tIDLE SINCE")
+		for _, queue := range selected {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", queue.Name, queue.Messages, queue.Consumers, queue.IdleSince)
+		}
+		w.Flush()
+	})
+}
+
+func adminPurgeQueue(c *cli.Context) error {
+	names := c.Args().Slice()
+	if c.Bool("orphaned") {
+		queues, err := config.ListRabbitMQQueues()
+		if err != nil {
+			return err
+		}
+		for _, queue := range queues {
+			if queue.IsOrphaned() {
+				names = append(names, queue.Name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("no queue given: pass queue names or --orphaned")
+	}
+
+	config.SetupRabbitMQ()
+	defer config.CleanupRabbitMQ()
+
+	type result struct {
+		Queue   string `json:"queue"`
+		Deleted bool   `json:"deleted"`
+		Purged  int    `json:"purged"`
+		Error   string `json:"error,omitempty"`
+	}
+
+	results := make([]result, 0, len(names))
+	for _, name := range names {
+		res := result{Queue: name}
+		if c.Bool("delete") {
+			if err := config.CleanupQueue(name); err != nil {
+				res.Error = err.Error()
+			} else {
+				res.Deleted = true
+			}
+		} else {
+			purged, err := config.PurgeQueue(name)
+			if err != nil {
+				res.Error = err.Error()
+			}
+			res.Purged = purged
+		}
+		results = append(results, res)
+	}
+
+	return printResult(c, results, func() {
+		for _, res := range results {
+			switch {
+			case res.Error != "":
+				fmt.Printf("%s: %s\n", res.Queue, res.Error)
+			case res.Deleted:
+				fmt.Printf("%s: deleted\n", res.Queue)
+			default:
+				fmt.Printf("%s: purged %d messages\n", res.Queue, res.Purged)
+			}
+		}
+	})
+}
+
+func adminStats(c *cli.Context) error {
+	config.InitDatabase()
+
+	stats, err := apiservices.GetSystemStats(c.Context, repositories.NewGormRepositories(config.DB))
+	if err != nil {
+		return err
+	}
+
+	result := map[string]interface{}{
+		"users":             stats.Users,
+		"active_users":      stats.ActiveUsers,
+		"deactivated_users": stats.DeactivatedUsers,
+		"messages":          stats.Messages,
+		"messages_last_24h": stats.MessagesLast24h,
+	}
+
+	// Connection counts come from the broker; report them when it is reachable
+	queues, queueErr := config.ListRabbitMQQueues()
+	amqpConnections, connErr := config.CountRabbitMQConnections()
+	if queueErr == nil && connErr == nil {
+		websockets, orphaned := 0, 0
+		for _, queue := range queues {
+			if queue.IsOrphaned() {
+				orphaned++
+			} else if queue.IsConnectionQueue() {
+				websockets++
+			}
+		}
+		result["websocket_connections"] = websockets
+		result["orphaned_queues"] = orphaned
+		result["amqp_connections"] = amqpConnections
+	} else {
+		slog.Warn("Connection counts unavailable", "error", errors.Join(queueErr, connErr))
+	}
+
+	return printResult(c, result, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, key := range []string{"users", "active_users", "deactivated_users", "messages", "messages_last_24h", "websocket_connections", "orphaned_queues", "amqp_connections"} {
+			if value, ok := result[key]; ok {
+				fmt.Fprintf(w, "%s\t%v\n", key, value)
+			}
+		}
+		w.Flush()
+	})
+}
+
+// readPassword returns the password given with --password-stdin, or prompts
+// for it twice on the terminal, so that it never shows in the shell history
+// or the process list
+func readPassword(c *cli.Context) (string, error) {
+	var password string
+	if c.Bool("password-stdin") {
+		line, err := bufio.NewReader(c.App.Reader).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("unable to read the password from stdin: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	} else {
+		stdin, ok := c.App.Reader.(*os.File)
+		if !ok || !term.IsTerminal(int(stdin.Fd())) {
+			return "", errors.New("stdin is not a terminal: pipe the password with --password-stdin")
+		}
+		prompt := func(label string) (string, error) {
+			fmt.Fprint(c.App.ErrWriter, label)
+			input, err := term.ReadPassword(int(stdin.Fd()))
+			fmt.Fprintln(c.App.ErrWriter)
+			return string(input), err
+		}
+		var err error
+		if password, err = prompt("Password: "); err != nil {
+			return "", err
+		}
+		confirmation, err := prompt("Confirm password: ")
+		if err != nil {
+			return "", err
+		}
+		if confirmation != password {
+			return "", errors.New("the passwords do not match")
+		}
+	}
+
+	if len(password) < 6 {
+		return "", errors.New("password must be at least 6 characters long")
+	}
+	return password, nil
+}
+
+// printResult writes value as JSON when --json is set and calls text otherwise
+func printResult(c *cli.Context, value interface{}, text func()) error {
+	if !c.Bool("json") {
+		text()
+		return nil
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
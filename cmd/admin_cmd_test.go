@@ -0,0 +1,73 @@
+package cmd_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"instant-messaging-app/cmd"
+	"instant-messaging-app/config"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/user/services"
+
+	"github.com/urfave/cli/v2"
+)
+
+// runAdmin runs the admin command with args, stdin reading from input
+func runAdmin(t *testing.T, input io.Reader, args ...string) error {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	app := &cli.App{Commands: []*cli.Command{cmd.AdminCommand()}, Reader: input}
+	runErr := app.RunContext(context.Background(), append([]string{"app", "admin"}, args...))
+	writer.Close()
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Fatal(err)
+	}
+	return runErr
+}
+
+// expectLogin fails unless username can log in with password
+func expectLogin(t *testing.T, username, password string, success bool) {
+	t.Helper()
+	_, err := services.ProcessUserLogin(context.Background(), repositories.NewUserRepository(config.DB), username, password)
+	if (err == nil) != success {
+		t.Fatalf("expected the login of %s with %q to succeed: %t, got %v", username, password, success, err)
+	}
+}
+
+func TestAdminPasswords(t *testing.T) {
+	username := "operator"
+
+	// The password is read from stdin, never from a flag
+	if err := runAdmin(t, strings.NewReader("first-secret\n"), "create-user", "--username", username, "--password-stdin"); err != nil {
+		t.Fatal(err)
+	}
+	expectLogin(t, username, "first-secret", true)
+	if err := runAdmin(t, strings.NewReader("second-secret"), "reset-password", "--username", username, "--password-stdin"); err != nil {
+		t.Fatal(err)
+	}
+	expectLogin(t, username, "first-secret", false)
+	expectLogin(t, username, "second-secret", true)
+
+	if err := runAdmin(t, strings.NewReader("short\n"), "reset-password", "--username", username, "--password-stdin"); err == nil {
+		t.Fatal("a short password was accepted")
+	}
+	if err := runAdmin(t, strings.NewReader(""), "reset-password", "--username", username, "--password", "third-secret"); err == nil {
+		t.Fatal("the password was accepted as a flag")
+	}
+
+	// Without --password-stdin, a prompt needs a terminal
+	if err := runAdmin(t, strings.NewReader("third-secret\n"), "reset-password", "--username", username); err == nil || !strings.Contains(err.Error(), "--password-stdin") {
+		t.Fatalf("expected the prompt to need a terminal, got %v", err)
+	}
+	expectLogin(t, username, "second-secret", true)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/health"
+	"instant-messaging-app/metrics"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// startAdminListener serves the health probes and metrics of a headless daemon on
+// http.admin_port until ctx is canceled. An empty port disables it.
+func startAdminListener(ctx context.Context, checker *health.Checker) {
+	port := config.Cfg.HTTP.AdminPort
+	if port == "" {
+		return
+	}
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	checker.Register(app)
+	metrics.Register(app)
+
+	go func() {
+		slog.Info("Admin listener running", "port", port)
+		if err := app.Listen(":" + port); err != nil {
+			slog.Error("Admin listener error", "port", port, "error", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		if err := app.Shutdown(); err != nil {
+			slog.Error("Error during admin listener shutdown", "error", err)
+		}
+	}()
+}
@@ -2,25 +2,28 @@ package cmd
 
 import (
 	"context"
-	"log"
-	"os"
-	"os/signal"
-	"syscall"
+	"fmt"
+	"log/slog"
+	"strings"
 
+	"instant-messaging-app/api/responses"
 	"instant-messaging-app/api/routes"
+	"instant-messaging-app/api/rpc"
 	"instant-messaging-app/config"
+	"instant-messaging-app/health"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/metrics"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/tracing"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/joho/godotenv"
 )
 
 func StartWebServer() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found. Using system environment variables.")
-	}
+	// Export traces under the name of the gateway
+	config.SetupTracing("api-gateway")
+	defer config.ShutdownTracing()
 
 	// Connect to the database
 	config.InitDatabase()
@@ -29,58 +32,76 @@ func StartWebServer() {
 	config.SetupRabbitMQ()
 	defer config.CleanupRabbitMQ()
 
+	// Create a context canceled on SIGINT or SIGTERM for graceful shutdown
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	RunWebServer(ctx, repositories.NewGormRepositories(config.DB))
+}
+
+// declareWebServerTopology declares the exchanges the gateway consumes from
+func declareWebServerTopology() {
 	// Declare the notification exchange
-	config.InitDirectRabbitMQExchange("notification_exchange")
+	config.InitDirectRabbitMQExchange(config.Cfg.Exchanges.Notification)
 
 	// Declare the notification broadcast exchange
-	config.InitFanoutRabbitMQExchange("notification_broadcast_exchange")
+	config.InitFanoutRabbitMQExchange(config.Cfg.Exchanges.NotificationBroadcast)
+}
 
-	// Create a context for managing graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// RunWebServer serves the API gateway until ctx is canceled; the database and
+// broker must already be set up
+func RunWebServer(ctx context.Context, repos repositories.Repositories) {
+	declareWebServerTopology()
 
 	// Initialize Fiber app
-	app := fiber.New()
-	app.Use(logger.New())
+	app := fiber.New(fiber.Config{ErrorHandler: responses.ErrorHandler})
+	app.Use(logging.Middleware())
+	app.Use(tracing.Middleware())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "http://localhost:3000",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowOrigins: strings.Join(config.Cfg.HTTP.CORSOrigins, ","),
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, Last-Event-ID",
 	}))
 
-	// Set up routes
-	routes.SetupRoutes(app, ctx)
-
-	// Channel to listen for OS signals
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	// Health probes, reporting on the consumers of this process too
+	health.NewServiceChecker(config.DB, config.Broker).Register(app)
+	metrics.Register(app)
 
-	// Start the Fiber app in a goroutine
-	fiberErrChan := make(chan error, 1)
+	// Set up routes
+	routes.SetupRoutes(app, ctx, repos)
+
+	// Start the Fiber app in a goroutine, and the gRPC API next to it
+	fiberErrChan := make(chan error, 2)
+	if port := config.Cfg.HTTP.GRPCPort; port != "" {
+		go func() {
+			if err := rpc.Serve(ctx, repos, port); err != nil {
+				fiberErrChan <- fmt.Errorf("gRPC server: %w", err)
+			}
+		}()
+	}
 	go func() {
-		port := os.Getenv("APP_PORT")
-		if port == "" {
-			port = "5000"
+		port := config.Cfg.HTTP.Port
+		if config.Cfg.HTTP.TLSEnabled() {
+			slog.Info("API Gateway running", "port", port, "tls", true)
+			fiberErrChan <- app.ListenTLS(":"+port, config.Cfg.HTTP.TLSCertFile, config.Cfg.HTTP.TLSKeyFile)
+			return
 		}
-		log.Printf("API Gateway running on port %s", port)
+		slog.Info("API Gateway running", "port", port, "tls", false)
 		fiberErrChan <- app.Listen(":" + port)
 	}()
 
 	select {
-	case sig := <-sigChan:
-		log.Printf("Received signal: %v. Initiating shutdown...", sig)
+	case <-ctx.Done():
+		slog.Info("Initiating API Gateway shutdown")
 	case err := <-fiberErrChan:
 		if err != nil {
-			log.Printf("Fiber app error: %v", err)
+			slog.Error("Fiber app error", "error", err)
 		}
 	}
 
 	// Gracefully shutdown Fiber
 	if err := app.Shutdown(); err != nil {
-		log.Printf("Error during Fiber shutdown: %v", err)
+		slog.Error("Error during Fiber shutdown", "error", err)
 	}
 
-	// Cancel the context to clean up other resources
-	cancel()
-
-	log.Println("API Gateway stopped gracefully.")
-}
\ No newline at end of file
+	slog.Info("API Gateway stopped gracefully")
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+// chatFlags select the gateway and the account of the chat commands
+func chatFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{Name: "server", EnvVars: []string{"CHAT_SERVER"}, Usage: "Address of the gateway (default: http://localhost:<http.port>)"},
+		&cli.StringFlag{Name: "username", EnvVars: []string{"CHAT_USERNAME"}, Usage: "Username to log in with"},
+		&cli.StringFlag{Name: "password", EnvVars: []string{"CHAT_PASSWORD"}, Usage: "Password to log in with"},
+		&cli.StringFlag{Name: "token", EnvVars: []string{"CHAT_TOKEN"}, Usage: "JWT to use instead of logging in"},
+	}
+}
+
+// ChatCommand builds the terminal chat client
+func ChatCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "chat",
+		Usage:  "Chat from the terminal through a running gateway",
+		Flags:  chatFlags(),
+		Action: chatInteractive,
+		Subcommands: []*cli.Command{
+			{
+				Name:      "send",
+				Usage:     "Send a message and exit",
+				ArgsUsage: "<message...>",
+				Flags: append(chatFlags(),
+					&cli.StringFlag{Name: "to", Required: true, Usage: "Username or ID of the receiver"},
+					jsonFlag,
+				),
+				Action: chatSend,
+			},
+			{
+				Name:  "tail",
+				Usage: "Print the messages of the user as they arrive, until interrupted",
+				Flags: append(chatFlags(),
+					&cli.StringFlag{Name: "with", Usage: "Only print the conversation with this username or ID, after its history"},
+					jsonFlag,
+				),
+				Action: chatTail,
+			},
+		},
+	}
+}
+
+// chatLogin returns a client of the gateway authenticated with --token, or
+// by logging in with --username and --password
+func chatLogin(ctx context.Context, c *cli.Context) (*client.Client, error) {
+	server := c.String("server")
+	if server == "" {
+		server = "http://localhost:" + config.Cfg.HTTP.Port
+	}
+	api := client.New(strings.TrimRight(server, "/"), c.String("token"))
+	if api.Token != "" {
+		return api, nil
+	}
+
+	if c.String("username") == "" || c.String("password") == "" {
+		return nil, errors.New("--token, or --username and --password, are required")
+	}
+	if _, err := api.Login(ctx, c.String("username"), c.String("password")); err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+	return api, nil
+}
+
+// chatUsers returns the names of the users by ID
+func chatUsers(ctx context.Context, api *client.Client) (map[uint64]string, error) {
+	list, err := api.GetUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uint64]string, len(list.Users))
+	for _, user := range list.Users {
+		names[user.ID] = user.Username
+	}
+	return names, nil
+}
+
+// resolveUser returns the ID of the user named by a username or an ID
+func resolveUser(names map[uint64]string, user string) (uint64, error) {
+	if id, err := strconv.ParseUint(user, 10, 64); err == nil {
+		return id, nil
+	}
+	for id, name := range names {
+		if name == user {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown user %q", user)
+}
+
+// chatName returns the username of a user, or its ID when it is unknown
+func chatName(names map[uint64]string, id uint64) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return "#" + strconv.FormatUint(id, 10)
+}
+
+// chatSender is the name shown for the sender of a message: the name set by
+// an incoming webhook, else the sender's
+func chatSender(names map[uint64]string, message client.Message) string {
+	if message.SenderName != "" {
+		return message.SenderName
+	}
+	return chatName(names, message.SenderID)
+}
+
+// chatLine is the JSON output of the send and tail commands
+type chatLine struct {
+	Seq        uint64 `json:"seq,omitempty"`
+	ID         uint64 `json:"id"`
+	SenderID   uint64 `json:"sender_id"`
+	Sender     string `json:"sender"`
+	ReceiverID uint64 `json:"receiver_id"`
+	Receiver   string `json:"receiver"`
+	Content    string `json:"content"`
+}
+
+// printMessage prints a message as "sender -> receiver: content", or as a
+// JSON line with --json
+func printMessage(c *cli.Context, names map[uint64]string, seq uint64, message client.Message) error {
+	line := chatLine{
+		Seq:        seq,
+		ID:         message.ID,
+		SenderID:   message.SenderID,
+		Sender:     chatSender(names, message),
+		ReceiverID: message.ReceiverID,
+		Receiver:   chatName(names, message.ReceiverID),
+		Content:    message.Content,
+	}
+	if c.Bool("json") {
+		return json.NewEncoder(os.Stdout).Encode(line)
+	}
+	_, err := fmt.Printf("%s -> %s: %s\n", line.Sender, line.Receiver, line.Content)
+	return err
+}
+
+func chatSend(c *cli.Context) error {
+	content := strings.Join(c.Args().Slice(), " ")
+	if content == "" {
+		return errors.New("the message is empty")
+	}
+
+	ctx := c.Context
+	api, err := chatLogin(ctx, c)
+	if err != nil {
+		return err
+	}
+	names, err := chatUsers(ctx, api)
+	if err != nil {
+		return err
+	}
+	receiverID, err := resolveUser(names, c.String("to"))
+	if err != nil {
+		return err
+	}
+
+	sent, err := api.Send(ctx, receiverID, content)
+	if err != nil {
+		return err
+	}
+	return printMessage(c, names, sent.Seq, sent.Message)
+}
+
+func chatTail(c *cli.Context) error {
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	api, err := chatLogin(ctx, c)
+	if err != nil {
+		return err
+	}
+	names, err := chatUsers(ctx, api)
+	if err != nil {
+		return err
+	}
+
+	var peerID uint64
+	if with := c.String("with"); with != "" {
+		if peerID, err = resolveUser(names, with); err != nil {
+			return err
+		}
+		history, err := api.Conversation(ctx, peerID)
+		if err != nil {
+			return err
+		}
+		for _, message := range history {
+			if err := printMessage(c, names, 0, message); err != nil {
+				return err
+			}
+		}
+	}
+
+	realtime, err := api.Connect(ctx, client.Handlers{
+		OnMessage: func(event client.MessageEvent) {
+			message := event.Message
+			if peerID != 0 && message.SenderID != peerID && message.ReceiverID != peerID {
+				return
+			}
+			if _, ok := names[message.SenderID]; !ok {
+				// A user registered since the start
+				if fresh, err := chatUsers(ctx, api); err == nil {
+					names = fresh
+				}
+			}
+			printMessage(c, names, event.Seq, message)
+		},
+		OnDisconnect: func(err error) {
+			fmt.Fprintf(os.Stderr, "Connection lost, reconnecting: %v\n", err)
+		},
+	}, client.RealtimeOptions{})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return realtime.Close()
+	case <-realtime.Done():
+		return realtime.Err()
+	}
+}
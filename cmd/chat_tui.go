@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"instant-messaging-app/client"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/urfave/cli/v2"
+)
+
+// chatView is the state of the interactive chat. The realtime handlers run
+// on the goroutine of the connection and hand their changes to the UI
+// goroutine through updates.
+type chatView struct {
+	app      *tview.Application
+	list     *tview.List
+	messages *tview.TextView
+	input    *tview.InputField
+	status   *tview.TextView
+	// updates queues the changes of the handlers in order; QueueUpdateDraw
+	// waits for the UI goroutine, which must not block the connection
+	updates chan func()
+	// stopped is closed once the UI goroutine no longer runs them
+	stopped chan struct{}
+
+	realtime *client.Realtime
+	self     client.User
+	users    []client.User
+	names    map[uint64]string
+	unread   map[uint64]int
+	// shown holds the IDs of the messages in the scrollback
+	shown map[uint64]bool
+	// peer is the user of the open conversation, zero before one is picked
+	peer uint64
+}
+
+// chatInteractive opens the TUI: the conversations on the left, the open
+// conversation and its compose line on the right
+func chatInteractive(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(c.Context)
+	defer cancel()
+
+	api, err := chatLogin(ctx, c)
+	if err != nil {
+		return err
+	}
+	self, err := api.GetSelf(ctx)
+	if err != nil {
+		return err
+	}
+
+	v := &chatView{
+		app:      tview.NewApplication(),
+		list:     tview.NewList().ShowSecondaryText(false),
+		messages: tview.NewTextView().SetDynamicColors(true).SetScrollable(true),
+		input:    tview.NewInputField().SetLabel("> "),
+		status:   tview.NewTextView().SetDynamicColors(true),
+		self:     self.User,
+		names:    map[uint64]string{},
+		unread:   map[uint64]int{},
+		shown:    map[uint64]bool{},
+		updates:  make(chan func(), 256),
+		stopped:  make(chan struct{}),
+	}
+	v.layout()
+	go v.forwardUpdates()
+
+	v.realtime, err = api.Connect(ctx, client.Handlers{
+		OnConnect: func() {
+			// Queued before the first connection returns; the UI goroutine
+			// only runs them once v.realtime is set
+			v.update(func() {
+				v.setStatus("[green]connected")
+				v.realtime.RequestUsers()
+				v.reload()
+			})
+		},
+		OnDisconnect: func(err error) {
+			v.update(func() { v.setStatus(fmt.Sprintf("[red]reconnecting: %s", tview.Escape(err.Error()))) })
+		},
+		OnUsers:        func(list client.UserList) { v.update(func() { v.setUsers(list.Users) }) },
+		OnConversation: func(conversation client.Conversation) { v.update(func() { v.showConversation(conversation.Messages) }) },
+		OnMessage:      func(event client.MessageEvent) { v.update(func() { v.receive(event.Message) }) },
+		OnResumed: func(resumed client.Resumed) {
+			// Too many messages were missed to be replayed
+			if !resumed.Complete {
+				v.update(v.reload)
+			}
+		},
+		OnError: func(err *client.RealtimeError) {
+			v.update(func() { v.setStatus("[red]" + tview.Escape(err.Error())) })
+		},
+		OnForceLogout: func() {
+			v.update(func() { v.setStatus("[red]logged out") })
+		},
+	}, client.RealtimeOptions{})
+	if err != nil {
+		return err
+	}
+	defer v.realtime.Close()
+
+	// The connection ends for good after a forced logout
+	go func() {
+		<-v.realtime.Done()
+		v.app.Stop()
+	}()
+
+	err = v.app.Run()
+	close(v.stopped)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-v.realtime.Done():
+		return v.realtime.Err()
+	default:
+		return nil
+	}
+}
+
+// layout builds the widgets and their key bindings. Tab switches between
+// the conversations and the compose line, Ctrl-C quits.
+func (v *chatView) layout() {
+	v.list.SetBorder(true).SetTitle(" Conversations ")
+	v.messages.SetBorder(true).SetTitle(" Messages ")
+	v.setStatus("[yellow]connecting")
+
+	v.list.SetSelectedFunc(func(index int, _ string, _ string, _ rune) {
+		v.open(v.users[index].ID)
+		v.app.SetFocus(v.input)
+	})
+	v.input.SetDoneFunc(func(key tcell.Key) {
+		if key != tcell.KeyEnter {
+			return
+		}
+		content := v.input.GetText()
+		if content == "" || v.peer == 0 {
+			return
+		}
+		if err := v.realtime.SendMessage(v.peer, content); err != nil {
+			v.setStatus("[red]" + tview.Escape(err.Error()))
+			return
+		}
+		v.input.SetText("")
+	})
+
+	conversation := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(v.messages, 0, 1, false).
+		AddItem(v.input, 1, 0, true)
+	panes := tview.NewFlex().
+		AddItem(v.list, 28, 0, true).
+		AddItem(conversation, 0, 1, false)
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(panes, 0, 1, true).
+		AddItem(v.status, 1, 0, false)
+
+	v.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyTab {
+			return event
+		}
+		if v.list.HasFocus() {
+			v.app.SetFocus(v.input)
+		} else {
+			v.app.SetFocus(v.list)
+		}
+		return nil
+	})
+	v.app.SetRoot(root, true).SetFocus(v.list)
+}
+
+// update runs f on the UI goroutine, unless the UI is closed
+func (v *chatView) update(f func()) {
+	select {
+	case v.updates <- f:
+	case <-v.stopped:
+	}
+}
+
+// forwardUpdates passes the queued changes to the UI goroutine
+func (v *chatView) forwardUpdates() {
+	for {
+		select {
+		case f := <-v.updates:
+			v.app.QueueUpdateDraw(f)
+		case <-v.stopped:
+			return
+		}
+	}
+}
+
+func (v *chatView) setStatus(status string) {
+	v.status.SetText(fmt.Sprintf("%s  [white]%s (Tab: switch pane, Enter: open/send, Ctrl-C: quit)", status, tview.Escape(v.self.Username)))
+}
+
+// setUsers replaces the conversation list, keeping the selected user
+func (v *chatView) setUsers(users []client.User) {
+	v.users = v.users[:0]
+	for _, user := range users {
+		v.names[user.ID] = user.Username
+		if user.ID != v.self.ID {
+			v.users = append(v.users, user)
+		}
+	}
+	sort.Slice(v.users, func(i, j int) bool { return v.users[i].Username < v.users[j].Username })
+	v.drawList()
+}
+
+// drawList renders the users with their unread counts
+func (v *chatView) drawList() {
+	selected := v.list.GetCurrentItem()
+	v.list.Clear()
+	for _, user := range v.users {
+		label := tview.Escape(user.Username)
+		if user.ID == v.peer {
+			label = "[::b]" + label
+		}
+		if n := v.unread[user.ID]; n > 0 {
+			label += fmt.Sprintf(" [yellow](%d)", n)
+		}
+		v.list.AddItem(label, "", 0, nil)
+	}
+	if selected < len(v.users) {
+		v.list.SetCurrentItem(selected)
+	}
+}
+
+// open shows the conversation with a user, loading its history with getMessages
+func (v *chatView) open(userID uint64) {
+	v.peer = userID
+	delete(v.unread, userID)
+	v.drawList()
+	v.messages.SetTitle(fmt.Sprintf(" %s ", tview.Escape(chatName(v.names, userID))))
+	v.messages.SetText("[gray]loading...")
+	v.shown = map[uint64]bool{}
+	v.reload()
+}
+
+// reload asks for the history of the open conversation again
+func (v *chatView) reload() {
+	if v.peer == 0 {
+		return
+	}
+	if err := v.realtime.RequestConversation(v.peer); err != nil {
+		v.setStatus("[red]" + tview.Escape(err.Error()))
+	}
+}
+
+// showConversation replaces the scrollback with the history of the open
+// conversation. Replies for a conversation left since are dropped.
+func (v *chatView) showConversation(messages []client.Message) {
+	if len(messages) > 0 && !v.inConversation(messages[0]) {
+		return
+	}
+	v.messages.Clear()
+	v.shown = map[uint64]bool{}
+	for _, message := range messages {
+		v.appendMessage(message)
+	}
+	v.messages.ScrollToEnd()
+}
+
+// receive shows a live message, or counts it as unread in the list
+func (v *chatView) receive(message client.Message) {
+	if v.inConversation(message) {
+		v.appendMessage(message)
+		v.messages.ScrollToEnd()
+		return
+	}
+
+	peer := message.SenderID
+	if peer == v.self.ID {
+		peer = message.ReceiverID
+	}
+	v.unread[peer]++
+	if _, ok := v.names[peer]; !ok {
+		// A user registered since the list was loaded
+		v.realtime.RequestUsers()
+	}
+	v.drawList()
+}
+
+// inConversation tells whether a message belongs to the open conversation
+func (v *chatView) inConversation(message client.Message) bool {
+	return v.peer != 0 && (message.SenderID == v.peer || message.ReceiverID == v.peer)
+}
+
+// appendMessage adds a message to the scrollback, once: a replayed message
+// may also be part of a reloaded history
+func (v *chatView) appendMessage(message client.Message) {
+	if v.shown[message.ID] {
+		return
+	}
+	v.shown[message.ID] = true
+	color := "aqua"
+	if message.SenderID == v.self.ID {
+		color = "green"
+	}
+	fmt.Fprintf(v.messages, "[%s]%s[white]: %s\n", color, tview.Escape(chatSender(v.names, message)), tview.Escape(message.Content))
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+
+	"instant-messaging-app/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ConfigCommand builds the configuration inspection commands
+func ConfigCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Inspect the effective configuration",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "print",
+				Usage: "Print the effective configuration with secrets redacted",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "format", Value: "yaml", Usage: "Output format: yaml, toml or json"},
+				},
+				Action: func(c *cli.Context) error {
+					out, err := config.Cfg.Print(c.String("format"))
+					if err != nil {
+						return err
+					}
+					fmt.Print(out)
+
+					// Report problems after printing so the offending values are visible
+					return config.Cfg.Validate()
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"instant-messaging-app/api/routes"
+	"instant-messaging-app/docs"
+	"instant-messaging-app/repositories"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/urfave/cli/v2"
+)
+
+// DocsCommand builds the commands of the API documents
+func DocsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "docs",
+		Usage: "Inspect the OpenAPI and AsyncAPI documents",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "check",
+				Usage: "Check that the OpenAPI document matches the routes of the gateway",
+				Action: func(c *cli.Context) error {
+					// The routes only capture the repositories, so none is needed
+					app := fiber.New()
+					routes.SetupRoutes(app, context.Background(), repositories.Repositories{})
+					if err := docs.CheckRoutes(app); err != nil {
+						return err
+					}
+					fmt.Println("The OpenAPI document matches the routes")
+					return nil
+				},
+			},
+			{
+				Name:  "openapi",
+				Usage: "Print the OpenAPI document",
+				Action: func(c *cli.Context) error {
+					fmt.Print(string(docs.OpenAPI))
+					return nil
+				},
+			},
+			{
+				Name:  "asyncapi",
+				Usage: "Print the AsyncAPI document",
+				Action: func(c *cli.Context) error {
+					fmt.Print(string(docs.AsyncAPI))
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,35 @@
+package cmd_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/utils"
+)
+
+// TestMain points the commands at a throwaway SQLite database, which each
+// command opens itself
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "cmd-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cfg := config.Default()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = filepath.Join(dir, "cmd.db")
+	cfg.JWT.Secret = utils.GenerateUUID()
+	config.Cfg = cfg
+	config.InitDatabase()
+
+	code := m.Run()
+	if sqlDB, err := config.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
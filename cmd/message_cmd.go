@@ -2,75 +2,74 @@ package cmd
 
 import (
 	"context"
-	"log"
-	"os"
-	"os/signal"
-	"syscall"
+	"log/slog"
 
 	"instant-messaging-app/config"
+	"instant-messaging-app/health"
+	"instant-messaging-app/message/commands"
 	"instant-messaging-app/message/handlers"
-
-	"github.com/joho/godotenv"
+	"instant-messaging-app/repositories"
 )
 
 // StartMessageService starts the MessageService daemon
 func StartMessageService() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found. Using system environment variables.")
-	}
+	// Export traces under the name of the daemon
+	config.SetupTracing("message-service")
+	defer config.ShutdownTracing()
 
 	// Initialize the database
 	config.InitDatabase()
 
-	log.Println("Starting UserService daemon...")
+	slog.Info("Starting MessageService daemon")
 
 	// Setup RabbitMQ connection and channel
 	config.SetupRabbitMQ()
 	defer config.CleanupRabbitMQ()
 
-	// Declare the direct exchange for registration, login, and user queries
-	config.InitDirectRabbitMQExchange("message_direct_exchange")
+	// Create a context canceled on SIGINT or SIGTERM for graceful shutdown
+	ctx, cancel := signalContext()
+	defer cancel()
 
-	// Declare and bind the getUsers queue
-	getMessagesQueue := "message_service_get_messages_queue"
-	config.InitQueue(getMessagesQueue)
-	config.BindQueueToExchange(getMessagesQueue, "user_direct_exchange", "getMessages")
+	// Expose the health probes since the daemon has no other HTTP listener
+	startAdminListener(ctx, health.NewServiceChecker(config.DB, config.Broker))
 
-	// Declare and bind the sendMessage queue
-	sendMessageQueue := "message_service_send_message_queue"
-	config.InitQueue(sendMessageQueue)
-	config.BindQueueToExchange(sendMessageQueue, "user_direct_exchange", "sendMessage")
+	RunMessageService(ctx, repositories.NewGormRepositories(config.DB))
+}
 
-	// Declare the notification exchange
-	config.InitDirectRabbitMQExchange("notification_exchange")
+// declareMessageServiceTopology declares the exchanges and queues of the MessageService
+func declareMessageServiceTopology() {
+	// Declare the direct exchanges for message requests
+	config.InitDirectRabbitMQExchange(config.Cfg.Exchanges.MessageDirect)
+	config.InitDirectRabbitMQExchange(config.Cfg.Exchanges.UserDirect)
 
-	// Create a context for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Declare and bind the getMessages queue
+	config.InitQueue(config.Cfg.Queues.GetMessages)
+	config.BindQueueToExchange(config.Cfg.Queues.GetMessages, config.Cfg.Exchanges.UserDirect, "getMessages")
+
+	// Declare and bind the sendMessage queue
+	config.InitQueue(config.Cfg.Queues.SendMessage)
+	config.BindQueueToExchange(config.Cfg.Queues.SendMessage, config.Cfg.Exchanges.UserDirect, "sendMessage")
+
+	// Declare the notification exchanges
+	config.InitDirectRabbitMQExchange(config.Cfg.Exchanges.Notification)
+	config.InitFanoutRabbitMQExchange(config.Cfg.Exchanges.NotificationBroadcast)
+}
 
-	// Handle system signals for shutdown
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-		sig := <-sigChan
-		log.Printf("Received signal: %v. Initiating shutdown...", sig)
-		cancel()
-	}()
+// RunMessageService consumes the MessageService queues until ctx is canceled;
+// the database and broker must already be set up
+func RunMessageService(ctx context.Context, repos repositories.Repositories) {
+	declareMessageServiceTopology()
 
 	// Start consuming getMessages requests
-	go func() {
-		log.Println("Starting consumer for getMessages queue...")
-		handlers.ConsumeGetMessagesQueue(ctx, getMessagesQueue, "notification_exchange")
-	}()
+	slog.Info("Starting consumer", "queue", config.Cfg.Queues.GetMessages)
+	handlers.ConsumeGetMessagesQueue(ctx, repos.Messages, config.Cfg.Queues.GetMessages, config.Cfg.Exchanges.Notification)
 
 	// Start consuming sendMessage requests
-	go func() {
-		log.Println("Starting consumer for sendMessage queue...")
-		handlers.ConsumeSendMessageQueue(ctx, sendMessageQueue, "notification_broadcast_exchange")
-	}()
+	slog.Info("Starting consumer", "queue", config.Cfg.Queues.SendMessage)
+	registry := commands.NewRegistry(repos, config.Cfg.Exchanges.NotificationBroadcast)
+	handlers.ConsumeSendMessageQueue(ctx, repos, registry, config.Cfg.Queues.SendMessage, config.Cfg.Exchanges.Notification, config.Cfg.Exchanges.NotificationBroadcast)
 
 	// Block until context is canceled
 	<-ctx.Done()
-	log.Println("UserService daemon stopped gracefully.")
-}
\ No newline at end of file
+	slog.Info("MessageService daemon stopped gracefully")
+}
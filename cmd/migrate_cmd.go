@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/migrations"
+
+	"github.com/urfave/cli/v2"
+)
+
+// MigrateCommand builds the schema migration commands
+func MigrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "Manage the versioned database schema",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "up",
+				Usage: "Apply pending migrations",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "steps", Usage: "Apply at most this many migrations (0 applies all)"},
+				},
+				Action: func(c *cli.Context) error {
+					config.ConnectDatabase()
+
+					applied, err := migrations.Up(config.DB, c.Int("steps"))
+					for _, migration := range applied {
+						fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+					}
+					if err == nil && len(applied) == 0 {
+						fmt.Println("Schema is up to date")
+					}
+					return err
+				},
+			},
+			{
+				Name:  "down",
+				Usage: "Roll back applied migrations",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "steps", Value: 1, Usage: "Number of migrations to roll back"},
+				},
+				Action: func(c *cli.Context) error {
+					config.ConnectDatabase()
+
+					reverted, err := migrations.Down(config.DB, c.Int("steps"))
+					for _, migration := range reverted {
+						fmt.Printf("Rolled back %04d_%s\n", migration.Version, migration.Name)
+					}
+					return err
+				},
+			},
+			{
+				Name:  "status",
+				Usage: "Show applied and pending migrations",
+				Flags: []cli.Flag{jsonFlag},
+				Action: func(c *cli.Context) error {
+					config.ConnectDatabase()
+
+					statuses, err := migrations.Status(config.DB)
+					if err != nil {
+						return err
+					}
+
+					return printResult(c, statuses, func() {
+						w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+						fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+						for _, status := range statuses {
+							appliedAt := "pending"
+							if status.AppliedAt != nil {
+								appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+							}
+							fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+						}
+						w.Flush()
+					})
+				},
+			},
+			{
+				Name:      "create",
+				Usage:     "Create an empty up/down migration pair",
+				ArgsUsage: "<name>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "dir", Usage: "Directory holding the migration files (default: one pair per dialect under migrations/sql)"},
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return fmt.Errorf("expected exactly one migration name")
+					}
+
+					// Every schema change needs a Postgres and a SQLite version
+					dirs := []string{"migrations/sql/postgres", "migrations/sql/sqlite"}
+					if c.String("dir") != "" {
+						dirs = []string{c.String("dir")}
+					}
+
+					for _, dir := range dirs {
+						upPath, downPath, err := migrations.Create(dir, c.Args().First())
+						if err != nil {
+							return err
+						}
+						fmt.Printf("Created %s\nCreated %s\n", upPath, downPath)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/health"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/scheduler/services"
+)
+
+// StartSchedulerService starts the scheduler daemon
+func StartSchedulerService() {
+	// Export traces under the name of the daemon
+	config.SetupTracing("scheduler-service")
+	defer config.ShutdownTracing()
+
+	// Initialize the database
+	config.InitDatabase()
+
+	slog.Info("Starting scheduler daemon")
+
+	// Setup RabbitMQ connection and channel
+	config.SetupRabbitMQ()
+	defer config.CleanupRabbitMQ()
+
+	// Create a context canceled on SIGINT or SIGTERM for graceful shutdown
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	// Expose the health probes since the daemon has no other HTTP listener
+	startAdminListener(ctx, health.NewServiceChecker(config.DB, config.Broker))
+
+	RunSchedulerService(ctx, repositories.NewGormRepositories(config.DB))
+}
+
+// declareSchedulerServiceTopology declares the exchange the scheduled
+// messages are published to, bound to the sendMessage queue by the
+// MessageService
+func declareSchedulerServiceTopology() {
+	config.InitDirectRabbitMQExchange(config.Cfg.Exchanges.UserDirect)
+}
+
+// RunSchedulerService sends the due scheduled messages until ctx is canceled;
+// the database and broker must already be set up
+func RunSchedulerService(ctx context.Context, repos repositories.Repositories) {
+	declareSchedulerServiceTopology()
+
+	// Send the due messages until the context is canceled
+	slog.Info("Starting the scheduled messages poller", "interval", config.Cfg.Scheduler.PollInterval, "batch_size", config.Cfg.Scheduler.BatchSize)
+	services.NewScheduler(repos.Scheduled).Run(ctx)
+	slog.Info("Scheduler daemon stopped gracefully")
+}
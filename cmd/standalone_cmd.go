@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/repositories"
+)
+
+// StartStandalone runs the API gateway, the UserService, the MessageService,
+// the webhook daemon and the scheduler daemon in a single process sharing one
+// database and broker. With `--db-driver sqlite --broker memory` it needs no
+// external server at all.
+func StartStandalone() {
+	config.SetupTracing("instant-messaging-app")
+	defer config.ShutdownTracing()
+
+	config.InitDatabase()
+
+	config.SetupRabbitMQ()
+	defer config.CleanupRabbitMQ()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	slog.Info("Starting standalone mode", "database", config.Cfg.Database.Driver, "broker", config.Cfg.Broker.Driver)
+	RunStandalone(ctx, repositories.NewGormRepositories(config.DB))
+}
+
+// RunStandalone runs the five services until ctx is canceled or the gateway
+// fails; the database and broker must already be set up
+func RunStandalone(ctx context.Context, repos repositories.Repositories) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Declare every exchange and queue before serving requests, otherwise
+	// the first requests could be published before their queues exist
+	declareUserServiceTopology()
+	declareMessageServiceTopology()
+	declareWebhookServiceTopology()
+	declareSchedulerServiceTopology()
+	declareWebServerTopology()
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		RunUserService(ctx, repos)
+	}()
+	go func() {
+		defer wg.Done()
+		RunMessageService(ctx, repos)
+	}()
+	go func() {
+		defer wg.Done()
+		RunWebhookService(ctx, repos)
+	}()
+	go func() {
+		defer wg.Done()
+		RunSchedulerService(ctx, repos)
+	}()
+
+	// The gateway returns when ctx is canceled or the listener fails; stop
+	// the daemons in both cases
+	RunWebServer(ctx, repos)
+	cancel()
+	wg.Wait()
+}
@@ -2,97 +2,94 @@ package cmd
 
 import (
 	"context"
-	"log"
-	"os"
-	"os/signal"
-	"syscall"
+	"log/slog"
 
 	"instant-messaging-app/config"
+	"instant-messaging-app/health"
+	"instant-messaging-app/repositories"
 	"instant-messaging-app/user/handlers"
-
-	"github.com/joho/godotenv"
 )
 
 // StartUserService starts the UserService daemon
 func StartUserService() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found. Using system environment variables.")
-	}
+	// Export traces under the name of the daemon
+	config.SetupTracing("user-service")
+	defer config.ShutdownTracing()
 
 	// Initialize the database
 	config.InitDatabase()
 
-	log.Println("Starting UserService daemon...")
+	slog.Info("Starting UserService daemon")
 
 	// Setup RabbitMQ connection and channel
 	config.SetupRabbitMQ()
 	defer config.CleanupRabbitMQ()
 
+	// Create a context canceled on SIGINT or SIGTERM for graceful shutdown
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	// Expose the health probes since the daemon has no other HTTP listener
+	startAdminListener(ctx, health.NewServiceChecker(config.DB, config.Broker))
+
+	RunUserService(ctx, repositories.NewGormRepositories(config.DB))
+}
+
+// declareUserServiceTopology declares the exchanges and queues of the UserService
+func declareUserServiceTopology() {
 	// Declare the direct exchange for registration, login, and user queries
-	config.InitDirectRabbitMQExchange("user_direct_exchange")
+	config.InitDirectRabbitMQExchange(config.Cfg.Exchanges.UserDirect)
 
 	// Declare and bind the registration queue
-	registrationQueue := "user_service_registration_queue"
-	config.InitQueue(registrationQueue)
-	config.BindQueueToExchange(registrationQueue, "user_direct_exchange", "registration")
+	config.InitQueue(config.Cfg.Queues.Registration)
+	config.BindQueueToExchange(config.Cfg.Queues.Registration, config.Cfg.Exchanges.UserDirect, "registration")
 
 	// Declare and bind the login queue
-	loginQueue := "user_service_login_queue"
-	config.InitQueue(loginQueue)
-	config.BindQueueToExchange(loginQueue, "user_direct_exchange", "login")
+	config.InitQueue(config.Cfg.Queues.Login)
+	config.BindQueueToExchange(config.Cfg.Queues.Login, config.Cfg.Exchanges.UserDirect, "login")
 
 	// Declare and bind the getUsers queue
-	getUsersQueue := "user_service_get_users_queue"
-	config.InitQueue(getUsersQueue)
-	config.BindQueueToExchange(getUsersQueue, "user_direct_exchange", "getUsers")
+	config.InitQueue(config.Cfg.Queues.GetUsers)
+	config.BindQueueToExchange(config.Cfg.Queues.GetUsers, config.Cfg.Exchanges.UserDirect, "getUsers")
 
-	// Declare and bind the getUsers queue
-	getSelfQueue := "user_service_get_self_queue"
-	config.InitQueue(getSelfQueue)
-	config.BindQueueToExchange(getSelfQueue, "user_direct_exchange", "getSelf")
+	// Declare and bind the getSelf queue
+	config.InitQueue(config.Cfg.Queues.GetSelf)
+	config.BindQueueToExchange(config.Cfg.Queues.GetSelf, config.Cfg.Exchanges.UserDirect, "getSelf")
 
-	// Declare the notification exchange
-	config.InitDirectRabbitMQExchange("notification_exchange")
+	// Declare and bind the searchUsers queue
+	config.InitQueue(config.Cfg.Queues.SearchUsers)
+	config.BindQueueToExchange(config.Cfg.Queues.SearchUsers, config.Cfg.Exchanges.UserDirect, "searchUsers")
 
-	// Create a context for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Declare the notification exchange
+	config.InitDirectRabbitMQExchange(config.Cfg.Exchanges.Notification)
+}
 
-	// Handle system signals for shutdown
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-		sig := <-sigChan
-		log.Printf("Received signal: %v. Initiating shutdown...", sig)
-		cancel()
-	}()
+// RunUserService consumes the UserService queues until ctx is canceled; the
+// database and broker must already be set up
+func RunUserService(ctx context.Context, repos repositories.Repositories) {
+	declareUserServiceTopology()
 
 	// Start consuming registration requests
-	go func() {
-		log.Println("Starting consumer for registration queue...")
-		handlers.ConsumeRegistrationQueue(ctx, registrationQueue, "notification_exchange")
-	}()
+	slog.Info("Starting consumer", "queue", config.Cfg.Queues.Registration)
+	handlers.ConsumeRegistrationQueue(ctx, repos.Users, config.Cfg.Queues.Registration, config.Cfg.Exchanges.Notification)
 
 	// Start consuming login requests
-	go func() {
-		log.Println("Starting consumer for login queue...")
-		handlers.ConsumeLoginQueue(ctx, loginQueue, "notification_exchange")
-	}()
+	slog.Info("Starting consumer", "queue", config.Cfg.Queues.Login)
+	handlers.ConsumeLoginQueue(ctx, repos.Users, config.Cfg.Queues.Login, config.Cfg.Exchanges.Notification)
 
 	// Start consuming getUsers requests
-	go func() {
-		log.Println("Starting consumer for getUsers queue...")
-		handlers.ConsumeGetUsersQueue(ctx, getUsersQueue, "notification_exchange")
-	}()
+	slog.Info("Starting consumer", "queue", config.Cfg.Queues.GetUsers)
+	handlers.ConsumeGetUsersQueue(ctx, repos.Users, config.Cfg.Queues.GetUsers, config.Cfg.Exchanges.Notification)
 
-	// Start consuming getUsers requests
-	go func() {
-		log.Println("Starting consumer for getSelf queue...")
-		handlers.ConsumeGetSelfQueue(ctx, getSelfQueue, "notification_exchange")
-	}()
+	// Start consuming getSelf requests
+	slog.Info("Starting consumer", "queue", config.Cfg.Queues.GetSelf)
+	handlers.ConsumeGetSelfQueue(ctx, repos.Users, config.Cfg.Queues.GetSelf, config.Cfg.Exchanges.Notification)
+
+	// Start consuming searchUsers requests
+	slog.Info("Starting consumer", "queue", config.Cfg.Queues.SearchUsers)
+	handlers.ConsumeSearchUsersQueue(ctx, repos.Users, config.Cfg.Queues.SearchUsers, config.Cfg.Exchanges.Notification)
 
 	// Block until context is canceled
 	<-ctx.Done()
-	log.Println("UserService daemon stopped gracefully.")
-}
\ No newline at end of file
+	slog.Info("UserService daemon stopped gracefully")
+}
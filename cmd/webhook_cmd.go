@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/health"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/webhook/handlers"
+	"instant-messaging-app/webhook/services"
+)
+
+// StartWebhookService starts the webhook daemon
+func StartWebhookService() {
+	// Export traces under the name of the daemon
+	config.SetupTracing("webhook-service")
+	defer config.ShutdownTracing()
+
+	// Initialize the database
+	config.InitDatabase()
+
+	slog.Info("Starting webhook daemon")
+
+	// Setup RabbitMQ connection and channel
+	config.SetupRabbitMQ()
+	defer config.CleanupRabbitMQ()
+
+	// Create a context canceled on SIGINT or SIGTERM for graceful shutdown
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	// Expose the health probes since the daemon has no other HTTP listener
+	startAdminListener(ctx, health.NewServiceChecker(config.DB, config.Broker))
+
+	RunWebhookService(ctx, repositories.NewGormRepositories(config.DB))
+}
+
+// declareWebhookServiceTopology declares the queue of the message events,
+// which receives every broadcast notification
+func declareWebhookServiceTopology() {
+	config.InitFanoutRabbitMQExchange(config.Cfg.Exchanges.NotificationBroadcast)
+	config.InitQueue(config.Cfg.Queues.WebhookEvents)
+	config.BindQueueToExchange(config.Cfg.Queues.WebhookEvents, config.Cfg.Exchanges.NotificationBroadcast, "")
+}
+
+// RunWebhookService records the deliveries of the message events and sends
+// them until ctx is canceled; the database and broker must already be set up
+func RunWebhookService(ctx context.Context, repos repositories.Repositories) {
+	declareWebhookServiceTopology()
+
+	dispatcher := services.NewDispatcher(repos.Webhooks)
+
+	// Start consuming the message events
+	slog.Info("Starting consumer", "queue", config.Cfg.Queues.WebhookEvents)
+	handlers.ConsumeEventsQueue(ctx, repos.Webhooks, dispatcher, config.Cfg.Queues.WebhookEvents)
+
+	// Send the deliveries until the context is canceled
+	dispatcher.Run(ctx)
+	slog.Info("Webhook daemon stopped gracefully")
+}
@@ -0,0 +1,444 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the typed configuration shared by every command. Values come from
+// the defaults below, then an optional YAML/TOML file, then environment
+// variables and finally command line flags, each source overriding the last.
+type Config struct {
+	HTTP      HTTPConfig      `yaml:"http" toml:"http" json:"http"`
+	WebSocket WebSocketConfig `yaml:"websocket" toml:"websocket" json:"websocket"`
+	Poll      PollConfig      `yaml:"poll" toml:"poll" json:"poll"`
+	Bots      BotsConfig      `yaml:"bots" toml:"bots" json:"bots"`
+	Webhooks  WebhooksConfig  `yaml:"webhooks" toml:"webhooks" json:"webhooks"`
+	Scheduler SchedulerConfig `yaml:"scheduler" toml:"scheduler" json:"scheduler"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database" json:"database"`
+	Broker    BrokerConfig    `yaml:"broker" toml:"broker" json:"broker"`
+	RabbitMQ  RabbitMQConfig  `yaml:"rabbitmq" toml:"rabbitmq" json:"rabbitmq"`
+	JWT       JWTConfig       `yaml:"jwt" toml:"jwt" json:"jwt"`
+	Exchanges ExchangesConfig `yaml:"exchanges" toml:"exchanges" json:"exchanges"`
+	Queues    QueuesConfig    `yaml:"queues" toml:"queues" json:"queues"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing" json:"tracing"`
+	Log       LogConfig       `yaml:"log" toml:"log" json:"log"`
+}
+
+// HTTPConfig configures the api gateway listener
+type HTTPConfig struct {
+	Port           string        `yaml:"port" toml:"port" json:"port" env:"APP_PORT" flag:"port" usage:"API gateway listen port"`
+	CORSOrigins    []string      `yaml:"cors_origins" toml:"cors_origins" json:"cors_origins" env:"CORS_ORIGINS" flag:"cors-origins" usage:"Comma separated list of allowed CORS origins"`
+	TLSCertFile    string        `yaml:"tls_cert_file" toml:"tls_cert_file" json:"tls_cert_file" env:"TLS_CERT_FILE" flag:"tls-cert" usage:"TLS certificate file; enables HTTPS with --tls-key"`
+	TLSKeyFile     string        `yaml:"tls_key_file" toml:"tls_key_file" json:"tls_key_file" env:"TLS_KEY_FILE" flag:"tls-key" usage:"TLS private key file"`
+	AdminPort      string        `yaml:"admin_port" toml:"admin_port" json:"admin_port" env:"ADMIN_PORT" flag:"admin-port" usage:"Port of the health listener of the user and message daemons (empty disables it)"`
+	RequestTimeout time.Duration `yaml:"request_timeout" toml:"request_timeout" json:"request_timeout" env:"HTTP_REQUEST_TIMEOUT" usage:"Time a REST request waits for the reply of the user or message service"`
+	GRPCPort       string        `yaml:"grpc_port" toml:"grpc_port" json:"grpc_port" env:"GRPC_PORT" flag:"grpc-port" usage:"Port of the gRPC API of the gateway (empty disables it)"`
+}
+
+// TLSEnabled reports whether the gateway should serve HTTPS
+func (h HTTPConfig) TLSEnabled() bool {
+	return h.TLSCertFile != "" && h.TLSKeyFile != ""
+}
+
+// WebSocketConfig configures the heartbeat, the outbound buffer and the
+// session replay of the gateway WebSockets
+type WebSocketConfig struct {
+	PingInterval time.Duration `yaml:"ping_interval" toml:"ping_interval" json:"ping_interval" env:"WS_PING_INTERVAL" usage:"Interval between the pings sent to WebSocket clients"`
+	PongTimeout  time.Duration `yaml:"pong_timeout" toml:"pong_timeout" json:"pong_timeout" env:"WS_PONG_TIMEOUT" usage:"Time without a pong or message after which a WebSocket is closed"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout" json:"write_timeout" env:"WS_WRITE_TIMEOUT" usage:"Time allowed to write one frame to a WebSocket"`
+	SendBuffer   int           `yaml:"send_buffer" toml:"send_buffer" json:"send_buffer" env:"WS_SEND_BUFFER" usage:"Outbound frames queued per WebSocket before the slow consumer policy applies"`
+	SlowConsumer string        `yaml:"slow_consumer" toml:"slow_consumer" json:"slow_consumer" env:"WS_SLOW_CONSUMER" flag:"ws-slow-consumer" usage:"What to do when a WebSocket's buffer is full: drop the frame or disconnect the client; clients are disconnected rather than missing a message"`
+	ReplayLimit  int           `yaml:"replay_limit" toml:"replay_limit" json:"replay_limit" env:"WS_REPLAY_LIMIT" usage:"Maximum number of missed events replayed to a resuming WebSocket"`
+}
+
+// PollConfig configures the long-polling fallback of the gateway
+type PollConfig struct {
+	Timeout    time.Duration `yaml:"timeout" toml:"timeout" json:"timeout" env:"POLL_TIMEOUT" usage:"Longest time a long-poll request waits for notifications"`
+	SessionTTL time.Duration `yaml:"session_ttl" toml:"session_ttl" json:"session_ttl" env:"POLL_SESSION_TTL" usage:"Time without a poll after which a long-poll session and its queue are dropped"`
+}
+
+// BotsConfig limits the bot accounts and their API keys
+type BotsConfig struct {
+	MaxPerOwner      int `yaml:"max_per_owner" toml:"max_per_owner" json:"max_per_owner" env:"BOTS_MAX_PER_OWNER" usage:"Bots a user may own"`
+	DefaultRateLimit int `yaml:"default_rate_limit" toml:"default_rate_limit" json:"default_rate_limit" env:"API_KEY_RATE_LIMIT" usage:"Requests per minute allowed to an API key created without a rate limit, counted by each gateway instance separately"`
+	MaxRateLimit     int `yaml:"max_rate_limit" toml:"max_rate_limit" json:"max_rate_limit" env:"API_KEY_MAX_RATE_LIMIT" usage:"Highest rate limit an owner may give an API key, in requests per minute per gateway instance"`
+}
+
+// WebhooksConfig configures the outgoing webhooks and their delivery by the
+// webhook daemon, and limits the incoming webhooks
+type WebhooksConfig struct {
+	MaxPerUser         int           `yaml:"max_per_user" toml:"max_per_user" json:"max_per_user" env:"WEBHOOKS_MAX_PER_USER" usage:"Webhooks a user may register"`
+	Timeout            time.Duration `yaml:"timeout" toml:"timeout" json:"timeout" env:"WEBHOOK_TIMEOUT" usage:"Time allowed to a webhook endpoint to answer a delivery"`
+	MaxAttempts        int           `yaml:"max_attempts" toml:"max_attempts" json:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" usage:"Attempts of a delivery before it is marked as failed"`
+	MinBackoff         time.Duration `yaml:"min_backoff" toml:"min_backoff" json:"min_backoff" env:"WEBHOOK_MIN_BACKOFF" usage:"Delay before the first retry of a delivery, doubled after each attempt"`
+	MaxBackoff         time.Duration `yaml:"max_backoff" toml:"max_backoff" json:"max_backoff" env:"WEBHOOK_MAX_BACKOFF" usage:"Longest delay between two attempts of a delivery"`
+	DisableAfter       int           `yaml:"disable_after" toml:"disable_after" json:"disable_after" env:"WEBHOOK_DISABLE_AFTER" usage:"Consecutive failed deliveries after which a webhook is disabled"`
+	PollInterval       time.Duration `yaml:"poll_interval" toml:"poll_interval" json:"poll_interval" env:"WEBHOOK_POLL_INTERVAL" usage:"Interval at which the webhook daemon looks for due deliveries"`
+	Concurrency        int           `yaml:"concurrency" toml:"concurrency" json:"concurrency" env:"WEBHOOK_CONCURRENCY" usage:"Deliveries sent at the same time by a webhook daemon"`
+	AllowPrivate       bool          `yaml:"allow_private" toml:"allow_private" json:"allow_private" env:"WEBHOOK_ALLOW_PRIVATE" usage:"Allow webhook URLs resolving to loopback and private addresses"`
+	MaxIncomingPerUser int           `yaml:"max_incoming_per_user" toml:"max_incoming_per_user" json:"max_incoming_per_user" env:"INCOMING_WEBHOOKS_MAX_PER_USER" usage:"Incoming webhooks a user may create"`
+	IncomingRateLimit  int           `yaml:"incoming_rate_limit" toml:"incoming_rate_limit" json:"incoming_rate_limit" env:"INCOMING_WEBHOOK_RATE_LIMIT" usage:"Messages per minute accepted from an incoming webhook"`
+}
+
+// SchedulerConfig limits the scheduled messages and configures their sending
+// by the scheduler daemon
+type SchedulerConfig struct {
+	MaxPerUser   int           `yaml:"max_per_user" toml:"max_per_user" json:"max_per_user" env:"SCHEDULED_MESSAGES_MAX_PER_USER" usage:"Pending scheduled messages and reminders a user may have"`
+	MaxDelay     time.Duration `yaml:"max_delay" toml:"max_delay" json:"max_delay" env:"SCHEDULER_MAX_DELAY" usage:"Furthest in the future a message may be scheduled"`
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" json:"poll_interval" env:"SCHEDULER_POLL_INTERVAL" usage:"Interval at which the scheduler daemon looks for due messages"`
+	BatchSize    int           `yaml:"batch_size" toml:"batch_size" json:"batch_size" env:"SCHEDULER_BATCH_SIZE" usage:"Due messages a scheduler daemon claims and sends at once"`
+	ClaimTimeout time.Duration `yaml:"claim_timeout" toml:"claim_timeout" json:"claim_timeout" env:"SCHEDULER_CLAIM_TIMEOUT" usage:"Time after which the messages claimed by a scheduler daemon that stopped are sent by another"`
+}
+
+// DatabaseConfig configures the database connection and pool
+type DatabaseConfig struct {
+	Driver          string        `yaml:"driver" toml:"driver" json:"driver" env:"DB_DRIVER" flag:"db-driver" usage:"Database backend: postgres, or sqlite to run without a database server"`
+	Path            string        `yaml:"path" toml:"path" json:"path" env:"DB_PATH" flag:"db-path" usage:"SQLite database file (sqlite driver only)"`
+	Host            string        `yaml:"host" toml:"host" json:"host" env:"DB_HOST" flag:"db-host" usage:"Database host"`
+	Port            int           `yaml:"port" toml:"port" json:"port" env:"DB_PORT" flag:"db-port" usage:"Database port"`
+	User            string        `yaml:"user" toml:"user" json:"user" env:"DB_USER" flag:"db-user" usage:"Database user"`
+	Password        string        `yaml:"password" toml:"password" json:"password" env:"DB_PASSWORD" flag:"db-password" usage:"Database password" secret:"true"`
+	Name            string        `yaml:"name" toml:"name" json:"name" env:"DB_NAME" flag:"db-name" usage:"Database name"`
+	SSLMode         string        `yaml:"sslmode" toml:"sslmode" json:"sslmode" env:"DB_SSLMODE" usage:"Postgres sslmode"`
+	Migrations      string        `yaml:"migrations" toml:"migrations" json:"migrations" env:"DB_MIGRATIONS" flag:"db-migrations" usage:"Migration mode on start: auto, check or off"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" json:"max_open_conns" env:"DB_MAX_OPEN_CONNS" usage:"Maximum open connections (0 is unlimited)"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" json:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" usage:"Maximum idle connections"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" json:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" usage:"Maximum lifetime of a pooled connection"`
+}
+
+// DSN builds the Postgres connection string
+func (d DatabaseConfig) DSN() string {
+	return fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		d.Host, d.User, d.Password, d.Name, d.Port, d.SSLMode,
+	)
+}
+
+// BrokerConfig selects the message transport
+type BrokerConfig struct {
+	Driver string `yaml:"driver" toml:"driver" json:"driver" env:"BROKER_DRIVER" flag:"broker" usage:"Message broker: amqp, or memory to run every service in one process"`
+}
+
+// RabbitMQConfig configures the broker connection
+type RabbitMQConfig struct {
+	Host          string `yaml:"host" toml:"host" json:"host" env:"RABBITMQ_HOST" flag:"rabbitmq-host" usage:"RabbitMQ host"`
+	Port          int    `yaml:"port" toml:"port" json:"port" env:"RABBITMQ_PORT" flag:"rabbitmq-port" usage:"RabbitMQ port"`
+	User          string `yaml:"user" toml:"user" json:"user" env:"RABBITMQ_USER" flag:"rabbitmq-user" usage:"RabbitMQ user"`
+	Password      string `yaml:"password" toml:"password" json:"password" env:"RABBITMQ_PASSWORD" flag:"rabbitmq-password" usage:"RabbitMQ password" secret:"true"`
+	ManagementURL string `yaml:"management_url" toml:"management_url" json:"management_url" env:"RABBITMQ_MANAGEMENT_URL" usage:"RabbitMQ management API URL"`
+	Prefetch      int    `yaml:"prefetch" toml:"prefetch" json:"prefetch" env:"RABBITMQ_PREFETCH" usage:"Messages prefetched per consumer (0 is unlimited)"`
+}
+
+// URL builds the AMQP connection URL
+func (r RabbitMQConfig) URL() string {
+	address := url.URL{
+		Scheme: "amqp",
+		User:   url.UserPassword(r.User, r.Password),
+		Host:   fmt.Sprintf("%s:%d", r.Host, r.Port),
+	}
+	return address.String()
+}
+
+// ManagementBaseURL returns the management API URL, derived from the host when unset
+func (r RabbitMQConfig) ManagementBaseURL() string {
+	if r.ManagementURL != "" {
+		return r.ManagementURL
+	}
+	return fmt.Sprintf("http://%s:15672", r.Host)
+}
+
+// JWTConfig configures token signing
+type JWTConfig struct {
+	Secret string        `yaml:"secret" toml:"secret" json:"secret" env:"JWT_SECRET" flag:"jwt-secret" usage:"Secret used to sign JWTs" secret:"true"`
+	TTL    time.Duration `yaml:"ttl" toml:"ttl" json:"ttl" env:"JWT_TTL" usage:"Lifetime of issued JWTs"`
+}
+
+// ExchangesConfig names the RabbitMQ exchanges
+type ExchangesConfig struct {
+	UserDirect            string `yaml:"user_direct" toml:"user_direct" json:"user_direct" env:"EXCHANGE_USER_DIRECT"`
+	MessageDirect         string `yaml:"message_direct" toml:"message_direct" json:"message_direct" env:"EXCHANGE_MESSAGE_DIRECT"`
+	Notification          string `yaml:"notification" toml:"notification" json:"notification" env:"EXCHANGE_NOTIFICATION"`
+	NotificationBroadcast string `yaml:"notification_broadcast" toml:"notification_broadcast" json:"notification_broadcast" env:"EXCHANGE_NOTIFICATION_BROADCAST"`
+}
+
+// QueuesConfig names the durable service queues
+type QueuesConfig struct {
+	Registration  string `yaml:"registration" toml:"registration" json:"registration" env:"QUEUE_REGISTRATION"`
+	Login         string `yaml:"login" toml:"login" json:"login" env:"QUEUE_LOGIN"`
+	GetUsers      string `yaml:"get_users" toml:"get_users" json:"get_users" env:"QUEUE_GET_USERS"`
+	GetSelf       string `yaml:"get_self" toml:"get_self" json:"get_self" env:"QUEUE_GET_SELF"`
+	SearchUsers   string `yaml:"search_users" toml:"search_users" json:"search_users" env:"QUEUE_SEARCH_USERS"`
+	GetMessages   string `yaml:"get_messages" toml:"get_messages" json:"get_messages" env:"QUEUE_GET_MESSAGES"`
+	SendMessage   string `yaml:"send_message" toml:"send_message" json:"send_message" env:"QUEUE_SEND_MESSAGE"`
+	WebhookEvents string `yaml:"webhook_events" toml:"webhook_events" json:"webhook_events" env:"QUEUE_WEBHOOK_EVENTS"`
+}
+
+// TracingConfig configures the export of OpenTelemetry traces
+type TracingConfig struct {
+	Exporter     string `yaml:"exporter" toml:"exporter" json:"exporter" env:"TRACING_EXPORTER" flag:"tracing" usage:"Trace exporter: none, stdout or otlp"`
+	OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint" json:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT" flag:"otlp-endpoint" usage:"OTLP/HTTP traces URL, e.g. http://localhost:4318/v1/traces (empty uses the OTEL_EXPORTER_OTLP_* variables)"`
+}
+
+// LogConfig configures the process logger
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level" json:"level" env:"LOG_LEVEL" flag:"log-level" usage:"Minimum log level: debug, info, warn or error"`
+	Format string `yaml:"format" toml:"format" json:"format" env:"LOG_FORMAT" flag:"log-format" usage:"Log format: json or text"`
+	Redact bool   `yaml:"redact" toml:"redact" json:"redact" env:"LOG_REDACT" usage:"Mask credentials and message content in the logs"`
+}
+
+// Cfg is the configuration of the running command. It holds the defaults
+// until Load replaces it.
+var Cfg = Default()
+
+// Default returns the built-in configuration
+func Default() *Config {
+	return &Config{
+		HTTP: HTTPConfig{
+			Port:           "5000",
+			CORSOrigins:    []string{"http://localhost:3000"},
+			AdminPort:      "8081",
+			RequestTimeout: 10 * time.Second,
+			GRPCPort:       "9090",
+		},
+		WebSocket: WebSocketConfig{
+			PingInterval: 30 * time.Second,
+			PongTimeout:  60 * time.Second,
+			WriteTimeout: 10 * time.Second,
+			SendBuffer:   64,
+			SlowConsumer: "disconnect",
+			ReplayLimit:  500,
+		},
+		Poll: PollConfig{
+			Timeout:    25 * time.Second,
+			SessionTTL: time.Minute,
+		},
+		Bots: BotsConfig{
+			MaxPerOwner:      10,
+			DefaultRateLimit: 60,
+			MaxRateLimit:     600,
+		},
+		Webhooks: WebhooksConfig{
+			MaxPerUser:         10,
+			Timeout:            10 * time.Second,
+			MaxAttempts:        6,
+			MinBackoff:         30 * time.Second,
+			MaxBackoff:         time.Hour,
+			DisableAfter:       5,
+			PollInterval:       time.Second,
+			Concurrency:        8,
+			MaxIncomingPerUser: 10,
+			IncomingRateLimit:  30,
+		},
+		Scheduler: SchedulerConfig{
+			MaxPerUser:   100,
+			MaxDelay:     365 * 24 * time.Hour,
+			PollInterval: time.Second,
+			BatchSize:    100,
+			ClaimTimeout: time.Minute,
+		},
+		Database: DatabaseConfig{
+			Driver:          "postgres",
+			Path:            "instant_messaging_app.db",
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Name:            "instant_messaging_app",
+			SSLMode:         "disable",
+			Migrations:      "auto",
+			MaxOpenConns:    0,
+			MaxIdleConns:    2,
+			ConnMaxLifetime: time.Hour,
+		},
+		Broker: BrokerConfig{
+			Driver: "amqp",
+		},
+		RabbitMQ: RabbitMQConfig{
+			Host:     "localhost",
+			Port:     5672,
+			User:     "guest",
+			Password: "guest",
+		},
+		JWT: JWTConfig{
+			Secret: "default_secret",
+			TTL:    24 * time.Hour,
+		},
+		Exchanges: ExchangesConfig{
+			UserDirect:            "user_direct_exchange",
+			MessageDirect:         "message_direct_exchange",
+			Notification:          "notification_exchange",
+			NotificationBroadcast: "notification_broadcast_exchange",
+		},
+		Queues: QueuesConfig{
+			Registration:  "user_service_registration_queue",
+			Login:         "user_service_login_queue",
+			GetUsers:      "user_service_get_users_queue",
+			GetSelf:       "user_service_get_self_queue",
+			SearchUsers:   "user_service_search_users_queue",
+			GetMessages:   "message_service_get_messages_queue",
+			SendMessage:   "message_service_send_message_queue",
+			WebhookEvents: "webhook_service_events_queue",
+		},
+		Tracing: TracingConfig{
+			Exporter: "none",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+			Redact: true,
+		},
+	}
+}
+
+// Validate checks the configuration and reports every problem at once
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	// HTTP
+	port, err := strconv.Atoi(c.HTTP.Port)
+	check(err == nil && port > 0 && port < 65536, "http.port must be a TCP port, got %q", c.HTTP.Port)
+	if c.HTTP.AdminPort != "" {
+		adminPort, err := strconv.Atoi(c.HTTP.AdminPort)
+		check(err == nil && adminPort > 0 && adminPort < 65536, "http.admin_port must be a TCP port, got %q", c.HTTP.AdminPort)
+	}
+	if c.HTTP.GRPCPort != "" {
+		grpcPort, err := strconv.Atoi(c.HTTP.GRPCPort)
+		check(err == nil && grpcPort > 0 && grpcPort < 65536, "http.grpc_port must be a TCP port, got %q", c.HTTP.GRPCPort)
+		check(c.HTTP.GRPCPort != c.HTTP.Port, "http.grpc_port must differ from http.port")
+	}
+	check(len(c.HTTP.CORSOrigins) > 0, "http.cors_origins must list at least one origin")
+	check((c.HTTP.TLSCertFile == "") == (c.HTTP.TLSKeyFile == ""), "http.tls_cert_file and http.tls_key_file must be set together")
+	for _, file := range []string{c.HTTP.TLSCertFile, c.HTTP.TLSKeyFile} {
+		if file != "" {
+			_, err := os.Stat(file)
+			check(err == nil, "TLS file %s is not readable: %v", file, err)
+		}
+	}
+
+	// WebSocket
+	check(c.HTTP.RequestTimeout > 0, "http.request_timeout must be positive")
+	check(c.WebSocket.PingInterval > 0, "websocket.ping_interval must be positive")
+	check(c.WebSocket.PongTimeout > c.WebSocket.PingInterval,
+		"websocket.pong_timeout (%s) must exceed websocket.ping_interval (%s)", c.WebSocket.PongTimeout, c.WebSocket.PingInterval)
+	check(c.WebSocket.WriteTimeout > 0, "websocket.write_timeout must be positive")
+	check(c.WebSocket.SendBuffer > 0, "websocket.send_buffer must be positive")
+	check(oneOf(c.WebSocket.SlowConsumer, "drop", "disconnect"), "websocket.slow_consumer must be drop or disconnect, got %q", c.WebSocket.SlowConsumer)
+	check(c.WebSocket.ReplayLimit > 0, "websocket.replay_limit must be positive")
+	check(c.Poll.Timeout > 0, "poll.timeout must be positive")
+	check(c.Poll.SessionTTL > c.Poll.Timeout,
+		"poll.session_ttl (%s) must exceed poll.timeout (%s)", c.Poll.SessionTTL, c.Poll.Timeout)
+
+	// Bots
+	check(c.Bots.MaxPerOwner >= 0, "bots.max_per_owner must not be negative")
+	check(c.Bots.DefaultRateLimit > 0, "bots.default_rate_limit must be positive")
+	check(c.Bots.MaxRateLimit >= c.Bots.DefaultRateLimit,
+		"bots.max_rate_limit (%d) must not be below bots.default_rate_limit (%d)", c.Bots.MaxRateLimit, c.Bots.DefaultRateLimit)
+
+	// Webhooks
+	check(c.Webhooks.MaxPerUser >= 0, "webhooks.max_per_user must not be negative")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive")
+	check(c.Webhooks.MinBackoff > 0, "webhooks.min_backoff must be positive")
+	check(c.Webhooks.MaxBackoff >= c.Webhooks.MinBackoff,
+		"webhooks.max_backoff (%s) must not be below webhooks.min_backoff (%s)", c.Webhooks.MaxBackoff, c.Webhooks.MinBackoff)
+	check(c.Webhooks.DisableAfter > 0, "webhooks.disable_after must be positive")
+	check(c.Webhooks.PollInterval > 0, "webhooks.poll_interval must be positive")
+	check(c.Webhooks.Concurrency > 0, "webhooks.concurrency must be positive")
+	check(c.Webhooks.MaxIncomingPerUser >= 0, "webhooks.max_incoming_per_user must not be negative")
+	check(c.Webhooks.IncomingRateLimit > 0, "webhooks.incoming_rate_limit must be positive")
+
+	// Scheduler
+	check(c.Scheduler.MaxPerUser >= 0, "scheduler.max_per_user must not be negative")
+	check(c.Scheduler.MaxDelay > 0, "scheduler.max_delay must be positive")
+	check(c.Scheduler.PollInterval > 0, "scheduler.poll_interval must be positive")
+	check(c.Scheduler.BatchSize > 0, "scheduler.batch_size must be positive")
+	check(c.Scheduler.ClaimTimeout > 0, "scheduler.claim_timeout must be positive")
+
+	// Database
+	check(oneOf(c.Database.Driver, "postgres", "sqlite"), "database.driver must be postgres or sqlite, got %q", c.Database.Driver)
+	if c.Database.Driver == "sqlite" {
+		check(c.Database.Path != "", "database.path is required with the sqlite driver")
+	} else {
+		check(c.Database.Host != "", "database.host is required")
+		check(c.Database.Port > 0 && c.Database.Port < 65536, "database.port must be a TCP port, got %d", c.Database.Port)
+		check(c.Database.User != "", "database.user is required")
+		check(c.Database.Name != "", "database.name is required")
+	}
+	check(oneOf(c.Database.Migrations, "auto", "check", "off"), "database.migrations must be auto, check or off, got %q", c.Database.Migrations)
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+
+	// Broker
+	check(oneOf(c.Broker.Driver, "amqp", "memory"), "broker.driver must be amqp or memory, got %q", c.Broker.Driver)
+
+	// RabbitMQ
+	check(c.RabbitMQ.Host != "", "rabbitmq.host is required")
+	check(c.RabbitMQ.Port > 0 && c.RabbitMQ.Port < 65536, "rabbitmq.port must be a TCP port, got %d", c.RabbitMQ.Port)
+	check(c.RabbitMQ.Prefetch >= 0, "rabbitmq.prefetch must not be negative")
+	if c.RabbitMQ.ManagementURL != "" {
+		_, err := url.ParseRequestURI(c.RabbitMQ.ManagementURL)
+		check(err == nil, "rabbitmq.management_url is not a valid URL: %v", err)
+	}
+
+	// JWT
+	check(c.JWT.Secret != "", "jwt.secret is required")
+	check(c.JWT.TTL > 0, "jwt.ttl must be positive")
+
+	// Tracing
+	check(oneOf(c.Tracing.Exporter, "none", "stdout", "otlp"), "tracing.exporter must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	if c.Tracing.OTLPEndpoint != "" {
+		_, err := url.ParseRequestURI(c.Tracing.OTLPEndpoint)
+		check(err == nil, "tracing.otlp_endpoint is not a valid URL: %v", err)
+	}
+
+	// Logging
+	check(oneOf(strings.ToLower(c.Log.Level), "debug", "info", "warn", "error"), "log.level must be debug, info, warn or error, got %q", c.Log.Level)
+	check(oneOf(c.Log.Format, "json", "text"), "log.format must be json or text, got %q", c.Log.Format)
+
+	// Exchange and queue names
+	for name, value := range map[string]string{
+		"exchanges.user_direct":            c.Exchanges.UserDirect,
+		"exchanges.message_direct":         c.Exchanges.MessageDirect,
+		"exchanges.notification":           c.Exchanges.Notification,
+		"exchanges.notification_broadcast": c.Exchanges.NotificationBroadcast,
+		"queues.registration":              c.Queues.Registration,
+		"queues.login":                     c.Queues.Login,
+		"queues.get_users":                 c.Queues.GetUsers,
+		"queues.get_self":                  c.Queues.GetSelf,
+		"queues.search_users":              c.Queues.SearchUsers,
+		"queues.get_messages":              c.Queues.GetMessages,
+		"queues.send_message":              c.Queues.SendMessage,
+		"queues.webhook_events":            c.Queues.WebhookEvents,
+	} {
+		check(value != "", "%s must not be empty", name)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return errors.New("invalid configuration:\n  - " + strings.Join(problems, "\n  - "))
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
@@ -1,40 +1,93 @@
 package config
 
 import (
-	"fmt"
-	"log"
-	"os"
+	"log/slog"
 
-	"instant-messaging-app/models"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/metrics"
+	"instant-messaging-app/migrations"
+	"instant-messaging-app/tracing"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
+// InitDatabase connects to the database and applies the schema migrations
+// according to the database.migrations setting:
+//   - "auto" (default) applies pending migrations under an advisory lock
+//   - "check" refuses to start while migrations are pending
+//   - "off" skips migrations entirely
 func InitDatabase() {
+	ConnectDatabase()
+
+	switch mode := Cfg.Database.Migrations; mode {
+	case "", "auto":
+		applied, err := migrations.Up(DB, 0)
+		if err != nil {
+			logging.Fatal("Error during schema migration", "error", err)
+		}
+		for _, migration := range applied {
+			slog.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+		}
+	case "check":
+		pending, err := migrations.Pending(DB)
+		if err != nil {
+			logging.Fatal("Unable to check schema migrations", "error", err)
+		}
+		if len(pending) > 0 {
+			logging.Fatal("Refusing to start with pending migrations, run `migrate up` first",
+				"pending", len(pending), "version", pending[0].Version, "name", pending[0].Name)
+		}
+	case "off":
+		slog.Warn("Schema migrations disabled")
+	default:
+		logging.Fatal("Invalid migration mode: must be auto, check or off", "mode", mode)
+	}
+
+	slog.Info("Database connection and migration successful", "driver", Cfg.Database.Driver)
+}
+
+// ConnectDatabase opens the database connection without touching the schema
+func ConnectDatabase() {
 	var err error
 
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		os.Getenv("DB_PORT"),
-	)
+	// Report failed and slow statements through the structured logger
+	gormConfig := &gorm.Config{Logger: logging.NewGormLogger(Cfg.Log.Redact)}
 
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	switch Cfg.Database.Driver {
+	case "sqlite":
+		// Foreign keys are off by default in SQLite and concurrent writers
+		// should wait for the lock instead of failing immediately
+		dsn := Cfg.Database.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+		DB, err = gorm.Open(sqlite.Open(dsn), gormConfig)
+	default:
+		DB, err = gorm.Open(postgres.Open(Cfg.Database.DSN()), gormConfig)
+	}
 	if err != nil {
-		log.Fatalf("Unable to connect to the database: %v", err)
+		logging.Fatal("Unable to connect to the database", "driver", Cfg.Database.Driver, "error", err)
 	}
 
-	// Model migrations
-	err = DB.AutoMigrate(&models.User{}, &models.Message{})
-	if err != nil {
-		log.Fatalf("Error during model migration: %v", err)
+	// Export statement latencies and pool statistics
+	if err := metrics.InstrumentDB(DB); err != nil {
+		slog.Warn("Unable to instrument the database", "error", err)
+	}
+	if err := tracing.InstrumentDB(DB); err != nil {
+		slog.Warn("Unable to trace the database", "error", err)
 	}
 
-	log.Println("Database connection and migration successful!")
-}
\ No newline at end of file
+	// Size the connection pool
+	sqlDB, err := DB.DB()
+	if err != nil {
+		logging.Fatal("Unable to access the database pool", "error", err)
+	}
+	sqlDB.SetMaxOpenConns(Cfg.Database.MaxOpenConns)
+	if Cfg.Database.Driver == "sqlite" {
+		// SQLite allows a single writer; one connection avoids "database is locked"
+		sqlDB.SetMaxOpenConns(1)
+	}
+	sqlDB.SetMaxIdleConns(Cfg.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(Cfg.Database.ConnMaxLifetime)
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileFlag selects the YAML or TOML configuration file
+const ConfigFileFlag = "config"
+
+// redacted replaces secret values when the configuration is printed
+const redacted = "********"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Flags returns the global command line flags generated from the `flag` tags
+// of Config, plus the --config file flag
+func Flags() []cli.Flag {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:    ConfigFileFlag,
+			Usage:   "Path to a YAML or TOML configuration file",
+			EnvVars: []string{"CONFIG_FILE"},
+		},
+	}
+
+	walkFields(reflect.ValueOf(Default()).Elem(), func(field reflect.StructField, value reflect.Value) {
+		name := field.Tag.Get("flag")
+		if name == "" {
+			return
+		}
+		// Every flag is read as a string and parsed like its environment variable
+		flags = append(flags, &cli.StringFlag{
+			Name:  name,
+			Usage: fmt.Sprintf("%s (env %s)", field.Tag.Get("usage"), field.Tag.Get("env")),
+		})
+	})
+	return flags
+}
+
+// Load builds the configuration from the defaults, the configuration file,
+// the environment and the flags set on c, in increasing order of precedence
+func Load(c *cli.Context) (*Config, error) {
+	cfg := Default()
+
+	// Configuration file
+	if path := c.String(ConfigFileFlag); path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	// Environment variables, then flags
+	var err error
+	walkFields(reflect.ValueOf(cfg).Elem(), func(field reflect.StructField, value reflect.Value) {
+		if err != nil {
+			return
+		}
+		if name := field.Tag.Get("env"); name != "" {
+			if raw, ok := os.LookupEnv(name); ok && raw != "" {
+				if parseErr := setValue(value, raw); parseErr != nil {
+					err = fmt.Errorf("invalid value for %s: %w", name, parseErr)
+					return
+				}
+			}
+		}
+		if name := field.Tag.Get("flag"); name != "" && c.IsSet(name) {
+			if parseErr := setValue(value, c.String(name)); parseErr != nil {
+				err = fmt.Errorf("invalid value for --%s: %w", name, parseErr)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile decodes a YAML or TOML file over cfg, chosen by its extension
+func loadFile(cfg *Config, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(content), cfg)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown keys in %s: %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("unsupported configuration file %s: use .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration with every secret masked
+func (c *Config) Redacted() *Config {
+	clone := *c
+	clone.HTTP.CORSOrigins = append([]string(nil), c.HTTP.CORSOrigins...)
+
+	walkFields(reflect.ValueOf(&clone).Elem(), func(field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get("secret") == "true" && value.String() != "" {
+			value.SetString(redacted)
+		}
+	})
+	return &clone
+}
+
+// Print writes the redacted configuration in the given format (yaml, toml or json)
+func (c *Config) Print(format string) (string, error) {
+	safe := c.Redacted()
+
+	switch format {
+	case "yaml", "":
+		out, err := yaml.Marshal(safe)
+		return string(out), err
+	case "toml":
+		var buf bytes.Buffer
+		err := toml.NewEncoder(&buf).Encode(safe)
+		return buf.String(), err
+	case "json":
+		out, err := json.MarshalIndent(safe, "", "  ")
+		return string(out) + "\n", err
+	default:
+		return "", fmt.Errorf("unknown format %q: use yaml, toml or json", format)
+	}
+}
+
+// walkFields calls fn for every leaf field of the nested configuration structs
+func walkFields(v reflect.Value, fn func(field reflect.StructField, value reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := v.Field(i)
+		if value.Kind() == reflect.Struct {
+			walkFields(value, fn)
+			continue
+		}
+		fn(field, value)
+	}
+}
+
+// setValue parses raw into the field according to its type
+func setValue(value reflect.Value, raw string) error {
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int:
+		number, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		value.SetInt(int64(number))
+	case reflect.Bool:
+		flag, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		value.SetBool(flag)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported configuration field type %s", value.Type())
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package config
+
+import (
+	"os"
+
+	"instant-messaging-app/logging"
+)
+
+// SetupLogging installs the structured logger configured by the log section.
+// Lines are written to stderr, like the standard logger.
+func SetupLogging() error {
+	return logging.Setup(os.Stderr, logging.Options{
+		Level:  Cfg.Log.Level,
+		Format: Cfg.Log.Format,
+		Redact: Cfg.Log.Redact,
+	})
+}
@@ -2,143 +2,116 @@ package config
 
 import (
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 
-	amqp "github.com/rabbitmq/amqp091-go"
+	"instant-messaging-app/broker"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/metrics"
+	"instant-messaging-app/tracing"
 )
 
-var RabbitMQConn *amqp.Connection
-var RabbitMQCh *amqp.Channel
+// Broker is the message transport shared by every package of the process
+var Broker broker.Broker
 
-// SetupRabbitMQ initializes the global RabbitMQ connection and channel
-func SetupRabbitMQ() {
-	addr := fmt.Sprintf(
-		"amqp://%s:%s@%s:%s",
-		os.Getenv("RABBITMQ_USER"),
-		os.Getenv("RABBITMQ_PASSWORD"),
-		os.Getenv("RABBITMQ_HOST"),
-		os.Getenv("RABBITMQ_PORT"),
-	)
-
-	fmt.Println(addr)
+// memoryBroker is shared by every service started in this process when the
+// memory driver is selected, so that they can talk to each other
+var memoryBroker *broker.MemoryBroker
 
-	var err error
-	RabbitMQConn, err = amqp.Dial(addr)
-	if err != nil {
-		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
+// SetupRabbitMQ initializes the global broker according to broker.driver
+func SetupRabbitMQ() {
+	if Cfg.Broker.Driver == "memory" {
+		if memoryBroker == nil {
+			memoryBroker = broker.NewMemoryBroker()
+		}
+		Broker = tracing.InstrumentBroker(metrics.InstrumentBroker(memoryBroker), "memory")
+		slog.Info("In-memory broker initialized")
+		return
 	}
 
-	RabbitMQCh, err = RabbitMQConn.Channel()
+	// The URL embeds the password, log its parts instead
+	slog.Info("Connecting to RabbitMQ", "host", Cfg.RabbitMQ.Host, "port", Cfg.RabbitMQ.Port, "user", Cfg.RabbitMQ.User)
+	amqpBroker, err := broker.NewAMQPBroker(Cfg.RabbitMQ.URL(), Cfg.RabbitMQ.Prefetch)
 	if err != nil {
-		log.Fatalf("Failed to open RabbitMQ channel: %v", err)
+		logging.Fatal("Failed to connect to RabbitMQ", "host", Cfg.RabbitMQ.Host, "port", Cfg.RabbitMQ.Port, "error", err)
 	}
+	Broker = tracing.InstrumentBroker(metrics.InstrumentBroker(amqpBroker), "rabbitmq")
 
-	log.Println("RabbitMQ connection and channel initialized.")
+	slog.Info("RabbitMQ connection and channel initialized")
 }
 
 // InitQueue sets up a durable queue
 func InitQueue(queueName string) {
-	_, err := RabbitMQCh.QueueDeclare(
-		queueName, // Queue name
-		true,      // Durable
-		false,     // Auto-delete
-		false,     // Exclusive
-		false,     // No-wait
-		nil,       // Arguments
-	)
+	err := Broker.DeclareQueue(queueName, broker.QueueOptions{Durable: true})
 	if err != nil {
-		log.Fatalf("Failed to declare queue: %v", err)
+		logging.Fatal("Failed to declare queue", "queue", queueName, "error", err)
 	}
-	log.Printf("Queue declared: %s", queueName)
+	slog.Debug("Queue declared", "queue", queueName)
 }
 
 // BindQueueToExchange binds a queue to an exchange with a specific routing key
 func BindQueueToExchange(queueName, exchangeName, routingKey string) {
-	err := RabbitMQCh.QueueBind(
-		queueName,    // Queue name
-		routingKey,   // Routing key
-		exchangeName, // Exchange name
-		false,
-		nil,
-	)
+	err := Broker.BindQueue(queueName, exchangeName, routingKey)
 	if err != nil {
-		log.Fatalf("Failed to bind queue %s to exchange %s: %v", queueName, exchangeName, err)
+		logging.Fatal("Failed to bind queue", "queue", queueName, "exchange", exchangeName, "error", err)
 	}
-	log.Printf("Queue %s bound to exchange %s with routing key %s", queueName, exchangeName, routingKey)
+	slog.Debug("Queue bound", "queue", queueName, "exchange", exchangeName, "routing_key", routingKey)
 }
 
 // InitDirectRabbitMQExchange sets up a direct exchange for notifications
 func InitDirectRabbitMQExchange(exchangeName string) {
-	err := RabbitMQCh.ExchangeDeclare(
-		exchangeName, // Exchange name
-		"direct",     // Type
-		true,         // Durable
-		false,        // Auto-deleted
-		false,        // Internal
-		false,        // No-wait
-		nil,          // Arguments
-	)
+	err := Broker.DeclareExchange(exchangeName, broker.ExchangeDirect)
 	if err != nil {
-		log.Fatalf("Failed to declare direct exchange %s: %v", exchangeName, err)
+		logging.Fatal("Failed to declare direct exchange", "exchange", exchangeName, "error", err)
 	}
-	log.Printf("Declared RabbitMQ direct exchange: %s", exchangeName)
+	slog.Debug("Declared direct exchange", "exchange", exchangeName)
 }
 
 func InitFanoutRabbitMQExchange(exchangeName string) {
-	err := RabbitMQCh.ExchangeDeclare(
-		exchangeName, // Exchange name
-		"fanout",     // Type
-		true,         // Durable
-		false,        // Auto-deleted
-		false,        // Internal
-		false,        // No-wait
-		nil,          // Arguments
-	)
+	err := Broker.DeclareExchange(exchangeName, broker.ExchangeFanout)
 	if err != nil {
-		log.Fatalf("Failed to declare fanout exchange %s: %v", exchangeName, err)
+		logging.Fatal("Failed to declare fanout exchange", "exchange", exchangeName, "error", err)
 	}
-	log.Printf("Declared RabbitMQ fanout exchange: %s", exchangeName)
+	slog.Debug("Declared fanout exchange", "exchange", exchangeName)
 }
 
-// CleanupRabbitMQ closes the RabbitMQ connection and channel
+// CleanupRabbitMQ closes the broker connection. The shared in-memory broker
+// outlives the individual services and is left open.
 func CleanupRabbitMQ() {
-	if RabbitMQCh != nil {
-		if err := RabbitMQCh.Close(); err != nil {
-			log.Printf("Failed to close RabbitMQ channel: %v", err)
-		}
+	if Broker == nil || Cfg.Broker.Driver == "memory" {
+		return
 	}
-	if RabbitMQConn != nil {
-		if err := RabbitMQConn.Close(); err != nil {
-			log.Printf("Failed to close RabbitMQ connection: %v", err)
-		}
+	if err := Broker.Close(); err != nil {
+		slog.Error("Failed to close RabbitMQ connection", "error", err)
 	}
-	log.Println("RabbitMQ connection and channel closed.")
+	slog.Info("RabbitMQ connection and channel closed")
 }
 
 // queueExists checks if a RabbitMQ queue exists
 func QueueExists(queueName string) bool {
-	_, err := RabbitMQCh.QueueDeclarePassive(
-		queueName, // Queue name
-		true,      // Durable
-		true,      // Auto-delete
-		false,     // Exclusive
-		false,     // No-wait
-		nil,       // Arguments
-	)
-	if err != nil {
-		log.Printf("Queue %s does not exist: %v", queueName, err)
+	if !Broker.QueueExists(queueName) {
+		slog.Debug("Queue does not exist", "queue", queueName)
 		return false
 	}
 	return true
 }
 
 func CleanupQueue(queueName string) error {
-	_, err := RabbitMQCh.QueueDelete(
-		queueName, // Queue name
-		false,     // IfUnused
-		false,     // IfEmpty
-		false,     // NoWait
-	)
-	return err
-}
\ No newline at end of file
+	return Broker.DeleteQueue(queueName)
+}
+
+// DeclareConnectionQueue declares the auto-deleted queue of a client
+// connection and binds it to the notification exchanges
+func DeclareConnectionQueue(queueName string, broadcast bool) error {
+	if err := Broker.DeclareQueue(queueName, broker.QueueOptions{Durable: true, AutoDelete: true}); err != nil {
+		return fmt.Errorf("failed to declare queue %s: %w", queueName, err)
+	}
+	if err := Broker.BindQueue(queueName, Cfg.Exchanges.Notification, queueName); err != nil {
+		return fmt.Errorf("failed to bind queue %s: %w", queueName, err)
+	}
+	if broadcast {
+		if err := Broker.BindQueue(queueName, Cfg.Exchanges.NotificationBroadcast, ""); err != nil {
+			return fmt.Errorf("failed to bind queue %s: %w", queueName, err)
+		}
+	}
+	return nil
+}
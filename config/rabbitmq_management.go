@@ -0,0 +1,86 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// connectionQueuePattern matches the UUID named queues created per WebSocket connection
+var connectionQueuePattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// QueueInfo is the subset of the RabbitMQ management queue description we use
+type QueueInfo struct {
+	Name       string `json:"name"`
+	VHost      string `json:"vhost"`
+	Messages   int    `json:"messages"`
+	Consumers  int    `json:"consumers"`
+	Durable    bool   `json:"durable"`
+	AutoDelete bool   `json:"auto_delete"`
+	IdleSince  string `json:"idle_since,omitempty"`
+}
+
+// IsConnectionQueue reports whether the queue was created for a WebSocket connection
+func (q QueueInfo) IsConnectionQueue() bool {
+	return connectionQueuePattern.MatchString(q.Name)
+}
+
+// IsOrphaned reports whether the queue is a per-connection queue nobody consumes anymore
+func (q QueueInfo) IsOrphaned() bool {
+	return q.IsConnectionQueue() && q.Consumers == 0
+}
+
+// ListRabbitMQQueues lists every queue through the RabbitMQ management API
+func ListRabbitMQQueues() ([]QueueInfo, error) {
+	var queues []QueueInfo
+	err := getRabbitMQManagement("/api/queues", &queues)
+	return queues, err
+}
+
+// CountRabbitMQConnections returns the number of open AMQP connections
+func CountRabbitMQConnections() (int, error) {
+	var connections []struct {
+		Name string `json:"name"`
+	}
+	if err := getRabbitMQManagement("/api/connections", &connections); err != nil {
+		return 0, err
+	}
+	return len(connections), nil
+}
+
+// getRabbitMQManagement performs an authenticated GET against the management API
+func getRabbitMQManagement(path string, target interface{}) error {
+	base := Cfg.RabbitMQ.ManagementBaseURL()
+
+	endpoint, err := url.JoinPath(base, path)
+	if err != nil {
+		return fmt.Errorf("invalid management URL %s: %w", base, err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(Cfg.RabbitMQ.User, Cfg.RabbitMQ.Password)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach RabbitMQ management API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("RabbitMQ management API returned %s for %s", resp.Status, path)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// PurgeQueue removes every ready message from a queue and returns how many were dropped
+func PurgeQueue(queueName string) (int, error) {
+	return Broker.PurgeQueue(queueName)
+}
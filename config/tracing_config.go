@@ -0,0 +1,43 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"instant-messaging-app/logging"
+	"instant-messaging-app/tracing"
+)
+
+// shutdownTracing flushes the spans of the tracer provider installed by SetupTracing
+var shutdownTracing func(context.Context) error
+
+// SetupTracing installs the trace exporter selected by tracing.exporter;
+// service is reported as the service.name of every span
+func SetupTracing(service string) {
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    Cfg.Tracing.Exporter,
+		Endpoint:    Cfg.Tracing.OTLPEndpoint,
+		ServiceName: service,
+	})
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "exporter", Cfg.Tracing.Exporter, "error", err)
+	}
+	shutdownTracing = shutdown
+
+	if Cfg.Tracing.Exporter != "none" {
+		slog.Info("Tracing enabled", "exporter", Cfg.Tracing.Exporter, "service", service)
+	}
+}
+
+// ShutdownTracing exports the spans still buffered before the process exits
+func ShutdownTracing() {
+	if shutdownTracing == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+}
@@ -0,0 +1,101 @@
+package docs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Operation is one operation of the OpenAPI document
+type Operation struct {
+	Method string
+	Path   string
+}
+
+// Operations lists the operations of the OpenAPI document, with the path
+// parameters written the Fiber way (:userId)
+func Operations() ([]Operation, error) {
+	var document struct {
+		Paths map[string]map[string]yaml.Node `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(OpenAPI, &document); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	var operations []Operation
+	for path, item := range document.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			operations = append(operations, Operation{
+				Method: strings.ToUpper(method),
+				Path:   fiberPath(path),
+			})
+		}
+	}
+	sortOperations(operations)
+	return operations, nil
+}
+
+// CheckRoutes compares the /api routes registered on app with the OpenAPI
+// document and reports the routes that are missing from either
+func CheckRoutes(app *fiber.App) error {
+	documented, err := Operations()
+	if err != nil {
+		return err
+	}
+
+	registered := map[Operation]bool{}
+	for _, route := range app.GetRoutes(true) {
+		// Fiber adds a HEAD route to every GET route
+		if route.Method == fiber.MethodHead || !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		registered[Operation{Method: route.Method, Path: route.Path}] = true
+	}
+
+	var problems []string
+	for _, operation := range documented {
+		if !registered[operation] {
+			problems = append(problems, fmt.Sprintf("%s %s is documented but not registered", operation.Method, operation.Path))
+		}
+		delete(registered, operation)
+	}
+	undocumented := make([]Operation, 0, len(registered))
+	for operation := range registered {
+		undocumented = append(undocumented, operation)
+	}
+	sortOperations(undocumented)
+	for _, operation := range undocumented {
+		problems = append(problems, fmt.Sprintf("%s %s is registered but not documented", operation.Method, operation.Path))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("the OpenAPI document does not match the routes:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// fiberPath turns the {param} segments of an OpenAPI path into :param
+func fiberPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}")
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func sortOperations(operations []Operation) {
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Path != operations[j].Path {
+			return operations[i].Path < operations[j].Path
+		}
+		return operations[i].Method < operations[j].Method
+	})
+}
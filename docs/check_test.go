@@ -0,0 +1,21 @@
+package docs_test
+
+import (
+	"context"
+	"testing"
+
+	"instant-messaging-app/api/routes"
+	"instant-messaging-app/docs"
+	"instant-messaging-app/repositories"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	// The routes only capture the repositories, so none is needed
+	app := fiber.New()
+	routes.SetupRoutes(app, context.Background(), repositories.Repositories{})
+	if err := docs.CheckRoutes(app); err != nil {
+		t.Fatal(err)
+	}
+}
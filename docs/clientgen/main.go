@@ -0,0 +1,447 @@
+// Command clientgen generates the Go client of the REST API from the OpenAPI
+// document of the docs package. Run it through go generate in the client
+// package.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"instant-messaging-app/docs"
+
+	"gopkg.in/yaml.v3"
+)
+
+type document struct {
+	Paths      map[string]map[string]*operation `yaml:"paths"`
+	Components struct {
+		Schemas    map[string]*schema    `yaml:"schemas"`
+		Parameters map[string]*parameter `yaml:"parameters"`
+		Responses  map[string]*response  `yaml:"responses"`
+	} `yaml:"components"`
+}
+
+type operation struct {
+	OperationID string               `yaml:"operationId"`
+	Summary     string               `yaml:"summary"`
+	Description string               `yaml:"description"`
+	Deprecated  bool                 `yaml:"deprecated"`
+	Parameters  []*parameter         `yaml:"parameters"`
+	RequestBody *body                `yaml:"requestBody"`
+	Responses   map[string]*response `yaml:"responses"`
+}
+
+type parameter struct {
+	Ref         string  `yaml:"$ref"`
+	Name        string  `yaml:"name"`
+	In          string  `yaml:"in"`
+	Description string  `yaml:"description"`
+	Schema      *schema `yaml:"schema"`
+}
+
+type body struct {
+	Content map[string]struct {
+		Schema *schema `yaml:"schema"`
+	} `yaml:"content"`
+}
+
+type response struct {
+	Ref  string `yaml:"$ref"`
+	body `yaml:",inline"`
+}
+
+type schema struct {
+	Ref         string     `yaml:"$ref"`
+	Type        string     `yaml:"type"`
+	Format      string     `yaml:"format"`
+	Description string     `yaml:"description"`
+	Nullable    bool       `yaml:"nullable"`
+	Required    []string   `yaml:"required"`
+	Properties  properties `yaml:"properties"`
+	Items       *schema    `yaml:"items"`
+	Enum        []string   `yaml:"enum"`
+}
+
+// properties keeps the properties of a schema in the order of the document
+type properties struct {
+	names   []string
+	schemas map[string]*schema
+}
+
+func (p *properties) UnmarshalYAML(node *yaml.Node) error {
+	p.schemas = map[string]*schema{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var s schema
+		if err := node.Content[i+1].Decode(&s); err != nil {
+			return err
+		}
+		p.names = append(p.names, node.Content[i].Value)
+		p.schemas[node.Content[i].Value] = &s
+	}
+	return nil
+}
+
+// methods is the order in which the operations of a path are generated
+var methods = []string{"get", "post", "put", "patch", "delete"}
+
+func main() {
+	out := flag.String("o", "client.gen.go", "output file")
+	pkg := flag.String("package", "client", "package name")
+	skip := flag.String("skip", "Error", "comma separated schemas declared by hand in the package")
+	flag.Parse()
+
+	var doc document
+	if err := yaml.Unmarshal(docs.OpenAPI, &doc); err != nil {
+		log.Fatalf("invalid OpenAPI document: %v", err)
+	}
+
+	g := &generator{doc: &doc, skip: map[string]bool{}}
+	for _, name := range strings.Split(*skip, ",") {
+		g.skip[name] = true
+	}
+	g.schemas()
+	if err := g.operations(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Import the packages the generated code uses
+	var file bytes.Buffer
+	file.WriteString("// Code generated by clientgen from docs/openapi.yaml. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&file, "package %s\n\nimport (\n", *pkg)
+	for _, imported := range []string{"context", "encoding/json", "fmt", "net/http", "net/url", "time"} {
+		if bytes.Contains(g.buf.Bytes(), []byte(imported[strings.LastIndex(imported, "/")+1:]+".")) {
+			fmt.Fprintf(&file, "%q\n", imported)
+		}
+	}
+	file.WriteString(")\n\n")
+	file.Write(g.buf.Bytes())
+
+	source, err := format.Source(file.Bytes())
+	if err != nil {
+		log.Fatalf("invalid generated code: %v\n%s", err, file.Bytes())
+	}
+	if err := os.WriteFile(*out, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type generator struct {
+	doc  *document
+	skip map[string]bool
+	buf  bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// schemas generates a struct per component schema
+func (g *generator) schemas() {
+	for _, name := range sortedKeys(g.doc.Components.Schemas) {
+		if g.skip[name] {
+			continue
+		}
+		s := g.doc.Components.Schemas[name]
+		comment(&g.buf, name+" is the "+name+" schema of the API", s.Description)
+		g.printf("type %s %s\n\n", name, g.goType(s, true))
+	}
+}
+
+// goType returns the Go type of a schema; top level objects become structs
+func (g *generator) goType(s *schema, top bool) string {
+	if s.Ref != "" {
+		return refName(s.Ref)
+	}
+	switch s.Type {
+	case "integer":
+		switch s.Format {
+		case "uint64":
+			return "uint64"
+		case "int64":
+			return "int64"
+		default:
+			return "int"
+		}
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "string":
+		if s.Format == "date-time" {
+			if s.Nullable {
+				return "*time.Time"
+			}
+			return "time.Time"
+		}
+		return "string"
+	case "array":
+		return "[]" + g.goType(s.Items, false)
+	case "object":
+		if len(s.Properties.names) == 0 {
+			return "json.RawMessage"
+		}
+		if !top {
+			log.Fatalf("nested object schemas are not supported, declare them in components")
+		}
+		var b bytes.Buffer
+		b.WriteString("struct {\n")
+		required := map[string]bool{}
+		for _, name := range s.Required {
+			required[name] = true
+		}
+		for _, name := range s.Properties.names {
+			property := s.Properties.schemas[name]
+			if property.Description != "" {
+				fmt.Fprintf(&b, "// %s\n", property.Description)
+			}
+			tag := name
+			if !required[name] {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&b, "%s %s `json:%q`\n", goName(name), g.goType(property, false), tag)
+		}
+		b.WriteString("}")
+		return b.String()
+	}
+	log.Fatalf("unsupported schema type %q", s.Type)
+	return ""
+}
+
+// operations generates a method per operation answering JSON
+func (g *generator) operations() error {
+	for _, path := range sortedKeys(g.doc.Paths) {
+		item := g.doc.Paths[path]
+		for _, method := range methods {
+			op, ok := item[method]
+			if !ok {
+				continue
+			}
+			if err := g.operation(strings.ToUpper(method), path, op); err != nil {
+				return fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *generator) operation(method, path string, op *operation) error {
+	status, result := g.result(op)
+	if status == "" {
+		// Streams and documents are not JSON, so they have no method
+		return nil
+	}
+	name := exported(op.OperationID)
+
+	var pathParams, queryParams []*parameter
+	for _, p := range op.Parameters {
+		p = g.parameter(p)
+		switch p.In {
+		case "path":
+			pathParams = append(pathParams, p)
+		case "query":
+			queryParams = append(queryParams, p)
+		}
+	}
+
+	// The query parameters are passed as a struct
+	paramsType := name + "Params"
+	if len(queryParams) > 0 {
+		g.printf("// %s holds the query parameters of %s\n", paramsType, name)
+		g.printf("type %s struct {\n", paramsType)
+		for _, p := range queryParams {
+			if p.Description != "" {
+				g.printf("// %s\n", p.Description)
+			}
+			g.printf("%s %s\n", goName(p.Name), g.goType(p.Schema, false))
+		}
+		g.printf("}\n\n")
+	}
+
+	args := []string{"ctx context.Context"}
+	pathFormat := path
+	var pathValues []string
+	for _, p := range pathParams {
+		arg := unexported(goName(p.Name))
+		args = append(args, fmt.Sprintf("%s %s", arg, g.goType(p.Schema, false)))
+		pathFormat = strings.Replace(pathFormat, "{"+p.Name+"}", "%s", 1)
+		pathValues = append(pathValues, fmt.Sprintf("url.PathEscape(fmt.Sprint(%s))", arg))
+	}
+	if len(queryParams) > 0 {
+		args = append(args, "params "+paramsType)
+	}
+	bodyArg := "nil"
+	if op.RequestBody != nil {
+		bodySchema := op.RequestBody.Content["application/json"].Schema
+		if bodySchema == nil {
+			return fmt.Errorf("the request body is not JSON")
+		}
+		args = append(args, "body "+g.goType(bodySchema, false))
+		bodyArg = "body"
+	}
+
+	summary := fmt.Sprintf("%s calls %s %s", name, method, path)
+	if op.Summary != "" {
+		summary += ": " + lowerFirst(op.Summary)
+	}
+	comment(&g.buf, summary, op.Description)
+	if op.Deprecated {
+		g.printf("//\n// Deprecated: the API marks this operation as deprecated.\n")
+	}
+
+	resultType := g.goType(result, false)
+	zero := "nil"
+	returnType := resultType
+	if !strings.HasPrefix(resultType, "[]") {
+		returnType = "*" + resultType
+	}
+	g.printf("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), returnType)
+	if len(pathValues) > 0 {
+		g.printf("path := fmt.Sprintf(%q, %s)\n", pathFormat, strings.Join(pathValues, ", "))
+	} else {
+		g.printf("path := %q\n", path)
+	}
+	queryArg := "nil"
+	if len(queryParams) > 0 {
+		queryArg = "query"
+		g.printf("query := url.Values{}\n")
+		for _, p := range queryParams {
+			field := "params." + goName(p.Name)
+			switch g.goType(p.Schema, false) {
+			case "string":
+				g.printf("if %s != \"\" {\nquery.Set(%q, %s)\n}\n", field, p.Name, field)
+			case "bool":
+				g.printf("if %s {\nquery.Set(%q, \"true\")\n}\n", field, p.Name)
+			default:
+				g.printf("if %s != 0 {\nquery.Set(%q, fmt.Sprint(%s))\n}\n", field, p.Name, field)
+			}
+		}
+	}
+	g.printf("var result %s\n", resultType)
+	g.printf("if err := c.do(ctx, http.Method%s, path, %s, %s, %s, &result); err != nil {\nreturn %s, err\n}\n",
+		methodName(method), queryArg, bodyArg, status, zero)
+	if strings.HasPrefix(resultType, "[]") {
+		g.printf("return result, nil\n}\n\n")
+	} else {
+		g.printf("return &result, nil\n}\n\n")
+	}
+	return nil
+}
+
+// result returns the success status of an operation and its JSON schema, or
+// an empty status when it does not answer JSON
+func (g *generator) result(op *operation) (string, *schema) {
+	for _, status := range sortedKeys(op.Responses) {
+		if !strings.HasPrefix(status, "2") {
+			continue
+		}
+		r := op.Responses[status]
+		if r.Ref != "" {
+			r = g.doc.Components.Responses[refName(r.Ref)]
+		}
+		content, ok := r.Content["application/json"]
+		if !ok {
+			return "", nil
+		}
+		return status, content.Schema
+	}
+	return "", nil
+}
+
+func (g *generator) parameter(p *parameter) *parameter {
+	if p.Ref == "" {
+		return p
+	}
+	resolved, ok := g.doc.Components.Parameters[refName(p.Ref)]
+	if !ok {
+		log.Fatalf("unknown parameter %s", p.Ref)
+	}
+	return resolved
+}
+
+func comment(b *bytes.Buffer, summary, description string) {
+	fmt.Fprintf(b, "// %s\n", summary)
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return
+	}
+	b.WriteString("//\n")
+	for _, line := range strings.Split(description, "\n") {
+		fmt.Fprintf(b, "// %s\n", line)
+	}
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// initialisms are kept upper case in Go names
+var initialisms = map[string]string{"id": "ID", "ip": "IP", "url": "URL", "uuid": "UUID", "api": "API"}
+
+// goName turns a snake_case or camelCase name into an exported Go name
+func goName(name string) string {
+	var words []string
+	for _, word := range strings.Split(name, "_") {
+		words = append(words, splitCamel(word)...)
+	}
+	var b strings.Builder
+	for _, word := range words {
+		if initialism, ok := initialisms[strings.ToLower(word)]; ok {
+			b.WriteString(initialism)
+			continue
+		}
+		b.WriteString(exported(word))
+	}
+	return b.String()
+}
+
+func splitCamel(word string) []string {
+	var words []string
+	start := 0
+	for i, r := range word {
+		if i > 0 && unicode.IsUpper(r) {
+			words = append(words, word[start:i])
+			start = i
+		}
+	}
+	return append(words, word[start:])
+}
+
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func unexported(name string) string {
+	for initialism := range initialisms {
+		if strings.HasPrefix(name, initialisms[initialism]) {
+			return initialism + name[len(initialism):]
+		}
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+func lowerFirst(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func methodName(method string) string {
+	return exported(strings.ToLower(method))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,53 @@
+// Package docs holds the OpenAPI document of the REST API and the AsyncAPI
+// document of the WebSocket and AMQP messages, and serves them.
+package docs
+
+import (
+	_ "embed"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// OpenAPI is the OpenAPI 3 document of the REST API
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+// AsyncAPI is the AsyncAPI document of the WebSocket frames and AMQP messages
+//
+//go:embed asyncapi.yaml
+var AsyncAPI []byte
+
+// index renders the OpenAPI document with Swagger UI
+const index = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Instant Messaging App API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <p>WebSocket and AMQP messages: <a href="/api/docs/asyncapi.yaml">asyncapi.yaml</a></p>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>SwaggerUIBundle({url: "/api/docs/openapi.yaml", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+// Register serves the documents under /docs of the api group
+func Register(api fiber.Router) {
+	api.Get("/docs", func(c *fiber.Ctx) error {
+		c.Type("html", "utf-8")
+		return c.SendString(index)
+	})
+	api.Get("/docs/openapi.yaml", serveYAML(OpenAPI))
+	api.Get("/docs/asyncapi.yaml", serveYAML(AsyncAPI))
+}
+
+func serveYAML(document []byte) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "application/yaml")
+		return c.Send(document)
+	}
+}
@@ -0,0 +1,71 @@
+package dtos
+
+import (
+	"time"
+
+	"instant-messaging-app/models"
+)
+
+// BotDTO exposes a bot to its owner
+type BotDTO struct {
+	ID            uint       `json:"id"`
+	Username      string     `json:"username"`
+	DisplayName   string     `json:"display_name,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+}
+
+func ToBotDTO(bot models.User) BotDTO {
+	return BotDTO{
+		ID:            bot.ID,
+		Username:      bot.Username,
+		DisplayName:   bot.DisplayName,
+		CreatedAt:     bot.CreatedAt,
+		DeactivatedAt: bot.DeactivatedAt,
+	}
+}
+
+func ToBotDTOs(bots []models.User) []BotDTO {
+	dtos := make([]BotDTO, len(bots))
+	for i, bot := range bots {
+		dtos[i] = ToBotDTO(bot)
+	}
+	return dtos
+}
+
+// APIKeyDTO exposes an API key without its secret
+type APIKeyDTO struct {
+	ID         uint       `json:"id"`
+	BotID      uint       `json:"bot_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	RateLimit  int        `json:"rate_limit"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func ToAPIKeyDTO(key models.APIKey) APIKeyDTO {
+	return APIKeyDTO{
+		ID:         key.ID,
+		BotID:      key.UserID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		RateLimit:  key.RateLimit,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+func ToAPIKeyDTOs(keys []models.APIKey) []APIKeyDTO {
+	dtos := make([]APIKeyDTO, len(keys))
+	for i, key := range keys {
+		dtos[i] = ToAPIKeyDTO(key)
+	}
+	return dtos
+}
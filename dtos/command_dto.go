@@ -0,0 +1,58 @@
+package dtos
+
+import (
+	"time"
+
+	"instant-messaging-app/models"
+)
+
+// CommandDTO is an entry of the autocomplete listing of the slash commands
+type CommandDTO struct {
+	Name        string `json:"name"`
+	Usage       string `json:"usage"`
+	Description string `json:"description"`
+	// BotID is the bot running the command, omitted for the built-in ones
+	BotID uint `json:"bot_id,omitempty"`
+}
+
+func ToCommandDTOs(commands []models.CommandInfo) []CommandDTO {
+	dtos := make([]CommandDTO, len(commands))
+	for i, command := range commands {
+		dtos[i] = CommandDTO{
+			Name:        command.Name,
+			Usage:       command.Usage,
+			Description: command.Description,
+			BotID:       command.BotID,
+		}
+	}
+	return dtos
+}
+
+// BotCommandDTO exposes a command registered by a bot to its owner
+type BotCommandDTO struct {
+	ID          uint      `json:"id"`
+	BotID       uint      `json:"bot_id"`
+	Name        string    `json:"name"`
+	Usage       string    `json:"usage"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func ToBotCommandDTO(command models.BotCommand) BotCommandDTO {
+	return BotCommandDTO{
+		ID:          command.ID,
+		BotID:       command.BotID,
+		Name:        command.Name,
+		Usage:       command.Usage,
+		Description: command.Description,
+		CreatedAt:   command.CreatedAt,
+	}
+}
+
+func ToBotCommandDTOs(commands []models.BotCommand) []BotCommandDTO {
+	dtos := make([]BotCommandDTO, len(commands))
+	for i, command := range commands {
+		dtos[i] = ToBotCommandDTO(command)
+	}
+	return dtos
+}
@@ -7,6 +7,8 @@ type MessageDTO struct {
 	SenderID   uint `json:"sender_id"`
 	ReceiverID uint `json:"receiver_id"`
 	Content    string `json:"content"`
+	// SenderName replaces the name of the sender, for the messages of incoming webhooks
+	SenderName string `json:"sender_name,omitempty"`
 }
 
 func ToMessageDTO(message models.Message) MessageDTO {
@@ -15,6 +17,7 @@ func ToMessageDTO(message models.Message) MessageDTO {
 		SenderID:   message.SenderID,
 		ReceiverID: message.ReceiverID,
 		Content:    message.Content,
+		SenderName: message.SenderName,
 	}
 }
 
@@ -0,0 +1,45 @@
+package dtos
+
+import (
+	"time"
+
+	"instant-messaging-app/models"
+)
+
+// ScheduledMessageDTO exposes a scheduled message to its user. SendAt is in
+// UTC and LocalSendAt the same time in the time zone of the message.
+type ScheduledMessageDTO struct {
+	ID          uint       `json:"id"`
+	ReceiverID  uint       `json:"receiver_id"`
+	Kind        string     `json:"kind"`
+	Content     string     `json:"content"`
+	SendAt      time.Time  `json:"send_at"`
+	TimeZone    string     `json:"time_zone"`
+	LocalSendAt string     `json:"local_send_at"`
+	Status      string     `json:"status"`
+	SentAt      *time.Time `json:"sent_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func ToScheduledMessageDTO(message models.ScheduledMessage) ScheduledMessageDTO {
+	return ScheduledMessageDTO{
+		ID:          message.ID,
+		ReceiverID:  message.ReceiverID,
+		Kind:        message.Kind,
+		Content:     message.Content,
+		SendAt:      message.SendAt.UTC(),
+		TimeZone:    message.TimeZone,
+		LocalSendAt: message.LocalSendAt().Format(time.RFC3339),
+		Status:      message.Status,
+		SentAt:      message.SentAt,
+		CreatedAt:   message.CreatedAt,
+	}
+}
+
+func ToScheduledMessageDTOs(messages []models.ScheduledMessage) []ScheduledMessageDTO {
+	dtos := make([]ScheduledMessageDTO, len(messages))
+	for i, message := range messages {
+		dtos[i] = ToScheduledMessageDTO(message)
+	}
+	return dtos
+}
@@ -1,18 +1,24 @@
 package dtos
 
 import (
+	"time"
+
 	"instant-messaging-app/models"
 )
 
 type UserDTO struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+	Bot         bool   `json:"bot,omitempty"`
 }
 
 func ToUserDTO(user models.User) UserDTO {
 	return UserDTO{
-		ID:        user.ID,
-		Username:  user.Username,
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Bot:         user.IsBot(),
 	}
 }
 
@@ -22,4 +28,35 @@ func ToUserDTOs(users []models.User) []UserDTO {
 		dtos[i] = ToUserDTO(user)
 	}
 	return dtos
-}
\ No newline at end of file
+}
+
+// AdminUserDTO exposes the account fields visible to administrators
+type AdminUserDTO struct {
+	ID            uint       `json:"id"`
+	Username      string     `json:"username"`
+	DisplayName   string     `json:"display_name,omitempty"`
+	Role          string     `json:"role"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	OwnerID       *uint      `json:"owner_id,omitempty"`
+}
+
+func ToAdminUserDTO(user models.User) AdminUserDTO {
+	return AdminUserDTO{
+		ID:            user.ID,
+		Username:      user.Username,
+		DisplayName:   user.DisplayName,
+		Role:          user.Role,
+		CreatedAt:     user.CreatedAt,
+		DeactivatedAt: user.DeactivatedAt,
+		OwnerID:       user.OwnerID,
+	}
+}
+
+func ToAdminUserDTOs(users []models.User) []AdminUserDTO {
+	dtos := make([]AdminUserDTO, len(users))
+	for i, user := range users {
+		dtos[i] = ToAdminUserDTO(user)
+	}
+	return dtos
+}
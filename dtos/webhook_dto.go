@@ -0,0 +1,112 @@
+package dtos
+
+import (
+	"time"
+
+	"instant-messaging-app/models"
+)
+
+// WebhookDTO exposes a webhook to its owner, without its secret
+type WebhookDTO struct {
+	ID           uint       `json:"id"`
+	URL          string     `json:"url"`
+	Events       []string   `json:"events"`
+	AllUsers     bool       `json:"all_users"`
+	FailureCount int        `json:"failure_count"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func ToWebhookDTO(webhook models.Webhook) WebhookDTO {
+	events := webhook.EventList()
+	if events == nil {
+		events = models.WebhookEvents
+	}
+	return WebhookDTO{
+		ID:           webhook.ID,
+		URL:          webhook.URL,
+		Events:       events,
+		AllUsers:     webhook.AllUsers,
+		FailureCount: webhook.FailureCount,
+		DisabledAt:   webhook.DisabledAt,
+		CreatedAt:    webhook.CreatedAt,
+	}
+}
+
+func ToWebhookDTOs(webhooks []models.Webhook) []WebhookDTO {
+	dtos := make([]WebhookDTO, len(webhooks))
+	for i, webhook := range webhooks {
+		dtos[i] = ToWebhookDTO(webhook)
+	}
+	return dtos
+}
+
+// WebhookDeliveryDTO is an entry of the delivery log of a webhook
+type WebhookDeliveryDTO struct {
+	ID             uint       `json:"id"`
+	WebhookID      uint       `json:"webhook_id"`
+	Event          string     `json:"event"`
+	EventID        string     `json:"event_id"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	ReplayOf       *uint      `json:"replay_of,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func ToWebhookDeliveryDTO(delivery models.WebhookDelivery) WebhookDeliveryDTO {
+	return WebhookDeliveryDTO{
+		ID:             delivery.ID,
+		WebhookID:      delivery.WebhookID,
+		Event:          delivery.Event,
+		EventID:        delivery.EventID,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		ReplayOf:       delivery.ReplayOf,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+}
+
+func ToWebhookDeliveryDTOs(deliveries []models.WebhookDelivery) []WebhookDeliveryDTO {
+	dtos := make([]WebhookDeliveryDTO, len(deliveries))
+	for i, delivery := range deliveries {
+		dtos[i] = ToWebhookDeliveryDTO(delivery)
+	}
+	return dtos
+}
+
+// IncomingWebhookDTO exposes an incoming webhook to its owner, without its token
+type IncomingWebhookDTO struct {
+	ID         uint       `json:"id"`
+	ReceiverID uint       `json:"receiver_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func ToIncomingWebhookDTO(webhook models.IncomingWebhook) IncomingWebhookDTO {
+	return IncomingWebhookDTO{
+		ID:         webhook.ID,
+		ReceiverID: webhook.ReceiverID,
+		Name:       webhook.Name,
+		Prefix:     webhook.Prefix,
+		CreatedAt:  webhook.CreatedAt,
+		LastUsedAt: webhook.LastUsedAt,
+	}
+}
+
+func ToIncomingWebhookDTOs(webhooks []models.IncomingWebhook) []IncomingWebhookDTO {
+	dtos := make([]IncomingWebhookDTO, len(webhooks))
+	for i, webhook := range webhooks {
+		dtos[i] = ToIncomingWebhookDTO(webhook)
+	}
+	return dtos
+}
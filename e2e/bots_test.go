@@ -0,0 +1,121 @@
+package e2e_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/e2e"
+
+	messagingv1 "instant-messaging-app/proto/messaging/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBotAPIKeys(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+	defer cancel()
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	owner := client.New(h.BaseURL, alice.Token)
+
+	bot, err := owner.CreateBot(ctx, client.CreateBotRequest{Username: alice.Username + "-bot", DisplayName: "Alice's bot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey := func(name string, rateLimit int, scopes ...string) (*client.Client, client.APIKey) {
+		t.Helper()
+		created, err := owner.CreateAPIKey(ctx, bot.ID, client.CreateAPIKeyRequest{Name: name, Scopes: scopes, RateLimit: rateLimit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client.New(h.BaseURL, created.Key), created.APIKey
+	}
+
+	t.Run("scopes", func(t *testing.T) {
+		// A key sends messages as the bot but cannot read them without the scope
+		sender, _ := newKey("sender", 0, "messages:send")
+		sent, err := sender.PostMessage(ctx, uint64(bob.ID), client.MessageContent{Content: "beep"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sent.Message.SenderID != bot.ID {
+			t.Fatalf("expected the bot to send the message, got %+v", sent.Message)
+		}
+		_, err = sender.GetConversation(ctx, uint64(bob.ID))
+		e2e.ExpectStatus(t, err, http.StatusForbidden, "reading without messages:read")
+		// Keys do not manage bots nor reach the admin API
+		_, err = sender.ListBots(ctx)
+		e2e.ExpectStatus(t, err, http.StatusForbidden, "listing bots with a key")
+
+		users, err := owner.GetUsers(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, user := range users.Users {
+			if user.ID == bot.ID && !user.Bot {
+				t.Fatalf("expected the bot to be flagged, got %+v", user)
+			}
+		}
+	})
+
+	t.Run("realtime and revocation", func(t *testing.T) {
+		// A reading key connects to /ws/auth, where sending is refused
+		reader, readerKey := newKey("reader", 0, "messages:read")
+		events := make(chan interface{}, 16)
+		realtime, err := reader.Connect(ctx, client.Handlers{
+			OnConnect:     func() { events <- "connect" },
+			OnError:       func(err *client.RealtimeError) { events <- err },
+			OnForceLogout: func() { events <- "force_logout" },
+		}, client.RealtimeOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer realtime.Close()
+		next := func(expected string) interface{} {
+			t.Helper()
+			select {
+			case event := <-events:
+				return event
+			case <-time.After(e2e.DefaultTimeout):
+				t.Fatalf("timed out waiting for %s", expected)
+				return nil
+			}
+		}
+		if event := next("the connection"); event != "connect" {
+			t.Fatalf("expected the connection, got %v", event)
+		}
+		if err := realtime.SendMessage(uint64(bob.ID), "not allowed"); err != nil {
+			t.Fatal(err)
+		}
+		if realtimeErr, ok := next("the error").(*client.RealtimeError); !ok || realtimeErr.Code != "forbidden" {
+			t.Fatalf("expected a forbidden error, got %+v", realtimeErr)
+		}
+
+		// gRPC applies the same scopes
+		conn, err := h.Client().DialGRPC()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_, err = messagingv1.NewMessagesClient(conn).SendMessage(e2e.WithToken(ctx, reader.Token), &messagingv1.SendMessageRequest{
+			ReceiverId: uint64(bob.ID),
+			Content:    "not allowed",
+		})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("expected PermissionDenied over gRPC, got %v", err)
+		}
+
+		// Revoking the key closes its connection and rejects it from then on
+		if _, err := owner.RevokeAPIKey(ctx, bot.ID, readerKey.ID); err != nil {
+			t.Fatal(err)
+		}
+		if event := next("the forced logout"); event != "force_logout" {
+			t.Fatalf("expected a forced logout, got %v", event)
+		}
+		_, err = reader.GetConversation(ctx, uint64(bob.ID))
+		e2e.ExpectStatus(t, err, http.StatusUnauthorized, "a revoked key")
+	})
+}
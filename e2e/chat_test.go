@@ -0,0 +1,76 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"instant-messaging-app/cmd"
+	"instant-messaging-app/e2e"
+
+	"github.com/urfave/cli/v2"
+)
+
+// runChat runs the chat command with args and returns what it printed
+func runChat(t *testing.T, args ...string) ([]byte, error) {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	app := &cli.App{Commands: []*cli.Command{cmd.ChatCommand()}}
+	runErr := app.RunContext(context.Background(), append([]string{"app", "chat"}, args...))
+	writer.Close()
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output, runErr
+}
+
+func TestChatSend(t *testing.T) {
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	bobWS := h.Connect(t, bob)
+
+	// The receiver is named by its username, the sender logs in
+	output, err := runChat(t, "send", "--server", h.BaseURL, "--username", alice.Username, "--password", alice.Password,
+		"--to", bob.Username, "--json", "hello", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line struct {
+		Seq      uint64 `json:"seq"`
+		Sender   string `json:"sender"`
+		Receiver string `json:"receiver"`
+		Content  string `json:"content"`
+	}
+	if err := json.Unmarshal(output, &line); err != nil {
+		t.Fatalf("invalid output %q: %v", output, err)
+	}
+	if line.Seq != 1 || line.Sender != alice.Username || line.Receiver != bob.Username || line.Content != "hello bob" {
+		t.Fatalf("unexpected output %s", output)
+	}
+	e2e.ExpectMessage(t, bobWS, alice, bob, "hello bob")
+
+	// Without --json, the message is printed as a line of text
+	output, err = runChat(t, "send", "--server", h.BaseURL, "--token", alice.Token, "--to", bob.Username, "again")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := alice.Username + " -> " + bob.Username + ": again\n"; string(output) != expected {
+		t.Fatalf("expected %q, got %q", expected, output)
+	}
+
+	if _, err := runChat(t, "send", "--server", h.BaseURL, "--token", alice.Token, "--to", "nobody-at-all", "hi"); err == nil {
+		t.Fatal("expected an unknown receiver to fail")
+	}
+	if _, err := runChat(t, "send", "--server", h.BaseURL, "--username", alice.Username, "--password", "wrong", "--to", bob.Username, "hi"); err == nil {
+		t.Fatal("expected a wrong password to fail")
+	}
+}
@@ -0,0 +1,238 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"instant-messaging-app/types"
+
+	"github.com/fasthttp/websocket"
+)
+
+// DefaultTimeout bounds every wait for a WebSocket frame
+const DefaultTimeout = 5 * time.Second
+
+// ErrUnexpectedStatus is returned when the gateway answers with an unexpected HTTP status
+var ErrUnexpectedStatus = errors.New("unexpected HTTP status")
+
+// Client is a scripted client for the gateway's HTTP and WebSocket endpoints
+type Client struct {
+	BaseURL string
+	Timeout time.Duration
+	// GRPCAddr is the address of the gRPC API, if the gateway serves it
+	GRPCAddr string
+}
+
+// Frame is a decoded WebSocket frame. Notifications carry Type and Data, and
+// the events of the user a Seq; registration and login results carry
+// Success, Message and Token instead.
+type Frame struct {
+	Type    string          `json:"type"`
+	Seq     uint64          `json:"seq"`
+	Data    json.RawMessage `json:"data"`
+	Error   string          `json:"error"`
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Token   string          `json:"token"`
+	// Raw is the undecoded frame
+	Raw []byte `json:"-"`
+}
+
+// Decode unmarshals the data of a notification frame into v
+func (f Frame) Decode(v interface{}) error {
+	return json.Unmarshal(f.Data, v)
+}
+
+// Session is an open WebSocket to /ws/auth or /ws/:uuid
+type Session struct {
+	conn    *websocket.Conn
+	timeout time.Duration
+}
+
+// NewClient returns a client for the gateway at baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), Timeout: DefaultTimeout}
+}
+
+// Register posts a registration and reads its result from /ws/:uuid
+func (c *Client) Register(username, password, displayName string) (types.RegistrationResponse, error) {
+	var response types.RegistrationResponse
+	frame, err := c.authenticate("/api/register", map[string]string{
+		"username":     username,
+		"password":     password,
+		"display_name": displayName,
+	})
+	if err != nil {
+		return response, err
+	}
+	err = json.Unmarshal(frame.Raw, &response)
+	return response, err
+}
+
+// Login posts a login and reads its result, including the token, from /ws/:uuid
+func (c *Client) Login(username, password string) (types.LoginResponse, error) {
+	var response types.LoginResponse
+	frame, err := c.authenticate("/api/login", map[string]string{
+		"username": username,
+		"password": password,
+	})
+	if err != nil {
+		return response, err
+	}
+	err = json.Unmarshal(frame.Raw, &response)
+	return response, err
+}
+
+// Connect opens /ws/auth with token and waits for the authentication acknowledgment
+func (c *Client) Connect(token string) (*Session, error) {
+	return c.open(types.TokenRequest{Token: token})
+}
+
+// Resume opens /ws/auth with token and asks for the events after seq to be
+// replayed. The replayed events come first, followed by a resumed frame.
+func (c *Client) Resume(token string, seq uint64) (*Session, error) {
+	return c.open(types.TokenRequest{Token: token, ResumeFrom: &seq})
+}
+
+// open sends request on a new /ws/auth WebSocket and waits for the
+// authentication acknowledgment
+func (c *Client) open(request types.TokenRequest) (*Session, error) {
+	session, err := c.Dial("/ws/auth")
+	if err != nil {
+		return nil, err
+	}
+	if err := session.Send(request); err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	frame, err := session.Next()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	if frame.Type != "auth" || !frame.Success {
+		session.Close()
+		return nil, fmt.Errorf("authentication rejected: %s", frame.Raw)
+	}
+	return session, nil
+}
+
+// Dial opens a raw WebSocket on path. A refused upgrade is reported as
+// ErrUnexpectedStatus wrapped with the HTTP status code.
+func (c *Client) Dial(path string) (*Session, error) {
+	url := "ws" + strings.TrimPrefix(c.BaseURL, "http") + path
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("%w %d from %s", ErrUnexpectedStatus, resp.StatusCode, path)
+		}
+		return nil, err
+	}
+	return &Session{conn: conn, timeout: c.Timeout}, nil
+}
+
+// authenticate posts body to path, then reads the single result published
+// to the returned UUID
+func (c *Client) authenticate(path string, body interface{}) (Frame, error) {
+	var accepted struct {
+		UUID  string `json:"uuid"`
+		Error string `json:"error"`
+	}
+	status, err := c.postJSON(path, body, &accepted)
+	if err != nil {
+		return Frame{}, err
+	}
+	if status != http.StatusAccepted {
+		return Frame{}, fmt.Errorf("%w %d from %s: %s", ErrUnexpectedStatus, status, path, accepted.Error)
+	}
+
+	session, err := c.Dial("/ws/" + accepted.UUID)
+	if err != nil {
+		return Frame{}, err
+	}
+	defer session.Close()
+	return session.Next()
+}
+
+// postJSON sends body as JSON and decodes the response into out
+func (c *Client) postJSON(path string, body, out interface{}) (int, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+
+	httpClient := &http.Client{Timeout: c.Timeout}
+	resp, err := httpClient.Post(c.BaseURL+path, "application/json", bytes.NewReader(raw))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(content, out); err != nil {
+		return resp.StatusCode, fmt.Errorf("invalid response from %s: %s", path, content)
+	}
+	return resp.StatusCode, nil
+}
+
+// Send writes v as a JSON text frame
+func (s *Session) Send(v interface{}) error {
+	return s.conn.WriteJSON(v)
+}
+
+// SendRaw writes a text frame as is
+func (s *Session) SendRaw(message []byte) error {
+	return s.conn.WriteMessage(websocket.TextMessage, message)
+}
+
+// SendMessage sends a sendMessage request with content to receiverID
+func (s *Session) SendMessage(receiverID uint, content string) error {
+	return s.Send(map[string]interface{}{
+		"type":        "sendMessage",
+		"receiver_id": receiverID,
+		"content":     content,
+	})
+}
+
+// Next waits for the next frame
+func (s *Session) Next() (Frame, error) {
+	s.conn.SetReadDeadline(time.Now().Add(s.timeout))
+	_, raw, err := s.conn.ReadMessage()
+	if err != nil {
+		return Frame{}, err
+	}
+
+	var frame Frame
+	if err := json.Unmarshal(raw, &frame); err != nil {
+		return Frame{}, fmt.Errorf("invalid frame %q: %w", raw, err)
+	}
+	frame.Raw = raw
+	return frame, nil
+}
+
+// Expect waits for the next frame and fails unless it has the given type
+func (s *Session) Expect(frameType string) (Frame, error) {
+	frame, err := s.Next()
+	if err != nil {
+		return frame, fmt.Errorf("waiting for %s: %w", frameType, err)
+	}
+	if frame.Type != frameType {
+		return frame, fmt.Errorf("expected a %s frame, got %s", frameType, frame.Raw)
+	}
+	return frame, nil
+}
+
+// Close closes the WebSocket
+func (s *Session) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,51 @@
+package e2e_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/e2e"
+)
+
+func TestClientLoginAndSend(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+	defer cancel()
+	bob := h.NewUser(t, "bob")
+	username := e2e.UniqueName("carol")
+
+	carol := client.New(h.BaseURL, "")
+	if err := carol.Register(ctx, username, "secret-carol", "Carol"); err != nil {
+		t.Fatal(err)
+	}
+	var authErr *client.AuthError
+	if err := carol.Register(ctx, username, "another-secret", ""); !errors.As(err, &authErr) {
+		t.Fatalf("expected a duplicate registration to fail, got %v", err)
+	}
+	if _, err := carol.Login(ctx, username, "wrong-secret"); !errors.As(err, &authErr) {
+		t.Fatalf("expected a wrong password to fail, got %v", err)
+	}
+	token, err := carol.Login(ctx, username, "secret-carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == "" || carol.Token != token {
+		t.Fatalf("expected the client to keep the token, got %q and %q", token, carol.Token)
+	}
+
+	sent, err := carol.Send(ctx, uint64(bob.ID), "hello bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint(sent.Message.ReceiverID) != bob.ID || sent.Message.Content != "hello bob" || sent.Seq != 1 {
+		t.Fatalf("unexpected sent message %+v", sent)
+	}
+	conversation, err := carol.Conversation(ctx, uint64(bob.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conversation) != 1 || conversation[0].ID != sent.Message.ID {
+		t.Fatalf("unexpected conversation %+v", conversation)
+	}
+}
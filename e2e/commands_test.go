@@ -0,0 +1,234 @@
+package e2e_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/e2e"
+	"instant-messaging-app/types"
+)
+
+// nextMessage returns the next send_message_response of session, ephemeral
+// answers included
+func nextMessage(t *testing.T, session *e2e.Session) types.SendMessageResponse {
+	t.Helper()
+	frame, err := session.Expect("send_message_response")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response types.SendMessageResponse
+	if err := frame.Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestSlashCommands(t *testing.T) {
+	t.Run("built-in commands", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+		defer cancel()
+		alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+		aliceWS, bobWS := h.Connect(t, alice), h.Connect(t, bob)
+		api := client.New(h.BaseURL, alice.Token)
+		expectBoth := func(content string) {
+			t.Helper()
+			for _, session := range []*e2e.Session{aliceWS, bobWS} {
+				if _, response := e2e.ExpectMessage(t, session, alice, bob, content); response.Message.SenderName != "" || response.Ephemeral {
+					t.Fatalf("unexpected message %+v", response)
+				}
+			}
+		}
+
+		// Over the WebSocket and REST
+		if err := aliceWS.SendMessage(bob.ID, "/shrug fine"); err != nil {
+			t.Fatal(err)
+		}
+		expectBoth(`fine ¯\_(ツ)_/¯`)
+		sent, err := api.PostMessage(ctx, uint64(bob.ID), client.MessageContent{Content: "/me waves"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sent.Message.Content != "* Alice waves" || sent.Ephemeral {
+			t.Fatalf("unexpected /me answer %+v", sent)
+		}
+		expectBoth("* Alice waves")
+
+		// A doubled slash sends the message as is
+		if _, err := api.PostMessage(ctx, uint64(bob.ID), client.MessageContent{Content: "//tmp is full"}); err != nil {
+			t.Fatal(err)
+		}
+		expectBoth("/tmp is full")
+
+		_, err = api.PostMessage(ctx, uint64(bob.ID), client.MessageContent{Content: "/nope"})
+		e2e.ExpectStatus(t, err, http.StatusBadRequest, "an unknown command")
+		_, err = api.PostMessage(ctx, uint64(bob.ID), client.MessageContent{Content: "/remind soon"})
+		e2e.ExpectStatus(t, err, http.StatusBadRequest, "invalid arguments")
+	})
+
+	t.Run("mute", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+		defer cancel()
+		alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+		aliceWS, bobWS := h.Connect(t, alice), h.Connect(t, bob)
+		api := client.New(h.BaseURL, alice.Token)
+
+		// A mute is confirmed to bob only and flags the messages of alice for him
+		if err := bobWS.SendMessage(alice.ID, "/mute 1h"); err != nil {
+			t.Fatal(err)
+		}
+		if confirmation := nextMessage(t, bobWS); !confirmation.Ephemeral || confirmation.Message.ID != 0 || confirmation.Message.Content != "Muted Alice for 1h" {
+			t.Fatalf("unexpected /mute answer %+v", confirmation)
+		}
+		if _, err := api.PostMessage(ctx, uint64(bob.ID), client.MessageContent{Content: "are you there?"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, response := e2e.ExpectMessage(t, aliceWS, alice, bob, "are you there?"); response.Muted {
+			t.Fatalf("alice: unexpected muted flag in %+v", response)
+		}
+		if _, response := e2e.ExpectMessage(t, bobWS, alice, bob, "are you there?"); !response.Muted {
+			t.Fatalf("bob: expected the muted flag in %+v", response)
+		}
+
+		if err := bobWS.SendMessage(alice.ID, "/unmute"); err != nil {
+			t.Fatal(err)
+		}
+		if confirmation := nextMessage(t, bobWS); confirmation.Message.Content != "Unmuted Alice" {
+			t.Fatalf("unexpected /unmute answer %+v", confirmation)
+		}
+		if _, err := api.PostMessage(ctx, uint64(bob.ID), client.MessageContent{Content: "still there?"}); err != nil {
+			t.Fatal(err)
+		}
+		e2e.ExpectMessage(t, aliceWS, alice, bob, "still there?")
+		if _, response := e2e.ExpectMessage(t, bobWS, alice, bob, "still there?"); response.Muted {
+			t.Fatalf("expected bob to be unmuted, got %+v", response)
+		}
+	})
+
+	t.Run("bot commands", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+		defer cancel()
+		alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+		aliceWS, bobWS := h.Connect(t, alice), h.Connect(t, bob)
+		api := client.New(h.BaseURL, alice.Token)
+
+		// A bot registers a command, listed by the autocomplete
+		bot, err := api.CreateBot(ctx, client.CreateBotRequest{Username: alice.Username + "-deployer", DisplayName: "Deployer"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		command, err := api.RegisterBotCommand(ctx, bot.ID, client.RegisterBotCommandRequest{Name: "deploy", Usage: "/deploy <env>"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = api.RegisterBotCommand(ctx, bot.ID, client.RegisterBotCommandRequest{Name: "shrug"})
+		e2e.ExpectStatus(t, err, http.StatusBadRequest, "a built-in name")
+		_, err = api.RegisterBotCommand(ctx, bot.ID, client.RegisterBotCommandRequest{Name: "deploy"})
+		e2e.ExpectStatus(t, err, http.StatusBadRequest, "a taken name")
+		listed, err := api.ListCommands(ctx, client.ListCommandsParams{Prefix: "/d"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(listed.Commands) != 1 || listed.Commands[0].Name != "deploy" || listed.Commands[0].BotID != bot.ID {
+			t.Fatalf("unexpected autocomplete %+v", listed.Commands)
+		}
+		if listed, err = api.ListCommands(ctx, client.ListCommandsParams{}); err != nil || len(listed.Commands) != 6 {
+			t.Fatalf("expected the 5 built-in commands and /deploy, got %+v (%v)", listed, err)
+		}
+
+		// The bot gets the invocation on its realtime connection
+		key, err := api.CreateAPIKey(ctx, bot.ID, client.CreateAPIKeyRequest{Name: "commands", Scopes: []string{"messages:read", "messages:send"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		deployer := client.New(h.BaseURL, key.Key)
+		connected := make(chan struct{}, 1)
+		invocations := make(chan client.CommandInvocation, 4)
+		realtime, err := deployer.Connect(ctx, client.Handlers{
+			OnConnect:           func() { connected <- struct{}{} },
+			OnCommandInvocation: func(invocation client.CommandInvocation) { invocations <- invocation },
+		}, client.RealtimeOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer realtime.Close()
+		select {
+		case <-connected:
+		case <-time.After(e2e.DefaultTimeout):
+			t.Fatal("the bot did not connect")
+		}
+
+		invoke := func(args string) client.CommandInvocation {
+			t.Helper()
+			echo, err := api.PostMessage(ctx, uint64(bob.ID), client.MessageContent{Content: "/deploy " + args})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !echo.Ephemeral || echo.Message.Content != "/deploy "+args {
+				t.Fatalf("unexpected echo of the invocation %+v", echo)
+			}
+			var invocation client.CommandInvocation
+			select {
+			case invocation = <-invocations:
+			case <-time.After(e2e.DefaultTimeout):
+				t.Fatal("the bot did not get the invocation")
+			}
+			if invocation.Command != "deploy" || invocation.Args != args || invocation.UserID != uint64(alice.ID) || invocation.ReceiverID != uint64(bob.ID) {
+				t.Fatalf("unexpected invocation %+v", invocation)
+			}
+			return invocation
+		}
+
+		// The answer reaches alice only, under the name of the bot, and
+		// answers the invocation
+		invocation := invoke("prod")
+		answer, err := deployer.RespondToCommand(ctx, invocation.ID, client.CommandAnswer{Text: "deploying prod"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !answer.Ephemeral || answer.Message.ID != 0 || answer.Message.SenderName != "Deployer" {
+			t.Fatalf("unexpected answer %+v", answer)
+		}
+		if response := nextMessage(t, aliceWS); !response.Ephemeral || response.Message.Content != "deploying prod" {
+			t.Fatalf("unexpected answer frame %+v", response)
+		}
+		if err := aliceWS.SendMessage(bob.ID, "thanks"); err != nil {
+			t.Fatal(err)
+		}
+		e2e.ExpectMessage(t, bobWS, alice, bob, "thanks")
+		_, err = deployer.RespondToCommand(ctx, invocation.ID, client.CommandAnswer{Text: "prod deployed"})
+		e2e.ExpectStatus(t, err, http.StatusConflict, "a second answer")
+		_, err = deployer.RespondToCommand(ctx, invocation.ID+1000, client.CommandAnswer{Text: "nope"})
+		e2e.ExpectStatus(t, err, http.StatusNotFound, "an unknown invocation")
+
+		// Only the owner of the bot may delete the command, which is then unknown again
+		_, err = client.New(h.BaseURL, bob.Token).DeleteBotCommand(ctx, bot.ID, command.ID)
+		e2e.ExpectStatus(t, err, http.StatusNotFound, "the command of another user")
+		if _, err := api.DeleteBotCommand(ctx, bot.ID, command.ID); err != nil {
+			t.Fatal(err)
+		}
+		_, err = api.PostMessage(ctx, uint64(bob.ID), client.MessageContent{Content: "/deploy prod"})
+		e2e.ExpectStatus(t, err, http.StatusBadRequest, "a deleted command")
+	})
+
+	t.Run("reminders", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+		defer cancel()
+		alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+		aliceWS := h.Connect(t, alice)
+
+		// Reminders are messages of alice to herself
+		reminder, err := client.New(h.BaseURL, alice.Token).PostMessage(ctx, uint64(bob.ID), client.MessageContent{Content: "/remind 1s stand up"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reminder.Ephemeral {
+			t.Fatalf("unexpected /remind answer %+v", reminder)
+		}
+		if _, response := e2e.ExpectMessage(t, aliceWS, alice, alice, "stand up"); response.Message.SenderName != "Reminder" {
+			t.Fatalf("unexpected reminder %+v", response.Message)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package e2e
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"instant-messaging-app/types"
+)
+
+// EventStream is an open Server-Sent Events stream from /api/events
+type EventStream struct {
+	body    io.ReadCloser
+	frames  chan Frame
+	err     error
+	timeout time.Duration
+}
+
+// Events opens /api/events with token. A non-zero lastEventID resumes after
+// that sequence number, like a reconnecting EventSource.
+func (c *Client) Events(token string, lastEventID uint64) (*EventStream, error) {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/api/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(lastEventID, 10))
+	}
+
+	// The stream stays open, so only the wait for each event is bounded
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w %d from /api/events", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	stream := &EventStream{body: resp.Body, frames: make(chan Frame), timeout: c.Timeout}
+	go stream.read()
+	return stream, nil
+}
+
+// read decodes the events of the stream until it ends
+func (s *EventStream) read() {
+	defer close(s.frames)
+
+	reader := bufio.NewReader(s.body)
+	var data strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			s.err = err
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case line == "" && data.Len() > 0:
+			raw := []byte(data.String())
+			data.Reset()
+
+			var frame Frame
+			if err := json.Unmarshal(raw, &frame); err != nil {
+				s.err = fmt.Errorf("invalid event %q: %w", raw, err)
+				return
+			}
+			frame.Raw = raw
+			s.frames <- frame
+		}
+		// Comments, event IDs and retry hints need no handling: the ID is
+		// also the seq of the frame
+	}
+}
+
+// Next waits for the next event
+func (s *EventStream) Next() (Frame, error) {
+	select {
+	case frame, ok := <-s.frames:
+		if !ok {
+			return Frame{}, fmt.Errorf("event stream ended: %w", s.err)
+		}
+		return frame, nil
+	case <-time.After(s.timeout):
+		return Frame{}, fmt.Errorf("no event within %s", s.timeout)
+	}
+}
+
+// Expect waits for the next event and fails unless it has the given type
+func (s *EventStream) Expect(frameType string) (Frame, error) {
+	frame, err := s.Next()
+	if err != nil {
+		return frame, fmt.Errorf("waiting for %s: %w", frameType, err)
+	}
+	if frame.Type != frameType {
+		return frame, fmt.Errorf("expected a %s frame, got %s", frameType, frame.Raw)
+	}
+	return frame, nil
+}
+
+// Close ends the stream
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
+
+// Poll sends one long-poll request with token. An empty session opens a new
+// one; otherwise cursor acknowledges the events of the previous poll. The
+// gateway holds the request until a notification arrives, so Poll fails when
+// none does within the client timeout.
+func (c *Client) Poll(token, session string, cursor uint64) (types.PollResponse, error) {
+	var response types.PollResponse
+
+	query := url.Values{}
+	if session != "" {
+		query.Set("session", session)
+		query.Set("cursor", strconv.FormatUint(cursor, 10))
+	}
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/api/poll?"+query.Encode(), nil)
+	if err != nil {
+		return response, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	httpClient := &http.Client{Timeout: c.Timeout}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return response, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return response, fmt.Errorf("%w %d from /api/poll", ErrUnexpectedStatus, resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	return response, err
+}
@@ -0,0 +1,74 @@
+package e2e_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"instant-messaging-app/e2e"
+	"instant-messaging-app/types"
+)
+
+func TestFallbackTransports(t *testing.T) {
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	aliceWS, bobWS := h.Connect(t, alice), h.Connect(t, bob)
+	c := h.Client()
+
+	stream, err := c.Events(bob.Token, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	opened, err := c.Poll(bob.Token, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened.Session == "" || len(opened.Events) != 0 {
+		t.Fatalf("unexpected poll session %+v", opened)
+	}
+
+	if err := aliceWS.SendMessage(bob.ID, "over the fallbacks"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every connection of both peers gets the message, with the same number
+	e2e.ExpectMessage(t, aliceWS, alice, bob, "over the fallbacks")
+	e2e.ExpectMessage(t, bobWS, alice, bob, "over the fallbacks")
+
+	// check checks the message as delivered to bob by a fallback
+	check := func(transport string, frame e2e.Frame) {
+		t.Helper()
+		if frame.Type != "send_message_response" {
+			t.Fatalf("%s: expected a send_message_response frame, got %s", transport, frame.Type)
+		}
+		var response types.SendMessageResponse
+		if err := frame.Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		message := response.Message
+		if message.SenderID != alice.ID || message.ReceiverID != bob.ID || message.Content != "over the fallbacks" {
+			t.Fatalf("%s: unexpected message %+v", transport, message)
+		}
+		if frame.Seq != 1 {
+			t.Fatalf("%s: expected sequence number 1, got %d", transport, frame.Seq)
+		}
+	}
+
+	frame, err := stream.Expect("send_message_response")
+	if err != nil {
+		t.Fatalf("SSE: %v", err)
+	}
+	check("SSE", frame)
+
+	polled, err := c.Poll(bob.Token, opened.Session, opened.Cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(polled.Events) != 1 {
+		t.Fatalf("long-poll: expected 1 event, got %d", len(polled.Events))
+	}
+	var polledFrame e2e.Frame
+	if err := json.Unmarshal(polled.Events[0], &polledFrame); err != nil {
+		t.Fatal(err)
+	}
+	check("long-poll", polledFrame)
+}
@@ -0,0 +1,20 @@
+package e2e
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// DialGRPC connects to the gRPC API of the gateway
+func (c *Client) DialGRPC() (*grpc.ClientConn, error) {
+	return grpc.NewClient(c.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+// WithToken returns a context sending token in the authorization metadata of
+// the gRPC calls
+func WithToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
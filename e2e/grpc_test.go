@@ -0,0 +1,75 @@
+package e2e_test
+
+import (
+	"context"
+	"testing"
+
+	"instant-messaging-app/e2e"
+
+	messagingv1 "instant-messaging-app/proto/messaging/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCAPI(t *testing.T) {
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	aliceWS, bobWS := h.Connect(t, alice), h.Connect(t, bob)
+
+	conn, err := h.Client().DialGRPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	users := messagingv1.NewUsersClient(conn)
+	messages := messagingv1.NewMessagesClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), e2e.DefaultTimeout)
+	defer cancel()
+
+	if _, err := users.GetSelf(ctx, &messagingv1.GetSelfRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without a token, got %v", err)
+	}
+	self, err := users.GetSelf(e2e.WithToken(ctx, bob.Token), &messagingv1.GetSelfRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint(self.User.Id) != bob.ID {
+		t.Fatalf("unexpected self %v", self.User)
+	}
+
+	// The resumed notification tells that the subscription is bound
+	resumeFrom := uint64(0)
+	stream, err := messages.Subscribe(e2e.WithToken(ctx, bob.Token), &messagingv1.SubscribeRequest{ResumeFrom: &resumeFrom})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notification, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed := notification.GetResumed(); resumed == nil || !resumed.Complete || resumed.Replayed != 0 {
+		t.Fatalf("expected an empty resumed notification, got %v", notification)
+	}
+
+	sent, err := messages.SendMessage(e2e.WithToken(ctx, alice.Token), &messagingv1.SendMessageRequest{
+		ReceiverId: uint64(bob.ID),
+		Content:    "over gRPC",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notification, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := notification.GetMessage()
+	if message == nil || message.Id != sent.Message.Id || message.Content != "over gRPC" || notification.Seq != 1 {
+		t.Fatalf("unexpected notification %v", notification)
+	}
+
+	// The WebSockets of both users get it too
+	if frame, _ := e2e.ExpectMessage(t, aliceWS, alice, bob, "over gRPC"); frame.Seq != sent.Seq {
+		t.Fatalf("alice: sequence number %d does not match %d", frame.Seq, sent.Seq)
+	}
+	e2e.ExpectMessage(t, bobWS, alice, bob, "over gRPC")
+}
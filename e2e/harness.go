@@ -0,0 +1,144 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"instant-messaging-app/cmd"
+	"instant-messaging-app/config"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/utils"
+)
+
+// Harness runs the api gateway and the daemons in-process against a
+// throwaway SQLite database and the in-memory broker. It replaces the global
+// configuration, so only one harness may run at a time.
+type Harness struct {
+	// BaseURL is the HTTP address of the gateway, e.g. http://127.0.0.1:40123
+	BaseURL string
+	// GRPCAddr is the address of the gRPC API, e.g. 127.0.0.1:40124
+	GRPCAddr string
+
+	dir    string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start boots the services and waits until the gateway accepts requests
+func Start() (*Harness, error) {
+	dir, err := os.MkdirTemp("", "instant-messaging-e2e-")
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	grpcPort, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	cfg := config.Default()
+	cfg.HTTP.Port = fmt.Sprint(port)
+	cfg.HTTP.GRPCPort = fmt.Sprint(grpcPort)
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = filepath.Join(dir, "e2e.db")
+	cfg.Broker.Driver = "memory"
+	cfg.JWT.Secret = utils.GenerateUUID()
+	// Webhooks reach a local stand-in and give up quickly
+	cfg.Webhooks.AllowPrivate = true
+	cfg.Webhooks.MaxAttempts = 2
+	cfg.Webhooks.MinBackoff = 50 * time.Millisecond
+	cfg.Webhooks.MaxBackoff = 200 * time.Millisecond
+	cfg.Webhooks.DisableAfter = 2
+	cfg.Webhooks.PollInterval = 50 * time.Millisecond
+	cfg.Webhooks.IncomingRateLimit = 3
+	// Scheduled messages are sent soon after they are due
+	cfg.Scheduler.PollInterval = 50 * time.Millisecond
+	if err := cfg.Validate(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	config.Cfg = cfg
+
+	config.InitDatabase()
+	config.SetupRabbitMQ()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Harness{
+		BaseURL:  fmt.Sprintf("http://127.0.0.1:%d", port),
+		GRPCAddr: fmt.Sprintf("127.0.0.1:%d", grpcPort),
+		dir:      dir,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(h.done)
+		cmd.RunStandalone(ctx, repositories.NewGormRepositories(config.DB))
+	}()
+
+	if err := h.waitReady(10 * time.Second); err != nil {
+		h.Stop()
+		return nil, err
+	}
+	return h, nil
+}
+
+// Client returns a scripted client talking to the harness gateway
+func (h *Harness) Client() *Client {
+	c := NewClient(h.BaseURL)
+	c.GRPCAddr = h.GRPCAddr
+	return c
+}
+
+// Stop shuts the services down and deletes the throwaway database
+func (h *Harness) Stop() {
+	h.cancel()
+	<-h.done
+
+	if sqlDB, err := config.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	os.RemoveAll(h.dir)
+}
+
+// waitReady polls the gateway until it answers HTTP requests
+func (h *Harness) waitReady(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-h.done:
+			return fmt.Errorf("gateway stopped during startup")
+		default:
+		}
+
+		resp, err := http.Get(h.BaseURL + "/")
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("gateway not ready after %s", timeout)
+}
+
+// freePort asks the kernel for an unused TCP port
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,90 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/e2e"
+)
+
+// postHook sends a payload to url the way a CI tool would, without the client
+func postHook(t *testing.T, url string, payload map[string]string) (int, dtos.MessageDTO) {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(url, "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var sent struct {
+		Message dtos.MessageDTO `json:"message"`
+	}
+	if resp.StatusCode == http.StatusCreated {
+		if err := json.NewDecoder(resp.Body).Decode(&sent); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, sent.Message
+}
+
+func TestIncomingWebhooks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+	defer cancel()
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	aliceWS, bobWS := h.Connect(t, alice), h.Connect(t, bob)
+	owner := client.New(h.BaseURL, alice.Token)
+
+	created, err := owner.CreateIncomingWebhook(ctx, client.CreateIncomingWebhookRequest{ReceiverID: uint64(bob.ID), Name: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// expectDelivered checks the message both peers get on their WebSocket
+	expectDelivered := func(message dtos.MessageDTO, content string) {
+		t.Helper()
+		for _, session := range []*e2e.Session{aliceWS, bobWS} {
+			_, response := e2e.ExpectMessage(t, session, alice, bob, content)
+			if response.Message.ID != message.ID || response.Message.SenderName != "CI" {
+				t.Fatalf("unexpected message %+v", response.Message)
+			}
+		}
+	}
+
+	// The message is sent by alice to bob under the name of the payload
+	status, message := postHook(t, created.URL, map[string]string{"text": "build passed", "username": "CI"})
+	if status != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", status)
+	}
+	if message.SenderID != alice.ID || message.ReceiverID != bob.ID || message.Content != "build passed" {
+		t.Fatalf("unexpected message %+v", message)
+	}
+	expectDelivered(message, "build passed")
+
+	// The token is required and the payload validated
+	wrongToken := fmt.Sprintf("%s/api/hooks/%d?token=imh_wrong", h.BaseURL, created.IncomingWebhook.ID)
+	if status, _ := postHook(t, wrongToken, map[string]string{"text": "nope"}); status != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong token to be refused, got %d", status)
+	}
+	if status, _ := postHook(t, created.URL, map[string]string{"text": " "}); status != http.StatusBadRequest {
+		t.Fatalf("expected an empty text to be refused, got %d", status)
+	}
+
+	// The third message of the minute is the last one accepted
+	status, message = postHook(t, created.URL, map[string]string{"text": "deployed", "username": "CI"})
+	if status != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", status)
+	}
+	expectDelivered(message, "deployed")
+	if status, _ := postHook(t, created.URL, map[string]string{"text": "too many"}); status != http.StatusTooManyRequests {
+		t.Fatalf("expected the rate limit to apply, got %d", status)
+	}
+}
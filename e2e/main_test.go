@@ -0,0 +1,14 @@
+package e2e_test
+
+import (
+	"os"
+	"testing"
+
+	"instant-messaging-app/e2e"
+)
+
+var h *e2e.Harness
+
+func TestMain(m *testing.M) {
+	os.Exit(e2e.Run(m, &h))
+}
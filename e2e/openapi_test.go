@@ -0,0 +1,44 @@
+package e2e_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/e2e"
+	"instant-messaging-app/types"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	resp, err := http.Get(h.BaseURL + "/api/docs/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("/api/docs/openapi.yaml: expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestGeneratedClient(t *testing.T) {
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	ctx, cancel := context.WithTimeout(context.Background(), e2e.DefaultTimeout)
+	defer cancel()
+	api := client.New(h.BaseURL, alice.Token)
+
+	result, err := api.SearchUsers(ctx, client.SearchUsersParams{Query: bob.Username})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Users) == 0 || uint(result.Users[0].ID) != bob.ID {
+		t.Fatalf("expected %s first, got %+v", bob.Username, result)
+	}
+
+	_, err = api.PostMessage(ctx, 999999, client.MessageContent{Content: "nobody"})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound || apiErr.Code != types.ErrorCodeNotFound {
+		t.Fatalf("expected a not_found error, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package e2e
+
+import (
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Proxy forwards TCP connections to the gateway and can cut them, to
+// simulate the network failures realtime clients must recover from
+type Proxy struct {
+	// BaseURL is the HTTP address of the proxy
+	BaseURL string
+
+	target   string
+	listener net.Listener
+
+	mu    sync.Mutex
+	down  bool
+	conns map[net.Conn]bool
+}
+
+// NewProxy listens on a free local port and forwards to the gateway at baseURL
+func NewProxy(baseURL string) (*Proxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &Proxy{
+		BaseURL:  "http://" + listener.Addr().String(),
+		target:   strings.TrimPrefix(baseURL, "http://"),
+		listener: listener,
+		conns:    map[net.Conn]bool{},
+	}
+	go p.accept()
+	return p, nil
+}
+
+// Down closes the open connections and refuses new ones until Up is called
+func (p *Proxy) Down() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down = true
+	for conn := range p.conns {
+		conn.Close()
+	}
+}
+
+// Up accepts connections again
+func (p *Proxy) Up() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down = false
+}
+
+// Close stops the proxy and closes its connections
+func (p *Proxy) Close() error {
+	p.Down()
+	return p.listener.Close()
+}
+
+func (p *Proxy) accept() {
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go p.forward(client)
+	}
+}
+
+// forward copies the bytes of client to the gateway and back
+func (p *Proxy) forward(client net.Conn) {
+	defer client.Close()
+	if !p.track(client) {
+		return
+	}
+	defer p.untrack(client)
+
+	server, err := net.Dial("tcp", p.target)
+	if err != nil {
+		return
+	}
+	defer server.Close()
+	if !p.track(server) {
+		return
+	}
+	defer p.untrack(server)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(server, client)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(client, server)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// track registers conn to be closed by Down, unless the proxy is down
+func (p *Proxy) track(conn net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.down {
+		return false
+	}
+	p.conns[conn] = true
+	return true
+}
+
+func (p *Proxy) untrack(conn net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.conns, conn)
+}
@@ -0,0 +1,97 @@
+package e2e_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/e2e"
+)
+
+func TestRealtimeReconnectAndResume(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+	defer cancel()
+	alice, carol := h.NewUser(t, "alice"), h.NewUser(t, "carol")
+	sender := client.New(h.BaseURL, alice.Token)
+
+	// Carol goes through a proxy, so that her connection can be cut
+	proxy, err := e2e.NewProxy(h.BaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	events := make(chan interface{}, 16)
+	handlers := client.Handlers{
+		OnConnect:    func() { events <- "connect" },
+		OnDisconnect: func(error) { events <- "disconnect" },
+		OnMessage:    func(event client.MessageEvent) { events <- event },
+		OnResumed:    func(resumed client.Resumed) { events <- resumed },
+		OnSelf:       func(self client.Self) { events <- self },
+	}
+	next := func(expected string) interface{} {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(e2e.DefaultTimeout):
+			t.Fatalf("timed out waiting for %s", expected)
+			return nil
+		}
+	}
+	expectMessage := func(content string, seq uint64) {
+		t.Helper()
+		event := next("the message " + content)
+		message, ok := event.(client.MessageEvent)
+		if !ok || message.Seq != seq || message.Message.Content != content || uint(message.Message.SenderID) != alice.ID {
+			t.Fatalf("expected message %q numbered %d, got %+v", content, seq, event)
+		}
+	}
+
+	resumeFrom := uint64(0)
+	realtime, err := client.New(proxy.BaseURL, carol.Token).Connect(ctx, handlers, client.RealtimeOptions{ResumeFrom: &resumeFrom, MinBackoff: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer realtime.Close()
+	if event := next("the connection"); event != "connect" {
+		t.Fatalf("expected the connection, got %v", event)
+	}
+	if event := next("the resumed event"); event != (client.Resumed{Complete: true}) {
+		t.Fatalf("expected an empty resumed event, got %+v", event)
+	}
+
+	if err := realtime.RequestSelf(); err != nil {
+		t.Fatal(err)
+	}
+	if self, ok := next("the profile").(client.Self); !ok || uint(self.User.ID) != carol.ID {
+		t.Fatalf("unexpected profile %+v", self)
+	}
+
+	if _, err := sender.Send(ctx, uint64(carol.ID), "hello carol"); err != nil {
+		t.Fatal(err)
+	}
+	expectMessage("hello carol", 1)
+
+	// A message sent while the connection is down is replayed once it is back
+	proxy.Down()
+	if event := next("the disconnection"); event != "disconnect" {
+		t.Fatalf("expected the disconnection, got %v", event)
+	}
+	if _, err := sender.Send(ctx, uint64(carol.ID), "while you were away"); err != nil {
+		t.Fatal(err)
+	}
+	proxy.Up()
+	if event := next("the reconnection"); event != "connect" {
+		t.Fatalf("expected the reconnection, got %v", event)
+	}
+	expectMessage("while you were away", 2)
+	event := next("the resumed event")
+	if resumed, ok := event.(client.Resumed); !ok || resumed.ResumeFrom != 1 || resumed.Replayed != 1 || !resumed.Complete {
+		t.Fatalf("unexpected resumed event %+v", event)
+	}
+	if realtime.LastSeq() != 2 {
+		t.Fatalf("expected the last sequence number to be 2, got %d", realtime.LastSeq())
+	}
+}
@@ -0,0 +1,60 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// APIError is the error envelope of the REST API
+type APIError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// REST sends a request to the REST API with token and decodes the JSON
+// response into out, or into an APIError when the status is not 2xx
+func (c *Client) REST(method, path, token string, body, out interface{}) (int, *APIError, error) {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+path, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := &http.Client{Timeout: c.Timeout}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		var apiErr APIError
+		if err := json.Unmarshal(content, &apiErr); err != nil || apiErr.Code == "" {
+			return resp.StatusCode, nil, fmt.Errorf("invalid error response from %s: %s", path, content)
+		}
+		return resp.StatusCode, &apiErr, nil
+	}
+	if err := json.Unmarshal(content, out); err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("invalid response from %s: %s", path, content)
+	}
+	return resp.StatusCode, nil, nil
+}
@@ -0,0 +1,110 @@
+package e2e_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/e2e"
+	"instant-messaging-app/types"
+)
+
+func TestRESTMessages(t *testing.T) {
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	aliceWS, bobWS := h.Connect(t, alice), h.Connect(t, bob)
+	c := h.Client()
+
+	var sent struct {
+		Message dtos.MessageDTO `json:"message"`
+		Seq     uint64          `json:"seq"`
+	}
+	path := fmt.Sprintf("/api/conversations/%d/messages", bob.ID)
+	status, apiErr, err := c.REST(http.MethodPost, path, alice.Token, map[string]string{"content": "over REST"}, &sent)
+	if err != nil || apiErr != nil {
+		t.Fatalf("%s: %v %v", path, apiErr, err)
+	}
+	if status != http.StatusCreated {
+		t.Fatalf("%s: expected status 201, got %d", path, status)
+	}
+	if sent.Message.SenderID != alice.ID || sent.Message.ReceiverID != bob.ID || sent.Message.Content != "over REST" {
+		t.Fatalf("unexpected message %+v", sent.Message)
+	}
+
+	// Both peers get the message live, as if it was sent over the WebSocket
+	frame, response := e2e.ExpectMessage(t, aliceWS, alice, bob, "over REST")
+	if response.Message.ID != sent.Message.ID || frame.Seq != sent.Seq {
+		t.Fatalf("alice: expected message %d numbered %d, got %d numbered %d", sent.Message.ID, sent.Seq, response.Message.ID, frame.Seq)
+	}
+	if _, response := e2e.ExpectMessage(t, bobWS, alice, bob, "over REST"); response.Message.ID != sent.Message.ID {
+		t.Fatalf("bob: unexpected message %+v", response.Message)
+	}
+
+	var conversation types.GetMessagesResponse
+	path = fmt.Sprintf("/api/conversations/%d", alice.ID)
+	if _, apiErr, err := c.REST(http.MethodGet, path, bob.Token, nil, &conversation); err != nil || apiErr != nil {
+		t.Fatalf("%s: %v %v", path, apiErr, err)
+	}
+	if len(conversation.Messages) != 1 || conversation.Messages[0].ID != sent.Message.ID {
+		t.Fatalf("unexpected conversation %+v", conversation.Messages)
+	}
+}
+
+func TestRESTErrors(t *testing.T) {
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	c := h.Client()
+
+	cases := []struct {
+		name   string
+		path   string
+		token  string
+		body   interface{}
+		status int
+		code   string
+	}{
+		{"empty content", fmt.Sprintf("/api/conversations/%d/messages", bob.ID), alice.Token, map[string]string{"content": " "}, http.StatusBadRequest, types.ErrorCodeBadRequest},
+		{"unknown receiver", "/api/conversations/999999/messages", alice.Token, map[string]string{"content": "nobody"}, http.StatusNotFound, types.ErrorCodeNotFound},
+		{"invalid receiver", "/api/conversations/abc/messages", alice.Token, map[string]string{"content": "nobody"}, http.StatusBadRequest, types.ErrorCodeBadRequest},
+		{"invalid token", "/api/users/me", "invalid", nil, http.StatusUnauthorized, "unauthorized"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			method := http.MethodPost
+			if tc.body == nil {
+				method = http.MethodGet
+			}
+			status, apiErr, err := c.REST(method, tc.path, tc.token, tc.body, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status != tc.status || apiErr == nil || apiErr.Code != tc.code {
+				t.Fatalf("%s: expected %d %s, got %d %+v", tc.path, tc.status, tc.code, status, apiErr)
+			}
+		})
+	}
+}
+
+func TestRESTUsers(t *testing.T) {
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	c := h.Client()
+
+	var self types.GetSelfResponse
+	if _, apiErr, err := c.REST(http.MethodGet, "/api/users/me", alice.Token, nil, &self); err != nil || apiErr != nil {
+		t.Fatalf("/api/users/me: %v %v", apiErr, err)
+	}
+	if self.User.ID != alice.ID || self.User.Username != alice.Username {
+		t.Fatalf("unexpected self %+v", self.User)
+	}
+
+	var users types.GetUsersResponse
+	if _, apiErr, err := c.REST(http.MethodGet, "/api/users", alice.Token, nil, &users); err != nil || apiErr != nil {
+		t.Fatalf("/api/users: %v %v", apiErr, err)
+	}
+	found := map[uint]bool{}
+	for _, user := range users.Users {
+		found[user.ID] = true
+	}
+	if !found[alice.ID] || !found[bob.ID] {
+		t.Fatalf("expected %s and %s, got %+v", alice.Username, bob.Username, users.Users)
+	}
+}
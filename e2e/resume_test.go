@@ -0,0 +1,78 @@
+package e2e_test
+
+import (
+	"testing"
+
+	"instant-messaging-app/e2e"
+	"instant-messaging-app/types"
+)
+
+func TestResumeSession(t *testing.T) {
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	aliceWS, bobWS := h.Connect(t, alice), h.Connect(t, bob)
+
+	if err := aliceWS.SendMessage(bob.ID, "hello bob"); err != nil {
+		t.Fatal(err)
+	}
+	e2e.ExpectMessage(t, aliceWS, alice, bob, "hello bob")
+	frame, _ := e2e.ExpectMessage(t, bobWS, alice, bob, "hello bob")
+	lastSeq := frame.Seq
+
+	// A message sent while bob is away is replayed when he resumes
+	bobWS.Close()
+	if err := aliceWS.SendMessage(bob.ID, "are you there?"); err != nil {
+		t.Fatal(err)
+	}
+	e2e.ExpectMessage(t, aliceWS, alice, bob, "are you there?")
+
+	session, err := h.Client().Resume(bob.Token, lastSeq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+	frame, _ = e2e.ExpectMessage(t, session, alice, bob, "are you there?")
+	if frame.Seq != lastSeq+1 {
+		t.Fatalf("expected sequence number %d, got %d", lastSeq+1, frame.Seq)
+	}
+
+	frame, err = session.Expect("resumed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resumed types.ResumedNotification
+	if err := frame.Decode(&resumed); err != nil {
+		t.Fatal(err)
+	}
+	if !resumed.Complete || resumed.Replayed != 1 || resumed.LastSeq != lastSeq+1 {
+		t.Fatalf("unexpected resumed notification %s", frame.Data)
+	}
+}
+
+func TestResumeUpToDate(t *testing.T) {
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	aliceWS := h.Connect(t, alice)
+
+	if err := aliceWS.SendMessage(bob.ID, "hello bob"); err != nil {
+		t.Fatal(err)
+	}
+	e2e.ExpectMessage(t, aliceWS, alice, bob, "hello bob")
+
+	// The message is the first event of bob: resumed from it, there is
+	// nothing to replay
+	session, err := h.Client().Resume(bob.Token, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+	frame, err := session.Expect("resumed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resumed types.ResumedNotification
+	if err := frame.Decode(&resumed); err != nil {
+		t.Fatal(err)
+	}
+	if !resumed.Complete || resumed.Replayed != 0 || resumed.LastSeq != 1 {
+		t.Fatalf("unexpected resumed notification %s", frame.Data)
+	}
+}
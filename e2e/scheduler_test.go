@@ -0,0 +1,209 @@
+package e2e_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/config"
+	"instant-messaging-app/e2e"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+)
+
+func TestScheduledMessages(t *testing.T) {
+	t.Run("time zones and cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+		defer cancel()
+		alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+		api := client.New(h.BaseURL, alice.Token)
+
+		// A local time is read in the time zone of the request
+		newYork, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			t.Fatal(err)
+		}
+		local := time.Now().In(newYork).Add(time.Hour).Truncate(time.Second)
+		scheduled, err := api.ScheduleMessage(ctx, client.ScheduleMessageRequest{
+			ReceiverID: uint64(bob.ID),
+			Content:    "happy birthday",
+			SendAt:     local.Format("2006-01-02T15:04:05"),
+			TimeZone:   "America/New_York",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !scheduled.SendAt.Equal(local) || scheduled.LocalSendAt != local.Format(time.RFC3339) || scheduled.Status != "pending" {
+			t.Fatalf("unexpected scheduled message %+v, expected it at %s", scheduled, local.Format(time.RFC3339))
+		}
+		_, err = api.ScheduleMessage(ctx, client.ScheduleMessageRequest{ReceiverID: uint64(bob.ID), Content: "hi", SendAt: local.Format(time.RFC3339), TimeZone: "Mars/Olympus"})
+		e2e.ExpectStatus(t, err, http.StatusBadRequest, "an unknown time zone")
+		_, err = api.ScheduleMessage(ctx, client.ScheduleMessageRequest{ReceiverID: uint64(bob.ID), Content: "hi", SendAt: time.Now().Add(-time.Minute).Format(time.RFC3339)})
+		e2e.ExpectStatus(t, err, http.StatusBadRequest, "a time in the past")
+
+		// Moving the message to another time zone keeps its local time
+		moved, err := api.UpdateScheduledMessage(ctx, scheduled.ID, client.UpdateScheduledMessageRequest{TimeZone: "America/Los_Angeles"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		losAngeles, err := time.LoadLocation("America/Los_Angeles")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, losAngeles)
+		if moved.TimeZone != "America/Los_Angeles" || !moved.SendAt.Equal(expected) || moved.Content != "happy birthday" {
+			t.Fatalf("unexpected moved message %+v, expected it at %s", moved, expected.Format(time.RFC3339))
+		}
+
+		// Only its user sees and cancels a scheduled message
+		other := client.New(h.BaseURL, bob.Token)
+		_, err = other.GetScheduledMessage(ctx, scheduled.ID)
+		e2e.ExpectStatus(t, err, http.StatusNotFound, "the scheduled message of another user")
+		_, err = other.CancelScheduledMessage(ctx, scheduled.ID)
+		e2e.ExpectStatus(t, err, http.StatusNotFound, "canceling the scheduled message of another user")
+		pending, err := api.ListScheduledMessages(ctx, client.ListScheduledMessagesParams{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending.ScheduledMessages) != 1 || pending.ScheduledMessages[0].ID != scheduled.ID {
+			t.Fatalf("unexpected pending messages %+v", pending.ScheduledMessages)
+		}
+		canceled, err := api.CancelScheduledMessage(ctx, scheduled.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if canceled.Status != "canceled" {
+			t.Fatalf("unexpected canceled message %+v", canceled)
+		}
+		_, err = api.CancelScheduledMessage(ctx, scheduled.ID)
+		e2e.ExpectStatus(t, err, http.StatusConflict, "a canceled message")
+	})
+
+	t.Run("due messages", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+		defer cancel()
+		alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+		aliceWS, bobWS := h.Connect(t, alice), h.Connect(t, bob)
+		api := client.New(h.BaseURL, alice.Token)
+
+		// A due message reaches both peers like any other message
+		soon, err := api.ScheduleMessage(ctx, client.ScheduleMessageRequest{
+			ReceiverID: uint64(bob.ID),
+			Content:    "/not a command",
+			SendAt:     time.Now().Add(500 * time.Millisecond).Format(time.RFC3339Nano),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e2e.ExpectMessage(t, aliceWS, alice, bob, "/not a command")
+		e2e.ExpectMessage(t, bobWS, alice, bob, "/not a command")
+
+		sent, err := api.GetScheduledMessage(ctx, soon.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sent.Status != "sent" || sent.SentAt == nil {
+			t.Fatalf("unexpected sent message %+v", sent)
+		}
+		_, err = api.UpdateScheduledMessage(ctx, soon.ID, client.UpdateScheduledMessageRequest{Content: "too late"})
+		e2e.ExpectStatus(t, err, http.StatusConflict, "a sent message")
+	})
+
+	t.Run("deactivated senders", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+		defer cancel()
+		alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+		repos := repositories.NewGormRepositories(config.DB)
+
+		scheduled, err := client.New(h.BaseURL, alice.Token).ScheduleMessage(ctx, client.ScheduleMessageRequest{
+			ReceiverID: uint64(bob.ID),
+			Content:    "sent by a deactivated user",
+			SendAt:     time.Now().Add(300 * time.Millisecond).Format(time.RFC3339Nano),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = config.DB.Model(&models.User{}).Where("id = ?", alice.ID).Update("deactivated_at", time.Now()).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The scheduler cancels the message once it is due, without sending it
+		var message models.ScheduledMessage
+		for deadline := time.Now().Add(e2e.DefaultTimeout); ; time.Sleep(50 * time.Millisecond) {
+			if message, err = repos.Scheduled.FindByID(ctx, uint(scheduled.ID)); err != nil {
+				t.Fatal(err)
+			}
+			if message.Status != models.SchedulePending || time.Now().After(deadline) {
+				break
+			}
+		}
+		if message.Status != models.ScheduleCanceled || message.SentAt != nil {
+			t.Fatalf("expected the message to be canceled, got %+v", message)
+		}
+		if messages, err := repos.Messages.ListBetween(ctx, alice.ID, bob.ID); err != nil || len(messages) != 0 {
+			t.Fatalf("expected no message, got %+v (%v)", messages, err)
+		}
+	})
+
+	t.Run("claims", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+		defer cancel()
+		alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+		bobWS := h.Connect(t, bob)
+		repos := repositories.NewGormRepositories(config.DB)
+
+		// A message claimed by a daemon that is still sending it is left
+		// alone, one claimed by a daemon that stopped is sent again
+		claim := func(content string, claimedAt time.Time) models.ScheduledMessage {
+			t.Helper()
+			message := models.ScheduledMessage{
+				UserID:     alice.ID,
+				ReceiverID: bob.ID,
+				Kind:       models.ScheduleKindMessage,
+				Content:    content,
+				SendAt:     claimedAt,
+				TimeZone:   "UTC",
+				Status:     models.ScheduleSending,
+				ClaimedAt:  &claimedAt,
+			}
+			if err := repos.Scheduled.Create(ctx, &message); err != nil {
+				t.Fatal(err)
+			}
+			return message
+		}
+		sending := claim("still sending", time.Now().UTC())
+		stale := claim("claimed by a stopped daemon", time.Now().UTC().Add(-2*config.Cfg.Scheduler.ClaimTimeout))
+
+		e2e.ExpectMessage(t, bobWS, alice, bob, "claimed by a stopped daemon")
+		if message, err := repos.Scheduled.FindByID(ctx, stale.ID); err != nil || message.Status != models.ScheduleSent {
+			t.Fatalf("expected the stale claim to be sent, got %+v (%v)", message, err)
+		}
+		if message, err := repos.Scheduled.FindByID(ctx, sending.ID); err != nil || message.Status != models.ScheduleSending {
+			t.Fatalf("expected the claim to be left alone, got %+v (%v)", message, err)
+		}
+	})
+
+	t.Run("reminders", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 4*e2e.DefaultTimeout)
+		defer cancel()
+		alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+		aliceWS := h.Connect(t, alice)
+		api := client.New(h.BaseURL, alice.Token)
+
+		// The reminders of /remind are sent by the scheduler too
+		if _, err := api.PostMessage(ctx, uint64(bob.ID), client.MessageContent{Content: "/remind 1s stand up"}); err != nil {
+			t.Fatal(err)
+		}
+		e2e.ExpectMessage(t, aliceWS, alice, alice, "stand up")
+		history, err := api.ListScheduledMessages(ctx, client.ListScheduledMessagesParams{Status: "sent"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history.ScheduledMessages) != 1 || history.ScheduledMessages[0].Kind != "reminder" || history.ScheduledMessages[0].Content != "stand up" {
+			t.Fatalf("unexpected sent messages %+v", history.ScheduledMessages)
+		}
+	})
+}
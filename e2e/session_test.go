@@ -0,0 +1,149 @@
+package e2e_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"instant-messaging-app/e2e"
+	"instant-messaging-app/types"
+)
+
+func TestRegistration(t *testing.T) {
+	alice := h.NewUser(t, "alice")
+
+	response, err := h.Client().Register(alice.Username, "another-secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Success {
+		t.Fatal("a duplicate username was accepted")
+	}
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	alice := h.NewUser(t, "alice")
+
+	response, err := h.Client().Login(alice.Username, "wrong-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Success || response.Token != "" {
+		t.Fatal("login succeeded with a wrong password")
+	}
+}
+
+func TestConnect(t *testing.T) {
+	t.Run("invalid token", func(t *testing.T) {
+		session, err := h.Client().Connect("not-a-token")
+		if err == nil {
+			session.Close()
+			t.Fatal("an invalid token was accepted")
+		}
+	})
+
+	t.Run("unknown connection UUID", func(t *testing.T) {
+		session, err := h.Client().Dial("/ws/00000000-0000-0000-0000-000000000000")
+		if err == nil {
+			session.Close()
+			t.Fatal("a WebSocket opened for a queue that does not exist")
+		}
+		if !errors.Is(err, e2e.ErrUnexpectedStatus) {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestGetSelf(t *testing.T) {
+	alice := h.NewUser(t, "alice")
+	session := h.Connect(t, alice)
+
+	if err := session.Send(map[string]string{"type": "getSelf"}); err != nil {
+		t.Fatal(err)
+	}
+	frame, err := session.Expect("get_self_response")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response types.GetSelfResponse
+	if err := frame.Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.User.ID != alice.ID || response.User.Username != alice.Username {
+		t.Fatalf("getSelf of %s returned %+v", alice.Username, response.User)
+	}
+}
+
+func TestGetUsers(t *testing.T) {
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	session := h.Connect(t, alice)
+
+	if err := session.Send(map[string]string{"type": "getUsers"}); err != nil {
+		t.Fatal(err)
+	}
+	frame, err := session.Expect("get_users_response")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response types.GetUsersResponse
+	if err := frame.Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, user := range response.Users {
+		found[user.Username] = true
+	}
+	if !found[alice.Username] || !found[bob.Username] {
+		t.Fatalf("expected %s and %s, got %s", alice.Username, bob.Username, frame.Data)
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	aliceWS, bobWS := h.Connect(t, alice), h.Connect(t, bob)
+
+	if err := aliceWS.SendMessage(bob.ID, "hello bob"); err != nil {
+		t.Fatal(err)
+	}
+	// The message is broadcast: the sender and the receiver both get it,
+	// numbered in the events of each
+	for name, session := range map[string]*e2e.Session{"alice": aliceWS, "bob": bobWS} {
+		frame, _ := e2e.ExpectMessage(t, session, alice, bob, "hello bob")
+		if frame.Seq != 1 {
+			t.Fatalf("%s: expected sequence number 1, got %d", name, frame.Seq)
+		}
+	}
+
+	if err := bobWS.Send(map[string]interface{}{"type": "getMessages", "receiver_id": alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+	frame, err := bobWS.Expect("get_messages_response")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response types.GetMessagesResponse
+	if err := frame.Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(response.Messages))
+	}
+	if message := response.Messages[0]; message.SenderID != alice.ID || message.ReceiverID != bob.ID || message.Content != "hello bob" {
+		t.Fatalf("unexpected message %+v", message)
+	}
+}
+
+func TestUnknownMessageType(t *testing.T) {
+	session := h.Connect(t, h.NewUser(t, "alice"))
+
+	if err := session.SendRaw([]byte(`{"type":"doesNotExist"}`)); err != nil {
+		t.Fatal(err)
+	}
+	frame, err := session.Expect("error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(frame.Error, "doesNotExist") {
+		t.Fatalf("unexpected error message %q", frame.Error)
+	}
+}
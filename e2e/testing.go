@@ -0,0 +1,125 @@
+package e2e
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/types"
+)
+
+// names numbers the users of the harness, so that every test gets its own
+var names atomic.Int64
+
+// User is a registered and logged in user of the harness
+type User struct {
+	ID          uint
+	Username    string
+	DisplayName string
+	Password    string
+	Token       string
+}
+
+// Run starts a harness for the scenarios of this package, stores it in *h,
+// runs them and stops it. The harness replaces the global configuration, so
+// a test binary runs a single one, from TestMain:
+//
+//	var h *e2e.Harness
+//
+//	func TestMain(m *testing.M) { os.Exit(e2e.Run(m, &h)) }
+func Run(m *testing.M, h **Harness) int {
+	harness, err := Start()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "e2e: starting the harness:", err)
+		return 1
+	}
+	defer harness.Stop()
+
+	*h = harness
+	return m.Run()
+}
+
+// UniqueName returns prefix followed by a number no other call returned
+func UniqueName(prefix string) string {
+	return fmt.Sprintf("%s%d", prefix, names.Add(1))
+}
+
+// NewUser registers and logs in a user whose username starts with name and
+// whose display name is name, capitalized
+func (h *Harness) NewUser(t testing.TB, name string) User {
+	t.Helper()
+	user := User{
+		Username:    UniqueName(name),
+		DisplayName: strings.ToUpper(name[:1]) + name[1:],
+		Password:    "secret-" + name,
+	}
+	c := h.Client()
+
+	registration, err := c.Register(user.Username, user.Password, user.DisplayName)
+	if err != nil {
+		t.Fatalf("registering %s: %v", user.Username, err)
+	}
+	if !registration.Success {
+		t.Fatalf("registering %s: %s", user.Username, registration.Message)
+	}
+	login, err := c.Login(user.Username, user.Password)
+	if err != nil {
+		t.Fatalf("logging in %s: %v", user.Username, err)
+	}
+	if !login.Success || login.Token == "" {
+		t.Fatalf("logging in %s: %s", user.Username, login.Message)
+	}
+	user.Token = login.Token
+
+	var self types.GetSelfResponse
+	if _, apiErr, err := c.REST(http.MethodGet, "/api/users/me", user.Token, nil, &self); err != nil || apiErr != nil {
+		t.Fatalf("/api/users/me of %s: %v %v", user.Username, apiErr, err)
+	}
+	user.ID = self.User.ID
+	return user
+}
+
+// Connect opens an authenticated WebSocket of user, closed when the test ends
+func (h *Harness) Connect(t testing.TB, user User) *Session {
+	t.Helper()
+	session, err := h.Client().Connect(user.Token)
+	if err != nil {
+		t.Fatalf("connecting %s: %v", user.Username, err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+// ExpectMessage waits for the next send_message_response frame of session and
+// checks that it carries content sent by sender to receiver
+func ExpectMessage(t testing.TB, session *Session, sender, receiver User, content string) (Frame, types.SendMessageResponse) {
+	t.Helper()
+	var response types.SendMessageResponse
+	frame, err := session.Expect("send_message_response")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := frame.Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	message := response.Message
+	if message.SenderID != sender.ID || message.ReceiverID != receiver.ID || message.Content != content {
+		t.Fatalf("expected %q from %s to %s, got %+v", content, sender.Username, receiver.Username, message)
+	}
+	return frame, response
+}
+
+// ExpectStatus fails unless err is an error of the generated client with the
+// given HTTP status
+func ExpectStatus(t testing.TB, err error, status int, what string) {
+	t.Helper()
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != status {
+		t.Fatalf("%s: expected status %d, got %v", what, status, err)
+	}
+}
@@ -0,0 +1,112 @@
+package e2e
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// WebhookRequest is a delivery received by a WebhookReceiver
+type WebhookRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+// WebhookReceiver is a local HTTP stand-in for the endpoint of a webhook. It
+// answers each delivery with the next scripted status, then with 200.
+type WebhookReceiver struct {
+	URL string
+
+	server   *httptest.Server
+	requests chan WebhookRequest
+	mu       sync.Mutex
+	statuses []int
+	fallback int
+}
+
+// NewWebhookReceiver starts a WebhookReceiver
+func NewWebhookReceiver() *WebhookReceiver {
+	r := &WebhookReceiver{
+		requests: make(chan WebhookRequest, 64),
+		fallback: http.StatusOK,
+	}
+	r.server = httptest.NewServer(http.HandlerFunc(r.serve))
+	r.URL = r.server.URL + "/hook"
+	return r
+}
+
+// Respond scripts the statuses of the next deliveries
+func (r *WebhookReceiver) Respond(statuses ...int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses = append(r.statuses, statuses...)
+}
+
+// RespondAlways answers every delivery after the scripted ones with status
+func (r *WebhookReceiver) RespondAlways(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = status
+}
+
+// Next waits for the next delivery
+func (r *WebhookReceiver) Next(timeout time.Duration) (WebhookRequest, error) {
+	select {
+	case req := <-r.requests:
+		return req, nil
+	case <-time.After(timeout):
+		return WebhookRequest{}, fmt.Errorf("no webhook delivery after %s", timeout)
+	}
+}
+
+// Drain discards the deliveries received so far
+func (r *WebhookReceiver) Drain() {
+	for {
+		select {
+		case <-r.requests:
+		default:
+			return
+		}
+	}
+}
+
+// Close stops the receiver
+func (r *WebhookReceiver) Close() {
+	r.server.Close()
+}
+
+func (r *WebhookReceiver) serve(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.requests <- WebhookRequest{Header: req.Header.Clone(), Body: body}
+
+	r.mu.Lock()
+	status := r.fallback
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+// VerifySignature checks the X-Webhook-Signature of a delivery the way a
+// receiver would, without the code of the webhook daemon
+func (req WebhookRequest) VerifySignature(secret string) error {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(req.Header.Get("X-Webhook-Timestamp") + "."))
+	mac.Write(req.Body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(req.Header.Get("X-Webhook-Signature"))) {
+		return fmt.Errorf("invalid signature %q", req.Header.Get("X-Webhook-Signature"))
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"instant-messaging-app/client"
+	"instant-messaging-app/e2e"
+	"instant-messaging-app/types"
+)
+
+// waitFor polls done until it reports true
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(4 * e2e.DefaultTimeout); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if done() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestWebhooks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 8*e2e.DefaultTimeout)
+	defer cancel()
+	alice, bob := h.NewUser(t, "alice"), h.NewUser(t, "bob")
+	owner := client.New(h.BaseURL, alice.Token)
+	sender := client.New(h.BaseURL, bob.Token)
+
+	receiver := e2e.NewWebhookReceiver()
+	defer receiver.Close()
+
+	// Only the admins may receive the events of every user
+	_, err := owner.CreateWebhook(ctx, client.CreateWebhookRequest{URL: receiver.URL, AllUsers: true})
+	e2e.ExpectStatus(t, err, http.StatusForbidden, "an all-users webhook")
+	created, err := owner.CreateWebhook(ctx, client.CreateWebhookRequest{URL: receiver.URL, Events: []string{"message_created"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhookID := created.Webhook.ID
+	send := func(content string) uint64 {
+		t.Helper()
+		sent, err := sender.PostMessage(ctx, uint64(alice.ID), client.MessageContent{Content: content})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sent.Message.ID
+	}
+	next := func() e2e.WebhookRequest {
+		t.Helper()
+		req, err := receiver.Next(e2e.DefaultTimeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	// A failed delivery is retried and the receiver can check the signature
+	receiver.Respond(http.StatusInternalServerError)
+	messageID := send("hook me")
+	first, retry := next(), next()
+	if retry.Header.Get("X-Webhook-Delivery") != first.Header.Get("X-Webhook-Delivery") || string(retry.Body) != string(first.Body) {
+		t.Fatal("the retry does not resend the same delivery")
+	}
+	if err := retry.VerifySignature(created.Secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := retry.VerifySignature("whsec_not-the-secret"); err == nil {
+		t.Fatal("the signature verifies with another secret")
+	}
+	var payload struct {
+		ID    string                    `json:"id"`
+		Event string                    `json:"event"`
+		Data  types.MessageCreatedEvent `json:"data"`
+	}
+	if err := json.Unmarshal(retry.Body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ID == "" || payload.Event != "message_created" || retry.Header.Get("X-Webhook-Event") != "message_created" ||
+		uint64(payload.Data.Message.ID) != messageID || payload.Data.Message.Content != "hook me" {
+		t.Fatalf("unexpected payload %s", retry.Body)
+	}
+
+	// The delivery log records both attempts
+	var delivered client.WebhookDelivery
+	waitFor(t, "the delivery to succeed", func() bool {
+		log, err := owner.ListWebhookDeliveries(ctx, webhookID, client.ListWebhookDeliveriesParams{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(log.Deliveries) == 0 {
+			return false
+		}
+		delivered = log.Deliveries[0]
+		return delivered.Status == "succeeded"
+	})
+	if delivered.Attempts != 2 || delivered.EventID != payload.ID || delivered.LastStatusCode != http.StatusOK {
+		t.Fatalf("unexpected delivery %+v", delivered)
+	}
+
+	// Deliveries failing for good disable the webhook
+	receiver.RespondAlways(http.StatusServiceUnavailable)
+	send("fail once")
+	send("fail twice")
+	waitFor(t, "the webhook to be disabled", func() bool {
+		registered, err := owner.ListWebhooks(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(registered.Webhooks) == 1 && registered.Webhooks[0].DisabledAt != nil
+	})
+	receiver.Drain()
+	if _, err := owner.ReplayWebhookDelivery(ctx, webhookID, delivered.ID); err == nil {
+		t.Fatal("a disabled webhook accepted a replay")
+	}
+
+	// Once enabled again, a replay resends the original event
+	receiver.RespondAlways(http.StatusOK)
+	enabled, err := owner.EnableWebhook(ctx, webhookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled.DisabledAt != nil || enabled.FailureCount != 0 {
+		t.Fatalf("unexpected enabled webhook %+v", enabled)
+	}
+	replay, err := owner.ReplayWebhookDelivery(ctx, webhookID, delivered.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.ReplayOf != delivered.ID || replay.EventID != delivered.EventID {
+		t.Fatalf("unexpected replay %+v", replay)
+	}
+	replayed := next()
+	if string(replayed.Body) != string(first.Body) {
+		t.Fatalf("the replay sent %s instead of %s", replayed.Body, first.Body)
+	}
+	if err := replayed.VerifySignature(created.Secret); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the owner may delete the webhook, which takes its delivery log along
+	_, err = sender.DeleteWebhook(ctx, webhookID)
+	e2e.ExpectStatus(t, err, http.StatusNotFound, "the webhook of another user")
+	if _, err := owner.DeleteWebhook(ctx, webhookID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = owner.ListWebhookDeliveries(ctx, webhookID, client.ListWebhookDeliveriesParams{})
+	e2e.ExpectStatus(t, err, http.StatusNotFound, "the deliveries of a deleted webhook")
+}
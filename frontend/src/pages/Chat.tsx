@@ -58,7 +58,10 @@ const Chat: React.FC = () => {
             setMessages((prev) => [...prev, ...data.data.messages]);
             break;
           case "send_message_response":
-            setMessages((prev) => [...prev, data.data.message]);
+            setMessages((prev) => [
+              ...prev,
+              { ...data.data.message, ephemeral: data.data.ephemeral },
+            ]);
             break;
           case "error":
             console.error("Error from WebSocket:", data.message);
@@ -228,9 +231,9 @@ const Chat: React.FC = () => {
               ref={chatContainerRef}
               className="flex-1 overflow-y-auto p-4 space-y-4"
             >
-              {messages.map((msg) => (
+              {messages.map((msg, index) => (
                 <div
-                  key={msg.id}
+                  key={msg.ephemeral ? `ephemeral-${index}` : msg.id}
                   className={`flex ${
                     msg.sender_id === currentUserId
                       ? "justify-end"
@@ -244,7 +247,17 @@ const Chat: React.FC = () => {
                         : "bg-gray-200"
                     }`}
                   >
+                    {msg.sender_name && (
+                      <div className="text-xs font-semibold mb-1">
+                        {msg.sender_name}
+                      </div>
+                    )}
                     {msg.content}
+                    {msg.ephemeral && (
+                      <div className="text-xs italic mt-1 opacity-75">
+                        Only visible to you
+                      </div>
+                    )}
                   </div>
                 </div>
               ))}
@@ -1,6 +1,7 @@
 export interface User {
   id: number;
   username: string;
+  display_name?: string;
 }
 
 export interface Message {
@@ -8,4 +9,7 @@ export interface Message {
   sender_id: number;
   receiver_id: number;
   content: string;
+  sender_name?: string;
+  // Set on the answers of commands only shown to the sender, which are not stored
+  ephemeral?: boolean;
 }
@@ -0,0 +1,54 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"instant-messaging-app/broker"
+	"instant-messaging-app/migrations"
+
+	"gorm.io/gorm"
+)
+
+// DatabaseCheck pings the database
+func DatabaseCheck(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// BrokerCheck fails when the broker connection or channel is unusable
+func BrokerCheck(b broker.Broker) Check {
+	return func(ctx context.Context) error {
+		return b.Ping()
+	}
+}
+
+// MigrationsCheck fails while schema migrations are pending
+func MigrationsCheck(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		pending, err := migrations.ReadPending(db.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migration(s), first is %04d_%s", len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	}
+}
+
+// NewServiceChecker returns the checks shared by every daemon: the consumers
+// decide liveness, the database, broker and schema decide readiness
+func NewServiceChecker(db *gorm.DB, b broker.Broker) *Checker {
+	checker := NewChecker()
+	checker.AddLiveness("consumers", ConsumersCheck())
+	checker.AddReadiness("database", DatabaseCheck(db))
+	checker.AddReadiness("broker", BrokerCheck(b))
+	checker.AddReadiness("migrations", MigrationsCheck(db))
+	return checker
+}
@@ -0,0 +1,148 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxBusy is how long a consumer may spend on one message before it is
+// reported as wedged
+var MaxBusy = 30 * time.Second
+
+// Consumer tracks the state of one queue consumer loop
+type Consumer struct {
+	name string
+
+	mu        sync.Mutex
+	busySince time.Time
+	failed    bool
+	stopped   bool
+	finally   []func(failed bool)
+}
+
+// HandledFunc is told how long a consumer spent on a message and whether
+// processing it failed
+type HandledFunc func(queue string, took time.Duration, failed bool)
+
+var (
+	consumersMu sync.Mutex
+	consumers   = map[string]*Consumer{}
+	observers   []HandledFunc
+)
+
+// OnHandled registers fn to be called every time a tracked consumer finishes a message
+func OnHandled(fn HandledFunc) {
+	consumersMu.Lock()
+	defer consumersMu.Unlock()
+	observers = append(observers, fn)
+}
+
+// TrackConsumer registers the consumer of a queue. The loop calls Busy when it
+// takes a message, Fail when the message could not be processed, Idle when it
+// is back waiting and Stop when it exits.
+func TrackConsumer(name string) *Consumer {
+	consumer := &Consumer{name: name}
+
+	consumersMu.Lock()
+	defer consumersMu.Unlock()
+	consumers[name] = consumer
+	return consumer
+}
+
+// Busy marks the start of the processing of a message
+func (c *Consumer) Busy() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.busySince = time.Now()
+	c.failed = false
+}
+
+// Fail marks the message being processed as failed
+func (c *Consumer) Fail() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failed = true
+}
+
+// Finally registers fn to be called once the message being processed is
+// finished, with whether it failed
+func (c *Consumer) Finally(fn func(failed bool)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finally = append(c.finally, fn)
+}
+
+// Idle marks the consumer as waiting for the next message and reports the
+// message it just finished, if any, to the observers
+func (c *Consumer) Idle() {
+	c.mu.Lock()
+	busySince, failed, finally := c.busySince, c.failed, c.finally
+	c.busySince = time.Time{}
+	c.finally = nil
+	c.mu.Unlock()
+
+	for _, fn := range finally {
+		fn(failed)
+	}
+	if busySince.IsZero() {
+		return
+	}
+	took := time.Since(busySince)
+
+	consumersMu.Lock()
+	notify := observers
+	consumersMu.Unlock()
+	for _, observer := range notify {
+		observer(c.name, took, failed)
+	}
+}
+
+// Stop marks the consumer loop as exited
+func (c *Consumer) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+}
+
+// err reports a stopped consumer or one stuck on a message for more than maxBusy
+func (c *Consumer) err(now time.Time, maxBusy time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopped {
+		return fmt.Errorf("%s stopped", c.name)
+	}
+	if !c.busySince.IsZero() && now.Sub(c.busySince) > maxBusy {
+		return fmt.Errorf("%s busy for %s", c.name, now.Sub(c.busySince).Round(time.Second))
+	}
+	return nil
+}
+
+// ConsumersCheck fails when any tracked consumer has stopped or is wedged
+func ConsumersCheck() Check {
+	return func(ctx context.Context) error {
+		consumersMu.Lock()
+		tracked := make([]*Consumer, 0, len(consumers))
+		for _, consumer := range consumers {
+			tracked = append(tracked, consumer)
+		}
+		consumersMu.Unlock()
+
+		now := time.Now()
+		var problems []string
+		for _, consumer := range tracked {
+			if err := consumer.err(now, MaxBusy); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+		if len(problems) > 0 {
+			sort.Strings(problems)
+			return fmt.Errorf("%s", strings.Join(problems, "; "))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,133 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// checkTimeout bounds the time a single probe may take
+const checkTimeout = 2 * time.Second
+
+// Check reports an error when the component it probes is unhealthy
+type Check func(ctx context.Context) error
+
+// Checker serves the liveness (/healthz) and readiness (/readyz) probes.
+// Liveness failures mean the process is wedged and should be restarted;
+// readiness failures mean it should not receive traffic yet.
+type Checker struct {
+	mu        sync.Mutex
+	liveness  map[string]Check
+	readiness map[string]Check
+}
+
+// Report is the JSON body of a probe response
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// NewChecker returns a checker without any check
+func NewChecker() *Checker {
+	return &Checker{
+		liveness:  map[string]Check{},
+		readiness: map[string]Check{},
+	}
+}
+
+// AddLiveness registers a check run by /healthz and /readyz
+func (c *Checker) AddLiveness(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness[name] = check
+}
+
+// AddReadiness registers a check run by /readyz only
+func (c *Checker) AddReadiness(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readiness[name] = check
+}
+
+// Live runs the liveness checks
+func (c *Checker) Live(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := copyChecks(c.liveness)
+	c.mu.Unlock()
+	return run(ctx, checks)
+}
+
+// Ready runs the liveness and readiness checks
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := copyChecks(c.liveness)
+	for name, check := range c.readiness {
+		checks[name] = check
+	}
+	c.mu.Unlock()
+	return run(ctx, checks)
+}
+
+// Register mounts /healthz and /readyz on app
+func (c *Checker) Register(app fiber.Router) {
+	app.Get("/healthz", func(ctx *fiber.Ctx) error {
+		return respond(ctx, c.Live(ctx.UserContext()))
+	})
+	app.Get("/readyz", func(ctx *fiber.Ctx) error {
+		return respond(ctx, c.Ready(ctx.UserContext()))
+	})
+}
+
+// respond writes the report with 200 when every check passed and 503 otherwise
+func respond(c *fiber.Ctx, report Report) error {
+	status := fiber.StatusOK
+	if report.Status != "ok" {
+		status = fiber.StatusServiceUnavailable
+	}
+	return c.Status(status).JSON(report)
+}
+
+// run executes the checks concurrently, each under checkTimeout
+func run(ctx context.Context, checks map[string]Check) Report {
+	report := Report{Status: "ok", Checks: make(map[string]string, len(checks))}
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			results[i] = check(checkCtx)
+		}(i, checks[name])
+	}
+	wg.Wait()
+
+	for i, name := range names {
+		if results[i] != nil {
+			report.Status = "fail"
+			report.Checks[name] = results[i].Error()
+		} else {
+			report.Checks[name] = "ok"
+		}
+	}
+	return report
+}
+
+func copyChecks(checks map[string]Check) map[string]Check {
+	copied := make(map[string]Check, len(checks))
+	for name, check := range checks {
+		copied[name] = check
+	}
+	return copied
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Fields identify the request a log line belongs to
+type Fields struct {
+	// CorrelationID follows one client request across every service
+	CorrelationID string
+	// Connection is the UUID of the WebSocket the request came from
+	Connection string
+	// UserID is the authenticated user, 0 when anonymous
+	UserID uint
+}
+
+type fieldsKey struct{}
+
+// FromContext returns the fields carried by ctx
+func FromContext(ctx context.Context) Fields {
+	fields, _ := ctx.Value(fieldsKey{}).(Fields)
+	return fields
+}
+
+// WithFields returns a copy of ctx carrying fields
+func WithFields(ctx context.Context, fields Fields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// WithCorrelationID returns a copy of ctx carrying the correlation ID of a request
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	fields := FromContext(ctx)
+	fields.CorrelationID = id
+	return WithFields(ctx, fields)
+}
+
+// WithConnection returns a copy of ctx carrying the WebSocket UUID and user ID
+func WithConnection(ctx context.Context, connection string, userID uint) context.Context {
+	fields := FromContext(ctx)
+	fields.Connection = connection
+	fields.UserID = userID
+	return WithFields(ctx, fields)
+}
+
+// NewCorrelationID returns a random correlation ID
+func NewCorrelationID() string {
+	return uuid.New().String()
+}
+
+// attrs returns the attributes added to the log lines written with ctx
+func attrs(ctx context.Context) []slog.Attr {
+	var attrs []slog.Attr
+	fields := FromContext(ctx)
+	if fields.CorrelationID != "" {
+		attrs = append(attrs, slog.String("correlation_id", fields.CorrelationID))
+	}
+	if fields.Connection != "" {
+		attrs = append(attrs, slog.String("connection", fields.Connection))
+	}
+	if fields.UserID != 0 {
+		attrs = append(attrs, slog.Uint64("user_id", uint64(fields.UserID)))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		attrs = append(attrs,
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	return attrs
+}
+
+// contextHandler adds the request fields and the current span to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		record.AddAttrs(attrs(ctx)...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
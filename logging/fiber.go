@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// correlationIDHTTPHeader carries the correlation ID of HTTP requests and responses
+const correlationIDHTTPHeader = "X-Correlation-ID"
+
+// correlationIDPattern bounds the correlation IDs accepted from clients
+var correlationIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Middleware gives every HTTP request a correlation ID, taken from the
+// X-Correlation-ID header when the client sent a valid one, echoes it in the
+// response and writes one access log line per request
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		id := c.Get(correlationIDHTTPHeader)
+		if !correlationIDPattern.MatchString(id) {
+			id = NewCorrelationID()
+		}
+		c.Set(correlationIDHTTPHeader, id)
+		c.SetUserContext(WithCorrelationID(c.UserContext(), id))
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			status = fiberErr.Code
+		}
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(c.UserContext(), level, "HTTP request",
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("ip", c.IP()),
+		)
+		return err
+	}
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// slowStatement is the duration above which a statement is logged as a warning
+const slowStatement = 200 * time.Millisecond
+
+// gormLogger writes the GORM logs through slog under the request fields of
+// the statement context
+type gormLogger struct {
+	level  gormlogger.LogLevel
+	redact bool
+}
+
+// NewGormLogger returns a GORM logger reporting failed and slow statements.
+// With redact, the bound values are left out of the logged SQL.
+func NewGormLogger(redact bool) gormlogger.Interface {
+	return &gormLogger{level: gormlogger.Warn, redact: redact}
+}
+
+func (l *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		slog.InfoContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		slog.WarnContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		slog.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		sql, rows := fc()
+		slog.ErrorContext(ctx, "Database statement failed", "sql", sql, "rows", rows, "elapsed", elapsed, "error", err)
+	case elapsed > slowStatement && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		slog.WarnContext(ctx, "Slow database statement", "sql", sql, "rows", rows, "elapsed", elapsed)
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		slog.DebugContext(ctx, "Database statement", "sql", sql, "rows", rows, "elapsed", elapsed)
+	}
+}
+
+// ParamsFilter keeps the bound values, such as password hashes and message
+// content, out of the logged SQL when redacting
+func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.redact {
+		return sql, nil
+	}
+	return sql, params
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Options configures the process logger
+type Options struct {
+	// Level is debug, info, warn or error
+	Level string
+	// Format is json or text
+	Format string
+	// Redact masks credentials and message content
+	Redact bool
+}
+
+// Setup installs the slog logger described by opts as the default logger.
+// The standard log package is routed through it too, at the info level.
+func Setup(w io.Writer, opts Options) error {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: level}
+	if opts.Redact {
+		handlerOptions.ReplaceAttr = redact
+	}
+
+	var handler slog.Handler
+	switch opts.Format {
+	case "json", "":
+		handler = slog.NewJSONHandler(w, handlerOptions)
+	case "text":
+		handler = slog.NewTextHandler(w, handlerOptions)
+	default:
+		return fmt.Errorf("unknown log format %q: use json or text", opts.Format)
+	}
+
+	slog.SetDefault(slog.New(&contextHandler{Handler: handler}))
+	return nil
+}
+
+// Fatal logs msg with args at the error level and exits, like log.Fatal
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// ParseLevel converts a level name to a slog level
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q: use debug, info, warn or error", name)
+	}
+}
+
+// redactedValue replaces sensitive attributes
+const redactedValue = "[REDACTED]"
+
+// sensitiveKeys are the attribute keys whose values never reach the logs
+// unless redaction is disabled
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"token":         true,
+	"secret":        true,
+	"authorization": true,
+	"dsn":           true,
+	"content":       true,
+	"body":          true,
+}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, redactedValue)
+	}
+	return attr
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"context"
+	"strconv"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Headers carrying the request fields between services
+const (
+	CorrelationIDHeader = "x-correlation-id"
+	ConnectionHeader    = "x-connection-id"
+	UserIDHeader        = "x-user-id"
+)
+
+// Propagator carries the request fields in message and HTTP headers so that
+// the services handling a request log under the same correlation ID
+type Propagator struct{}
+
+var _ propagation.TextMapPropagator = Propagator{}
+
+// Inject writes the fields of ctx into carrier
+func (Propagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	fields := FromContext(ctx)
+	if fields.CorrelationID != "" {
+		carrier.Set(CorrelationIDHeader, fields.CorrelationID)
+	}
+	if fields.Connection != "" {
+		carrier.Set(ConnectionHeader, fields.Connection)
+	}
+	if fields.UserID != 0 {
+		carrier.Set(UserIDHeader, strconv.FormatUint(uint64(fields.UserID), 10))
+	}
+}
+
+// Extract returns a copy of ctx carrying the fields found in carrier; fields
+// missing from the carrier are left untouched
+func (Propagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	fields := FromContext(ctx)
+	if id := carrier.Get(CorrelationIDHeader); id != "" {
+		fields.CorrelationID = id
+	}
+	if connection := carrier.Get(ConnectionHeader); connection != "" {
+		fields.Connection = connection
+	}
+	if userID, err := strconv.ParseUint(carrier.Get(UserIDHeader), 10, 64); err == nil {
+		fields.UserID = uint(userID)
+	}
+	return WithFields(ctx, fields)
+}
+
+// Fields returns the headers set by Inject
+func (Propagator) Fields() []string {
+	return []string{CorrelationIDHeader, ConnectionHeader, UserIDHeader}
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"os"
 
 	"instant-messaging-app/cmd"
+	"instant-messaging-app/config"
+	"instant-messaging-app/logging"
 
+	"github.com/joho/godotenv"
 	"github.com/urfave/cli/v2"
 )
 
@@ -13,6 +16,31 @@ func main() {
 	app := &cli.App{
 		Name:  "instant-messaging-app",
 		Usage: "An instant messaging app with which utilizes RabbitMQ",
+		Flags: config.Flags(),
+		Before: func(c *cli.Context) error {
+			// Load environment variables
+			if err := godotenv.Load(); err != nil {
+				slog.Info("No .env file found, using the system environment variables")
+			}
+
+			// Build the configuration from the file, environment and flags
+			cfg, err := config.Load(c)
+			if err != nil {
+				return err
+			}
+			config.Cfg = cfg
+
+			// `config print` reports validation errors itself
+			if c.Args().First() == "config" {
+				return nil
+			}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			// Switch to structured logs once the level and format are known
+			return config.SetupLogging()
+		},
 		Commands: []*cli.Command{
 			{
 				Name:  "api",
@@ -38,10 +66,39 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:  "webhook",
+				Usage: "Start the webhook daemon, which posts the message events to the registered webhooks",
+				Action: func(c *cli.Context) error {
+					cmd.StartWebhookService()
+					return nil
+				},
+			},
+			{
+				Name:  "scheduler",
+				Usage: "Start the scheduler daemon, which sends the scheduled messages and reminders when they are due",
+				Action: func(c *cli.Context) error {
+					cmd.StartSchedulerService()
+					return nil
+				},
+			},
+			{
+				Name:  "standalone",
+				Usage: "Start the api gateway and the daemons in one process",
+				Action: func(c *cli.Context) error {
+					cmd.StartStandalone()
+					return nil
+				},
+			},
+			cmd.AdminCommand(),
+			cmd.MigrateCommand(),
+			cmd.ConfigCommand(),
+			cmd.DocsCommand(),
+			cmd.ChatCommand(),
 		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
-		log.Fatal(err)
+		logging.Fatal("Command failed", "error", err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"instant-messaging-app/models"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+)
+
+// invokeBot records an invocation of a bot command and publishes it on the
+// broadcast, where the realtime connections of the bot pick it up. The bot
+// answers later through the REST API; meanwhile the invoker only sees the
+// command echoed.
+func (r *Registry) invokeBot(ctx context.Context, command models.BotCommand, invocation Invocation) (Response, error) {
+	recorded := models.CommandInvocation{
+		CommandID:  command.ID,
+		BotID:      command.BotID,
+		UserID:     invocation.UserID,
+		ReceiverID: invocation.ReceiverID,
+		Args:       invocation.Args,
+	}
+	if err := r.repos.Commands.CreateInvocation(ctx, &recorded); err != nil {
+		return Response{}, err
+	}
+
+	utils.PublishNotification(ctx, r.broadcastExchange, "", "command_invocation", types.CommandInvocation{
+		ID:         recorded.ID,
+		BotID:      recorded.BotID,
+		Command:    command.Name,
+		Args:       recorded.Args,
+		UserID:     recorded.UserID,
+		ReceiverID: recorded.ReceiverID,
+		ExpiresAt:  recorded.ExpiresAt(),
+	})
+
+	content := "/" + command.Name
+	if invocation.Args != "" {
+		content = fmt.Sprintf("/%s %s", command.Name, invocation.Args)
+	}
+	return Response{Content: content, Ephemeral: true}, nil
+}
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+)
+
+// me posts an action of the invoker, such as "* Alice waves"
+func (r *Registry) me(ctx context.Context, invocation Invocation) (Response, error) {
+	if invocation.Args == "" {
+		return Response{}, usage(invocation.Name)
+	}
+	name, err := r.displayName(ctx, invocation.UserID)
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Content: fmt.Sprintf("* %s %s", name, invocation.Args)}, nil
+}
+
+// shrug appends a shrug to the message
+func (r *Registry) shrug(ctx context.Context, invocation Invocation) (Response, error) {
+	return Response{Content: strings.TrimSpace(invocation.Args + ` ¯\_(ツ)_/¯`)}, nil
+}
+
+// mute stops alerting the invoker for the messages of the other user of the
+// conversation, for the duration of the arguments or until /unmute
+func (r *Registry) mute(ctx context.Context, invocation Invocation) (Response, error) {
+	if invocation.ReceiverID == invocation.UserID {
+		return Response{}, &UsageError{Message: "You cannot mute yourself"}
+	}
+	var until *time.Time
+	var duration time.Duration
+	if invocation.Args != "" {
+		var err error
+		if duration, err = parseDelay(invocation.Args); err != nil {
+			return Response{}, usage(invocation.Name)
+		}
+		end := time.Now().Add(duration)
+		until = &end
+	}
+	name, err := r.displayName(ctx, invocation.ReceiverID)
+	if err != nil {
+		return Response{}, err
+	}
+	if err := r.repos.Mutes.Mute(ctx, invocation.UserID, invocation.ReceiverID, until); err != nil {
+		return Response{}, err
+	}
+
+	text := fmt.Sprintf("Muted %s until you /unmute", name)
+	if until != nil {
+		text = fmt.Sprintf("Muted %s for %s", name, formatDelay(duration))
+	}
+	return Response{Content: text, Ephemeral: true}, nil
+}
+
+// unmute lifts the mute of the other user of the conversation
+func (r *Registry) unmute(ctx context.Context, invocation Invocation) (Response, error) {
+	name, err := r.displayName(ctx, invocation.ReceiverID)
+	if err != nil {
+		return Response{}, err
+	}
+	unmuted, err := r.repos.Mutes.Unmute(ctx, invocation.UserID, invocation.ReceiverID)
+	if err != nil {
+		return Response{}, err
+	}
+	if !unmuted {
+		return Response{Content: fmt.Sprintf("%s is not muted", name), Ephemeral: true}, nil
+	}
+	return Response{Content: fmt.Sprintf("Unmuted %s", name), Ephemeral: true}, nil
+}
+
+// remind schedules a message of the invoker to themselves with the text of
+// the arguments, sent by the scheduler daemon once the delay elapsed. The
+// arguments may start with "me" or "me in", as in /remind me in 1h call Bob.
+func (r *Registry) remind(ctx context.Context, invocation Invocation) (Response, error) {
+	args := invocation.Args
+	for _, word := range []string{"me", "in"} {
+		if rest, ok := strings.CutPrefix(args, word+" "); ok {
+			args = strings.TrimSpace(rest)
+		}
+	}
+	delay, text, _ := strings.Cut(args, " ")
+	text = strings.TrimSpace(text)
+	duration, err := parseDelay(delay)
+	if err != nil || text == "" {
+		return Response{}, usage(invocation.Name)
+	}
+	if duration > config.Cfg.Scheduler.MaxDelay {
+		return Response{}, &UsageError{Message: fmt.Sprintf("Reminders are limited to %s", formatDelay(config.Cfg.Scheduler.MaxDelay))}
+	}
+	pending, err := r.repos.Scheduled.CountPending(ctx, invocation.UserID)
+	if err != nil {
+		return Response{}, err
+	}
+	if pending >= int64(config.Cfg.Scheduler.MaxPerUser) {
+		return Response{}, &UsageError{Message: fmt.Sprintf("You already have %d pending reminders and scheduled messages", pending)}
+	}
+
+	reminder := models.ScheduledMessage{
+		UserID:     invocation.UserID,
+		ReceiverID: invocation.UserID,
+		Kind:       models.ScheduleKindReminder,
+		Content:    text,
+		SendAt:     time.Now().Add(duration).UTC(),
+		TimeZone:   "UTC",
+		Status:     models.SchedulePending,
+	}
+	if err := r.repos.Scheduled.Create(ctx, &reminder); err != nil {
+		return Response{}, err
+	}
+	return Response{Content: fmt.Sprintf("I will remind you in %s: %s", formatDelay(duration), text), Ephemeral: true}, nil
+}
+
+// displayName returns the name shown for a user
+func (r *Registry) displayName(ctx context.Context, userID uint) (string, error) {
+	user, err := r.repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if user.DisplayName != "" {
+		return user.DisplayName, nil
+	}
+	return user.Username, nil
+}
+
+// parseDelay parses a positive duration such as 30m or 1h30m
+func parseDelay(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("the duration must be positive")
+	}
+	return duration, nil
+}
+
+// formatDelay formats a duration without its zero units, 1h rather than 1h0m0s
+func formatDelay(duration time.Duration) string {
+	text := duration.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
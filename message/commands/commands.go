@@ -0,0 +1,129 @@
+// Package commands runs the slash commands of the messages starting with /,
+// either built in or registered by a bot
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownCommand is returned for a name that is neither built in nor
+// registered by an active bot
+var ErrUnknownCommand = errors.New("unknown command")
+
+// UsageError reports invalid arguments; its message is shown to the invoker
+type UsageError struct {
+	Message string
+}
+
+func (e *UsageError) Error() string {
+	return e.Message
+}
+
+// usage returns a UsageError telling how to run a built-in command
+func usage(name string) error {
+	for _, command := range models.BuiltinCommands {
+		if command.Name == name {
+			return &UsageError{Message: "Usage: " + command.Usage}
+		}
+	}
+	return &UsageError{Message: "Invalid arguments for /" + name}
+}
+
+// Invocation is a command run by a message of UserID to ReceiverID
+type Invocation struct {
+	UserID     uint
+	ReceiverID uint
+	Name       string
+	Args       string
+}
+
+// Response is the answer to an invocation. It is posted to the conversation
+// as a message of the invoker unless it is ephemeral, in which case only the
+// invoking session gets it.
+type Response struct {
+	Content string
+	// SenderName is shown instead of the name of the invoker when set
+	SenderName string
+	Ephemeral  bool
+}
+
+// Handler runs a command
+type Handler func(ctx context.Context, invocation Invocation) (Response, error)
+
+// Parse splits a message starting with a command into the name of the
+// command and its arguments. Messages starting with // are not commands.
+func Parse(content string) (name, args string, ok bool) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "/") || strings.HasPrefix(content, "//") {
+		return "", "", false
+	}
+	name, args, _ = strings.Cut(content[1:], " ")
+	name = strings.ToLower(name)
+	if name == "" {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(args), true
+}
+
+// Unescape drops the first slash of a message starting with //, which is
+// how a message starting with / is sent as is
+func Unescape(content string) string {
+	trimmed := strings.TrimLeft(content, " \t\n")
+	if strings.HasPrefix(trimmed, "//") {
+		return trimmed[1:]
+	}
+	return content
+}
+
+// Registry runs the built-in commands and hands the others to their bot
+type Registry struct {
+	repos             repositories.Repositories
+	builtins          map[string]Handler
+	broadcastExchange string
+}
+
+// NewRegistry returns a registry whose bot invocations are published on
+// broadcastExchange
+func NewRegistry(repos repositories.Repositories, broadcastExchange string) *Registry {
+	r := &Registry{
+		repos:             repos,
+		broadcastExchange: broadcastExchange,
+	}
+	r.builtins = map[string]Handler{
+		"me":     r.me,
+		"mute":   r.mute,
+		"remind": r.remind,
+		"shrug":  r.shrug,
+		"unmute": r.unmute,
+	}
+	for _, command := range models.BuiltinCommands {
+		if r.builtins[command.Name] == nil {
+			panic(fmt.Sprintf("built-in command /%s has no handler", command.Name))
+		}
+	}
+	return r
+}
+
+// Run runs the command of an invocation
+func (r *Registry) Run(ctx context.Context, invocation Invocation) (Response, error) {
+	if handler, ok := r.builtins[invocation.Name]; ok {
+		return handler(ctx, invocation)
+	}
+
+	command, err := r.repos.Commands.FindByName(ctx, invocation.Name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Response{}, ErrUnknownCommand
+	}
+	if err != nil {
+		return Response{}, err
+	}
+	return r.invokeBot(ctx, command, invocation)
+}
@@ -0,0 +1,198 @@
+package commands_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"instant-messaging-app/broker"
+	"instant-messaging-app/config"
+	"instant-messaging-app/message/commands"
+	"instant-messaging-app/models"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+)
+
+// expectUsage fails unless err is a UsageError
+func expectUsage(t *testing.T, err error, what string) {
+	t.Helper()
+	var usageErr *commands.UsageError
+	if !errors.As(err, &usageErr) {
+		t.Fatalf("%s: expected a usage error, got %v", what, err)
+	}
+}
+
+func TestBuiltinCommands(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := newUser(t, "alice", "Alice"), newUser(t, "bob", "")
+	run := func(userID uint, name, args string) (commands.Response, error) {
+		return registry.Run(ctx, commands.Invocation{UserID: userID, ReceiverID: bob.ID, Name: name, Args: args})
+	}
+
+	// The answers of /me and /shrug are posted to the conversation
+	response, err := run(alice.ID, "me", "waves")
+	if err != nil || response != (commands.Response{Content: "* Alice waves"}) {
+		t.Fatalf("unexpected /me answer %+v (%v)", response, err)
+	}
+	if response, err := run(bob.ID, "me", "waves"); err != nil || response.Content != "* "+bob.Username+" waves" {
+		t.Fatalf("expected the username without a display name, got %+v (%v)", response, err)
+	}
+	if response, err := run(alice.ID, "shrug", "fine"); err != nil || response.Content != `fine ¯\_(ツ)_/¯` || response.Ephemeral {
+		t.Fatalf("unexpected /shrug answer %+v (%v)", response, err)
+	}
+
+	_, err = run(alice.ID, "me", "")
+	expectUsage(t, err, "/me without an action")
+	if _, err := run(alice.ID, "nope", ""); !errors.Is(err, commands.ErrUnknownCommand) {
+		t.Fatalf("expected an unknown command, got %v", err)
+	}
+}
+
+func TestMute(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := newUser(t, "alice", "Alice"), newUser(t, "bob", "")
+	run := func(name, args string) (commands.Response, error) {
+		return registry.Run(ctx, commands.Invocation{UserID: bob.ID, ReceiverID: alice.ID, Name: name, Args: args})
+	}
+	muted := func() bool {
+		t.Helper()
+		muted, err := repos.Mutes.IsMuted(ctx, bob.ID, alice.ID, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return muted
+	}
+
+	// The confirmations are only shown to the invoker
+	response, err := run("mute", "1h")
+	if err != nil || response != (commands.Response{Content: "Muted Alice for 1h", Ephemeral: true}) {
+		t.Fatalf("unexpected /mute answer %+v (%v)", response, err)
+	}
+	if !muted() {
+		t.Fatal("expected alice to be muted")
+	}
+	response, err = run("unmute", "")
+	if err != nil || response != (commands.Response{Content: "Unmuted Alice", Ephemeral: true}) {
+		t.Fatalf("unexpected /unmute answer %+v (%v)", response, err)
+	}
+	if muted() {
+		t.Fatal("expected alice to be unmuted")
+	}
+	if response, err := run("unmute", ""); err != nil || response.Content != "Alice is not muted" {
+		t.Fatalf("unexpected second /unmute answer %+v (%v)", response, err)
+	}
+
+	_, err = run("mute", "soon")
+	expectUsage(t, err, "an invalid duration")
+	_, err = registry.Run(ctx, commands.Invocation{UserID: bob.ID, ReceiverID: bob.ID, Name: "mute"})
+	expectUsage(t, err, "muting oneself")
+}
+
+func TestRemind(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := newUser(t, "alice", ""), newUser(t, "bob", "")
+	run := func(args string) (commands.Response, error) {
+		return registry.Run(ctx, commands.Invocation{UserID: alice.ID, ReceiverID: bob.ID, Name: "remind", Args: args})
+	}
+
+	// The reminder is a scheduled message of alice to herself
+	response, err := run("me in 1h30m call Bob")
+	if err != nil || response != (commands.Response{Content: "I will remind you in 1h30m: call Bob", Ephemeral: true}) {
+		t.Fatalf("unexpected /remind answer %+v (%v)", response, err)
+	}
+	pending, err := repos.Scheduled.ListByUser(ctx, alice.ID, models.SchedulePending, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("expected a reminder, got %+v", pending)
+	}
+	reminder := pending[0]
+	if reminder.ReceiverID != alice.ID || reminder.Kind != models.ScheduleKindReminder || reminder.Content != "call Bob" {
+		t.Fatalf("unexpected reminder %+v", reminder)
+	}
+	if delay := time.Until(reminder.SendAt); delay < 89*time.Minute || delay > 90*time.Minute {
+		t.Fatalf("expected the reminder in 1h30m, got %s", delay)
+	}
+
+	_, err = run("soon call Bob")
+	expectUsage(t, err, "an invalid delay")
+	_, err = run("1h")
+	expectUsage(t, err, "a missing text")
+	_, err = run((config.Cfg.Scheduler.MaxDelay + time.Hour).String() + " later")
+	expectUsage(t, err, "a delay over the limit")
+}
+
+func TestBotCommands(t *testing.T) {
+	ctx := context.Background()
+	owner, alice, bob := newUser(t, "owner", ""), newUser(t, "alice", ""), newUser(t, "bob", "")
+	bot := models.User{Username: owner.Username + "-bot", Password: "-", OwnerID: &owner.ID}
+	if err := repos.Users.Create(ctx, &bot); err != nil {
+		t.Fatal(err)
+	}
+	name := strings.ToLower(bot.Username)
+	if err := repos.Commands.Create(ctx, &models.BotCommand{BotID: bot.ID, Name: name}); err != nil {
+		t.Fatal(err)
+	}
+	invocations := subscribe(t)
+
+	// The invoker sees the command echoed while the bot gets the invocation
+	response, err := registry.Run(ctx, commands.Invocation{UserID: alice.ID, ReceiverID: bob.ID, Name: name, Args: "ping"})
+	if err != nil || response != (commands.Response{Content: "/" + name + " ping", Ephemeral: true}) {
+		t.Fatalf("unexpected answer %+v (%v)", response, err)
+	}
+	var invocation types.CommandInvocation
+	select {
+	case delivery := <-invocations:
+		var notification struct {
+			Type string                  `json:"type"`
+			Data types.CommandInvocation `json:"data"`
+		}
+		if err := json.Unmarshal(delivery.Body, &notification); err != nil {
+			t.Fatal(err)
+		}
+		if notification.Type != "command_invocation" {
+			t.Fatalf("unexpected notification %s", delivery.Body)
+		}
+		invocation = notification.Data
+	case <-time.After(5 * time.Second):
+		t.Fatal("no command_invocation")
+	}
+	if invocation.BotID != bot.ID || invocation.Command != name || invocation.Args != "ping" || invocation.UserID != alice.ID || invocation.ReceiverID != bob.ID {
+		t.Fatalf("unexpected invocation %+v", invocation)
+	}
+	recorded, err := repos.Commands.FindInvocation(ctx, invocation.ID)
+	if err != nil || recorded.UserID != alice.ID || recorded.AnsweredAt != nil {
+		t.Fatalf("unexpected recorded invocation %+v (%v)", recorded, err)
+	}
+
+	// The commands of a deactivated bot are unknown
+	if err := config.DB.Model(&bot).Update("deactivated_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Run(ctx, commands.Invocation{UserID: alice.ID, ReceiverID: bob.ID, Name: name}); !errors.Is(err, commands.ErrUnknownCommand) {
+		t.Fatalf("expected the command of a deactivated bot to be unknown, got %v", err)
+	}
+}
+
+// subscribe consumes the broadcast exchange until the test ends
+func subscribe(t *testing.T) <-chan broker.Delivery {
+	t.Helper()
+	queue := utils.GenerateUUID()
+	if err := config.Broker.DeclareQueue(queue, broker.QueueOptions{AutoDelete: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Broker.BindQueue(queue, broadcast, ""); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	deliveries, err := config.Broker.Consume(ctx, queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deliveries
+}
@@ -0,0 +1,71 @@
+package commands_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"instant-messaging-app/broker"
+	"instant-messaging-app/config"
+	"instant-messaging-app/message/commands"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+)
+
+// broadcast is the exchange the bot invocations are published to
+const broadcast = "notifications_broadcast"
+
+var (
+	// repos are the repositories the commands run on
+	repos repositories.Repositories
+	// registry runs the commands of the tests
+	registry *commands.Registry
+)
+
+// TestMain runs the commands on a SQLite database and an in-process broker
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "message-commands-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cfg := config.Default()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = filepath.Join(dir, "commands.db")
+	config.Cfg = cfg
+	config.InitDatabase()
+	config.Broker = broker.NewMemoryBroker()
+	if err := config.Broker.DeclareExchange(broadcast, broker.ExchangeFanout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	repos = repositories.NewGormRepositories(config.DB)
+	registry = commands.NewRegistry(repos, broadcast)
+
+	code := m.Run()
+	config.Broker.Close()
+	if sqlDB, err := config.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// names numbers the users of the tests, so that every test gets its own
+var names atomic.Int64
+
+// newUser stores a user whose username starts with name, with the given
+// display name
+func newUser(t *testing.T, name, displayName string) models.User {
+	t.Helper()
+	user := models.User{Username: fmt.Sprintf("%s%d", name, names.Add(1)), DisplayName: displayName, Password: "-"}
+	if err := repos.Users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
@@ -0,0 +1,40 @@
+package commands_test
+
+import (
+	"testing"
+
+	"instant-messaging-app/message/commands"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		content    string
+		name, args string
+		ok         bool
+	}{
+		{"/shrug", "shrug", "", true},
+		{"  /Remind 10m  stand up ", "remind", "10m  stand up", true},
+		{"//tmp is full", "", "", false},
+		{"/ nothing", "", "", false},
+		{"hello /me", "", "", false},
+	}
+	for _, tc := range cases {
+		name, args, ok := commands.Parse(tc.content)
+		if name != tc.name || args != tc.args || ok != tc.ok {
+			t.Errorf("Parse(%q) = %q, %q, %v, expected %q, %q, %v", tc.content, name, args, ok, tc.name, tc.args, tc.ok)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	for content, expected := range map[string]string{
+		"//tmp is full": "/tmp is full",
+		"  //x":         "/x",
+		"/me waves":     "/me waves",
+		"a // b":        "a // b",
+	} {
+		if unescaped := commands.Unescape(content); unescaped != expected {
+			t.Errorf("Unescape(%q) = %q, expected %q", content, unescaped, expected)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"instant-messaging-app/broker"
+	"instant-messaging-app/config"
+	"instant-messaging-app/message/commands"
+	"instant-messaging-app/message/handlers"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/utils"
+)
+
+// The exchanges the handlers publish to: the replies, routed by the UUID of
+// the request, and the messages sent to every gateway
+const (
+	notifications = "notifications"
+	broadcast     = "notifications_broadcast"
+)
+
+// The request queues, one per handler
+const (
+	getMessagesQueue = "getMessages"
+	sendMessageQueue = "sendMessage"
+)
+
+// repos are the repositories the handlers run on
+var repos repositories.Repositories
+
+// TestMain runs the handlers of the message service on a SQLite database and
+// an in-process broker, without the gateway
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "message-handlers-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cfg := config.Default()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = filepath.Join(dir, "messages.db")
+	config.Cfg = cfg
+	config.InitDatabase()
+	config.Broker = broker.NewMemoryBroker()
+	if err := declare(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repos = repositories.NewGormRepositories(config.DB)
+	handlers.ConsumeGetMessagesQueue(ctx, repos.Messages, getMessagesQueue, notifications)
+	handlers.ConsumeSendMessageQueue(ctx, repos, commands.NewRegistry(repos, broadcast), sendMessageQueue, notifications, broadcast)
+
+	code := m.Run()
+	cancel()
+	config.Broker.Close()
+	if sqlDB, err := config.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// declare declares the notification exchanges and the request queues
+func declare() error {
+	if err := config.Broker.DeclareExchange(notifications, broker.ExchangeDirect); err != nil {
+		return err
+	}
+	if err := config.Broker.DeclareExchange(broadcast, broker.ExchangeFanout); err != nil {
+		return err
+	}
+	for _, queue := range []string{getMessagesQueue, sendMessageQueue} {
+		if err := config.Broker.DeclareQueue(queue, broker.QueueOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// names numbers the users of the tests, so that every test gets its own
+var names atomic.Int64
+
+// newUser stores a user whose username starts with name
+func newUser(t *testing.T, name string) models.User {
+	t.Helper()
+	user := models.User{Username: fmt.Sprintf("%s%d", name, names.Add(1)), Password: "-"}
+	if err := repos.Users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// response is a notification published by a handler
+type response struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// subscribe binds a new queue to exchange with routingKey and consumes it
+// until the test ends
+func subscribe(t *testing.T, exchange, routingKey string) <-chan broker.Delivery {
+	t.Helper()
+	queue := utils.GenerateUUID()
+	if err := config.Broker.DeclareQueue(queue, broker.QueueOptions{AutoDelete: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Broker.BindQueue(queue, exchange, routingKey); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	deliveries, err := config.Broker.Consume(ctx, queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deliveries
+}
+
+// publish publishes the request build returns for a new UUID to queue, and
+// returns the replies published for that UUID
+func publish(t *testing.T, queue string, build func(uuid string) interface{}) <-chan broker.Delivery {
+	t.Helper()
+	uuid := utils.GenerateUUID()
+	replies := subscribe(t, notifications, uuid)
+	body, err := json.Marshal(build(uuid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = config.Broker.Publish(context.Background(), "", queue, broker.Message{ContentType: "application/json", Body: body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return replies
+}
+
+// next waits for the next notification of deliveries and unmarshals its data
+// into v, failing unless it has the given type
+func next(t *testing.T, deliveries <-chan broker.Delivery, responseType string, v interface{}) {
+	t.Helper()
+	select {
+	case delivery := <-deliveries:
+		var r response
+		if err := json.Unmarshal(delivery.Body, &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Type != responseType {
+			t.Fatalf("expected a %s, got %s %s", responseType, r.Type, r.Data)
+		}
+		if err := json.Unmarshal(r.Data, v); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no %s", responseType)
+	}
+}
+
+// expectNone fails if deliveries gets a notification soon
+func expectNone(t *testing.T, deliveries <-chan broker.Delivery) {
+	t.Helper()
+	select {
+	case delivery := <-deliveries:
+		t.Fatalf("unexpected notification %s", delivery.Body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
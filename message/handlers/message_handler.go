@@ -3,52 +3,67 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"instant-messaging-app/config"
 	"instant-messaging-app/dtos"
+	"instant-messaging-app/health"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/message/commands"
 	"instant-messaging-app/message/services"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/tracing"
 	"instant-messaging-app/types"
 	"instant-messaging-app/utils"
-	"log"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 // ConsumeGetUsersQueue listens to getUsers requests and processes them
-func ConsumeGetMessagesQueue(ctx context.Context, queueName string, notificationExchange string) {
-	msgs, err := config.RabbitMQCh.Consume(
-		queueName, // Queue name
-		"",
-		true,  // Auto-acknowledge
-		false, // Exclusive
-		false, // No-local
-		false, // No-wait
-		nil,
-	)
+func ConsumeGetMessagesQueue(ctx context.Context, messageRepo repositories.MessageRepository, queueName string, notificationExchange string) {
+	msgs, err := config.Broker.Consume(ctx, queueName)
 	if err != nil {
-		log.Fatalf("Failed to start consuming from queue %s: %v", queueName, err)
+		logging.Fatal("Failed to start consuming", "queue", queueName, "error", err)
 	}
 
+	consumer := health.TrackConsumer(queueName)
 	go func() {
+		defer consumer.Stop()
 		for {
+			consumer.Idle()
 			select {
 			case <-ctx.Done():
-				log.Println("Stopping getMessages queue consumption...")
+				slog.Info("Stopping getMessages queue consumption", "queue", queueName)
 				return
-			case msg := <-msgs:
+			case msg, ok := <-msgs:
+				if !ok {
+					slog.Warn("Consumer closed", "queue", queueName)
+					return
+				}
+				consumer.Busy()
+				msgCtx := tracing.StartTrackedConsume(ctx, consumer, queueName, msg)
 				var request types.GetMessagesRequest
 				if err := json.Unmarshal(msg.Body, &request); err != nil {
-					log.Printf("Failed to unmarshal getMessages request: %v", err)
+					slog.ErrorContext(msgCtx, "Failed to unmarshal getMessages request", "error", err)
+					consumer.Fail()
 					continue
 				}
 
 				// Fetch users from the database
-				log.Printf("Fetching messages between %v and %v", request.UserID, request.ReceiverID)
-				messages, err := services.GetMessagesBetweenUsers(request.UserID, request.ReceiverID)
+				slog.DebugContext(msgCtx, "Fetching messages", "receiver_id", request.ReceiverID)
+				messages, err := services.GetMessagesBetweenUsers(msgCtx, messageRepo, request.UserID, request.ReceiverID)
 				if err != nil {
-					log.Printf("Failed to fetch messages for user id: %s: %v", request.UUID, err)
+					slog.ErrorContext(msgCtx, "Failed to fetch messages", "error", err)
+					utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeInternal, "Failed to retrieve messages")
+					consumer.Fail()
 					continue
 				}
 
 				// Publish notification with the message type
-				utils.PublishNotification(notificationExchange, request.UUID, "get_messages_response", types.GetMessagesResponse{
+				utils.PublishNotification(msgCtx, notificationExchange, request.UUID, "get_messages_response", types.GetMessagesResponse{
 					Messages: dtos.ToMessageDTOs(messages),
 				})
 
@@ -57,47 +72,167 @@ func ConsumeGetMessagesQueue(ctx context.Context, queueName string, notification
 	}()
 }
 
-// ConsumeGetUsersQueue listens to getUsers requests and processes them
-func ConsumeSendMessageQueue(ctx context.Context, queueName string, notificationExchange string) {
-	msgs, err := config.RabbitMQCh.Consume(
-		queueName, // Queue name
-		"",
-		true,  // Auto-acknowledge
-		false, // Exclusive
-		false, // No-local
-		false, // No-wait
-		nil,
-	)
+// ConsumeSendMessageQueue listens to sendMessage requests, stores the messages
+// and broadcasts them; errors and replies go to the sender on notificationExchange.
+// Messages starting with a command are run by registry instead.
+func ConsumeSendMessageQueue(ctx context.Context, repos repositories.Repositories, registry *commands.Registry, queueName string, notificationExchange string, broadcastExchange string) {
+	msgs, err := config.Broker.Consume(ctx, queueName)
 	if err != nil {
-		log.Fatalf("Failed to start consuming from queue %s: %v", queueName, err)
+		logging.Fatal("Failed to start consuming", "queue", queueName, "error", err)
 	}
 
+	consumer := health.TrackConsumer(queueName)
 	go func() {
+		defer consumer.Stop()
 		for {
+			consumer.Idle()
 			select {
 			case <-ctx.Done():
-				log.Println("Stopping sendMessage queue consumption...")
+				slog.Info("Stopping sendMessage queue consumption", "queue", queueName)
 				return
-			case msg := <-msgs:
+			case msg, ok := <-msgs:
+				if !ok {
+					slog.Warn("Consumer closed", "queue", queueName)
+					return
+				}
+				consumer.Busy()
+				msgCtx := tracing.StartTrackedConsume(ctx, consumer, queueName, msg)
 				var request types.SendMessageRequest
 				if err := json.Unmarshal(msg.Body, &request); err != nil {
-					log.Printf("Failed to unmarshal sendMessage request: %v", err)
+					slog.ErrorContext(msgCtx, "Failed to unmarshal sendMessage request", "error", err)
+					consumer.Fail()
 					continue
 				}
 
-				// Fetch users from the database
-				log.Printf("Fetching messages between %v and %v", request.UserID, request.ReceiverID)
-				message, err := services.CreateMessage(request.UserID, request.ReceiverID, request.Content)
-				if err != nil {
-					log.Printf("Failed to fetch messages for user id: %s: %v", request.UUID, err)
+				// The answers of bots are never stored, since a message in the
+				// conversation would be attributed to the invoker
+				if request.InvocationID != 0 && !request.Ephemeral {
+					utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeBadRequest, "The answers of commands are ephemeral")
 					continue
 				}
 
-				// Publish notification with the message type
-				utils.PublishNotification(notificationExchange, "", "send_message_response", types.SendMessageResponse{
+				// Ephemeral messages are answers of bots, which reach every
+				// session of the invoker
+				if request.Ephemeral {
+					if strings.TrimSpace(request.Content) == "" {
+						utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeBadRequest, "Message content is empty")
+						continue
+					}
+					if request.InvocationID != 0 {
+						err := repos.Commands.MarkAnswered(msgCtx, request.InvocationID, time.Now())
+						switch {
+						case errors.Is(err, repositories.ErrInvocationAnswered):
+							utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeConflict, "The invocation was already answered")
+							continue
+						case errors.Is(err, gorm.ErrRecordNotFound):
+							utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeNotFound, "Invocation not found")
+							continue
+						case err != nil:
+							slog.ErrorContext(msgCtx, "Failed to mark the invocation answered", "invocation_id", request.InvocationID, "error", err)
+							utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeInternal, "Failed to answer the invocation")
+							consumer.Fail()
+							continue
+						}
+					}
+					response := ephemeralResponse(request.UserID, request.ReceiverID, request.Content, request.SenderName)
+					utils.PublishNotification(msgCtx, broadcastExchange, "", "send_message_response", response)
+					if request.Reply {
+						utils.PublishNotification(msgCtx, notificationExchange, request.UUID, "send_message_response", response)
+					}
+					continue
+				}
+
+				content, senderName := request.Content, request.SenderName
+				if !request.Verbatim {
+					if name, args, ok := commands.Parse(content); ok {
+						slog.DebugContext(msgCtx, "Running command", "command", name, "receiver_id", request.ReceiverID)
+						response, err := registry.Run(msgCtx, commands.Invocation{
+							UserID:     request.UserID,
+							ReceiverID: request.ReceiverID,
+							Name:       name,
+							Args:       args,
+						})
+						var usageErr *commands.UsageError
+						switch {
+						case errors.Is(err, commands.ErrUnknownCommand):
+							utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeBadRequest,
+								fmt.Sprintf("Unknown command /%s; start the message with // to send it as is", name))
+							continue
+						case errors.As(err, &usageErr):
+							utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeBadRequest, usageErr.Message)
+							continue
+						case errors.Is(err, gorm.ErrRecordNotFound):
+							utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeNotFound, "Recipient not found")
+							continue
+						case err != nil:
+							slog.ErrorContext(msgCtx, "Failed to run command", "command", name, "error", err)
+							utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeInternal, "Failed to run the command")
+							consumer.Fail()
+							continue
+						}
+
+						// Only the invoking session gets ephemeral answers
+						if response.Ephemeral {
+							utils.PublishNotification(msgCtx, notificationExchange, request.UUID, "send_message_response",
+								ephemeralResponse(request.UserID, request.ReceiverID, response.Content, response.SenderName))
+							continue
+						}
+						content, senderName = response.Content, response.SenderName
+					} else {
+						content = commands.Unescape(content)
+					}
+				}
+
+				// Store the message
+				slog.DebugContext(msgCtx, "Storing message", "receiver_id", request.ReceiverID)
+				message, err := services.CreateMessage(msgCtx, repos, request.UserID, request.ReceiverID, content, senderName)
+				switch {
+				case errors.Is(err, services.ErrEmptyMessage):
+					utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeBadRequest, "Message content is empty")
+					continue
+				case errors.Is(err, gorm.ErrRecordNotFound):
+					utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeNotFound, "Recipient not found")
+					continue
+				case err != nil:
+					slog.ErrorContext(msgCtx, "Failed to store message", "error", err)
+					utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeInternal, "Failed to send message")
+					consumer.Fail()
+					continue
+				}
+
+				// Publish notification with the message type and its sequence
+				// numbers for both peers
+				response := types.SendMessageResponse{
 					Message: dtos.ToMessageDTO(message),
-				})
+				}
+				for _, event := range message.Events {
+					if event.UserID == message.SenderID {
+						response.SenderSeq = event.Seq
+					}
+					if event.UserID == message.ReceiverID {
+						response.ReceiverSeq = event.Seq
+					}
+				}
+				response.Muted = message.Muted
+				utils.PublishNotification(msgCtx, broadcastExchange, "", "send_message_response", response)
+				if request.Reply {
+					utils.PublishNotification(msgCtx, notificationExchange, request.UUID, "send_message_response", response)
+				}
 			}
 		}
 	}()
-}
\ No newline at end of file
+}
+
+// ephemeralResponse returns an ephemeral message, which has no ID nor
+// sequence numbers since it is not stored
+func ephemeralResponse(userID, receiverID uint, content, senderName string) types.SendMessageResponse {
+	return types.SendMessageResponse{
+		Message: dtos.MessageDTO{
+			SenderID:   userID,
+			ReceiverID: receiverID,
+			Content:    content,
+			SenderName: senderName,
+		},
+		Ephemeral: true,
+	}
+}
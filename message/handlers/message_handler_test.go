@@ -0,0 +1,209 @@
+package handlers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"instant-messaging-app/broker"
+	"instant-messaging-app/models"
+	"instant-messaging-app/types"
+)
+
+// send publishes a sendMessage request and returns the replies to its UUID
+func send(t *testing.T, request types.SendMessageRequest) <-chan broker.Delivery {
+	t.Helper()
+	return publish(t, sendMessageQueue, func(uuid string) interface{} {
+		request.UUID = uuid
+		return request
+	})
+}
+
+func TestSendMessage(t *testing.T) {
+	alice, bob := newUser(t, "alice"), newUser(t, "bob")
+	broadcasts := subscribe(t, broadcast, "")
+
+	// A stored message is broadcast with its sequence numbers for both peers,
+	// and replied to its UUID on request
+	replies := send(t, types.SendMessageRequest{UserID: alice.ID, ReceiverID: bob.ID, Content: "hello bob", Reply: true})
+	var sent, replied types.SendMessageResponse
+	next(t, broadcasts, "send_message_response", &sent)
+	next(t, replies, "send_message_response", &replied)
+	message := sent.Message
+	if message.ID == 0 || message.SenderID != alice.ID || message.ReceiverID != bob.ID || message.Content != "hello bob" {
+		t.Fatalf("unexpected message %+v", message)
+	}
+	if sent.SenderSeq != 1 || sent.ReceiverSeq != 1 || sent.Ephemeral || replied.Message.ID != message.ID {
+		t.Fatalf("unexpected responses %+v %+v", sent, replied)
+	}
+
+	// Without Reply, only the broadcast gets it
+	replies = send(t, types.SendMessageRequest{UserID: bob.ID, ReceiverID: alice.ID, Content: "hi alice"})
+	next(t, broadcasts, "send_message_response", &sent)
+	if sent.SenderSeq != 2 || sent.ReceiverSeq != 2 {
+		t.Fatalf("unexpected sequence numbers %+v", sent)
+	}
+	expectNone(t, replies)
+
+	stored, err := repos.Messages.ListBetween(context.Background(), alice.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 || stored[0].Content != "hello bob" || stored[1].Content != "hi alice" {
+		t.Fatalf("unexpected stored messages %+v", stored)
+	}
+}
+
+func TestSendMutedMessage(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := newUser(t, "alice"), newUser(t, "bob")
+	broadcasts := subscribe(t, broadcast, "")
+	if err := repos.Mutes.Mute(ctx, bob.ID, alice.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The muted flag is broadcast and kept on the event of the receiver, so
+	// that a resumed session replays it
+	send(t, types.SendMessageRequest{UserID: alice.ID, ReceiverID: bob.ID, Content: "quiet"})
+	var sent types.SendMessageResponse
+	next(t, broadcasts, "send_message_response", &sent)
+	if !sent.Muted {
+		t.Fatalf("expected the message to be muted, got %+v", sent)
+	}
+	for _, user := range []models.User{alice, bob} {
+		events, err := repos.Messages.ListEventsAfter(ctx, user.ID, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].Message.Content != "quiet" || events[0].Muted != (user.ID == bob.ID) {
+			t.Fatalf("unexpected events of %s %+v", user.Username, events)
+		}
+	}
+}
+
+func TestSendMessageErrors(t *testing.T) {
+	alice := newUser(t, "alice")
+
+	for _, tc := range []struct {
+		name    string
+		request types.SendMessageRequest
+		code    string
+	}{
+		{"empty content", types.SendMessageRequest{UserID: alice.ID, ReceiverID: alice.ID, Content: "  "}, types.ErrorCodeBadRequest},
+		{"unknown receiver", types.SendMessageRequest{UserID: alice.ID, ReceiverID: alice.ID + 1000, Content: "hello"}, types.ErrorCodeNotFound},
+		{"unknown command", types.SendMessageRequest{UserID: alice.ID, ReceiverID: alice.ID, Content: "/doesnotexist"}, types.ErrorCodeBadRequest},
+		{"empty ephemeral", types.SendMessageRequest{UserID: alice.ID, ReceiverID: alice.ID, Ephemeral: true}, types.ErrorCodeBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var response types.ErrorResponse
+			next(t, send(t, tc.request), "error", &response)
+			if response.Code != tc.code {
+				t.Fatalf("expected %s, got %+v", tc.code, response)
+			}
+		})
+	}
+}
+
+func TestSendCommands(t *testing.T) {
+	alice, bob := newUser(t, "alice"), newUser(t, "bob")
+	broadcasts := subscribe(t, broadcast, "")
+
+	// A command stores its answer instead of the content
+	send(t, types.SendMessageRequest{UserID: alice.ID, ReceiverID: bob.ID, Content: "/shrug ok"})
+	var sent types.SendMessageResponse
+	next(t, broadcasts, "send_message_response", &sent)
+	if sent.Message.Content != `ok ¯\_(ツ)_/¯` {
+		t.Fatalf("unexpected answer %+v", sent.Message)
+	}
+
+	// A doubled slash and a verbatim message are stored as is
+	send(t, types.SendMessageRequest{UserID: alice.ID, ReceiverID: bob.ID, Content: "//shrug"})
+	next(t, broadcasts, "send_message_response", &sent)
+	if sent.Message.Content != "/shrug" {
+		t.Fatalf("unexpected escaped message %+v", sent.Message)
+	}
+	send(t, types.SendMessageRequest{UserID: alice.ID, ReceiverID: bob.ID, Content: "/shrug", Verbatim: true})
+	next(t, broadcasts, "send_message_response", &sent)
+	if sent.Message.Content != "/shrug" {
+		t.Fatalf("unexpected verbatim message %+v", sent.Message)
+	}
+
+	// An ephemeral answer only goes to the invoking request
+	replies := send(t, types.SendMessageRequest{UserID: alice.ID, ReceiverID: bob.ID, Content: "/mute"})
+	var confirmation types.SendMessageResponse
+	next(t, replies, "send_message_response", &confirmation)
+	if !confirmation.Ephemeral || confirmation.Message.ID != 0 {
+		t.Fatalf("unexpected confirmation %+v", confirmation)
+	}
+	expectNone(t, broadcasts)
+}
+
+func TestCommandAnswers(t *testing.T) {
+	alice, bob, bot := newUser(t, "alice"), newUser(t, "bob"), newUser(t, "deployer")
+	broadcasts := subscribe(t, broadcast, "")
+	invoke := func() models.CommandInvocation {
+		t.Helper()
+		invocation := models.CommandInvocation{CommandID: 1, BotID: bot.ID, UserID: alice.ID, ReceiverID: bob.ID, CreatedAt: time.Now()}
+		if err := repos.Commands.CreateInvocation(context.Background(), &invocation); err != nil {
+			t.Fatal(err)
+		}
+		return invocation
+	}
+	answer := func(invocation models.CommandInvocation, content string, ephemeral bool) <-chan broker.Delivery {
+		t.Helper()
+		return send(t, types.SendMessageRequest{
+			UserID:       alice.ID,
+			ReceiverID:   bob.ID,
+			Content:      content,
+			SenderName:   "Deployer",
+			Verbatim:     true,
+			Ephemeral:    ephemeral,
+			InvocationID: invocation.ID,
+		})
+	}
+
+	// An answer is never stored, which would attribute it to alice
+	invocation := invoke()
+	var refused types.ErrorResponse
+	next(t, answer(invocation, "deployed", false), "error", &refused)
+	if refused.Code != types.ErrorCodeBadRequest {
+		t.Fatalf("expected bad request, got %+v", refused)
+	}
+
+	// An ephemeral answer is not stored either, yet answers the invocation
+	answer(invocation, "deploying", true)
+	var sent types.SendMessageResponse
+	next(t, broadcasts, "send_message_response", &sent)
+	if !sent.Ephemeral || sent.Message.ID != 0 || sent.Message.SenderName != "Deployer" {
+		t.Fatalf("unexpected ephemeral answer %+v", sent)
+	}
+	var conflict types.ErrorResponse
+	next(t, answer(invocation, "deployed", true), "error", &conflict)
+	if conflict.Code != types.ErrorCodeConflict {
+		t.Fatalf("expected conflict, got %+v", conflict)
+	}
+	expectNone(t, broadcasts)
+}
+
+func TestGetMessages(t *testing.T) {
+	alice, bob, carol := newUser(t, "alice"), newUser(t, "bob"), newUser(t, "carol")
+	broadcasts := subscribe(t, broadcast, "")
+	for _, request := range []types.SendMessageRequest{
+		{UserID: alice.ID, ReceiverID: bob.ID, Content: "to bob"},
+		{UserID: bob.ID, ReceiverID: alice.ID, Content: "to alice"},
+		{UserID: alice.ID, ReceiverID: carol.ID, Content: "to carol"},
+	} {
+		send(t, request)
+		var sent types.SendMessageResponse
+		next(t, broadcasts, "send_message_response", &sent)
+	}
+
+	// Only the conversation of the two users is returned, oldest first
+	var response types.GetMessagesResponse
+	next(t, publish(t, getMessagesQueue, func(uuid string) interface{} {
+		return types.GetMessagesRequest{UUID: uuid, UserID: alice.ID, ReceiverID: bob.ID}
+	}), "get_messages_response", &response)
+	if len(response.Messages) != 2 || response.Messages[0].Content != "to bob" || response.Messages[1].Content != "to alice" {
+		t.Fatalf("unexpected conversation %+v", response.Messages)
+	}
+}
@@ -1,27 +1,53 @@
 package services
 
 import (
-	"instant-messaging-app/config"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
 	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
 )
 
-func GetMessagesBetweenUsers(senderID uint, receiverID uint) ([]models.Message, error) {
-	var messages []models.Message
-	err := config.DB.Preload("Sender").Preload("Receiver").
-		Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
-			senderID, receiverID, receiverID, senderID).
-		Order("created_at asc").
-		Find(&messages).Error
-	return messages, err
+// GetMessagesBetweenUsers retrieves the conversation between two users, oldest first
+func GetMessagesBetweenUsers(ctx context.Context, messages repositories.MessageRepository, senderID uint, receiverID uint) ([]models.Message, error) {
+	return messages.ListBetween(ctx, senderID, receiverID)
 }
 
-func CreateMessage(senderID uint, receiverID uint, content string) (models.Message, error) {
+// ErrEmptyMessage is returned when a message has no content
+var ErrEmptyMessage = errors.New("message content is empty")
+
+// CreateMessage stores a new message from senderID to receiverID, shown as
+// sent by senderName when set, and records on its events whether the
+// receiver muted the sender
+func CreateMessage(ctx context.Context, repos repositories.Repositories, senderID uint, receiverID uint, content, senderName string) (models.Message, error) {
+	if strings.TrimSpace(content) == "" {
+		return models.Message{}, ErrEmptyMessage
+	}
+
 	message := models.Message{
 		SenderID:   senderID,
 		ReceiverID: receiverID,
 		Content:    content,
+		SenderName: senderName,
+	}
+	muted, err := IsMutedByReceiver(ctx, repos.Mutes, message)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to check whether the receiver muted the sender", "error", err)
 	}
+	message.Muted = muted
 
-	err := config.DB.Create(&message).Error
+	err = repos.Messages.Create(ctx, &message)
 	return message, err
-}
\ No newline at end of file
+}
+
+// IsMutedByReceiver reports whether the receiver of a message muted its
+// sender, in which case its clients should not alert
+func IsMutedByReceiver(ctx context.Context, mutes repositories.MuteRepository, message models.Message) (bool, error) {
+	if message.ReceiverID == message.SenderID {
+		return false, nil
+	}
+	return mutes.IsMuted(ctx, message.ReceiverID, message.SenderID, time.Now())
+}
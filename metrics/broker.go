@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+
+	"instant-messaging-app/broker"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// instrumentedBroker counts the messages flowing through a Broker
+type instrumentedBroker struct {
+	broker.Broker
+
+	mu     sync.Mutex
+	queues map[string]bool
+}
+
+var (
+	// current is the broker whose queues the backlog collector reports
+	currentMu    sync.Mutex
+	current      *instrumentedBroker
+	registerOnce sync.Once
+)
+
+// InstrumentBroker wraps b so that publications, deliveries and the backlog
+// of every consumed queue are exported
+func InstrumentBroker(b broker.Broker) broker.Broker {
+	instrumented := &instrumentedBroker{Broker: b, queues: map[string]bool{}}
+
+	currentMu.Lock()
+	current = instrumented
+	currentMu.Unlock()
+	registerOnce.Do(func() {
+		prometheus.MustRegister(backlogCollector{})
+	})
+	return instrumented
+}
+
+func (b *instrumentedBroker) Publish(ctx context.Context, exchange, routingKey string, message broker.Message) error {
+	err := b.Broker.Publish(ctx, exchange, routingKey, message)
+	if err != nil {
+		brokerPublishErrors.WithLabelValues(exchange, label(routingKey)).Inc()
+	} else {
+		brokerPublished.WithLabelValues(exchange, label(routingKey)).Inc()
+	}
+	return err
+}
+
+func (b *instrumentedBroker) Consume(ctx context.Context, queue string) (<-chan broker.Delivery, error) {
+	msgs, err := b.Broker.Consume(ctx, queue)
+	if err != nil {
+		brokerConsumeErrors.WithLabelValues(label(queue)).Inc()
+		return nil, err
+	}
+
+	// Per-connection queues come and go; only the service queues get a backlog
+	if label(queue) == queue {
+		b.mu.Lock()
+		b.queues[queue] = true
+		b.mu.Unlock()
+	}
+
+	deliveries := make(chan broker.Delivery)
+	go func() {
+		defer close(deliveries)
+		for delivery := range msgs {
+			brokerConsumed.WithLabelValues(label(delivery.RoutingKey)).Inc()
+			select {
+			case deliveries <- delivery:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+// consumedQueues returns the service queues consumed by this process
+func (b *instrumentedBroker) consumedQueues() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	queues := make([]string, 0, len(b.queues))
+	for queue := range b.queues {
+		queues = append(queues, queue)
+	}
+	return queues
+}
+
+// backlogCollector reports the ready messages of the consumed queues at scrape time
+type backlogCollector struct{}
+
+var backlogDesc = prometheus.NewDesc(
+	namespace+"_broker_queue_backlog",
+	"Messages waiting in the queues consumed by this process.",
+	[]string{"queue"}, nil,
+)
+
+func (backlogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- backlogDesc
+}
+
+func (backlogCollector) Collect(ch chan<- prometheus.Metric) {
+	currentMu.Lock()
+	b := current
+	currentMu.Unlock()
+	if b == nil {
+		return
+	}
+
+	for _, queue := range b.consumedQueues() {
+		length, err := b.QueueLength(queue)
+		if err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(backlogDesc, prometheus.GaugeValue, float64(length), queue)
+	}
+}
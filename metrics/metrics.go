@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"regexp"
+	"time"
+
+	"instant-messaging-app/health"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric of the application
+const namespace = "instant_messaging"
+
+var (
+	// WebSocketConnections is the number of open WebSockets on the gateway
+	WebSocketConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_connections",
+		Help:      "Open WebSocket connections.",
+	})
+
+	// WebSocketMessages counts the WebSocket frames by direction (in, out) and type
+	WebSocketMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_messages_total",
+		Help:      "WebSocket messages by direction and type.",
+	}, []string{"direction", "type"})
+
+	// WebSocketSlowConsumers counts the frames dropped (drop) and the clients
+	// disconnected (disconnect, or resume rather than dropping an event)
+	// because their outbound buffer was full
+	WebSocketSlowConsumers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_slow_consumers_total",
+		Help:      "Slow WebSocket clients by the action taken.",
+	}, []string{"action"})
+
+	// WebhookDeliveries counts the attempts of the webhook daemon by outcome:
+	// succeeded, retried (failed and scheduled again) or failed (given up)
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by outcome.",
+	}, []string{"outcome"})
+
+	// ScheduledMessagesSent counts the scheduled messages and reminders sent
+	// by the scheduler daemon
+	ScheduledMessagesSent = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scheduled_messages_sent_total",
+		Help:      "Scheduled messages and reminders sent.",
+	})
+
+	brokerPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "broker_published_total",
+		Help:      "Messages published by exchange and routing key.",
+	}, []string{"exchange", "routing_key"})
+
+	brokerPublishErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "broker_publish_errors_total",
+		Help:      "Failed publications by exchange and routing key.",
+	}, []string{"exchange", "routing_key"})
+
+	brokerConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "broker_consumed_total",
+		Help:      "Messages delivered to consumers by routing key.",
+	}, []string{"routing_key"})
+
+	brokerConsumeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "broker_consume_errors_total",
+		Help:      "Consumers that failed to start by queue.",
+	}, []string{"queue"})
+
+	handlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "consumer_handler_duration_seconds",
+		Help:      "Time spent by the queue consumers on each message.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"queue"})
+
+	handlerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "consumer_handler_errors_total",
+		Help:      "Messages the queue consumers failed to process.",
+	}, []string{"queue"})
+)
+
+// connectionQueuePattern matches the per-connection UUIDs used as queue
+// names and routing keys
+var connectionQueuePattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+func init() {
+	health.OnHandled(observeHandled)
+}
+
+// observeHandled records the latency and outcome of a consumed message
+func observeHandled(queue string, took time.Duration, failed bool) {
+	queue = label(queue)
+	handlerDuration.WithLabelValues(queue).Observe(took.Seconds())
+	if failed {
+		handlerErrors.WithLabelValues(queue).Inc()
+	}
+}
+
+// Register mounts the Prometheus /metrics endpoint on app
+func Register(app fiber.Router) {
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
+}
+
+// label folds per-connection UUIDs into a single value to bound the number of series
+func label(value string) string {
+	if connectionQueuePattern.MatchString(value) {
+		return "connection"
+	}
+	return value
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestReadPending(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrations.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	known, err := Load(Dialect(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without schema_migrations every migration is pending, and the table
+	// is not created
+	pending, err := ReadPending(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != len(known) {
+		t.Fatalf("expected %d pending migrations, got %d", len(known), len(pending))
+	}
+	if db.Migrator().HasTable(&schemaMigration{}) {
+		t.Fatal("ReadPending created schema_migrations")
+	}
+
+	if _, err := Up(db, 1); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = ReadPending(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != len(known)-1 || pending[0].Version != known[1].Version {
+		t.Fatalf("expected the migrations after %04d to be pending, got %+v", known[0].Version, pending)
+	}
+}
@@ -0,0 +1,27 @@
+-- Users and direct messages, matching the schema previously created by AutoMigrate.
+-- IF NOT EXISTS lets databases that were auto-migrated adopt versioned migrations.
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    username TEXT NOT NULL,
+    password TEXT NOT NULL,
+    CONSTRAINT uni_users_username UNIQUE (username)
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    sender_id BIGINT NOT NULL,
+    receiver_id BIGINT NOT NULL,
+    content TEXT NOT NULL,
+    CONSTRAINT fk_messages_sender FOREIGN KEY (sender_id) REFERENCES users (id),
+    CONSTRAINT fk_messages_receiver FOREIGN KEY (receiver_id) REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_messages_deleted_at ON messages (deleted_at);
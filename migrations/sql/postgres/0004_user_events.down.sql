@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_events;
+ALTER TABLE users DROP COLUMN IF EXISTS last_event_seq;
@@ -0,0 +1,14 @@
+-- Per-user event sequence numbers, replayed to resuming WebSocket clients
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_event_seq BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS user_events (
+    user_id BIGINT NOT NULL,
+    seq BIGINT NOT NULL,
+    message_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ,
+    PRIMARY KEY (user_id, seq),
+    CONSTRAINT fk_user_events_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_messages_events FOREIGN KEY (message_id) REFERENCES messages (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_events_message_id ON user_events (message_id);
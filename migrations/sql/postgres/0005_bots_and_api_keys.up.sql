@@ -0,0 +1,20 @@
+-- Bot accounts, owned by a user, and their hashed API keys
+ALTER TABLE users ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES users (id);
+
+CREATE INDEX IF NOT EXISTS idx_users_owner_id ON users (owner_id);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (id),
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    rate_limit INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
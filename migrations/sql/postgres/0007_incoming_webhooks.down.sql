@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS incoming_webhooks;
+ALTER TABLE messages DROP COLUMN IF EXISTS sender_name;
@@ -0,0 +1,16 @@
+-- Incoming webhooks posting into a conversation, and the sender name they
+-- may show on their messages
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS sender_name TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS incoming_webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (id),
+    receiver_id BIGINT NOT NULL REFERENCES users (id),
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_incoming_webhooks_user_id ON incoming_webhooks (user_id);
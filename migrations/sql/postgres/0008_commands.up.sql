@@ -0,0 +1,34 @@
+-- Slash commands registered by bots, their invocations and the muted
+-- conversations
+CREATE TABLE IF NOT EXISTS bot_commands (
+    id BIGSERIAL PRIMARY KEY,
+    bot_id BIGINT NOT NULL REFERENCES users (id),
+    name TEXT NOT NULL UNIQUE,
+    usage TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_bot_commands_bot_id ON bot_commands (bot_id);
+
+CREATE TABLE IF NOT EXISTS command_invocations (
+    id BIGSERIAL PRIMARY KEY,
+    command_id BIGINT NOT NULL,
+    bot_id BIGINT NOT NULL REFERENCES users (id),
+    user_id BIGINT NOT NULL REFERENCES users (id),
+    receiver_id BIGINT NOT NULL REFERENCES users (id),
+    args TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_command_invocations_bot_id ON command_invocations (bot_id);
+
+CREATE TABLE IF NOT EXISTS mutes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (id),
+    muted_user_id BIGINT NOT NULL REFERENCES users (id),
+    until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mutes_pair ON mutes (user_id, muted_user_id);
@@ -0,0 +1,17 @@
+-- Messages and reminders sent later by the scheduler daemon
+CREATE TABLE IF NOT EXISTS scheduled_messages (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (id),
+    receiver_id BIGINT NOT NULL REFERENCES users (id),
+    kind TEXT NOT NULL DEFAULT 'message',
+    content TEXT NOT NULL,
+    send_at TIMESTAMPTZ NOT NULL,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    status TEXT NOT NULL DEFAULT 'pending',
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_user_id ON scheduled_messages (user_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due ON scheduled_messages (status, send_at);
@@ -0,0 +1 @@
+ALTER TABLE command_invocations DROP COLUMN IF EXISTS answered_at;
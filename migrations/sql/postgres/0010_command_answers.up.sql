@@ -0,0 +1,3 @@
+-- An invocation is answered once; the answer is ephemeral, so only the time
+-- it was answered is stored
+ALTER TABLE command_invocations ADD COLUMN IF NOT EXISTS answered_at TIMESTAMPTZ;
@@ -0,0 +1 @@
+ALTER TABLE user_events DROP COLUMN IF EXISTS muted;
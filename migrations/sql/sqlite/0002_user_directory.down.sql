@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS blocks;
+ALTER TABLE users DROP COLUMN deactivated_at;
+ALTER TABLE users DROP COLUMN display_name;
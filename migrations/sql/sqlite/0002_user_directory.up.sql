@@ -0,0 +1,14 @@
+-- Display names, account deactivation and blocks for searchUsers.
+-- SQLite has no trigram index; searches fall back to LIKE matching.
+ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN deactivated_at DATETIME;
+
+CREATE TABLE blocks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    blocker_id INTEGER NOT NULL,
+    blocked_id INTEGER NOT NULL,
+    created_at DATETIME
+);
+
+CREATE UNIQUE INDEX idx_blocks_pair ON blocks (blocker_id, blocked_id);
+CREATE INDEX idx_blocks_blocked_id ON blocks (blocked_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_logs;
+ALTER TABLE users DROP COLUMN token_version;
+ALTER TABLE users DROP COLUMN role;
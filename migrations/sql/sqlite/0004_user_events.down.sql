@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_events;
+ALTER TABLE users DROP COLUMN last_event_seq;
@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN last_event_seq INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE user_events (
+    user_id INTEGER NOT NULL,
+    seq INTEGER NOT NULL,
+    message_id INTEGER NOT NULL,
+    created_at DATETIME,
+    PRIMARY KEY (user_id, seq),
+    CONSTRAINT fk_user_events_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_messages_events FOREIGN KEY (message_id) REFERENCES messages (id)
+);
+
+CREATE INDEX idx_user_events_message_id ON user_events (message_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS api_keys;
+DROP INDEX IF EXISTS idx_users_owner_id;
+ALTER TABLE users DROP COLUMN owner_id;
@@ -0,0 +1,19 @@
+ALTER TABLE users ADD COLUMN owner_id INTEGER REFERENCES users (id);
+
+CREATE INDEX idx_users_owner_id ON users (owner_id);
+
+CREATE TABLE api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users (id),
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    rate_limit INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME,
+    last_used_at DATETIME,
+    revoked_at DATETIME,
+    created_at DATETIME
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
@@ -0,0 +1,16 @@
+-- Incoming webhooks posting into a conversation, and the sender name they
+-- may show on their messages
+ALTER TABLE messages ADD COLUMN sender_name TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE incoming_webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users (id),
+    receiver_id INTEGER NOT NULL REFERENCES users (id),
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    last_used_at DATETIME,
+    created_at DATETIME
+);
+
+CREATE INDEX idx_incoming_webhooks_user_id ON incoming_webhooks (user_id);
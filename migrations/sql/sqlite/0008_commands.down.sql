@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS mutes;
+DROP TABLE IF EXISTS command_invocations;
+DROP TABLE IF EXISTS bot_commands;
@@ -0,0 +1,34 @@
+-- Slash commands registered by bots, their invocations and the muted
+-- conversations
+CREATE TABLE bot_commands (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    bot_id INTEGER NOT NULL REFERENCES users (id),
+    name TEXT NOT NULL UNIQUE,
+    usage TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    created_at DATETIME
+);
+
+CREATE INDEX idx_bot_commands_bot_id ON bot_commands (bot_id);
+
+CREATE TABLE command_invocations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    command_id INTEGER NOT NULL,
+    bot_id INTEGER NOT NULL REFERENCES users (id),
+    user_id INTEGER NOT NULL REFERENCES users (id),
+    receiver_id INTEGER NOT NULL REFERENCES users (id),
+    args TEXT NOT NULL DEFAULT '',
+    created_at DATETIME
+);
+
+CREATE INDEX idx_command_invocations_bot_id ON command_invocations (bot_id);
+
+CREATE TABLE mutes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users (id),
+    muted_user_id INTEGER NOT NULL REFERENCES users (id),
+    until DATETIME,
+    created_at DATETIME
+);
+
+CREATE UNIQUE INDEX idx_mutes_pair ON mutes (user_id, muted_user_id);
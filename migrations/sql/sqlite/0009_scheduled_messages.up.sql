@@ -0,0 +1,17 @@
+-- Messages and reminders sent later by the scheduler daemon
+CREATE TABLE scheduled_messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users (id),
+    receiver_id INTEGER NOT NULL REFERENCES users (id),
+    kind TEXT NOT NULL DEFAULT 'message',
+    content TEXT NOT NULL,
+    send_at DATETIME NOT NULL,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    status TEXT NOT NULL DEFAULT 'pending',
+    sent_at DATETIME,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+
+CREATE INDEX idx_scheduled_messages_user_id ON scheduled_messages (user_id);
+CREATE INDEX idx_scheduled_messages_due ON scheduled_messages (status, send_at);
@@ -0,0 +1,4 @@
+-- A due message is claimed by a scheduler daemon, which commits the claim
+-- before publishing it; claims older than scheduler.claim_timeout are taken
+-- over by another daemon
+ALTER TABLE scheduled_messages ADD COLUMN claimed_at DATETIME;
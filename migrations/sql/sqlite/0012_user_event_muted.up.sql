@@ -0,0 +1,3 @@
+-- Whether the receiver of the message had muted its sender, so that the
+-- replayed events keep the muted flag of the live ones
+ALTER TABLE user_events ADD COLUMN muted BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	ScopeMessagesRead = "messages:read"
+	ScopeMessagesSend = "messages:send"
+	ScopeUsersRead    = "users:read"
+)
+
+// Scopes lists the operations an API key may be restricted to
+var Scopes = []string{ScopeMessagesRead, ScopeMessagesSend, ScopeUsersRead}
+
+// IsValidScope reports whether scope is one of the known scopes
+func IsValidScope(scope string) bool {
+	for _, known := range Scopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey is a long-lived credential of a bot. Only the SHA-256 hash of the
+// key is stored; Prefix keeps its first characters so owners can tell keys apart.
+type APIKey struct {
+	ID     uint   `gorm:"primarykey" json:"id"`
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	Name   string `gorm:"not null" json:"name"`
+	Prefix string `gorm:"not null" json:"prefix"`
+	Hash   string `gorm:"not null;unique" json:"-"`
+	// Scopes is the comma-separated list of the operations allowed to the key
+	Scopes string `gorm:"not null" json:"scopes"`
+	// RateLimit is the number of requests allowed per minute, 0 for the default
+	RateLimit  int        `gorm:"not null;default:0" json:"rate_limit"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ScopeList returns the scopes of the key
+func (k APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return nil
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// IsUsable reports whether the key is neither revoked nor expired at now
+func (k APIKey) IsUsable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditLog records an administrative action performed through the admin API
+type AuditLog struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	ActorID    uint      `gorm:"not null;index" json:"actor_id"`
+	Action     string    `gorm:"not null;index" json:"action"`
+	TargetType string    `gorm:"not null" json:"target_type"`
+	TargetID   uint      `gorm:"index" json:"target_id"`
+	Details    string    `gorm:"type:text" json:"details"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// Block records that BlockerID no longer wants to see or be found by BlockedID
+type Block struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	BlockerID uint      `gorm:"not null;uniqueIndex:idx_blocks_pair" json:"blocker_id"`
+	BlockedID uint      `gorm:"not null;uniqueIndex:idx_blocks_pair;index" json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
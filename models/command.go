@@ -0,0 +1,84 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// CommandInfo describes a slash command for the autocomplete listing
+type CommandInfo struct {
+	Name        string
+	Usage       string
+	Description string
+	// BotID is the bot running the command, 0 for the built-in ones
+	BotID uint
+}
+
+// BuiltinCommands lists the commands run by the message service itself; bots
+// cannot register their names
+var BuiltinCommands = []CommandInfo{
+	{Name: "me", Usage: "/me <action>", Description: "Post an action, such as /me waves"},
+	{Name: "mute", Usage: "/mute [duration]", Description: "Stop alerting for the messages of this conversation, for a duration such as 1h or until /unmute"},
+	{Name: "remind", Usage: "/remind [me] [in] <duration> <text>", Description: "Get a reminder in a message to yourself after a duration such as 30m"},
+	{Name: "shrug", Usage: "/shrug [text]", Description: "Append ¯\\_(ツ)_/¯ to the message"},
+	{Name: "unmute", Usage: "/unmute", Description: "Alert again for the messages of this conversation"},
+}
+
+// IsBuiltinCommand reports whether name is one of the built-in commands
+func IsBuiltinCommand(name string) bool {
+	for _, command := range BuiltinCommands {
+		if command.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// commandName matches the names of the commands bots may register
+var commandName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// IsValidCommandName reports whether name may be registered by a bot
+func IsValidCommandName(name string) bool {
+	return commandName.MatchString(name)
+}
+
+// BotCommand is a slash command run by a bot. Names are shared by every
+// user, so each belongs to a single bot.
+type BotCommand struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	BotID       uint      `gorm:"not null;index" json:"bot_id"`
+	Name        string    `gorm:"not null;unique" json:"name"`
+	Usage       string    `gorm:"not null;default:''" json:"usage"`
+	Description string    `gorm:"not null;default:''" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Info describes the command for the autocomplete listing
+func (c BotCommand) Info() CommandInfo {
+	usage := c.Usage
+	if usage == "" {
+		usage = "/" + c.Name
+	}
+	return CommandInfo{Name: c.Name, Usage: usage, Description: c.Description, BotID: c.BotID}
+}
+
+// CommandInvocationTTL is how long a bot may answer an invocation
+const CommandInvocationTTL = 30 * time.Minute
+
+// CommandInvocation records a bot command run by UserID in its conversation
+// with ReceiverID, which the bot may answer once until it expires
+type CommandInvocation struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	CommandID  uint       `gorm:"not null" json:"command_id"`
+	BotID      uint       `gorm:"not null;index" json:"bot_id"`
+	UserID     uint       `gorm:"not null" json:"user_id"`
+	ReceiverID uint       `gorm:"not null" json:"receiver_id"`
+	Args       string     `gorm:"not null;default:''" json:"args"`
+	AnsweredAt *time.Time `json:"answered_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ExpiresAt returns when the bot can no longer answer the invocation
+func (i CommandInvocation) ExpiresAt() time.Time {
+	return i.CreatedAt.Add(CommandInvocationTTL)
+}
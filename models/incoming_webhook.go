@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IncomingWebhook lets external tools post messages from its user to
+// ReceiverID with a secret token. Only the SHA-256 hash of the token is
+// stored; Prefix keeps its first characters so owners can tell tokens apart.
+type IncomingWebhook struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	ReceiverID uint       `gorm:"not null" json:"receiver_id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"not null" json:"prefix"`
+	Hash       string     `gorm:"not null" json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -11,4 +11,7 @@ type Message struct {
 	ReceiverID uint `gorm:"not null" json:"receiver_id"`
 	Receiver   User `gorm:"foreignKey:ReceiverID" json:"receiver"` // Relation avec User
 	Content    string `gorm:"type:text;not null" json:"content"`
+	SenderName string `gorm:"not null;default:''" json:"sender_name,omitempty"` // Name shown instead of the sender's, set by incoming webhooks
+	Events     []UserEvent `gorm:"foreignKey:MessageID" json:"-"` // Sequence numbers of the message for its sender and receiver
+	Muted      bool `gorm:"-" json:"-"` // Whether the receiver muted the sender, recorded on its event
 }
\ No newline at end of file
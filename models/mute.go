@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// Mute records that UserID is not alerted for the messages of MutedUserID
+// until Until, or until it is lifted when Until is nil
+type Mute struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	UserID      uint       `gorm:"not null;uniqueIndex:idx_mutes_pair" json:"user_id"`
+	MutedUserID uint       `gorm:"not null;uniqueIndex:idx_mutes_pair" json:"muted_user_id"`
+	Until       *time.Time `json:"until,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
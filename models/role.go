@@ -0,0 +1,64 @@
+package models
+
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleUser      = "user"
+)
+
+const (
+	PermissionListUsers     = "users:list"
+	PermissionManageUsers   = "users:manage"
+	PermissionManageRoles   = "roles:manage"
+	PermissionRevokeSession = "sessions:revoke"
+	PermissionViewStats     = "stats:view"
+	PermissionViewAuditLog  = "audit:view"
+	// PermissionManageWebhooks allows webhooks receiving the events of every user
+	PermissionManageWebhooks = "webhooks:manage"
+)
+
+// RolePermissions lists the permissions granted to each role
+var RolePermissions = map[string][]string{
+	RoleAdmin: {
+		PermissionListUsers,
+		PermissionManageUsers,
+		PermissionManageRoles,
+		PermissionRevokeSession,
+		PermissionViewStats,
+		PermissionViewAuditLog,
+		PermissionManageWebhooks,
+	},
+	RoleModerator: {
+		PermissionListUsers,
+		PermissionManageUsers,
+		PermissionRevokeSession,
+	},
+	RoleUser: {},
+}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether role grants permission
+func HasPermission(role, permission string) bool {
+	for _, granted := range RolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RolesWith returns the roles granting permission
+func RolesWith(permission string) []string {
+	var roles []string
+	for role := range RolePermissions {
+		if HasPermission(role, permission) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
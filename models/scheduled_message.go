@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Kinds of scheduled messages: a message to another user, or a reminder sent
+// by /remind to its user
+const (
+	ScheduleKindMessage  = "message"
+	ScheduleKindReminder = "reminder"
+)
+
+// Statuses of a scheduled message
+const (
+	SchedulePending = "pending"
+	// ScheduleSending marks a due message claimed by a scheduler daemon that
+	// is publishing it
+	ScheduleSending  = "sending"
+	ScheduleSent     = "sent"
+	ScheduleCanceled = "canceled"
+)
+
+// IsScheduleStatus reports whether status is one of the statuses above
+func IsScheduleStatus(status string) bool {
+	return status == SchedulePending || status == ScheduleSending || status == ScheduleSent || status == ScheduleCanceled
+}
+
+// ReminderSenderName labels the messages of the reminders
+const ReminderSenderName = "Reminder"
+
+// ScheduledMessage is a message from UserID to ReceiverID that the scheduler
+// daemon sends at SendAt. TimeZone is the IANA name of the zone the time was
+// given in, so that it is shown back as it was entered.
+type ScheduledMessage struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	ReceiverID uint       `gorm:"not null" json:"receiver_id"`
+	Kind       string     `gorm:"not null;default:'message'" json:"kind"`
+	Content    string     `gorm:"not null" json:"content"`
+	SendAt     time.Time  `gorm:"not null" json:"send_at"`
+	TimeZone   string     `gorm:"not null;default:'UTC'" json:"time_zone"`
+	Status     string     `gorm:"not null;default:'pending'" json:"status"`
+	SentAt     *time.Time `json:"sent_at,omitempty"`
+	ClaimedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Location returns the time zone of the message, UTC when it is unknown
+func (m ScheduledMessage) Location() *time.Location {
+	location, err := time.LoadLocation(m.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// LocalSendAt returns SendAt in the time zone of the message
+func (m ScheduledMessage) LocalSendAt() time.Time {
+	return m.SendAt.In(m.Location())
+}
+
+// SenderName returns the name the message is shown as sent by, empty for the
+// name of its user
+func (m ScheduledMessage) SenderName() string {
+	if m.Kind == ScheduleKindReminder {
+		return ReminderSenderName
+	}
+	return ""
+}
@@ -1,11 +1,31 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type User struct {
 	gorm.Model
-	Username string `gorm:"unique;not null" json:"username"`
-	Password string `gorm:"not null" json:"-"`
-}
\ No newline at end of file
+	Username      string     `gorm:"unique;not null" json:"username"`
+	DisplayName   string     `gorm:"not null;default:''" json:"display_name"`
+	Password      string     `gorm:"not null" json:"-"`
+	Role          string     `gorm:"not null;default:'user'" json:"role"`
+	TokenVersion  uint       `gorm:"not null;default:0" json:"-"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	LastEventSeq  uint64     `gorm:"not null;default:0" json:"-"`
+	// OwnerID is the user who created the account when it is a bot
+	OwnerID *uint `gorm:"index" json:"owner_id,omitempty"`
+}
+
+// IsActive reports whether the account has not been deactivated
+func (u User) IsActive() bool {
+	return u.DeactivatedAt == nil
+}
+
+// IsBot reports whether the account is a bot, which authenticates with API
+// keys rather than a password
+func (u User) IsBot() bool {
+	return u.OwnerID != nil
+}
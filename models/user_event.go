@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// UserEvent is one entry of the event stream of a user. Seq grows by one for
+// every event delivered to the user, so a client that reconnects can ask for
+// the events after the last sequence number it received.
+type UserEvent struct {
+	UserID    uint    `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Seq       uint64  `gorm:"primaryKey;autoIncrement:false" json:"seq"`
+	MessageID uint    `gorm:"not null;index" json:"message_id"`
+	Message   Message `json:"message"`
+	// Muted records that the user, receiving the message, had muted its sender
+	Muted     bool      `gorm:"not null;default:false" json:"muted"`
+	CreatedAt time.Time `json:"created_at"`
+}
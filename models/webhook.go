@@ -0,0 +1,93 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// EventMessageCreated is sent when a message is stored
+const EventMessageCreated = "message_created"
+
+// WebhookEvents lists the events a webhook may subscribe to
+var WebhookEvents = []string{EventMessageCreated}
+
+// IsValidWebhookEvent reports whether event is one of the known events
+func IsValidWebhookEvent(event string) bool {
+	for _, known := range WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook is an URL the events of its user, or of every user when AllUsers
+// is set, are posted to, signed with Secret
+type Webhook struct {
+	ID     uint   `gorm:"primarykey" json:"id"`
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	URL    string `gorm:"not null" json:"url"`
+	Secret string `gorm:"not null" json:"-"`
+	// Events is the comma-separated list of the subscribed events, empty for all
+	Events   string `gorm:"not null;default:''" json:"events"`
+	AllUsers bool   `gorm:"not null;default:false" json:"all_users"`
+	// FailureCount counts the consecutive failed deliveries
+	FailureCount int        `gorm:"not null;default:0" json:"failure_count"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// EventList returns the subscribed events, nil for all
+func (w Webhook) EventList() []string {
+	if w.Events == "" {
+		return nil
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// Subscribes reports whether the webhook receives event
+func (w Webhook) Subscribes(event string) bool {
+	if w.Events == "" {
+		return true
+	}
+	for _, subscribed := range w.EventList() {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEnabled reports whether the webhook still receives deliveries
+func (w Webhook) IsEnabled() bool {
+	return w.DisabledAt == nil
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event posted to a webhook, retried until it
+// succeeds or runs out of attempts
+type WebhookDelivery struct {
+	ID        uint   `gorm:"primarykey" json:"id"`
+	WebhookID uint   `gorm:"not null;index" json:"webhook_id"`
+	Event     string `gorm:"not null" json:"event"`
+	// EventID is shared by the deliveries of an event, replays included
+	EventID string `gorm:"not null" json:"event_id"`
+	Payload string `gorm:"type:text;not null" json:"payload"`
+	Status  string `gorm:"not null" json:"status"`
+	// Attempts counts the requests sent so far
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `gorm:"not null;default:0" json:"last_status_code"`
+	LastError      string     `gorm:"not null;default:''" json:"last_error"`
+	// ReplayOf is the delivery this one replays
+	ReplayOf    *uint      `json:"replay_of,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
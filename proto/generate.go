@@ -0,0 +1,5 @@
+// Package proto holds the protocol buffer definitions of the gRPC API and the
+// code generated from them.
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative messaging/v1/messaging.proto
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"instant-messaging-app/models"
+
+	"gorm.io/gorm"
+)
+
+type gormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository returns an APIKeyRepository backed by db
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &gormAPIKeyRepository{db: db}
+}
+
+func (r *gormAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *gormAPIKeyRepository) FindByUser(ctx context.Context, userID, id uint) (models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&key, id).Error
+	return key, err
+}
+
+func (r *gormAPIKeyRepository) FindByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.WithContext(ctx).Where("hash = ?", hash).First(&key).Error
+	return key, err
+}
+
+func (r *gormAPIKeyRepository) ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id asc").Find(&keys).Error
+	return keys, err
+}
+
+func (r *gormAPIKeyRepository) Update(ctx context.Context, key *models.APIKey, fields map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(key).Updates(fields).Error
+}
+
+func (r *gormAPIKeyRepository) RevokeByUser(ctx context.Context, userID uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+
+	"instant-messaging-app/models"
+
+	"gorm.io/gorm"
+)
+
+type gormAuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository returns an AuditRepository backed by db
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &gormAuditRepository{db: db}
+}
+
+func (r *gormAuditRepository) Create(ctx context.Context, entry *models.AuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *gormAuditRepository) List(ctx context.Context, filter AuditLogFilter, page, pageSize int) ([]models.AuditLog, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.AuditLog{})
+	if filter.ActorID != 0 {
+		db = db.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		db = db.Where("action = ?", filter.Action)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []models.AuditLog
+	err := db.Order("id desc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&logs).Error
+	return logs, total, err
+}
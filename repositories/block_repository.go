@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"context"
+
+	"instant-messaging-app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormBlockRepository struct {
+	db *gorm.DB
+}
+
+// NewBlockRepository returns a BlockRepository backed by db
+func NewBlockRepository(db *gorm.DB) BlockRepository {
+	return &gormBlockRepository{db: db}
+}
+
+func (r *gormBlockRepository) Block(ctx context.Context, blockerID, blockedID uint) error {
+	block := models.Block{BlockerID: blockerID, BlockedID: blockedID}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "blocker_id"}, {Name: "blocked_id"}},
+		DoNothing: true,
+	}).Create(&block).Error
+}
+
+func (r *gormBlockRepository) Unblock(ctx context.Context, blockerID, blockedID uint) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Delete(&models.Block{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormBlockRepository) ListBlocked(ctx context.Context, blockerID uint) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).
+		Joins("JOIN blocks ON blocks.blocked_id = users.id").
+		Where("blocks.blocker_id = ?", blockerID).
+		Order("users.username asc").
+		Find(&users).Error
+	return users, err
+}
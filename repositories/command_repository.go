@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"instant-messaging-app/models"
+
+	"gorm.io/gorm"
+)
+
+type gormCommandRepository struct {
+	db *gorm.DB
+}
+
+// NewCommandRepository returns a CommandRepository backed by db
+func NewCommandRepository(db *gorm.DB) CommandRepository {
+	return &gormCommandRepository{db: db}
+}
+
+// activeBots keeps the commands whose bot has not been deactivated
+func (r *gormCommandRepository) activeBots(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Joins("JOIN users ON users.id = bot_commands.bot_id").
+		Where("users.deactivated_at IS NULL AND users.deleted_at IS NULL")
+}
+
+func (r *gormCommandRepository) Create(ctx context.Context, command *models.BotCommand) error {
+	return r.db.WithContext(ctx).Create(command).Error
+}
+
+func (r *gormCommandRepository) FindByName(ctx context.Context, name string) (models.BotCommand, error) {
+	var command models.BotCommand
+	err := r.activeBots(ctx).Where("bot_commands.name = ?", name).First(&command).Error
+	return command, err
+}
+
+func (r *gormCommandRepository) FindByBot(ctx context.Context, botID, id uint) (models.BotCommand, error) {
+	var command models.BotCommand
+	err := r.db.WithContext(ctx).Where("bot_id = ?", botID).First(&command, id).Error
+	return command, err
+}
+
+func (r *gormCommandRepository) IsNameTaken(ctx context.Context, name string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.BotCommand{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormCommandRepository) ListByBot(ctx context.Context, botID uint) ([]models.BotCommand, error) {
+	var commands []models.BotCommand
+	err := r.db.WithContext(ctx).Where("bot_id = ?", botID).Order("name asc").Find(&commands).Error
+	return commands, err
+}
+
+func (r *gormCommandRepository) CountByBot(ctx context.Context, botID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.BotCommand{}).Where("bot_id = ?", botID).Count(&count).Error
+	return count, err
+}
+
+func (r *gormCommandRepository) Delete(ctx context.Context, command *models.BotCommand) error {
+	return r.db.WithContext(ctx).Delete(command).Error
+}
+
+func (r *gormCommandRepository) Search(ctx context.Context, prefix string, limit int) ([]models.BotCommand, error) {
+	var commands []models.BotCommand
+	err := r.activeBots(ctx).
+		Where(`bot_commands.name LIKE ? ESCAPE '\'`, escapeLike(prefix)+"%").
+		Order("bot_commands.name asc").
+		Limit(limit).
+		Find(&commands).Error
+	return commands, err
+}
+
+func (r *gormCommandRepository) CreateInvocation(ctx context.Context, invocation *models.CommandInvocation) error {
+	return r.db.WithContext(ctx).Create(invocation).Error
+}
+
+func (r *gormCommandRepository) FindInvocation(ctx context.Context, id uint) (models.CommandInvocation, error) {
+	var invocation models.CommandInvocation
+	err := r.db.WithContext(ctx).First(&invocation, id).Error
+	return invocation, err
+}
+
+func (r *gormCommandRepository) MarkAnswered(ctx context.Context, id uint, at time.Time) error {
+	return markAnswered(r.db.WithContext(ctx), id, at)
+}
+
+// markAnswered sets the answer time of an invocation unless it is already
+// set, so that two concurrent answers cannot both succeed
+func markAnswered(tx *gorm.DB, id uint, at time.Time) error {
+	result := tx.Model(&models.CommandInvocation{}).
+		Where("id = ? AND answered_at IS NULL", id).
+		Update("answered_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := tx.Model(&models.CommandInvocation{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return ErrInvocationAnswered
+	}
+	return nil
+}
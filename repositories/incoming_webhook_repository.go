@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+
+	"instant-messaging-app/models"
+
+	"gorm.io/gorm"
+)
+
+type gormIncomingWebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewIncomingWebhookRepository returns an IncomingWebhookRepository backed by db
+func NewIncomingWebhookRepository(db *gorm.DB) IncomingWebhookRepository {
+	return &gormIncomingWebhookRepository{db: db}
+}
+
+func (r *gormIncomingWebhookRepository) Create(ctx context.Context, webhook *models.IncomingWebhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *gormIncomingWebhookRepository) FindByID(ctx context.Context, id uint) (models.IncomingWebhook, error) {
+	var webhook models.IncomingWebhook
+	err := r.db.WithContext(ctx).First(&webhook, id).Error
+	return webhook, err
+}
+
+func (r *gormIncomingWebhookRepository) FindByUser(ctx context.Context, userID, id uint) (models.IncomingWebhook, error) {
+	var webhook models.IncomingWebhook
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&webhook, id).Error
+	return webhook, err
+}
+
+func (r *gormIncomingWebhookRepository) ListByUser(ctx context.Context, userID uint) ([]models.IncomingWebhook, error) {
+	var webhooks []models.IncomingWebhook
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id asc").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *gormIncomingWebhookRepository) CountByUser(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.IncomingWebhook{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *gormIncomingWebhookRepository) Update(ctx context.Context, webhook *models.IncomingWebhook, fields map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(webhook).Updates(fields).Error
+}
+
+func (r *gormIncomingWebhookRepository) Delete(ctx context.Context, webhook *models.IncomingWebhook) error {
+	return r.db.WithContext(ctx).Delete(webhook).Error
+}
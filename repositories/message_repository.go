@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"instant-messaging-app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormMessageRepository struct {
+	db *gorm.DB
+}
+
+// NewMessageRepository returns a MessageRepository backed by db
+func NewMessageRepository(db *gorm.DB) MessageRepository {
+	return &gormMessageRepository{db: db}
+}
+
+func (r *gormMessageRepository) Create(ctx context.Context, message *models.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createMessage(tx, message)
+	})
+}
+
+// createMessage inserts a message and its events in tx
+func createMessage(tx *gorm.DB, message *models.Message) error {
+	// The counters are taken first, so that an unknown user is reported as
+	// not found, and in ID order, so that two users writing to each other
+	// at the same time cannot deadlock
+	userIDs := []uint{message.SenderID}
+	if message.ReceiverID != message.SenderID {
+		userIDs = append(userIDs, message.ReceiverID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	message.Events = make([]models.UserEvent, 0, len(userIDs))
+	for _, userID := range userIDs {
+		seq, err := nextEventSeq(tx, userID)
+		if err != nil {
+			return err
+		}
+		// Only the receiver is not alerted
+		muted := message.Muted && userID == message.ReceiverID
+		message.Events = append(message.Events, models.UserEvent{UserID: userID, Seq: seq, Muted: muted})
+	}
+
+	if err := tx.Omit(clause.Associations).Create(message).Error; err != nil {
+		return err
+	}
+	for i := range message.Events {
+		message.Events[i].MessageID = message.ID
+		message.Events[i].CreatedAt = message.CreatedAt
+	}
+	return tx.Omit(clause.Associations).Create(&message.Events).Error
+}
+
+// nextEventSeq increments the event counter of a user and returns the new
+// value. The update locks the user row until the transaction ends.
+func nextEventSeq(tx *gorm.DB, userID uint) (uint64, error) {
+	result := tx.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("last_event_seq", gorm.Expr("last_event_seq + 1"))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, fmt.Errorf("user %d: %w", userID, gorm.ErrRecordNotFound)
+	}
+
+	var seq uint64
+	err := tx.Model(&models.User{}).Where("id = ?", userID).Pluck("last_event_seq", &seq).Error
+	return seq, err
+}
+
+func (r *gormMessageRepository) ListBetween(ctx context.Context, userID, otherUserID uint) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.WithContext(ctx).Preload("Sender").Preload("Receiver").
+		Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
+			userID, otherUserID, otherUserID, userID).
+		Order("created_at asc").
+		Find(&messages).Error
+	return messages, err
+}
+
+func (r *gormMessageRepository) ListEventsAfter(ctx context.Context, userID uint, afterSeq uint64, limit int) ([]models.UserEvent, error) {
+	var events []models.UserEvent
+	err := r.db.WithContext(ctx).Preload("Message").
+		Where("user_id = ? AND seq > ?", userID, afterSeq).
+		Order("seq asc").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *gormMessageRepository) Count(ctx context.Context, since time.Time) (int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.Message{})
+	if !since.IsZero() {
+		db = db.Where("created_at > ?", since)
+	}
+	var count int64
+	err := db.Count(&count).Error
+	return count, err
+}
+
+func (r *gormMessageRepository) LastEventSeq(ctx context.Context, userID uint) (uint64, error) {
+	var seq uint64
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Pluck("last_event_seq", &seq).Error
+	return seq, err
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"instant-messaging-app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormMuteRepository struct {
+	db *gorm.DB
+}
+
+// NewMuteRepository returns a MuteRepository backed by db
+func NewMuteRepository(db *gorm.DB) MuteRepository {
+	return &gormMuteRepository{db: db}
+}
+
+func (r *gormMuteRepository) Mute(ctx context.Context, userID, mutedUserID uint, until *time.Time) error {
+	mute := models.Mute{UserID: userID, MutedUserID: mutedUserID, Until: until}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "muted_user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"until"}),
+	}).Create(&mute).Error
+}
+
+func (r *gormMuteRepository) Unmute(ctx context.Context, userID, mutedUserID uint) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND muted_user_id = ?", userID, mutedUserID).
+		Delete(&models.Mute{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormMuteRepository) IsMuted(ctx context.Context, userID, mutedUserID uint, now time.Time) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Mute{}).
+		Where("user_id = ? AND muted_user_id = ? AND (until IS NULL OR until > ?)", userID, mutedUserID, now).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,323 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"instant-messaging-app/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvocationAnswered is returned when a bot answers a command invocation
+// that it already answered
+var ErrInvocationAnswered = errors.New("the invocation was already answered")
+
+// UserRepository stores and queries user accounts. Every method runs its
+// statements under ctx, which carries the deadline and the trace of the caller.
+type UserRepository interface {
+	// Create inserts a new user and fills in its ID
+	Create(ctx context.Context, user *models.User) error
+	// FindByID returns the user with the given ID
+	FindByID(ctx context.Context, id uint) (models.User, error)
+	// FindByUsername returns the user with the given username
+	FindByUsername(ctx context.Context, username string) (models.User, error)
+	// ListVisible returns the public fields of the active users visible to
+	// requesterID, that is neither blocking nor blocked by them
+	ListVisible(ctx context.Context, requesterID uint) ([]models.User, error)
+	// Search returns one page of the active users visible to requesterID that match query
+	Search(ctx context.Context, requesterID uint, query string, page, pageSize int) ([]models.User, int64, error)
+	// ListForAdmin returns one page of users, including deactivated ones
+	ListForAdmin(ctx context.Context, filter AdminUserFilter, page, pageSize int) ([]models.User, int64, error)
+	// Count returns the number of users matching filter, including deactivated ones
+	Count(ctx context.Context, filter AdminUserFilter) (int64, error)
+	// Update writes the given columns of an existing user
+	Update(ctx context.Context, user *models.User, fields map[string]interface{}) error
+	// ListByOwner returns the bots created by ownerID, including deactivated ones
+	ListByOwner(ctx context.Context, ownerID uint) ([]models.User, error)
+	// CountByOwner returns the number of bots created by ownerID, including
+	// deactivated ones
+	CountByOwner(ctx context.Context, ownerID uint) (int64, error)
+	// FindBot returns the bot with the given ID created by ownerID; the bots
+	// of other users are not found
+	FindBot(ctx context.Context, ownerID, botID uint) (models.User, error)
+}
+
+// MessageRepository stores and queries direct messages
+type MessageRepository interface {
+	// Create inserts a new message and fills in its ID and the events that
+	// number it in the streams of its sender and receiver
+	Create(ctx context.Context, message *models.Message) error
+	// ListBetween returns the conversation between two users, oldest first
+	ListBetween(ctx context.Context, userID, otherUserID uint) ([]models.Message, error)
+	// ListEventsAfter returns at most limit events of a user with a sequence
+	// number above afterSeq, oldest first and with their message
+	ListEventsAfter(ctx context.Context, userID uint, afterSeq uint64, limit int) ([]models.UserEvent, error)
+	// LastEventSeq returns the sequence number of the latest event of a user
+	LastEventSeq(ctx context.Context, userID uint) (uint64, error)
+	// Count returns the number of messages created after since, or of every
+	// message when since is zero
+	Count(ctx context.Context, since time.Time) (int64, error)
+}
+
+// AuditRepository stores the audit log of the administrative actions
+type AuditRepository interface {
+	// Create inserts a new audit log entry and fills in its ID
+	Create(ctx context.Context, entry *models.AuditLog) error
+	// List returns one page of the entries matching filter, newest first
+	List(ctx context.Context, filter AuditLogFilter, page, pageSize int) ([]models.AuditLog, int64, error)
+}
+
+// APIKeyRepository stores the API keys of the bots
+type APIKeyRepository interface {
+	// Create inserts a new key and fills in its ID
+	Create(ctx context.Context, key *models.APIKey) error
+	// FindByUser returns the key with the given ID of a bot
+	FindByUser(ctx context.Context, userID, id uint) (models.APIKey, error)
+	// FindByHash returns the key whose secret hashes to hash
+	FindByHash(ctx context.Context, hash string) (models.APIKey, error)
+	// ListByUser returns the keys of a bot, including revoked ones, oldest first
+	ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error)
+	// Update writes the given columns of an existing key
+	Update(ctx context.Context, key *models.APIKey, fields map[string]interface{}) error
+	// RevokeByUser revokes the keys of a bot that are not revoked yet at the
+	// given time
+	RevokeByUser(ctx context.Context, userID uint, at time.Time) error
+}
+
+// WebhookRepository stores the outgoing webhooks and their deliveries
+type WebhookRepository interface {
+	// Create inserts a new webhook and fills in its ID
+	Create(ctx context.Context, webhook *models.Webhook) error
+	// FindByID returns the webhook with the given ID
+	FindByID(ctx context.Context, id uint) (models.Webhook, error)
+	// FindByUser returns the webhook with the given ID registered by userID
+	FindByUser(ctx context.Context, userID, id uint) (models.Webhook, error)
+	// ListByUser returns the webhooks registered by a user, oldest first
+	ListByUser(ctx context.Context, userID uint) ([]models.Webhook, error)
+	// CountByUser returns the number of webhooks registered by a user
+	CountByUser(ctx context.Context, userID uint) (int64, error)
+	// Update writes the given columns of an existing webhook
+	Update(ctx context.Context, webhook *models.Webhook, fields map[string]interface{}) error
+	// Delete removes a webhook with its deliveries
+	Delete(ctx context.Context, webhook *models.Webhook) error
+	// ListSubscribers returns the enabled webhooks of active users receiving
+	// the events of any of userIDs: theirs, and those of every user whose
+	// owner holds PermissionManageWebhooks
+	ListSubscribers(ctx context.Context, userIDs []uint) ([]models.Webhook, error)
+	// RecordSuccess resets the consecutive failures of a webhook
+	RecordSuccess(ctx context.Context, webhookID uint) error
+	// RecordFailure counts a failed delivery and disables the webhook once
+	// its consecutive failures reach disableAfter; it reports whether this
+	// call disabled it
+	RecordFailure(ctx context.Context, webhookID uint, disableAfter int) (bool, error)
+
+	// CreateDeliveries inserts new deliveries and fills in their IDs
+	CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	// FindDelivery returns the delivery with the given ID of a webhook
+	FindDelivery(ctx context.Context, webhookID, id uint) (models.WebhookDelivery, error)
+	// ListDeliveries returns one page of the deliveries of a webhook, newest first
+	ListDeliveries(ctx context.Context, webhookID uint, page, pageSize int) ([]models.WebhookDelivery, int64, error)
+	// ListDue returns at most limit pending deliveries of enabled webhooks
+	// whose next attempt is due at now, oldest first
+	ListDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// Claim postpones the next attempt of a delivery due at now to until, so
+	// that no other daemon sends it meanwhile; it reports whether the
+	// delivery was still due
+	Claim(ctx context.Context, delivery *models.WebhookDelivery, now, until time.Time) (bool, error)
+	// UpdateDelivery writes the given columns of an existing delivery
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery, fields map[string]interface{}) error
+}
+
+// IncomingWebhookRepository stores the incoming webhooks
+type IncomingWebhookRepository interface {
+	// Create inserts a new incoming webhook and fills in its ID
+	Create(ctx context.Context, webhook *models.IncomingWebhook) error
+	// FindByID returns the incoming webhook with the given ID
+	FindByID(ctx context.Context, id uint) (models.IncomingWebhook, error)
+	// FindByUser returns the incoming webhook with the given ID of userID
+	FindByUser(ctx context.Context, userID, id uint) (models.IncomingWebhook, error)
+	// ListByUser returns the incoming webhooks of a user, oldest first
+	ListByUser(ctx context.Context, userID uint) ([]models.IncomingWebhook, error)
+	// CountByUser returns the number of incoming webhooks of a user
+	CountByUser(ctx context.Context, userID uint) (int64, error)
+	// Update writes the given columns of an existing incoming webhook
+	Update(ctx context.Context, webhook *models.IncomingWebhook, fields map[string]interface{}) error
+	// Delete removes an incoming webhook
+	Delete(ctx context.Context, webhook *models.IncomingWebhook) error
+}
+
+// CommandRepository stores the slash commands of the bots and their invocations
+type CommandRepository interface {
+	// Create inserts a new command and fills in its ID
+	Create(ctx context.Context, command *models.BotCommand) error
+	// FindByName returns the command called name, unless its bot was deactivated
+	FindByName(ctx context.Context, name string) (models.BotCommand, error)
+	// FindByBot returns the command with the given ID of a bot
+	FindByBot(ctx context.Context, botID, id uint) (models.BotCommand, error)
+	// IsNameTaken reports whether a bot registered a command called name,
+	// including the bots deactivated since
+	IsNameTaken(ctx context.Context, name string) (bool, error)
+	// ListByBot returns the commands of a bot, sorted by name
+	ListByBot(ctx context.Context, botID uint) ([]models.BotCommand, error)
+	// CountByBot returns the number of commands of a bot
+	CountByBot(ctx context.Context, botID uint) (int64, error)
+	// Delete removes a command, freeing its name
+	Delete(ctx context.Context, command *models.BotCommand) error
+	// Search returns at most limit commands of active bots whose name
+	// starts with prefix, sorted by name
+	Search(ctx context.Context, prefix string, limit int) ([]models.BotCommand, error)
+	// CreateInvocation inserts a new invocation and fills in its ID
+	CreateInvocation(ctx context.Context, invocation *models.CommandInvocation) error
+	// FindInvocation returns the invocation with the given ID
+	FindInvocation(ctx context.Context, id uint) (models.CommandInvocation, error)
+	// MarkAnswered records that the invocation with the given ID was answered
+	// at the given time, or returns ErrInvocationAnswered when it already was
+	MarkAnswered(ctx context.Context, id uint, at time.Time) error
+}
+
+// MuteRepository stores the users muted by each user
+type MuteRepository interface {
+	// Mute mutes mutedUserID for userID until until, or until Unmute when
+	// until is nil, replacing an earlier mute
+	Mute(ctx context.Context, userID, mutedUserID uint, until *time.Time) error
+	// Unmute lifts the mute of mutedUserID by userID and reports whether
+	// there was one
+	Unmute(ctx context.Context, userID, mutedUserID uint) (bool, error)
+	// IsMuted reports whether userID muted mutedUserID at now
+	IsMuted(ctx context.Context, userID, mutedUserID uint, now time.Time) (bool, error)
+}
+
+// BlockRepository stores the users blocked by each user. Two users one of
+// whom blocked the other do not find each other in the directory.
+type BlockRepository interface {
+	// Block blocks blockedID for blockerID; blocking twice is not an error
+	Block(ctx context.Context, blockerID, blockedID uint) error
+	// Unblock lifts the block of blockedID by blockerID and reports whether
+	// there was one
+	Unblock(ctx context.Context, blockerID, blockedID uint) (bool, error)
+	// ListBlocked returns the users blocked by blockerID, sorted by username
+	ListBlocked(ctx context.Context, blockerID uint) ([]models.User, error)
+}
+
+// ScheduledMessageRepository stores the messages and reminders sent later by
+// the scheduler daemon
+type ScheduledMessageRepository interface {
+	// Create inserts a new scheduled message and fills in its ID
+	Create(ctx context.Context, message *models.ScheduledMessage) error
+	// FindByID returns the scheduled message with the given ID
+	FindByID(ctx context.Context, id uint) (models.ScheduledMessage, error)
+	// FindByUser returns the scheduled message with the given ID of userID
+	FindByUser(ctx context.Context, userID, id uint) (models.ScheduledMessage, error)
+	// UpdatePending writes the given columns of a scheduled message that is
+	// still pending; it reports whether it was
+	UpdatePending(ctx context.Context, message *models.ScheduledMessage, fields map[string]interface{}) (bool, error)
+	// ListByUser returns at most limit scheduled messages of userID with the
+	// given status; pending ones come next to send first, the others latest
+	// first
+	ListByUser(ctx context.Context, userID uint, status string, limit int) ([]models.ScheduledMessage, error)
+	// CountPending returns the number of pending scheduled messages of userID
+	CountPending(ctx context.Context, userID uint) (int64, error)
+	// ClaimDue marks at most limit pending messages due at now as sending and
+	// returns them in order, skipping those locked by another daemon. The
+	// messages claimed more than claimTimeout ago, by a daemon that stopped
+	// before sending them, are claimed again. The due messages of
+	// deactivated users are canceled.
+	ClaimDue(ctx context.Context, now time.Time, claimTimeout time.Duration, limit int) ([]models.ScheduledMessage, error)
+	// MarkSent marks a claimed message as sent at the given time
+	MarkSent(ctx context.Context, id uint, at time.Time) error
+	// Release returns the claimed messages with the given IDs to pending
+	Release(ctx context.Context, ids []uint) error
+}
+
+// AdminUserFilter narrows the admin user listing
+type AdminUserFilter struct {
+	// Query matches a substring of the username or display name
+	Query string
+	// Role keeps only users with this role when set
+	Role string
+	// Status is "active", "deactivated" or empty for both
+	Status string
+}
+
+// AuditLogFilter narrows the audit log listing
+type AuditLogFilter struct {
+	// ActorID keeps only the actions of this user when set
+	ActorID uint
+	// Action keeps only this action when set
+	Action string
+}
+
+// Transactor runs the statements of several repositories in one transaction
+type Transactor interface {
+	// Transaction calls fn with repositories whose statements all run in one
+	// transaction, committed when fn returns nil and rolled back otherwise
+	Transaction(ctx context.Context, fn func(tx Repositories) error) error
+}
+
+// Repositories groups the repositories handed to the services
+type Repositories struct {
+	Tx               Transactor
+	Users            UserRepository
+	Messages         MessageRepository
+	APIKeys          APIKeyRepository
+	Webhooks         WebhookRepository
+	IncomingWebhooks IncomingWebhookRepository
+	Commands         CommandRepository
+	Mutes            MuteRepository
+	Blocks           BlockRepository
+	Scheduled        ScheduledMessageRepository
+	Audit            AuditRepository
+}
+
+// NewGormRepositories returns the GORM implementations backed by db, which
+// may be a Postgres or a SQLite connection
+func NewGormRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		Tx:               gormTransactor{db: db},
+		Users:            NewUserRepository(db),
+		Messages:         NewMessageRepository(db),
+		APIKeys:          NewAPIKeyRepository(db),
+		Webhooks:         NewWebhookRepository(db),
+		IncomingWebhooks: NewIncomingWebhookRepository(db),
+		Commands:         NewCommandRepository(db),
+		Mutes:            NewMuteRepository(db),
+		Blocks:           NewBlockRepository(db),
+		Scheduled:        NewScheduledMessageRepository(db),
+		Audit:            NewAuditRepository(db),
+	}
+}
+
+// gormTransactor opens the transactions of the GORM repositories
+type gormTransactor struct {
+	db *gorm.DB
+}
+
+func (t gormTransactor) Transaction(ctx context.Context, fn func(tx Repositories) error) error {
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewGormRepositories(tx))
+	})
+}
+
+// isPostgres reports whether db talks to Postgres rather than SQLite
+func isPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// likeOperator returns the case-insensitive LIKE operator of the dialect.
+// SQLite's LIKE already ignores the case of ASCII letters.
+func likeOperator(db *gorm.DB) string {
+	if isPostgres(db) {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// escapeLike escapes the LIKE wildcards so user input is matched literally;
+// queries must use ESCAPE '\' since SQLite has no default escape character
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return replacer.Replace(value)
+}
@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"instant-messaging-app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormScheduledMessageRepository struct {
+	db *gorm.DB
+}
+
+// NewScheduledMessageRepository returns a ScheduledMessageRepository backed by db
+func NewScheduledMessageRepository(db *gorm.DB) ScheduledMessageRepository {
+	return &gormScheduledMessageRepository{db: db}
+}
+
+func (r *gormScheduledMessageRepository) Create(ctx context.Context, message *models.ScheduledMessage) error {
+	return r.db.WithContext(ctx).Create(message).Error
+}
+
+func (r *gormScheduledMessageRepository) FindByID(ctx context.Context, id uint) (models.ScheduledMessage, error) {
+	var message models.ScheduledMessage
+	err := r.db.WithContext(ctx).First(&message, id).Error
+	return message, err
+}
+
+func (r *gormScheduledMessageRepository) FindByUser(ctx context.Context, userID, id uint) (models.ScheduledMessage, error) {
+	var message models.ScheduledMessage
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&message, id).Error
+	return message, err
+}
+
+func (r *gormScheduledMessageRepository) UpdatePending(ctx context.Context, message *models.ScheduledMessage, fields map[string]interface{}) (bool, error) {
+	result := r.db.WithContext(ctx).Model(message).Where("status = ?", models.SchedulePending).Updates(fields)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormScheduledMessageRepository) ListByUser(ctx context.Context, userID uint, status string, limit int) ([]models.ScheduledMessage, error) {
+	order := "send_at desc, id desc"
+	if status == models.SchedulePending {
+		order = "send_at asc, id asc"
+	}
+
+	var messages []models.ScheduledMessage
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, status).
+		Order(order).
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+func (r *gormScheduledMessageRepository) CountPending(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.ScheduledMessage{}).
+		Where("user_id = ? AND status = ?", userID, models.SchedulePending).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *gormScheduledMessageRepository) ClaimDue(ctx context.Context, now time.Time, claimTimeout time.Duration, limit int) ([]models.ScheduledMessage, error) {
+	var due []models.ScheduledMessage
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The due messages of deactivated or deleted users are canceled
+		// instead of sent
+		inactive := tx.Unscoped().Model(&models.User{}).Select("id").
+			Where("deactivated_at IS NOT NULL OR deleted_at IS NOT NULL")
+		err := tx.Model(&models.ScheduledMessage{}).
+			Where("status = ? AND send_at <= ? AND user_id IN (?)", models.SchedulePending, now, inactive).
+			Update("status", models.ScheduleCanceled).Error
+		if err != nil {
+			return err
+		}
+
+		query := tx.Where("(status = ? AND send_at <= ?) OR (status = ? AND claimed_at <= ?)",
+			models.SchedulePending, now, models.ScheduleSending, now.Add(-claimTimeout)).
+			Order("send_at asc, id asc").
+			Limit(limit)
+		// Postgres locks the rows until they are claimed, so that concurrent
+		// daemons skip them; SQLite serializes the writing transactions
+		// instead
+		if isPostgres(tx) {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+		if err := query.Find(&due).Error; err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(due))
+		for i := range due {
+			ids[i] = due[i].ID
+			due[i].Status = models.ScheduleSending
+			due[i].ClaimedAt = &now
+		}
+		return tx.Model(&models.ScheduledMessage{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":     models.ScheduleSending,
+			"claimed_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+func (r *gormScheduledMessageRepository) MarkSent(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.ScheduledMessage{}).
+		Where("id = ? AND status = ?", id, models.ScheduleSending).
+		Updates(map[string]interface{}{
+			"status":  models.ScheduleSent,
+			"sent_at": at,
+		}).Error
+}
+
+func (r *gormScheduledMessageRepository) Release(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&models.ScheduledMessage{}).
+		Where("id IN ? AND status = ?", ids, models.ScheduleSending).
+		Updates(map[string]interface{}{
+			"status":     models.SchedulePending,
+			"claimed_at": nil,
+		}).Error
+}
@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"instant-messaging-app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// searchSimilarityThreshold is the minimum trigram similarity for a fuzzy match
+const searchSimilarityThreshold = 0.3
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository returns a UserRepository backed by db
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &gormUserRepository{db: db}
+}
+
+func (r *gormUserRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *gormUserRepository) FindByID(ctx context.Context, id uint) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).First(&user, id).Error
+	return user, err
+}
+
+func (r *gormUserRepository) FindByUsername(ctx context.Context, username string) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	return user, err
+}
+
+func (r *gormUserRepository) ListVisible(ctx context.Context, requesterID uint) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Select("id, username, display_name, owner_id").
+		Where("deactivated_at IS NULL").
+		Where("id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = ?)", requesterID).
+		Where("id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = ?)", requesterID).
+		Find(&users).Error
+	return users, err
+}
+
+// Search matches query against the username or display name prefix. Postgres
+// also matches by trigram similarity; SQLite, which has no pg_trgm, matches
+// substrings instead. Users that blocked or were blocked by the requester are
+// excluded, and existing conversation partners are ranked first.
+func (r *gormUserRepository) Search(ctx context.Context, requesterID uint, query string, page, pageSize int) ([]models.User, int64, error) {
+	query = strings.TrimSpace(query)
+	prefix := escapeLike(query) + "%"
+	like := likeOperator(r.db)
+	postgres := isPostgres(r.db)
+
+	db := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("users.id <> ?", requesterID).
+		Where("users.deactivated_at IS NULL").
+		Where("users.id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = ?)", requesterID).
+		Where("users.id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = ?)", requesterID)
+
+	if query != "" {
+		if postgres {
+			db = db.Where(
+				"users.username ILIKE ? ESCAPE '\\' OR users.display_name ILIKE ? ESCAPE '\\' OR similarity(users.username, ?) > ? OR similarity(users.display_name, ?) > ?",
+				prefix, prefix, query, searchSimilarityThreshold, query, searchSimilarityThreshold,
+			)
+		} else {
+			contains := "%" + escapeLike(query) + "%"
+			db = db.Where("users.username LIKE ? ESCAPE '\\' OR users.display_name LIKE ? ESCAPE '\\'", contains, contains)
+		}
+	}
+
+	// Count the matches before applying pagination
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Conversation partners first, then prefix matches, then the closest fuzzy matches
+	orderSQL := fmt.Sprintf(`EXISTS (SELECT 1 FROM messages m WHERE m.deleted_at IS NULL AND ((m.sender_id = ? AND m.receiver_id = users.id) OR (m.receiver_id = ? AND m.sender_id = users.id))) DESC,
+			(users.username %[1]s ? ESCAPE '\' OR users.display_name %[1]s ? ESCAPE '\') DESC,`, like)
+	vars := []interface{}{requesterID, requesterID, prefix, prefix}
+	if postgres {
+		orderSQL += `
+			GREATEST(similarity(users.username, ?), similarity(users.display_name, ?)) DESC,`
+		vars = append(vars, query, query)
+	}
+	orderSQL += `
+			users.username ASC`
+
+	var users []models.User
+	err := db.Select("users.id, users.username, users.display_name, users.owner_id").
+		Clauses(clause.OrderBy{Expression: clause.Expr{SQL: orderSQL, Vars: vars, WithoutParentheses: true}}).
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&users).Error
+	return users, total, err
+}
+
+func (r *gormUserRepository) ListForAdmin(ctx context.Context, filter AdminUserFilter, page, pageSize int) ([]models.User, int64, error) {
+	db := r.filterForAdmin(ctx, filter)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := db.Order("id asc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&users).Error
+	return users, total, err
+}
+
+func (r *gormUserRepository) Count(ctx context.Context, filter AdminUserFilter) (int64, error) {
+	var count int64
+	err := r.filterForAdmin(ctx, filter).Count(&count).Error
+	return count, err
+}
+
+// filterForAdmin returns the query of the users matching filter
+func (r *gormUserRepository) filterForAdmin(ctx context.Context, filter AdminUserFilter) *gorm.DB {
+	db := r.db.WithContext(ctx).Model(&models.User{})
+
+	if query := strings.TrimSpace(filter.Query); query != "" {
+		contains := "%" + escapeLike(query) + "%"
+		like := likeOperator(r.db)
+		db = db.Where(fmt.Sprintf("username %[1]s ? ESCAPE '\\' OR display_name %[1]s ? ESCAPE '\\'", like), contains, contains)
+	}
+	if filter.Role != "" {
+		db = db.Where("role = ?", filter.Role)
+	}
+	switch filter.Status {
+	case "active":
+		db = db.Where("deactivated_at IS NULL")
+	case "deactivated":
+		db = db.Where("deactivated_at IS NOT NULL")
+	}
+	return db
+}
+
+func (r *gormUserRepository) Update(ctx context.Context, user *models.User, fields map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(user).Updates(fields).Error
+}
+
+func (r *gormUserRepository) ListByOwner(ctx context.Context, ownerID uint) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Order("id asc").Find(&users).Error
+	return users, err
+}
+
+func (r *gormUserRepository) CountByOwner(ctx context.Context, ownerID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("owner_id = ?", ownerID).Count(&count).Error
+	return count, err
+}
+
+func (r *gormUserRepository) FindBot(ctx context.Context, ownerID, botID uint) (models.User, error) {
+	var bot models.User
+	err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).First(&bot, botID).Error
+	return bot, err
+}
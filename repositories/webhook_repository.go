@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"instant-messaging-app/models"
+
+	"gorm.io/gorm"
+)
+
+type gormWebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository returns a WebhookRepository backed by db
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &gormWebhookRepository{db: db}
+}
+
+func (r *gormWebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *gormWebhookRepository) FindByID(ctx context.Context, id uint) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.db.WithContext(ctx).First(&webhook, id).Error
+	return webhook, err
+}
+
+func (r *gormWebhookRepository) FindByUser(ctx context.Context, userID, id uint) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&webhook, id).Error
+	return webhook, err
+}
+
+func (r *gormWebhookRepository) ListByUser(ctx context.Context, userID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id asc").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *gormWebhookRepository) CountByUser(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Webhook{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *gormWebhookRepository) Update(ctx context.Context, webhook *models.Webhook, fields map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(webhook).Updates(fields).Error
+}
+
+func (r *gormWebhookRepository) Delete(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(webhook).Error
+	})
+}
+
+func (r *gormWebhookRepository) ListSubscribers(ctx context.Context, userIDs []uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	// The webhooks of deactivated users are skipped, and those receiving
+	// the events of every user need an owner still allowed to create them
+	err := r.db.WithContext(ctx).
+		Joins("JOIN users ON users.id = webhooks.user_id").
+		Where("webhooks.disabled_at IS NULL AND users.deactivated_at IS NULL AND users.deleted_at IS NULL").
+		Where("webhooks.user_id IN ? OR (webhooks.all_users = ? AND users.role IN ?)",
+			userIDs, true, models.RolesWith(models.PermissionManageWebhooks)).
+		Order("webhooks.id asc").
+		Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *gormWebhookRepository) RecordSuccess(ctx context.Context, webhookID uint) error {
+	return r.db.WithContext(ctx).Model(&models.Webhook{}).
+		Where("id = ? AND failure_count <> 0", webhookID).
+		Update("failure_count", 0).Error
+}
+
+func (r *gormWebhookRepository) RecordFailure(ctx context.Context, webhookID uint, disableAfter int) (bool, error) {
+	disabled := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Webhook{}).Where("id = ?", webhookID).
+			Update("failure_count", gorm.Expr("failure_count + 1")).Error
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&models.Webhook{}).
+			Where("id = ? AND disabled_at IS NULL AND failure_count >= ?", webhookID, disableAfter).
+			Update("disabled_at", time.Now())
+		disabled = result.RowsAffected == 1
+		return result.Error
+	})
+	return disabled, err
+}
+
+func (r *gormWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&deliveries).Error
+}
+
+func (r *gormWebhookRepository) FindDelivery(ctx context.Context, webhookID, id uint) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.WithContext(ctx).Where("webhook_id = ?", webhookID).First(&delivery, id).Error
+	return delivery, err
+}
+
+func (r *gormWebhookRepository) ListDeliveries(ctx context.Context, webhookID uint, page, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := db.Order("id desc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+func (r *gormWebhookRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Joins("JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id").
+		Where("webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ? AND webhooks.disabled_at IS NULL", models.DeliveryPending, now).
+		Order("webhook_deliveries.next_attempt_at asc").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *gormWebhookRepository) Claim(ctx context.Context, delivery *models.WebhookDelivery, now, until time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, models.DeliveryPending, now).
+		Update("next_attempt_at", until)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	delivery.NextAttemptAt = &until
+	return true, nil
+}
+
+func (r *gormWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery, fields map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(delivery).Updates(fields).Error
+}
@@ -0,0 +1,63 @@
+package services_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"instant-messaging-app/broker"
+	"instant-messaging-app/config"
+	"instant-messaging-app/repositories"
+)
+
+// sendMessageQueue gets the messages the scheduler hands to the message
+// service
+const sendMessageQueue = "sendMessage"
+
+// repos are the repositories the scheduler runs on
+var repos repositories.Repositories
+
+// TestMain runs the scheduler on a SQLite database and an in-process broker,
+// without the message service
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "scheduler-services-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cfg := config.Default()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = filepath.Join(dir, "scheduler.db")
+	cfg.Scheduler.PollInterval = 10 * time.Millisecond
+	config.Cfg = cfg
+	config.InitDatabase()
+	config.Broker = broker.NewMemoryBroker()
+	if err := declare(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	repos = repositories.NewGormRepositories(config.DB)
+
+	code := m.Run()
+	config.Broker.Close()
+	if sqlDB, err := config.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// declare declares the exchange of the user service and the sendMessage
+// queue bound to it
+func declare() error {
+	if err := config.Broker.DeclareExchange(config.Cfg.Exchanges.UserDirect, broker.ExchangeDirect); err != nil {
+		return err
+	}
+	if err := config.Broker.DeclareQueue(sendMessageQueue, broker.QueueOptions{}); err != nil {
+		return err
+	}
+	return config.Broker.BindQueue(sendMessageQueue, config.Cfg.Exchanges.UserDirect, "sendMessage")
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"instant-messaging-app/broker"
+	"instant-messaging-app/config"
+	"instant-messaging-app/metrics"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+)
+
+// Scheduler sends the due scheduled messages through the sendMessage queue of
+// the message service, like the messages of the users. Several daemons may
+// share the database: the messages are claimed, and the claim committed,
+// before they are published.
+type Scheduler struct {
+	scheduled repositories.ScheduledMessageRepository
+}
+
+// NewScheduler returns a Scheduler of the messages stored in scheduled
+func NewScheduler(scheduled repositories.ScheduledMessageRepository) *Scheduler {
+	return &Scheduler{scheduled: scheduled}
+}
+
+// Run sends the due messages until ctx is canceled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(config.Cfg.Scheduler.PollInterval)
+	defer ticker.Stop()
+	for {
+		s.sendDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue sends the due messages, scheduler.batch_size at a time, until none
+// is left
+func (s *Scheduler) sendDue(ctx context.Context) {
+	batchSize := config.Cfg.Scheduler.BatchSize
+	for ctx.Err() == nil {
+		claimed, err := s.scheduled.ClaimDue(ctx, time.Now().UTC(), config.Cfg.Scheduler.ClaimTimeout, batchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Failed to claim the due scheduled messages", "error", err)
+			}
+			return
+		}
+		if err := s.send(ctx, claimed); err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Failed to send the due scheduled messages", "error", err)
+			}
+			return
+		}
+		if len(claimed) < batchSize {
+			return
+		}
+	}
+}
+
+// send publishes the claimed messages in order and marks them as sent. It
+// stops at the first error and releases the messages left, which wait for
+// the next poll.
+func (s *Scheduler) send(ctx context.Context, claimed []models.ScheduledMessage) error {
+	// The claims are settled even when the daemon is stopping
+	settle := context.WithoutCancel(ctx)
+	for i, message := range claimed {
+		if err := publish(ctx, message); err != nil {
+			ids := make([]uint, 0, len(claimed)-i)
+			for _, left := range claimed[i:] {
+				ids = append(ids, left.ID)
+			}
+			if releaseErr := s.scheduled.Release(settle, ids); releaseErr != nil {
+				slog.ErrorContext(ctx, "Failed to release the claimed scheduled messages", "count", len(ids), "error", releaseErr)
+			}
+			return err
+		}
+		metrics.ScheduledMessagesSent.Inc()
+		// A message published but not marked is sent again once its claim
+		// times out
+		if err := s.scheduled.MarkSent(settle, message.ID, time.Now().UTC()); err != nil {
+			slog.ErrorContext(ctx, "Failed to mark a scheduled message as sent", "scheduled_message_id", message.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// publish sends a scheduled message to the message service. Its content is
+// sent as is, even when it starts with a slash.
+func publish(ctx context.Context, message models.ScheduledMessage) error {
+	body, err := json.Marshal(types.SendMessageRequest{
+		UserID:     message.UserID,
+		ReceiverID: message.ReceiverID,
+		Content:    message.Content,
+		SenderName: message.SenderName(),
+		Verbatim:   true,
+	})
+	if err != nil {
+		return err
+	}
+	err = config.Broker.Publish(ctx, config.Cfg.Exchanges.UserDirect, "sendMessage", broker.Message{
+		ContentType: "application/json",
+		Body:        body,
+	})
+	if err != nil {
+		return err
+	}
+	slog.DebugContext(ctx, "Sent a scheduled message", "scheduled_message_id", message.ID, "kind", message.Kind)
+	return nil
+}
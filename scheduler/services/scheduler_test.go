@@ -0,0 +1,163 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+	"instant-messaging-app/scheduler/services"
+	"instant-messaging-app/types"
+)
+
+// names numbers the users of the tests, so that every test gets its own
+var names atomic.Int64
+
+// newUser stores a user whose username starts with name
+func newUser(t *testing.T, name string) models.User {
+	t.Helper()
+	user := models.User{Username: fmt.Sprintf("%s%d", name, names.Add(1)), Password: "-"}
+	if err := repos.Users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// schedule stores a message of user to receiver, in the given status, sent
+// at sendAt
+func schedule(t *testing.T, user, receiver models.User, kind, content, status string, sendAt time.Time) models.ScheduledMessage {
+	t.Helper()
+	message := models.ScheduledMessage{
+		UserID:     user.ID,
+		ReceiverID: receiver.ID,
+		Kind:       kind,
+		Content:    content,
+		SendAt:     sendAt.UTC(),
+		TimeZone:   "UTC",
+		Status:     status,
+	}
+	if status == models.ScheduleSending {
+		message.ClaimedAt = &message.SendAt
+	}
+	if err := repos.Scheduled.Create(context.Background(), &message); err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+// run runs a scheduler for a few polls
+func run(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*config.Cfg.Scheduler.PollInterval)
+	defer cancel()
+	services.NewScheduler(repos.Scheduled).Run(ctx)
+}
+
+// sent returns the requests published to the sendMessage queue so far
+func sent(t *testing.T) []types.SendMessageRequest {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	deliveries, err := config.Broker.Consume(ctx, sendMessageQueue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests []types.SendMessageRequest
+	for {
+		select {
+		case delivery := <-deliveries:
+			var request types.SendMessageRequest
+			if err := json.Unmarshal(delivery.Body, &request); err != nil {
+				t.Fatal(err)
+			}
+			requests = append(requests, request)
+		case <-time.After(100 * time.Millisecond):
+			return requests
+		}
+	}
+}
+
+// expectStatus fails unless the scheduled message has the given status
+func expectStatus(t *testing.T, message models.ScheduledMessage, status string) {
+	t.Helper()
+	stored, err := repos.Scheduled.FindByID(context.Background(), message.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != status {
+		t.Fatalf("expected %q to be %s, got %+v", message.Content, status, stored)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	alice, bob := newUser(t, "alice"), newUser(t, "bob")
+	now := time.Now()
+
+	// The due messages are sent in order, as is; a message claimed by a
+	// daemon that stopped is sent again, one still being sent is left alone
+	later := schedule(t, alice, bob, models.ScheduleKindMessage, "/not a command", models.SchedulePending, now.Add(-time.Second))
+	first := schedule(t, alice, bob, models.ScheduleKindMessage, "first", models.SchedulePending, now.Add(-time.Minute))
+	reminder := schedule(t, alice, alice, models.ScheduleKindReminder, "stand up", models.SchedulePending, now.Add(-30*time.Second))
+	stale := schedule(t, alice, bob, models.ScheduleKindMessage, "stale claim", models.ScheduleSending, now.Add(-2*config.Cfg.Scheduler.ClaimTimeout))
+	sending := schedule(t, alice, bob, models.ScheduleKindMessage, "still sending", models.ScheduleSending, now)
+	future := schedule(t, alice, bob, models.ScheduleKindMessage, "tomorrow", models.SchedulePending, now.Add(24*time.Hour))
+
+	run(t)
+	expected := []types.SendMessageRequest{
+		{UserID: alice.ID, ReceiverID: bob.ID, Content: "stale claim", Verbatim: true},
+		{UserID: alice.ID, ReceiverID: bob.ID, Content: "first", Verbatim: true},
+		{UserID: alice.ID, ReceiverID: alice.ID, Content: "stand up", SenderName: models.ReminderSenderName, Verbatim: true},
+		{UserID: alice.ID, ReceiverID: bob.ID, Content: "/not a command", Verbatim: true},
+	}
+	requests := sent(t)
+	if len(requests) != len(expected) {
+		t.Fatalf("expected %d messages, got %+v", len(expected), requests)
+	}
+	for i := range expected {
+		if requests[i] != expected[i] {
+			t.Fatalf("message %d: expected %+v, got %+v", i, expected[i], requests[i])
+		}
+	}
+	for _, message := range []models.ScheduledMessage{first, reminder, later, stale} {
+		expectStatus(t, message, models.ScheduleSent)
+	}
+	expectStatus(t, sending, models.ScheduleSending)
+	expectStatus(t, future, models.SchedulePending)
+}
+
+func TestSchedulerDeactivatedUsers(t *testing.T) {
+	alice, bob := newUser(t, "alice"), newUser(t, "bob")
+	due := schedule(t, alice, bob, models.ScheduleKindMessage, "too late", models.SchedulePending, time.Now().Add(-time.Minute))
+	if err := config.DB.Model(&alice).Update("deactivated_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// The due messages of a deactivated user are canceled instead of sent
+	run(t)
+	if requests := sent(t); len(requests) != 0 {
+		t.Fatalf("expected no message, got %+v", requests)
+	}
+	expectStatus(t, due, models.ScheduleCanceled)
+}
+
+func TestSchedulerPublishFailure(t *testing.T) {
+	alice, bob := newUser(t, "alice"), newUser(t, "bob")
+	due := schedule(t, alice, bob, models.ScheduleKindMessage, "unroutable", models.SchedulePending, time.Now().Add(-time.Minute))
+
+	// A message that could not be published waits for the next poll
+	exchange := config.Cfg.Exchanges.UserDirect
+	config.Cfg.Exchanges.UserDirect = "missing"
+	run(t)
+	config.Cfg.Exchanges.UserDirect = exchange
+	expectStatus(t, due, models.SchedulePending)
+
+	run(t)
+	if requests := sent(t); len(requests) != 1 || requests[0].Content != "unroutable" {
+		t.Fatalf("expected the message to be sent once, got %+v", requests)
+	}
+	expectStatus(t, due, models.ScheduleSent)
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+
+	"instant-messaging-app/broker"
+	"instant-messaging-app/health"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// headerCarrier lets the propagator read and write message headers
+type headerCarrier map[string]interface{}
+
+func (c headerCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// tracedBroker records a producer span for every publication and carries the
+// trace context in the message headers
+type tracedBroker struct {
+	broker.Broker
+	system string
+}
+
+// InstrumentBroker wraps b so that consumers can continue the trace of the
+// publisher; system names the transport in the spans (rabbitmq, memory)
+func InstrumentBroker(b broker.Broker, system string) broker.Broker {
+	return &tracedBroker{Broker: b, system: system}
+}
+
+func (b *tracedBroker) Publish(ctx context.Context, exchange, routingKey string, message broker.Message) error {
+	ctx, span := Tracer().Start(ctx, destination(exchange, routingKey)+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String(b.system),
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(exchange),
+			semconv.MessagingRabbitmqDestinationRoutingKey(routingKey),
+		),
+	)
+	defer span.End()
+
+	// Copy the headers so the caller's message is left untouched
+	headers := make(headerCarrier, len(message.Headers)+2)
+	for key, value := range message.Headers {
+		headers[key] = value
+	}
+	otel.GetTextMapPropagator().Inject(ctx, headers)
+	message.Headers = headers
+
+	err := b.Broker.Publish(ctx, exchange, routingKey, message)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// StartConsume starts the span of a consumed message as a child of the span
+// that published it. The returned context is detached from the cancellation
+// of ctx so that a message being processed is finished on shutdown.
+func StartConsume(ctx context.Context, name string, delivery broker.Delivery) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(context.WithoutCancel(ctx), headerCarrier(delivery.Headers))
+	return Tracer().Start(ctx, name+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingDestinationName(delivery.Exchange),
+			semconv.MessagingRabbitmqDestinationRoutingKey(delivery.RoutingKey),
+		),
+	)
+}
+
+// StartTrackedConsume starts the span of a message taken by a tracked
+// consumer; the span ends, marked as failed when needed, once the consumer
+// goes idle again
+func StartTrackedConsume(ctx context.Context, consumer *health.Consumer, queue string, delivery broker.Delivery) context.Context {
+	ctx, span := StartConsume(ctx, queue, delivery)
+	consumer.Finally(func(failed bool) {
+		if failed {
+			span.SetStatus(codes.Error, "message processing failed")
+		}
+		span.End()
+	})
+	return ctx
+}
+
+// destination names the target of a publication; the default exchange routes
+// by queue name
+func destination(exchange, routingKey string) string {
+	if exchange == "" {
+		return routingKey
+	}
+	return exchange
+}
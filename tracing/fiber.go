@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"fmt"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// untracedPaths are polled by orchestrators and scrapers and would only add noise
+var untracedPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// Middleware starts a server span for every HTTP request, continuing the trace
+// of the caller when it sent a traceparent header; the other propagated
+// fields are internal and never read from clients. Handlers find the span in
+// c.UserContext(). WebSocket upgrades are skipped since their frames get
+// spans of their own.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if untracedPaths[c.Path()] || websocket.IsWebSocketUpgrade(c) {
+			return c.Next()
+		}
+
+		ctx := propagation.TraceContext{}.Extract(c.UserContext(), propagation.HeaderCarrier(c.GetReqHeaders()))
+		ctx, span := Tracer().Start(ctx, c.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Method()),
+				semconv.URLPath(c.Path()),
+			),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// The route is only known once the router has matched it
+		route := c.Route().Path
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route))
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			span.RecordError(err)
+			if fiberErr, ok := err.(*fiber.Error); ok {
+				status = fiberErr.Code
+			}
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+		return err
+	}
+}
@@ -1,13 +1,17 @@
 package types
 
 import (
+	"encoding/json"
+	"time"
+
 	"instant-messaging-app/dtos"
 )
 
 type AuthenicationRequest struct {
-	UUID     string `json:"uuid"`
-	Username string `json:"username"`
-	Password string `json:"password"`
+	UUID        string `json:"uuid"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	DisplayName string `json:"display_name,omitempty"`
 }
 
 type RegistrationResponse struct {
@@ -29,17 +33,52 @@ type Notification struct {
 	Data interface{} `json:"data"`
 }
 
+// Error codes of ErrorResponse, shared with the HTTP error envelope
+const (
+	ErrorCodeBadRequest = "bad_request"
+	ErrorCodeNotFound   = "not_found"
+	ErrorCodeConflict   = "conflict"
+	ErrorCodeInternal   = "internal_server_error"
+)
+
+// ErrorResponse reports a request that a service could not handle
+type ErrorResponse struct {
+	Code	string	`json:"code"`
+	Error	string	`json:"error"`
+}
+
 type GetUsersRequest struct {
 	UUID 	string	`json:"uuid"`
+	UserID	uint	`json:"user_id"`
 }
 
 type GetUsersResponse struct {
 	Users	[]dtos.UserDTO	`json:"users"`
 }
 
+type SearchUsersRequest struct {
+	UUID		string	`json:"uuid"`
+	UserID		uint	`json:"user_id"`
+	Query		string	`json:"query"`
+	Page		int		`json:"page"`
+	PageSize	int		`json:"page_size"`
+}
+
+type SearchUsersResponse struct {
+	Users		[]dtos.UserDTO	`json:"users"`
+	Query		string			`json:"query"`
+	Page		int				`json:"page"`
+	PageSize	int				`json:"page_size"`
+	Total		int64			`json:"total"`
+	HasMore		bool			`json:"has_more"`
+}
+
 type TokenRequest struct {
 	Type	string	`json:"type"`
 	Token	string	`json:"token"`
+	// ResumeFrom asks for the events after this sequence number to be
+	// replayed before the live ones
+	ResumeFrom	*uint64	`json:"resume_from,omitempty"`
 }
 
 type GetSelfRequest struct {
@@ -66,8 +105,94 @@ type SendMessageRequest struct {
 	UserID		uint	`json:"user_id"`
 	ReceiverID	uint	`json:"receiver_id"`
 	Content		string	`json:"content"`
+	// SenderName is shown instead of the name of the sender, for the
+	// messages of incoming webhooks
+	SenderName	string	`json:"sender_name,omitempty"`
+	// Reply also sends the stored message to UUID, for callers that are not
+	// bound to the broadcast
+	Reply		bool	`json:"reply,omitempty"`
+	// Verbatim stores Content as is, even when it starts with a command
+	Verbatim	bool	`json:"verbatim,omitempty"`
+	// Ephemeral shows the message to its sender only, without storing it;
+	// the bots answer commands this way
+	Ephemeral	bool	`json:"ephemeral,omitempty"`
+	// InvocationID is the command invocation that the message answers,
+	// which must be ephemeral. The invocation is marked answered with it,
+	// and a second answer fails with ErrorCodeConflict.
+	InvocationID	uint	`json:"invocation_id,omitempty"`
 }
 
 type SendMessageResponse struct {
 	Message	dtos.MessageDTO	`json:"message"`
-}
\ No newline at end of file
+	// SenderSeq and ReceiverSeq number the message in the event streams of
+	// its sender and receiver; the gateway sends each user its own
+	SenderSeq	uint64	`json:"sender_seq,omitempty"`
+	ReceiverSeq	uint64	`json:"receiver_seq,omitempty"`
+	// Ephemeral messages answer a command of the sender, who is the only
+	// one to see them; they are not stored
+	Ephemeral	bool	`json:"ephemeral,omitempty"`
+	// Muted is set when the receiver muted the sender, so that its clients
+	// do not alert; the gateway only tells the receiver
+	Muted		bool	`json:"muted,omitempty"`
+}
+
+// CommandInvocation asks a bot to run one of its commands. The bot answers
+// with the ID, through the REST API, before the invocation expires.
+type CommandInvocation struct {
+	ID		uint		`json:"id"`
+	BotID		uint		`json:"bot_id"`
+	Command		string		`json:"command"`
+	Args		string		`json:"args"`
+	UserID		uint		`json:"user_id"`
+	ReceiverID	uint		`json:"receiver_id"`
+	ExpiresAt	time.Time	`json:"expires_at"`
+}
+
+// Event is a notification sent to a WebSocket client with its sequence
+// number in the event stream of the user
+type Event struct {
+	Type	string		`json:"type"`
+	Seq		uint64		`json:"seq"`
+	Data	interface{}	`json:"data"`
+}
+
+// ResumedNotification ends the replay of a resumed WebSocket session. When
+// Complete is false more events were missed than the gateway replays, and
+// the client should reload its conversations before relying on LastSeq.
+type ResumedNotification struct {
+	ResumeFrom	uint64	`json:"resume_from"`
+	LastSeq		uint64	`json:"last_seq"`
+	Replayed	int		`json:"replayed"`
+	Complete	bool	`json:"complete"`
+}
+
+type ForceLogoutNotification struct {
+	UserID	uint	`json:"user_id"`
+	// APIKeyID limits the logout to the sessions of one API key of the user
+	APIKeyID	uint	`json:"api_key_id,omitempty"`
+}
+
+// PollResponse is the result of a long-poll request. Events holds frames of
+// the WebSocket protocol; passing Cursor to the next poll of the session
+// acknowledges them, otherwise they are sent again. Closed is set once the
+// session ended, after a forced logout for instance.
+type PollResponse struct {
+	Session	string				`json:"session"`
+	Cursor	uint64				`json:"cursor"`
+	Events	[]json.RawMessage	`json:"events"`
+	Closed	bool				`json:"closed"`
+}
+
+// WebhookPayload is the body posted to the outgoing webhooks. ID is shared by
+// the deliveries of an event, so receivers can drop the replays they have seen.
+type WebhookPayload struct {
+	ID		string		`json:"id"`
+	Event		string		`json:"event"`
+	CreatedAt	time.Time	`json:"created_at"`
+	Data		interface{}	`json:"data"`
+}
+
+// MessageCreatedEvent is the data of a message_created webhook event
+type MessageCreatedEvent struct {
+	Message	dtos.MessageDTO	`json:"message"`
+}
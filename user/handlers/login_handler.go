@@ -3,46 +3,52 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 
 	"instant-messaging-app/config"
+	"instant-messaging-app/health"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/tracing"
 	"instant-messaging-app/types"
 	"instant-messaging-app/user/services"
 	"instant-messaging-app/utils"
 )
 
 // ConsumeLoginQueue listens to login requests and processes them
-func ConsumeLoginQueue(ctx context.Context, queueName string, notificationExchange string) {
-	msgs, err := config.RabbitMQCh.Consume(
-		queueName, // Queue name
-		"",
-		true,  // Auto-acknowledge
-		false, // Exclusive
-		false, // No-local
-		false, // No-wait
-		nil,
-	)
+func ConsumeLoginQueue(ctx context.Context, userRepo repositories.UserRepository, queueName string, notificationExchange string) {
+	msgs, err := config.Broker.Consume(ctx, queueName)
 	if err != nil {
-		log.Fatalf("Failed to start consuming from queue %s: %v", queueName, err)
+		logging.Fatal("Failed to start consuming", "queue", queueName, "error", err)
 	}
 
+	consumer := health.TrackConsumer(queueName)
 	go func() {
+		defer consumer.Stop()
 		for {
+			consumer.Idle()
 			select {
 			case <-ctx.Done():
-				log.Println("Stopping login queue consumption...")
+				slog.Info("Stopping login queue consumption", "queue", queueName)
 				return
-			case msg := <-msgs:
+			case msg, ok := <-msgs:
+				if !ok {
+					slog.Warn("Consumer closed", "queue", queueName)
+					return
+				}
+				consumer.Busy()
+				msgCtx := tracing.StartTrackedConsume(ctx, consumer, queueName, msg)
 				var request types.AuthenicationRequest
 				if err := json.Unmarshal(msg.Body, &request); err != nil {
-					log.Printf("Failed to unmarshal login request: %v", err)
+					slog.ErrorContext(msgCtx, "Failed to unmarshal login request", "error", err)
+					consumer.Fail()
 					continue
 				}
 
 				// Process the login
 				success := true
 				message := "Login successful"
-				token, err := services.ProcessUserLogin(request.Username, request.Password)
+				token, err := services.ProcessUserLogin(msgCtx, userRepo, request.Username, request.Password)
 				if err != nil {
 					success = false
 					message = "Login failed: " + err.Error()
@@ -50,7 +56,7 @@ func ConsumeLoginQueue(ctx context.Context, queueName string, notificationExchan
 				}
 
 				// Publish notification with the message type
-				utils.PublishNotification(notificationExchange, request.UUID, "login_response", types.LoginResponse{
+				utils.PublishNotification(msgCtx, notificationExchange, request.UUID, "login_response", types.LoginResponse{
 					UUID:    request.UUID,
 					Success: success,
 					Message: message,
@@ -0,0 +1,37 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+)
+
+func TestLogin(t *testing.T) {
+	alice := register(t, "alice", "secret-alice", "")
+
+	login := func(password string) types.LoginResponse {
+		t.Helper()
+		var response types.LoginResponse
+		request(t, loginQueue, func(uuid string) interface{} {
+			return types.AuthenicationRequest{UUID: uuid, Username: alice.Username, Password: password}
+		}).decode(t, "login_response", &response)
+		return response
+	}
+
+	response := login("secret-alice")
+	if !response.Success || response.Token == "" {
+		t.Fatalf("the login failed: %+v", response)
+	}
+	claims, err := utils.ParseJWT(response.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != alice.ID || claims.Username != alice.Username {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	if response := login("wrong-password"); response.Success || response.Token != "" {
+		t.Fatalf("a wrong password was accepted: %+v", response)
+	}
+}
@@ -0,0 +1,140 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"instant-messaging-app/broker"
+	"instant-messaging-app/config"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/user/handlers"
+	"instant-messaging-app/utils"
+)
+
+// notifications is the exchange the handlers publish their responses to,
+// routed by the UUID of the request
+const notifications = "notifications"
+
+// The request queues, one per handler
+const (
+	registrationQueue = "registration"
+	loginQueue        = "login"
+	getUsersQueue     = "getUsers"
+	getSelfQueue      = "getSelf"
+	searchUsersQueue  = "searchUsers"
+)
+
+// TestMain runs the handlers of the user service on a SQLite database and an
+// in-process broker, without the gateway
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "user-handlers-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cfg := config.Default()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = filepath.Join(dir, "users.db")
+	config.Cfg = cfg
+	config.InitDatabase()
+	config.Broker = broker.NewMemoryBroker()
+	if err := declare(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	users := repositories.NewUserRepository(config.DB)
+	handlers.ConsumeRegistrationQueue(ctx, users, registrationQueue, notifications)
+	handlers.ConsumeLoginQueue(ctx, users, loginQueue, notifications)
+	handlers.ConsumeGetUsersQueue(ctx, users, getUsersQueue, notifications)
+	handlers.ConsumeGetSelfQueue(ctx, users, getSelfQueue, notifications)
+	handlers.ConsumeSearchUsersQueue(ctx, users, searchUsersQueue, notifications)
+
+	code := m.Run()
+	cancel()
+	config.Broker.Close()
+	if sqlDB, err := config.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// declare declares the notification exchange and the request queues
+func declare() error {
+	if err := config.Broker.DeclareExchange(notifications, broker.ExchangeDirect); err != nil {
+		return err
+	}
+	for _, queue := range []string{registrationQueue, loginQueue, getUsersQueue, getSelfQueue, searchUsersQueue} {
+		if err := config.Broker.DeclareQueue(queue, broker.QueueOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// response is a notification published by a handler
+type response struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// request publishes a request built for a new UUID to queue, and waits for
+// the notification the handler publishes for that UUID
+func request(t *testing.T, queue string, build func(uuid string) interface{}) response {
+	t.Helper()
+	uuid := utils.GenerateUUID()
+	if err := config.Broker.DeclareQueue(uuid, broker.QueueOptions{AutoDelete: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Broker.BindQueue(uuid, notifications, uuid); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	deliveries, err := config.Broker.Consume(ctx, uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(build(uuid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Broker.Publish(ctx, "", queue, broker.Message{ContentType: "application/json", Body: body}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case delivery, ok := <-deliveries:
+		if !ok {
+			t.Fatalf("%s: the response queue was closed", queue)
+		}
+		var r response
+		if err := json.Unmarshal(delivery.Body, &r); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	case <-ctx.Done():
+		t.Fatalf("%s: no response", queue)
+	}
+	return response{}
+}
+
+// decode unmarshals the data of r into v, failing unless r has the given type
+func (r response) decode(t *testing.T, responseType string, v interface{}) {
+	t.Helper()
+	if r.Type != responseType {
+		t.Fatalf("expected a %s, got %s %s", responseType, r.Type, r.Data)
+	}
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,52 +3,58 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 
 	"instant-messaging-app/config"
+	"instant-messaging-app/health"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/tracing"
 	"instant-messaging-app/types"
 	"instant-messaging-app/user/services"
 	"instant-messaging-app/utils"
 )
 
 // ConsumeRegistrationQueue listens to registration requests and processes them
-func ConsumeRegistrationQueue(ctx context.Context, queueName string, notificationExchange string) {
-	msgs, err := config.RabbitMQCh.Consume(
-		queueName, // Queue name
-		"",
-		true,  // Auto-acknowledge
-		false, // Exclusive
-		false, // No-local
-		false, // No-wait
-		nil,
-	)
+func ConsumeRegistrationQueue(ctx context.Context, userRepo repositories.UserRepository, queueName string, notificationExchange string) {
+	msgs, err := config.Broker.Consume(ctx, queueName)
 	if err != nil {
-		log.Fatalf("Failed to start consuming from queue %s: %v", queueName, err)
+		logging.Fatal("Failed to start consuming", "queue", queueName, "error", err)
 	}
 
+	consumer := health.TrackConsumer(queueName)
 	go func() {
+		defer consumer.Stop()
 		for {
+			consumer.Idle()
 			select {
 			case <-ctx.Done():
-				log.Println("Stopping registration queue consumption...")
+				slog.Info("Stopping registration queue consumption", "queue", queueName)
 				return
-			case msg := <-msgs:
+			case msg, ok := <-msgs:
+				if !ok {
+					slog.Warn("Consumer closed", "queue", queueName)
+					return
+				}
+				consumer.Busy()
+				msgCtx := tracing.StartTrackedConsume(ctx, consumer, queueName, msg)
 				var request types.AuthenicationRequest
 				if err := json.Unmarshal(msg.Body, &request); err != nil {
-					log.Printf("Failed to unmarshal registration request: %v", err)
+					slog.ErrorContext(msgCtx, "Failed to unmarshal registration request", "error", err)
+					consumer.Fail()
 					continue
 				}
 
 				// Process the registration
 				success := true
 				message := "Registration successful"
-				if err := services.ProcessUserRegistration(request.Username, request.Password); err != nil {
+				if err := services.ProcessUserRegistration(msgCtx, userRepo, request.Username, request.Password, request.DisplayName); err != nil {
 					success = false
 					message = "Registration failed: " + err.Error()
 				}
 
 				// Publish notification with the message type
-				utils.PublishNotification(notificationExchange, request.UUID, "registration_response", types.RegistrationResponse{
+				utils.PublishNotification(msgCtx, notificationExchange, request.UUID, "registration_response", types.RegistrationResponse{
 					UUID:    request.UUID,
 					Success: success,
 					Message: message,
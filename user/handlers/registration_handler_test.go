@@ -0,0 +1,53 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+)
+
+// names numbers the users of the tests, so that every test gets its own
+var names atomic.Int64
+
+// register registers a user whose username starts with name through the
+// registration handler, and returns it as stored
+func register(t *testing.T, name, password, displayName string) models.User {
+	t.Helper()
+	username := fmt.Sprintf("%s%d", name, names.Add(1))
+	var registration types.RegistrationResponse
+	request(t, registrationQueue, func(uuid string) interface{} {
+		return types.AuthenicationRequest{UUID: uuid, Username: username, Password: password, DisplayName: displayName}
+	}).decode(t, "registration_response", &registration)
+	if !registration.Success {
+		t.Fatalf("registering %s: %s", username, registration.Message)
+	}
+
+	user, err := repositories.NewUserRepository(config.DB).FindByUsername(context.Background(), username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestRegistration(t *testing.T) {
+	alice := register(t, "alice", "secret-alice", "Alice")
+	if alice.DisplayName != "Alice" || alice.Password == "secret-alice" {
+		t.Fatalf("unexpected stored user %+v", alice)
+	}
+
+	// A taken username is refused
+	var duplicate types.RegistrationResponse
+	request(t, registrationQueue, func(uuid string) interface{} {
+		return types.AuthenicationRequest{UUID: uuid, Username: alice.Username, Password: "another-secret"}
+	}).decode(t, "registration_response", &duplicate)
+	if duplicate.Success || !strings.HasPrefix(duplicate.Message, "Registration failed") {
+		t.Fatalf("a duplicate username was accepted: %+v", duplicate)
+	}
+}
@@ -3,51 +3,62 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"instant-messaging-app/config"
 	"instant-messaging-app/dtos"
+	"instant-messaging-app/health"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/tracing"
 	"instant-messaging-app/types"
 	"instant-messaging-app/user/services"
 	"instant-messaging-app/utils"
-	"log"
+	"log/slog"
+
+	"gorm.io/gorm"
 )
 
 // ConsumeGetUsersQueue listens to getUsers requests and processes them
-func ConsumeGetUsersQueue(ctx context.Context, queueName string, notificationExchange string) {
-	msgs, err := config.RabbitMQCh.Consume(
-		queueName, // Queue name
-		"",
-		true,  // Auto-acknowledge
-		false, // Exclusive
-		false, // No-local
-		false, // No-wait
-		nil,
-	)
+func ConsumeGetUsersQueue(ctx context.Context, userRepo repositories.UserRepository, queueName string, notificationExchange string) {
+	msgs, err := config.Broker.Consume(ctx, queueName)
 	if err != nil {
-		log.Fatalf("Failed to start consuming from queue %s: %v", queueName, err)
+		logging.Fatal("Failed to start consuming", "queue", queueName, "error", err)
 	}
 
+	consumer := health.TrackConsumer(queueName)
 	go func() {
+		defer consumer.Stop()
 		for {
+			consumer.Idle()
 			select {
 			case <-ctx.Done():
-				log.Println("Stopping getUsers queue consumption...")
+				slog.Info("Stopping getUsers queue consumption", "queue", queueName)
 				return
-			case msg := <-msgs:
+			case msg, ok := <-msgs:
+				if !ok {
+					slog.Warn("Consumer closed", "queue", queueName)
+					return
+				}
+				consumer.Busy()
+				msgCtx := tracing.StartTrackedConsume(ctx, consumer, queueName, msg)
 				var request types.GetUsersRequest
 				if err := json.Unmarshal(msg.Body, &request); err != nil {
-					log.Printf("Failed to unmarshal getUsers request: %v", err)
+					slog.ErrorContext(msgCtx, "Failed to unmarshal getUsers request", "error", err)
+					consumer.Fail()
 					continue
 				}
 
 				// Fetch users from the database
-				users, err := services.GetAllUsers()
+				users, err := services.GetAllUsers(msgCtx, userRepo, request.UserID)
 				if err != nil {
-					log.Printf("Failed to fetch users for %s: %v", request.UUID, err)
+					slog.ErrorContext(msgCtx, "Failed to fetch users", "error", err)
+					utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeInternal, "Failed to retrieve users")
+					consumer.Fail()
 					continue
 				}
 
 				// Publish notification with the message type
-				utils.PublishNotification(notificationExchange, request.UUID, "get_users_response", types.GetUsersResponse{
+				utils.PublishNotification(msgCtx, notificationExchange, request.UUID, "get_users_response", types.GetUsersResponse{
 					Users: dtos.ToUserDTOs(users),
 				})
 
@@ -57,46 +68,108 @@ func ConsumeGetUsersQueue(ctx context.Context, queueName string, notificationExc
 }
 
 // ConsumeGetSelfQueue listens to getUsers requests and processes them
-func ConsumeGetSelfQueue(ctx context.Context, queueName string, notificationExchange string) {
-	msgs, err := config.RabbitMQCh.Consume(
-		queueName, // Queue name
-		"",
-		true,  // Auto-acknowledge
-		false, // Exclusive
-		false, // No-local
-		false, // No-wait
-		nil,
-	)
+func ConsumeGetSelfQueue(ctx context.Context, userRepo repositories.UserRepository, queueName string, notificationExchange string) {
+	msgs, err := config.Broker.Consume(ctx, queueName)
 	if err != nil {
-		log.Fatalf("Failed to start consuming from queue %s: %v", queueName, err)
+		logging.Fatal("Failed to start consuming", "queue", queueName, "error", err)
 	}
 
+	consumer := health.TrackConsumer(queueName)
 	go func() {
+		defer consumer.Stop()
 		for {
+			consumer.Idle()
 			select {
 			case <-ctx.Done():
-				log.Println("Stopping getUsers queue consumption...")
+				slog.Info("Stopping getSelf queue consumption", "queue", queueName)
 				return
-			case msg := <-msgs:
+			case msg, ok := <-msgs:
+				if !ok {
+					slog.Warn("Consumer closed", "queue", queueName)
+					return
+				}
+				consumer.Busy()
+				msgCtx := tracing.StartTrackedConsume(ctx, consumer, queueName, msg)
 				var request types.GetSelfRequest
 				if err := json.Unmarshal(msg.Body, &request); err != nil {
-					log.Printf("Failed to unmarshal getUsers request: %v", err)
+					slog.ErrorContext(msgCtx, "Failed to unmarshal getSelf request", "error", err)
+					consumer.Fail()
 					continue
 				}
 
 				// Fetch users from the database
-				user, err := services.GetUserByID(request.UserID)
+				user, err := services.GetUserByID(msgCtx, userRepo, request.UserID)
 				if err != nil {
-					log.Printf("Failed to fetch users for %s: %v", request.UUID, err)
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeNotFound, "User not found")
+						continue
+					}
+					slog.ErrorContext(msgCtx, "Failed to fetch user", "error", err)
+					utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeInternal, "Failed to retrieve user")
+					consumer.Fail()
 					continue
 				}
 
 				// Publish notification with the message type
-				utils.PublishNotification(notificationExchange, request.UUID, "get_self_response", types.GetSelfResponse{
+				utils.PublishNotification(msgCtx, notificationExchange, request.UUID, "get_self_response", types.GetSelfResponse{
 					User: dtos.ToUserDTO(user),
 				})
 
 			}
 		}
 	}()
-}
\ No newline at end of file
+}
+
+// ConsumeSearchUsersQueue listens to searchUsers requests and processes them
+func ConsumeSearchUsersQueue(ctx context.Context, userRepo repositories.UserRepository, queueName string, notificationExchange string) {
+	msgs, err := config.Broker.Consume(ctx, queueName)
+	if err != nil {
+		logging.Fatal("Failed to start consuming", "queue", queueName, "error", err)
+	}
+
+	consumer := health.TrackConsumer(queueName)
+	go func() {
+		defer consumer.Stop()
+		for {
+			consumer.Idle()
+			select {
+			case <-ctx.Done():
+				slog.Info("Stopping searchUsers queue consumption", "queue", queueName)
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					slog.Warn("Consumer closed", "queue", queueName)
+					return
+				}
+				consumer.Busy()
+				msgCtx := tracing.StartTrackedConsume(ctx, consumer, queueName, msg)
+				var request types.SearchUsersRequest
+				if err := json.Unmarshal(msg.Body, &request); err != nil {
+					slog.ErrorContext(msgCtx, "Failed to unmarshal searchUsers request", "error", err)
+					consumer.Fail()
+					continue
+				}
+
+				// Search the user directory
+				page, pageSize := utils.NormalizePagination(request.Page, request.PageSize)
+				users, total, err := services.SearchUsers(msgCtx, userRepo, request.UserID, request.Query, page, pageSize)
+				if err != nil {
+					slog.ErrorContext(msgCtx, "Failed to search users", "error", err)
+					utils.PublishError(msgCtx, notificationExchange, request.UUID, types.ErrorCodeInternal, "Failed to search users")
+					consumer.Fail()
+					continue
+				}
+
+				// Publish notification with the message type
+				utils.PublishNotification(msgCtx, notificationExchange, request.UUID, "search_users_response", types.SearchUsersResponse{
+					Users:    dtos.ToUserDTOs(users),
+					Query:    request.Query,
+					Page:     page,
+					PageSize: pageSize,
+					Total:    total,
+					HasMore:  int64(page*pageSize) < total,
+				})
+			}
+		}
+	}()
+}
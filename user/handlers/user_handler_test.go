@@ -0,0 +1,110 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+)
+
+func TestGetUsers(t *testing.T) {
+	alice, bob := register(t, "alice", "secret", ""), register(t, "bob", "secret", "")
+	carol, dave := register(t, "carol", "secret", ""), register(t, "dave", "secret", "")
+
+	getUsers := func() map[uint]bool {
+		t.Helper()
+		var response types.GetUsersResponse
+		request(t, getUsersQueue, func(uuid string) interface{} {
+			return types.GetUsersRequest{UUID: uuid, UserID: alice.ID}
+		}).decode(t, "get_users_response", &response)
+		found := map[uint]bool{}
+		for _, user := range response.Users {
+			found[user.ID] = true
+		}
+		return found
+	}
+	if found := getUsers(); !found[alice.ID] || !found[bob.ID] || !found[carol.ID] || !found[dave.ID] {
+		t.Fatalf("expected %s, %s, %s and %s, got %v", alice.Username, bob.Username, carol.Username, dave.Username, found)
+	}
+
+	// Like the search, the listing leaves out deactivated and blocked users
+	repos := repositories.NewGormRepositories(config.DB)
+	ctx := context.Background()
+	if err := repos.Users.Update(ctx, &bob, map[string]interface{}{"deactivated_at": bob.CreatedAt}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Blocks.Block(ctx, alice.ID, carol.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Blocks.Block(ctx, dave.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if found := getUsers(); !found[alice.ID] || found[bob.ID] || found[carol.ID] || found[dave.ID] {
+		t.Fatalf("expected only %s of the four users, got %v", alice.Username, found)
+	}
+}
+
+func TestGetSelf(t *testing.T) {
+	alice := register(t, "alice", "secret", "Alice")
+
+	var response types.GetSelfResponse
+	request(t, getSelfQueue, func(uuid string) interface{} {
+		return types.GetSelfRequest{UUID: uuid, UserID: alice.ID}
+	}).decode(t, "get_self_response", &response)
+	if response.User.ID != alice.ID || response.User.Username != alice.Username || response.User.DisplayName != "Alice" {
+		t.Fatalf("unexpected user %+v", response.User)
+	}
+
+	var missing types.ErrorResponse
+	request(t, getSelfQueue, func(uuid string) interface{} {
+		return types.GetSelfRequest{UUID: uuid, UserID: alice.ID + 1000}
+	}).decode(t, "error", &missing)
+	if missing.Code != types.ErrorCodeNotFound {
+		t.Fatalf("expected not_found, got %+v", missing)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	alice := register(t, "alice", "secret", "")
+	prefix := fmt.Sprintf("carol%d-", names.Add(1))
+	carols := []models.User{
+		register(t, prefix, "secret", ""),
+		register(t, prefix, "secret", ""),
+		register(t, prefix, "secret", ""),
+	}
+
+	search := func(query string, page, pageSize int) types.SearchUsersResponse {
+		t.Helper()
+		var response types.SearchUsersResponse
+		request(t, searchUsersQueue, func(uuid string) interface{} {
+			return types.SearchUsersRequest{UUID: uuid, UserID: alice.ID, Query: query, Page: page, PageSize: pageSize}
+		}).decode(t, "search_users_response", &response)
+		return response
+	}
+
+	first := search(prefix, 1, 2)
+	if len(first.Users) != 2 || !first.HasMore || first.Total != 3 || first.Page != 1 || first.PageSize != 2 {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+	if last := search(prefix, 2, 2); len(last.Users) != 1 || last.HasMore || last.Total != 3 {
+		t.Fatalf("unexpected last page %+v", last)
+	}
+
+	// Deactivated and blocked users are left out
+	repos := repositories.NewGormRepositories(config.DB)
+	ctx := context.Background()
+	if err := repos.Users.Update(ctx, &carols[0], map[string]interface{}{"deactivated_at": carols[0].CreatedAt}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Blocks.Block(ctx, carols[1].ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	remaining := search(prefix, 1, 100)
+	if len(remaining.Users) != 1 || remaining.Users[0].ID != carols[2].ID {
+		t.Fatalf("expected only %s, got %+v", carols[2].Username, remaining.Users)
+	}
+}
@@ -1,55 +1,93 @@
 package services
 
 import (
+	"context"
 	"errors"
 
-	"instant-messaging-app/config"
 	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
 	"instant-messaging-app/utils"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-func ProcessUserRegistration(username, password string) error {
+func ProcessUserRegistration(ctx context.Context, users repositories.UserRepository, username, password, displayName string) error {
+	_, err := CreateUser(ctx, users, username, password, displayName, models.RoleUser)
+	return err
+}
+
+// CreateUser hashes the password and stores a new account with the given role
+func CreateUser(ctx context.Context, users repositories.UserRepository, username, password, displayName, role string) (models.User, error) {
 	// Check if the user already exists
-	var existingUser models.User
-	if err := config.DB.Where("username = ?", username).First(&existingUser).Error; err == nil {
-		return errors.New("username already taken")
+	if existingUser, err := users.FindByUsername(ctx, username); err == nil {
+		return existingUser, errors.New("username already taken")
 	}
 
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return errors.New("failed to hash password")
+		return models.User{}, errors.New("failed to hash password")
 	}
 
 	// Create the user
 	user := models.User{
-		Username: username,
-		Password: string(hashedPassword),
+		Username:    username,
+		DisplayName: displayName,
+		Password:    string(hashedPassword),
+		Role:        role,
 	}
 
 	// Save to the database
-	return config.DB.Create(&user).Error
+	err = users.Create(ctx, &user)
+	return user, err
 }
 
+// ResetPassword replaces the password of a user and revokes their existing sessions
+func ResetPassword(ctx context.Context, users repositories.UserRepository, username, password string) (models.User, error) {
+	user, err := users.FindByUsername(ctx, username)
+	if err != nil {
+		return user, errors.New("user not found")
+	}
 
-// Pr authentifie un utilisateur et retourne un token
-func ProcessUserLogin(username, password string) (string, error) {
-	var user models.User
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return user, errors.New("failed to hash password")
+	}
+
+	user.Password = string(hashedPassword)
+	user.TokenVersion++
+	err = users.Update(ctx, &user, map[string]interface{}{
+		"password":      user.Password,
+		"token_version": user.TokenVersion,
+	})
+	return user, err
+}
+
+// GetUserByUsername retrieves a user by username
+func GetUserByUsername(ctx context.Context, users repositories.UserRepository, username string) (models.User, error) {
+	return users.FindByUsername(ctx, username)
+}
 
+// Pr authentifie un utilisateur et retourne un token
+func ProcessUserLogin(ctx context.Context, users repositories.UserRepository, username, password string) (string, error) {
 	// Vérifie si l'utilisateur existe
-	if err := config.DB.Where("username = ?", username).First(&user).Error; err != nil {
+	user, err := users.FindByUsername(ctx, username)
+	if err != nil {
 		return "", errors.New("user not found")
 	}
 
+	// Deactivated accounts cannot log in
+	if !user.IsActive() {
+		return "", errors.New("account deactivated")
+	}
+
 	// Vérifie le mot de passe
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
 		return "", errors.New("invalid password")
 	}
 
 	// Génère un token JWT
-	tokenString, err := utils.GenerateJWT(user.ID, user.Username)
+	tokenString, err := utils.GenerateJWT(user.ID, user.Username, user.Role, user.TokenVersion)
 	if err != nil {
 		return "", errors.New("failed to generate token")
 	}
@@ -57,15 +95,17 @@ func ProcessUserLogin(username, password string) (string, error) {
 	return tokenString, nil
 }
 
-// GetAllUsers retrieves all users from the database
-func GetAllUsers() ([]models.User, error) {
-	var users []models.User
-	err := config.DB.Select("id, username").Find(&users).Error
-	return users, err
+// GetAllUsers retrieves the active users visible to requesterID
+func GetAllUsers(ctx context.Context, users repositories.UserRepository, requesterID uint) ([]models.User, error) {
+	return users.ListVisible(ctx, requesterID)
 }
 
-func GetUserByID(id uint) (models.User, error) {
-	var user models.User
-	err := config.DB.First(&user, id).Error
-	return user, err
-}
\ No newline at end of file
+func GetUserByID(ctx context.Context, users repositories.UserRepository, id uint) (models.User, error) {
+	return users.FindByID(ctx, id)
+}
+
+// SearchUsers returns one page of active users matching query by username or
+// display name, excluding blocked users and ranking conversation partners first
+func SearchUsers(ctx context.Context, users repositories.UserRepository, requesterID uint, query string, page, pageSize int) ([]models.User, int64, error) {
+	return users.Search(ctx, requesterID, query, page, pageSize)
+}
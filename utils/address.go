@@ -0,0 +1,17 @@
+package utils
+
+import "net"
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which
+// net.IP.IsPrivate does not cover
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPrivateAddress reports whether ip is on a loopback, private, shared or
+// link-local network, or unspecified. Requests sent on behalf of users, such
+// as webhook deliveries, must not reach these addresses, which would expose
+// the internal services.
+func IsPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
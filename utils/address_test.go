@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsPrivateAddress(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":       true,
+		"10.1.2.3":        true,
+		"172.16.0.1":      true,
+		"192.168.1.1":     true,
+		"100.64.0.1":      true,
+		"100.127.255.254": true,
+		"169.254.169.254": true,
+		"0.0.0.0":         true,
+		"::1":             true,
+		"fd00::1":         true,
+		"fe80::1":         true,
+		"::ffff:10.0.0.1": true,
+		"100.128.0.1":     false,
+		"93.184.216.34":   false,
+		"2606:4700::1111": false,
+	}
+	for address, private := range cases {
+		if got := IsPrivateAddress(net.ParseIP(address)); got != private {
+			t.Errorf("IsPrivateAddress(%s) = %t, expected %t", address, got, private)
+		}
+	}
+}
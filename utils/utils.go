@@ -1,12 +1,14 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"instant-messaging-app/broker"
 	"instant-messaging-app/config"
+	"instant-messaging-app/models"
 	"instant-messaging-app/types"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
@@ -14,16 +16,56 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 
 	"github.com/google/uuid"
-
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
-func GenerateJWT(user_id uint, username string) (string, error) {
+// Claims holds the application specific claims carried by a JWT, or the
+// equivalent for the session of a bot API key
+type Claims struct {
+	UserID       uint
+	Username     string
+	Role         string
+	TokenVersion uint
+	// APIKeyID, Scopes and RateLimit are set for the sessions of an API key
+	APIKeyID  uint
+	Scopes    []string
+	RateLimit int
+}
+
+// HasScope reports whether the session may perform the operations of scope.
+// Sessions opened with a password are not restricted.
+func (c Claims) HasScope(scope string) bool {
+	if c.APIKeyID == 0 {
+		return true
+	}
+	for _, granted := range c.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a copy of ctx carrying the claims of the session
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by WithClaims
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
+
+func GenerateJWT(user_id uint, username string, role string, tokenVersion uint) (string, error) {
 	// Create the Claims
 	claims := jwt.MapClaims{
 		"user_id": user_id,
 		"username": username,
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
+		"role": role,
+		"token_version": tokenVersion,
+		"exp": time.Now().Add(config.Cfg.JWT.TTL).Unix(),
 	}
 
 	// Génération du token JWT
@@ -37,20 +79,16 @@ func GenerateJWT(user_id uint, username string) (string, error) {
 	return tokenString, nil
 }
 
-// getJWTSecret fetches the JWT secret from environment variables
+// getJWTSecret fetches the JWT secret from the configuration
 func GetJWTSecret() []byte {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return []byte("default_secret")
-	}
-	return []byte(secret)
+	return []byte(config.Cfg.JWT.Secret)
 }
 
 // GenerateUniqueID creates a unique identifier for this instance
 func GenerateUniqueID() string {
 	hostname, err := os.Hostname()
 	if err != nil {
-		log.Printf("Failed to get hostname: %v", err)
+		slog.Warn("Failed to get hostname", "error", err)
 		hostname = "unknown"
 	}
 	return strings.ReplaceAll(uuid.New().String()+"_"+hostname, ":", "_")
@@ -62,6 +100,15 @@ func GenerateUUID() string {
 
 // validateJWT validates the JWT token and extracts the user ID
 func ValidateJWT(tokenString string) (uint, error) {
+	claims, err := ParseJWT(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+// ParseJWT validates the JWT token and extracts its claims
+func ParseJWT(tokenString string) (Claims, error) {
 	// Parse the token
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// Validate the signing method
@@ -71,22 +118,42 @@ func ValidateJWT(tokenString string) (uint, error) {
 		return GetJWTSecret(), nil
 	})
 	if err != nil {
-		return 0, errors.New("failed to parse token")
+		return Claims{}, errors.New("failed to parse token")
 	}
 
 	// Extract claims
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// Retrieve user ID from claims
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			return 0, errors.New("invalid claims: user_id not found")
-		}
-		return uint(userID), nil
+		return ClaimsFromMap(claims)
 	}
-	return 0, errors.New("invalid token")
+	return Claims{}, errors.New("invalid token")
 }
 
-func PublishNotification(exchangeName, routingKey, notificationType string, data interface{}) {
+// ClaimsFromMap converts the raw JWT claims into Claims. Tokens issued before
+// roles existed carry neither a role nor a token version and default to a
+// regular user at version 0.
+func ClaimsFromMap(claims jwt.MapClaims) (Claims, error) {
+	// Retrieve user ID from claims
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return Claims{}, errors.New("invalid claims: user_id not found")
+	}
+
+	username, _ := claims["username"].(string)
+	role, _ := claims["role"].(string)
+	if role == "" {
+		role = models.RoleUser
+	}
+	tokenVersion, _ := claims["token_version"].(float64)
+
+	return Claims{
+		UserID:       uint(userID),
+		Username:     username,
+		Role:         role,
+		TokenVersion: uint(tokenVersion),
+	}, nil
+}
+
+func PublishNotification(ctx context.Context, exchangeName, routingKey, notificationType string, data interface{}) {
 	// Create a typed notification
 	notification := types.Notification{
 		Type: notificationType,
@@ -96,26 +163,54 @@ func PublishNotification(exchangeName, routingKey, notificationType string, data
 	// Marshal the notification into JSON
 	body, err := json.Marshal(notification)
 	if err != nil {
-		log.Printf("Failed to marshal notification: %v", err)
+		slog.ErrorContext(ctx, "Failed to marshal notification", "type", notificationType, "error", err)
 		return
 	}
 
-	fmt.Println("Publishing message:", string(body))
+	// The body holds chat content and tokens; it is redacted unless log.redact is off
+	slog.DebugContext(ctx, "Publishing notification", "exchange", exchangeName, "routing_key", routingKey, "type", notificationType, "body", string(body))
 
 	// Publish the message to RabbitMQ
-	err = config.RabbitMQCh.Publish(
+	err = config.Broker.Publish(
+		ctx,
 		exchangeName, // Exchange name
 		routingKey,   // Routing key
-		false,        // Mandatory
-		false,        // Immediate
-		amqp.Publishing{
+		broker.Message{
 			ContentType: "application/json",
 			Body:        body,
 		},
 	)
 	if err != nil {
-		log.Printf("Failed to publish notification to exchange %s: %v", exchangeName, err)
+		slog.ErrorContext(ctx, "Failed to publish notification", "exchange", exchangeName, "routing_key", routingKey, "type", notificationType, "error", err)
 	} else {
-		log.Printf("Notification published to exchange %s with routing key %s", exchangeName, routingKey)
+		slog.DebugContext(ctx, "Notification published", "exchange", exchangeName, "routing_key", routingKey, "type", notificationType)
 	}
-}
\ No newline at end of file
+}
+// PublishError reports a failed request to the connection that sent it
+func PublishError(ctx context.Context, exchangeName, routingKey, code, message string) {
+	PublishNotification(ctx, exchangeName, routingKey, "error", types.ErrorResponse{
+		Code:  code,
+		Error: message,
+	})
+}
+
+const (
+	// DefaultPageSize is used when a paginated request does not specify a page size
+	DefaultPageSize = 20
+	// MaxPageSize caps the number of items returned in a single page
+	MaxPageSize = 100
+)
+
+// NormalizePagination clamps the requested page and page size to sane bounds
+func NormalizePagination(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	return page, pageSize
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/health"
+	"instant-messaging-app/logging"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/tracing"
+	"instant-messaging-app/types"
+	"instant-messaging-app/webhook/services"
+)
+
+// ConsumeEventsQueue listens to the broadcast notifications, records a
+// delivery of their events to the subscribed webhooks and wakes dispatcher
+func ConsumeEventsQueue(ctx context.Context, webhooks repositories.WebhookRepository, dispatcher *services.Dispatcher, queueName string) {
+	msgs, err := config.Broker.Consume(ctx, queueName)
+	if err != nil {
+		logging.Fatal("Failed to start consuming", "queue", queueName, "error", err)
+	}
+
+	consumer := health.TrackConsumer(queueName)
+	go func() {
+		defer consumer.Stop()
+		for {
+			consumer.Idle()
+			select {
+			case <-ctx.Done():
+				slog.Info("Stopping webhook events queue consumption", "queue", queueName)
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					slog.Warn("Consumer closed", "queue", queueName)
+					return
+				}
+				consumer.Busy()
+				msgCtx := tracing.StartTrackedConsume(ctx, consumer, queueName, msg)
+				var notification struct {
+					Type string          `json:"type"`
+					Data json.RawMessage `json:"data"`
+				}
+				if err := json.Unmarshal(msg.Body, &notification); err != nil {
+					slog.ErrorContext(msgCtx, "Failed to unmarshal notification", "error", err)
+					consumer.Fail()
+					continue
+				}
+
+				// Only stored messages are events; logouts concern the gateway
+				if notification.Type != "send_message_response" {
+					continue
+				}
+				var response types.SendMessageResponse
+				if err := json.Unmarshal(notification.Data, &response); err != nil {
+					slog.ErrorContext(msgCtx, "Failed to unmarshal sendMessage response", "error", err)
+					consumer.Fail()
+					continue
+				}
+				// Ephemeral answers of bots are not stored
+				if response.Ephemeral {
+					continue
+				}
+
+				queued, err := services.QueueMessageCreated(msgCtx, webhooks, response.Message)
+				if err != nil {
+					slog.ErrorContext(msgCtx, "Failed to queue webhook deliveries", "message_id", response.Message.ID, "error", err)
+					consumer.Fail()
+					continue
+				}
+				if queued > 0 {
+					slog.DebugContext(msgCtx, "Queued webhook deliveries", "message_id", response.Message.ID, "deliveries", queued)
+					dispatcher.Wake()
+				}
+			}
+		}
+	}()
+}
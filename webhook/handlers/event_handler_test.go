@@ -0,0 +1,123 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"instant-messaging-app/broker"
+	"instant-messaging-app/config"
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/models"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+)
+
+// names numbers the users of the tests, so that every test gets its own
+var names atomic.Int64
+
+// newWebhook stores a user whose username starts with name and role, and a
+// webhook of theirs
+func newWebhook(t *testing.T, name, role string, allUsers bool) (models.User, models.Webhook) {
+	t.Helper()
+	ctx := context.Background()
+	user := models.User{Username: fmt.Sprintf("%s%d", name, names.Add(1)), Password: "-", Role: role}
+	if err := repos.Users.Create(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	webhook := models.Webhook{UserID: user.ID, URL: "https://example.com/" + user.Username, Secret: "whsec_test", AllUsers: allUsers}
+	if err := repos.Webhooks.Create(ctx, &webhook); err != nil {
+		t.Fatal(err)
+	}
+	return user, webhook
+}
+
+// publish publishes a notification on the broadcast exchange
+func publish(t *testing.T, notificationType string, data interface{}) {
+	t.Helper()
+	utils.PublishNotification(context.Background(), broadcast, "", notificationType, data)
+}
+
+// queued returns the IDs of the messages of the deliveries queued for webhook
+func queued(t *testing.T, webhook models.Webhook) []uint {
+	t.Helper()
+	deliveries, _, err := repos.Webhooks.ListDeliveries(context.Background(), webhook.ID, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]uint, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		var payload struct {
+			Event string                    `json:"event"`
+			Data  types.MessageCreatedEvent `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(delivery.Payload), &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.Event != models.EventMessageCreated || delivery.Status != models.DeliveryPending {
+			t.Fatalf("unexpected delivery %+v", delivery)
+		}
+		ids = append(ids, payload.Data.Message.ID)
+	}
+	return ids
+}
+
+// waitQueued waits until webhook has a delivery queued for the message id
+func waitQueued(t *testing.T, webhook models.Webhook, id uint) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for _, queuedID := range queued(t, webhook) {
+			if queuedID == id {
+				return
+			}
+		}
+	}
+	t.Fatalf("no delivery of message %d to webhook %d", id, webhook.ID)
+}
+
+func TestConsumeEventsQueue(t *testing.T) {
+	alice, aliceHook := newWebhook(t, "alice", models.RoleUser, false)
+	bob, bobHook := newWebhook(t, "bob", models.RoleUser, false)
+	_, carolHook := newWebhook(t, "carol", models.RoleUser, false)
+	_, adminHook := newWebhook(t, "admin", models.RoleAdmin, true)
+	_, disabledHook := newWebhook(t, "dave", models.RoleUser, false)
+	if err := repos.Webhooks.Update(context.Background(), &disabledHook, map[string]interface{}{"disabled_at": time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	// A demoted admin no longer gets the events of every user, nor does a
+	// deactivated one
+	_, demotedHook := newWebhook(t, "erin", models.RoleUser, true)
+	former, formerHook := newWebhook(t, "frank", models.RoleAdmin, true)
+	if err := config.DB.Model(&former).Update("deactivated_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither the ephemeral answers, the other notifications nor the
+	// malformed ones are events
+	publish(t, "send_message_response", types.SendMessageResponse{Message: dtos.MessageDTO{ID: 1, SenderID: alice.ID, ReceiverID: bob.ID}, Ephemeral: true})
+	publish(t, "logout", map[string]uint{"user_id": alice.ID})
+	if err := config.Broker.Publish(context.Background(), broadcast, "", broker.Message{ContentType: "application/json", Body: []byte("{")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A message is delivered to the webhooks of its sender and receiver,
+	// and to the enabled webhooks of every user
+	message := dtos.MessageDTO{ID: 2, SenderID: alice.ID, ReceiverID: bob.ID, Content: "hello"}
+	publish(t, "send_message_response", types.SendMessageResponse{Message: message})
+	for _, webhook := range []models.Webhook{aliceHook, bobHook, adminHook} {
+		waitQueued(t, webhook, message.ID)
+	}
+	for _, webhook := range []models.Webhook{aliceHook, bobHook} {
+		if ids := queued(t, webhook); len(ids) != 1 {
+			t.Fatalf("expected a single delivery to webhook %d, got the messages %v", webhook.ID, ids)
+		}
+	}
+	for _, webhook := range []models.Webhook{carolHook, disabledHook, demotedHook, formerHook} {
+		if ids := queued(t, webhook); len(ids) != 0 {
+			t.Fatalf("expected no delivery to webhook %d, got the messages %v", webhook.ID, ids)
+		}
+	}
+}
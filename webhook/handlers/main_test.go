@@ -0,0 +1,70 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"instant-messaging-app/broker"
+	"instant-messaging-app/config"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/webhook/handlers"
+	"instant-messaging-app/webhook/services"
+)
+
+// The broadcast exchange the message service publishes the messages to, and
+// the queue of the webhook service bound to it
+const (
+	broadcast   = "notifications_broadcast"
+	eventsQueue = "webhookEvents"
+)
+
+// repos are the repositories the handler runs on
+var repos repositories.Repositories
+
+// TestMain runs the events handler on a SQLite database and an in-process
+// broker. Its dispatcher is not run: the tests look at the queued deliveries.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "webhook-handlers-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cfg := config.Default()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = filepath.Join(dir, "webhooks.db")
+	config.Cfg = cfg
+	config.InitDatabase()
+	config.Broker = broker.NewMemoryBroker()
+	if err := declare(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repos = repositories.NewGormRepositories(config.DB)
+	handlers.ConsumeEventsQueue(ctx, repos.Webhooks, services.NewDispatcher(repos.Webhooks), eventsQueue)
+
+	code := m.Run()
+	cancel()
+	config.Broker.Close()
+	if sqlDB, err := config.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// declare declares the broadcast exchange and the events queue bound to it
+func declare() error {
+	if err := config.Broker.DeclareExchange(broadcast, broker.ExchangeFanout); err != nil {
+		return err
+	}
+	if err := config.Broker.DeclareQueue(eventsQueue, broker.QueueOptions{}); err != nil {
+		return err
+	}
+	return config.Broker.BindQueue(eventsQueue, broadcast, "")
+}
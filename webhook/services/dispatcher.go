@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/metrics"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/utils"
+)
+
+// Headers of a delivery. The signature is the hex HMAC-SHA256, keyed with the
+// secret of the webhook, of the timestamp, a dot and the body.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign returns the X-Webhook-Signature of a body sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay before the next attempt of a delivery that
+// failed attempts times: webhooks.min_backoff, doubled after each attempt up
+// to webhooks.max_backoff
+func Backoff(attempts int) time.Duration {
+	delay := config.Cfg.Webhooks.MinBackoff
+	for i := 1; i < attempts && delay < config.Cfg.Webhooks.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > config.Cfg.Webhooks.MaxBackoff {
+		delay = config.Cfg.Webhooks.MaxBackoff
+	}
+	return delay
+}
+
+// Dispatcher sends the due deliveries, retries the failed ones and disables
+// the webhooks that keep failing. Several daemons may share the database:
+// each delivery is claimed before it is sent.
+type Dispatcher struct {
+	webhooks repositories.WebhookRepository
+	client   *http.Client
+	wake     chan struct{}
+}
+
+// NewDispatcher returns a Dispatcher of the deliveries stored in webhooks
+func NewDispatcher(webhooks repositories.WebhookRepository) *Dispatcher {
+	return &Dispatcher{
+		webhooks: webhooks,
+		client:   newHTTPClient(config.Cfg.Webhooks.AllowPrivate),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Wake makes the dispatcher look for due deliveries without waiting for the
+// next webhooks.poll_interval
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends the due deliveries until ctx is canceled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(config.Cfg.Webhooks.PollInterval)
+	defer ticker.Stop()
+	for {
+		d.dispatchDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// dispatchDue sends the due deliveries, webhooks.concurrency at a time
+func (d *Dispatcher) dispatchDue(ctx context.Context) {
+	concurrency := config.Cfg.Webhooks.Concurrency
+	due, err := d.webhooks.ListDue(ctx, time.Now(), 4*concurrency)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Failed to list the due webhook deliveries", "error", err)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for i := range due {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(delivery models.WebhookDelivery) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			d.attempt(ctx, delivery)
+		}(due[i])
+	}
+	wg.Wait()
+}
+
+// attempt sends a delivery once and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery models.WebhookDelivery) {
+	// The lease outlasts the request, so the delivery is only sent again if
+	// this daemon stopped before recording the outcome
+	now := time.Now()
+	claimed, err := d.webhooks.Claim(ctx, &delivery, now, now.Add(2*config.Cfg.Webhooks.Timeout))
+	if err != nil || !claimed {
+		return
+	}
+	webhook, err := d.webhooks.FindByID(ctx, delivery.WebhookID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load the webhook of a delivery", "delivery_id", delivery.ID, "error", err)
+		return
+	}
+
+	logger := slog.With("webhook_id", webhook.ID, "delivery_id", delivery.ID, "event", delivery.Event)
+	statusCode, sendErr := d.send(ctx, webhook, delivery)
+	if ctx.Err() != nil {
+		// Shutting down: the lease expires and the attempt is made again
+		return
+	}
+
+	delivery.Attempts++
+	fields := map[string]interface{}{
+		"attempts":         delivery.Attempts,
+		"last_status_code": statusCode,
+		"last_error":       "",
+		"next_attempt_at":  nil,
+	}
+	var outcome string
+	switch {
+	case sendErr == nil:
+		outcome = models.DeliverySucceeded
+		fields["status"] = models.DeliverySucceeded
+		fields["delivered_at"] = time.Now()
+		logger.DebugContext(ctx, "Webhook delivered", "status_code", statusCode)
+	case delivery.Attempts >= config.Cfg.Webhooks.MaxAttempts:
+		outcome = models.DeliveryFailed
+		fields["status"] = models.DeliveryFailed
+		fields["last_error"] = sendErr.Error()
+		logger.WarnContext(ctx, "Webhook delivery failed for good", "attempts", delivery.Attempts, "error", sendErr)
+	default:
+		outcome = "retried"
+		fields["last_error"] = sendErr.Error()
+		fields["next_attempt_at"] = time.Now().Add(Backoff(delivery.Attempts))
+		logger.InfoContext(ctx, "Webhook delivery failed, retrying", "attempts", delivery.Attempts, "error", sendErr)
+	}
+	if err := d.webhooks.UpdateDelivery(ctx, &delivery, fields); err != nil {
+		logger.ErrorContext(ctx, "Failed to record a webhook delivery", "error", err)
+		return
+	}
+	metrics.WebhookDeliveries.WithLabelValues(outcome).Inc()
+
+	switch outcome {
+	case models.DeliverySucceeded:
+		err = d.webhooks.RecordSuccess(ctx, webhook.ID)
+	case models.DeliveryFailed:
+		var disabled bool
+		disabled, err = d.webhooks.RecordFailure(ctx, webhook.ID, config.Cfg.Webhooks.DisableAfter)
+		if disabled {
+			logger.WarnContext(ctx, "Webhook disabled after repeated failures", "failures", config.Cfg.Webhooks.DisableAfter)
+		}
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to record the outcome of a webhook delivery", "error", err)
+	}
+}
+
+// send posts the payload of a delivery and returns the status code of the
+// response; statuses other than 2xx are errors
+func (d *Dispatcher) send(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Cfg.Webhooks.Timeout)
+	defer cancel()
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "instant-messaging-app-webhooks")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// errPrivateAddress rejects the endpoints on loopback and private networks,
+// which would let users reach the internal services
+var errPrivateAddress = errors.New("webhook URL resolves to a private address")
+
+// newHTTPClient returns the client of the deliveries. It does not follow
+// redirects and, unless allowPrivate, refuses to connect to private addresses
+// whatever the URL resolves to.
+func newHTTPClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || utils.IsPrivateAddress(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would connect to the endpoint instead of the guarded dialer
+	transport.Proxy = nil
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"instant-messaging-app/config"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/utils"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "webhook-services-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cfg := config.Default()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = filepath.Join(dir, "webhooks.db")
+	// The endpoints are httptest servers on the loopback
+	cfg.Webhooks.AllowPrivate = true
+	cfg.Webhooks.MaxAttempts = 2
+	cfg.Webhooks.MinBackoff = time.Millisecond
+	cfg.Webhooks.MaxBackoff = time.Millisecond
+	cfg.Webhooks.DisableAfter = 2
+	config.Cfg = cfg
+	config.InitDatabase()
+
+	code := m.Run()
+	if sqlDB, err := config.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newWebhook stores a webhook of a new user posting to url
+func newWebhook(t *testing.T, url string) models.Webhook {
+	t.Helper()
+	user := models.User{Username: "webhook-owner-" + utils.GenerateUUID(), Password: "-"}
+	if err := config.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	webhook := models.Webhook{UserID: user.ID, URL: url, Secret: "whsec_test"}
+	if err := config.DB.Create(&webhook).Error; err != nil {
+		t.Fatal(err)
+	}
+	return webhook
+}
+
+// newDelivery stores a delivery to webhook due now
+func newDelivery(t *testing.T, webhook models.Webhook) models.WebhookDelivery {
+	t.Helper()
+	now := time.Now()
+	delivery := models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		Event:         models.EventMessageCreated,
+		EventID:       utils.GenerateUUID(),
+		Payload:       `{"event":"message_created"}`,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if err := repositories.NewWebhookRepository(config.DB).CreateDeliveries(context.Background(), []models.WebhookDelivery{delivery}); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DB.Where("event_id = ?", delivery.EventID).First(&delivery).Error; err != nil {
+		t.Fatal(err)
+	}
+	return delivery
+}
+
+// deliver makes the attempts of a delivery until it succeeds or fails for
+// good, and returns it as stored
+func deliver(t *testing.T, d *Dispatcher, delivery models.WebhookDelivery) models.WebhookDelivery {
+	t.Helper()
+	for i := 0; i < config.Cfg.Webhooks.MaxAttempts; i++ {
+		d.attempt(context.Background(), delivery)
+		if err := config.DB.First(&delivery, delivery.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if delivery.Status != models.DeliveryPending {
+			return delivery
+		}
+		time.Sleep(2 * config.Cfg.Webhooks.MaxBackoff)
+	}
+	t.Fatalf("the delivery is still pending after %d attempts", config.Cfg.Webhooks.MaxAttempts)
+	return delivery
+}
+
+// reload returns a webhook as stored
+func reload(t *testing.T, webhook models.Webhook) models.Webhook {
+	t.Helper()
+	if err := config.DB.First(&webhook, webhook.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return webhook
+}
+
+func TestDispatcherSignsDeliveries(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+	}))
+	defer server.Close()
+	webhook := newWebhook(t, server.URL)
+	delivery := newDelivery(t, webhook)
+
+	delivered := deliver(t, NewDispatcher(repositories.NewWebhookRepository(config.DB)), delivery)
+	if delivered.Status != models.DeliverySucceeded || delivered.Attempts != 1 || delivered.LastStatusCode != http.StatusOK || delivered.DeliveredAt == nil {
+		t.Fatalf("unexpected delivery %+v", delivered)
+	}
+
+	// The receiver checks the signature without the code of the daemon
+	req, body := <-requests, <-bodies
+	if string(body) != delivery.Payload {
+		t.Fatalf("expected the payload %s, got %s", delivery.Payload, body)
+	}
+	if req.Header.Get(HeaderEvent) != models.EventMessageCreated || req.Header.Get(HeaderDelivery) != strconv.FormatUint(uint64(delivery.ID), 10) {
+		t.Fatalf("unexpected headers %v", req.Header)
+	}
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write([]byte(req.Header.Get(HeaderTimestamp) + "."))
+	mac.Write(body)
+	if expected := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.Header.Get(HeaderSignature) != expected {
+		t.Fatalf("expected the signature %s, got %s", expected, req.Header.Get(HeaderSignature))
+	}
+}
+
+func TestDispatcherRetriesThenFails(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	webhook := newWebhook(t, server.URL)
+	d := NewDispatcher(repositories.NewWebhookRepository(config.DB))
+
+	// The first failure schedules a retry
+	delivery := newDelivery(t, webhook)
+	d.attempt(context.Background(), delivery)
+	if err := config.DB.First(&delivery, delivery.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != 1 || delivery.NextAttemptAt == nil || delivery.LastError == "" {
+		t.Fatalf("expected a retry, got %+v", delivery)
+	}
+	if webhook := reload(t, webhook); webhook.FailureCount != 0 {
+		t.Fatalf("a retried delivery counted as a failure: %+v", webhook)
+	}
+
+	// The last attempt fails the delivery for good
+	time.Sleep(2 * config.Cfg.Webhooks.MaxBackoff)
+	failed := deliver(t, d, delivery)
+	if failed.Status != models.DeliveryFailed || failed.Attempts != 2 || failed.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("unexpected delivery %+v", failed)
+	}
+	if attempts.Load() != 2 {
+		t.Fatalf("expected 2 requests, got %d", attempts.Load())
+	}
+	if webhook := reload(t, webhook); webhook.FailureCount != 1 || !webhook.IsEnabled() {
+		t.Fatalf("unexpected webhook %+v", webhook)
+	}
+}
+
+func TestDispatcherDisablesFailingWebhooks(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+	webhook := newWebhook(t, server.URL)
+	d := NewDispatcher(repositories.NewWebhookRepository(config.DB))
+
+	for i := 1; i <= config.Cfg.Webhooks.DisableAfter; i++ {
+		if webhook := reload(t, webhook); !webhook.IsEnabled() {
+			t.Fatalf("the webhook was disabled after %d failures", i-1)
+		}
+		deliver(t, d, newDelivery(t, webhook))
+	}
+	webhook = reload(t, webhook)
+	if webhook.IsEnabled() || webhook.FailureCount != config.Cfg.Webhooks.DisableAfter {
+		t.Fatalf("expected the webhook to be disabled, got %+v", webhook)
+	}
+
+	// A success in between resets the count
+	other := newWebhook(t, server.URL)
+	deliver(t, d, newDelivery(t, other))
+	status.Store(http.StatusOK)
+	deliver(t, d, newDelivery(t, other))
+	status.Store(http.StatusServiceUnavailable)
+	deliver(t, d, newDelivery(t, other))
+	if other := reload(t, other); !other.IsEnabled() || other.FailureCount != 1 {
+		t.Fatalf("expected the webhook to stay enabled, got %+v", other)
+	}
+}
+
+func TestDispatcherDoesNotFollowRedirects(t *testing.T) {
+	var followed atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/elsewhere", func(w http.ResponseWriter, r *http.Request) {
+		followed.Store(true)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	delivered := deliver(t, NewDispatcher(repositories.NewWebhookRepository(config.DB)), newDelivery(t, newWebhook(t, server.URL+"/hook")))
+	if delivered.Status != models.DeliveryFailed || delivered.LastStatusCode != http.StatusTemporaryRedirect {
+		t.Fatalf("expected the redirect to fail the delivery, got %+v", delivered)
+	}
+	if followed.Load() {
+		t.Fatal("the redirect was followed")
+	}
+}
+
+func TestHTTPClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := newHTTPClient(false).Get(server.URL)
+	if !errors.Is(err, errPrivateAddress) {
+		t.Fatalf("expected the loopback to be refused, got %v", err)
+	}
+	resp, err := newHTTPClient(true).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestBackoff(t *testing.T) {
+	saved := config.Cfg.Webhooks
+	defer func() { config.Cfg.Webhooks = saved }()
+	config.Cfg.Webhooks.MinBackoff = time.Second
+	config.Cfg.Webhooks.MaxBackoff = 5 * time.Second
+
+	for attempts, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if delay := Backoff(attempts); delay != expected {
+			t.Errorf("Backoff(%d) = %s, expected %s", attempts, delay, expected)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"instant-messaging-app/dtos"
+	"instant-messaging-app/models"
+	"instant-messaging-app/repositories"
+	"instant-messaging-app/types"
+	"instant-messaging-app/utils"
+)
+
+// QueueMessageCreated records a message_created delivery for every webhook
+// of the sender, of the receiver and of every user, and returns how many
+func QueueMessageCreated(ctx context.Context, webhooks repositories.WebhookRepository, message dtos.MessageDTO) (int, error) {
+	return queueEvent(ctx, webhooks, models.EventMessageCreated, []uint{message.SenderID, message.ReceiverID}, types.MessageCreatedEvent{
+		Message: message,
+	})
+}
+
+// queueEvent records a pending delivery of an event to each enabled webhook
+// subscribed to it that receives the events of userIDs
+func queueEvent(ctx context.Context, webhooks repositories.WebhookRepository, event string, userIDs []uint, data interface{}) (int, error) {
+	subscribers, err := webhooks.ListSubscribers(ctx, userIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	eventID := utils.GenerateUUID()
+	payload, err := json.Marshal(types.WebhookPayload{
+		ID:        eventID,
+		Event:     event,
+		CreatedAt: now,
+		Data:      data,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, webhook := range subscribers {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         event,
+			EventID:       eventID,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+		})
+	}
+	return len(deliveries), webhooks.CreateDeliveries(ctx, deliveries)
+}